@@ -7,6 +7,7 @@ import (
 	"fmt"
 
 	"github.com/accuknox/accuknox-cli-v2/pkg/aibom"
+	"github.com/accuknox/accuknox-cli-v2/pkg/version"
 	"github.com/spf13/cobra"
 )
 
@@ -20,7 +21,21 @@ var aibomCmd = &cobra.Command{
 inventories AI/ML model components including architecture, training datasets,
 performance metrics, and licensing information.
 
-Model metadata is fetched from the HuggingFace Hub API.`,
+Model metadata is fetched from the HuggingFace Hub API, AWS Bedrock, Azure
+OpenAI, Vertex AI, Ollama or any OpenAI-compatible server, or read from model
+files on disk.
+
+Every AIBOM records its provenance in metadata: the knoxctl version, the
+hash of the local model or source tree, the commit SHA of each HuggingFace
+model, and the options used. With --deterministic components are sorted, the
+serial number is derived from the content and the timestamp is taken from
+$SOURCE_DATE_EPOCH (or omitted), so two runs over the same input produce
+byte-identical AIBOMs.
+
+With --attest the AIBOM is also wrapped in a signed in-toto attestation
+(<out>.intoto.jsonl). Its subject is the hash of the local model or scanned
+source tree; for hosted models it is the AIBOM file unless --attest-subject
+names the artifact. Check it with "knoxctl sign verify-attestation".`,
 }
 
 var aibomGenerateCmd = &cobra.Command{
@@ -30,11 +45,21 @@ var aibomGenerateCmd = &cobra.Command{
 CycloneDX 1.6 AIBOM documenting the model's architecture, training datasets,
 performance metrics, licensing, and external references.
 
+The model's provenance is resolved recursively: each base_model named in a
+model card is fetched and added as a component, linked through the CycloneDX
+dependency graph and the descendant's pedigree, up to --lineage-depth
+generations. Training datasets are resolved for license and revision.
+
+Pickle-based weight files in the repository are recorded as an unsafe
+serialization risk; use --fail-on-unsafe-weights to reject models that only
+ship pickle weights.
+
 Examples:
   knoxctl aibom generate --model google-bert/bert-base-uncased
   knoxctl aibom generate --model meta-llama/Llama-2-7b-hf --token $HF_TOKEN
   knoxctl aibom generate --model openai-community/gpt2 --format table
-  knoxctl aibom generate --model mistralai/Mistral-7B-v0.1 --out aibom.json`,
+  knoxctl aibom generate --model mistralai/Mistral-7B-v0.1 --out aibom.json
+  knoxctl aibom generate --model acme/llama-3-ft --lineage-depth 2`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		bom, err := aibom.Generate(&aibomOpts)
 		if err != nil {
@@ -47,10 +72,21 @@ Examples:
 
 var aibomBedrockCmd = &cobra.Command{
 	Use:   "bedrock",
-	Short: "Generate AIBOM from AWS Bedrock foundation models",
+	Short: "Generate AIBOM from AWS Bedrock models and guardrails",
 	Long: `Fetch model metadata from AWS Bedrock and produce a CycloneDX 1.6 AIBOM
 documenting foundation models' capabilities, modalities, and licensing.
 
+The account's own AI resources are inventoried as well:
+
+  - custom models (fine-tuning, continued pre-training, distillation), linked
+    to their base model and to their S3 training and validation data
+  - imported models (Custom Model Import)
+  - provisioned throughputs, linked to the model they serve
+  - guardrails, with their content, topic, word and PII policies as properties
+
+Listing these needs further IAM permissions; a resource type that cannot be
+listed is reported as a warning. Use --foundation-only to skip them.
+
 By default the standard AWS credential chain is used (environment variables,
 ~/.aws/credentials, IAM instance role).  Supply --access-key-id and
 --secret-access-key to use explicit credentials instead.
@@ -58,6 +94,8 @@ By default the standard AWS credential chain is used (environment variables,
 Examples:
   knoxctl aibom bedrock --region us-east-1
   knoxctl aibom bedrock --region us-east-1 --model anthropic.claude-3-sonnet-20240229-v1:0
+  knoxctl aibom bedrock --region us-east-1 --model my-fine-tuned-model
+  knoxctl aibom bedrock --region us-east-1 --foundation-only
   knoxctl aibom bedrock --region us-east-1 --access-key-id AKIA... --secret-access-key ...`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Inherit shared metadata overrides from the persistent aibomCmd flags.
@@ -76,10 +114,165 @@ Examples:
 	},
 }
 
+// newAibomProviderCmd builds the subcommand for a model provider registered in
+// pkg/aibom. Provider-specific flags are added by the caller.
+func newAibomProviderCmd(provider, short, long string, opts *aibom.ProviderOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   provider,
+		Short: short,
+		Long:  long,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Provider = provider
+			opts.Name = aibomOpts.Name
+			opts.Version = aibomOpts.Version
+			opts.Manufacturer = aibomOpts.Manufacturer
+
+			bom, err := aibom.GenerateFromProvider(opts)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Found %d AI/ML model component(s)\n", aibom.ModelCount(bom))
+			return aibom.Output(bom, &aibomOpts)
+		},
+	}
+}
+
+var azureOpenAIOpts aibom.ProviderOptions
+
+var aibomAzureOpenAICmd = newAibomProviderCmd("azure-openai",
+	"Generate AIBOM from Azure OpenAI deployments",
+	`List the deployments of an Azure OpenAI resource and produce a CycloneDX
+AIBOM with one model component per deployment, linked to the OpenAI model it
+serves. Capabilities, lifecycle status and deprecation dates are read from the
+resource's model catalog.
+
+Authentication uses --api-key (or AZURE_OPENAI_API_KEY); without a key a
+Microsoft Entra ID token is obtained from the default Azure credential chain.
+
+Examples:
+  knoxctl aibom azure-openai --endpoint https://my-resource.openai.azure.com
+  knoxctl aibom azure-openai --endpoint https://my-resource.openai.azure.com --model chat-prod`,
+	&azureOpenAIOpts)
+
+var vertexOpts aibom.ProviderOptions
+
+var aibomVertexCmd = newAibomProviderCmd("vertexai",
+	"Generate AIBOM from the Vertex AI Model Registry",
+	`List the models in a Vertex AI Model Registry location and produce a
+CycloneDX AIBOM documenting each model's version, artifacts, serving container,
+deployed endpoints and Model Garden base model.
+
+Authentication uses --token or Google Application Default Credentials.
+
+Examples:
+  knoxctl aibom vertexai --project my-project --region europe-west4
+  knoxctl aibom vertexai --project my-project --model 1234567890`,
+	&vertexOpts)
+
+var ollamaOpts aibom.ProviderOptions
+
+var aibomOllamaCmd = newAibomProviderCmd("ollama",
+	"Generate AIBOM from a local Ollama server",
+	`List the models pulled into an Ollama server and produce a CycloneDX AIBOM
+documenting each model's digest, family, parameter size, quantisation, context
+length and license. The endpoint defaults to OLLAMA_HOST or
+http://localhost:11434.
+
+Examples:
+  knoxctl aibom ollama
+  knoxctl aibom ollama --endpoint http://gpu-box:11434 --model llama3.1:8b`,
+	&ollamaOpts)
+
+var openAIOpts aibom.ProviderOptions
+
+var aibomOpenAICmd = newAibomProviderCmd("openai",
+	"Generate AIBOM from an OpenAI-compatible API",
+	`List the models served through the OpenAI API, or any server implementing
+its /models endpoint (vLLM, LM Studio, LocalAI, llama.cpp), and produce a
+CycloneDX AIBOM. LoRA adapters served by vLLM are linked to their base model.
+
+The API key defaults to OPENAI_API_KEY.
+
+Examples:
+  knoxctl aibom openai
+  knoxctl aibom openai --endpoint http://localhost:8000/v1
+  knoxctl aibom openai --endpoint http://localhost:8000/v1 --model meta-llama/Llama-3.1-8B-Instruct`,
+	&openAIOpts)
+
+var aibomLocalCmd = &cobra.Command{
+	Use:   "local",
+	Short: "Generate AIBOM from model files on disk",
+	Long: `Inspect a local model directory (or a single weight file) and produce a
+CycloneDX AIBOM without any network access. The following are read:
+
+  - safetensors headers: tensor dtypes and parameter counts
+  - GGUF metadata: architecture, context length, quantisation, license
+  - ONNX graphs: producer, opset, operators and initializer parameter counts
+  - config.json: architecture, vocabulary and layer sizes
+  - README.md front-matter: license, task, datasets, base model, evaluation results
+
+Every weight file is hashed (SHA-256) and listed as a nested file component.
+Pickle-based weights (.bin, .pt, .pkl, .ckpt) are scanned statically for
+imports that can execute code on load (os.system, subprocess, builtins.eval).
+
+Examples:
+  knoxctl aibom local --path ./bert-base-uncased
+  knoxctl aibom local --path ./llama-2-7b.Q4_K_M.gguf --format table
+  knoxctl aibom local --path ./model --name my-classifier --version 1.2.0 --out aibom.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bom, err := aibom.GenerateFromLocal(&aibomOpts)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Found %d AI/ML model component(s)\n", aibom.ModelCount(bom))
+		return aibom.Output(bom, &aibomOpts)
+	},
+}
+
+var aibomScanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "Generate one AIBOM for every model an application uses",
+	Long: `Discover the models an application references and produce a single
+CycloneDX AIBOM with the application as the subject, every model as a
+component, and a dependency graph from the application to each model and its
+base models.
+
+References are found in source and config files under --path:
+
+  - transformers: from_pretrained("..."), pipeline(model="...")
+  - huggingface_hub: hf_hub_download / snapshot_download repo IDs
+  - LangChain and LlamaIndex model classes (ChatOpenAI, HuggingFaceEmbeddings, ...)
+  - Bedrock model IDs in boto3 / AWS SDK calls (modelId="...")
+  - model / model_id / base_model keys in YAML, JSON, TOML and .env files
+
+--models-file lists additional models, one per line. A "provider:" prefix
+(e.g. "openai:gpt-4o") names models not hosted on HuggingFace; Bedrock IDs are
+recognised automatically.
+
+Examples:
+  knoxctl aibom scan --path ./my-rag-app
+  knoxctl aibom scan --models-file models.txt --token $HF_TOKEN
+  knoxctl aibom scan --path . --models-file models.txt --name my-app --version 1.4.0 --out aibom.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bom, err := aibom.Scan(&aibomOpts)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Found %d AI/ML model component(s)\n", aibom.ModelCount(bom))
+		return aibom.Output(bom, &aibomOpts)
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(aibomCmd)
 	aibomCmd.AddCommand(aibomGenerateCmd)
 	aibomCmd.AddCommand(aibomBedrockCmd)
+	aibomCmd.AddCommand(aibomLocalCmd)
+	aibomCmd.AddCommand(aibomScanCmd)
+	aibomCmd.AddCommand(aibomAzureOpenAICmd)
+	aibomCmd.AddCommand(aibomVertexCmd)
+	aibomCmd.AddCommand(aibomOllamaCmd)
+	aibomCmd.AddCommand(aibomOpenAICmd)
 
 	// Required: HuggingFace model identifier
 	aibomGenerateCmd.Flags().StringVar(&aibomOpts.ModelID, "model", "", "HuggingFace model identifier (e.g. google-bert/bert-base-uncased)")
@@ -87,6 +280,18 @@ func init() {
 
 	// Optional: auth token for private models
 	aibomGenerateCmd.Flags().StringVar(&aibomOpts.Token, "token", "", "HuggingFace API token (required for private/gated models)")
+	aibomGenerateCmd.Flags().IntVar(&aibomOpts.LineageDepth, "lineage-depth", aibom.DefaultLineageDepth, "Number of base_model generations to resolve from the Hub (0 disables)")
+
+	// Local model inspection
+	aibomLocalCmd.Flags().StringVar(&aibomOpts.Path, "path", "", "Local model directory or weight file")
+	_ = aibomLocalCmd.MarkFlagRequired("path")
+
+	// Application scan
+	aibomScanCmd.Flags().StringVar(&aibomOpts.Path, "path", "", "Source tree to search for model references")
+	aibomScanCmd.Flags().StringVar(&aibomOpts.ModelsFile, "models-file", "", "File listing model IDs, one per line")
+	aibomScanCmd.Flags().StringVar(&aibomOpts.Token, "token", "", "HuggingFace API token (required for private/gated models)")
+	aibomScanCmd.Flags().IntVar(&aibomOpts.LineageDepth, "lineage-depth", aibom.DefaultLineageDepth, "Number of base_model generations to resolve from the Hub (0 disables)")
+	aibomScanCmd.MarkFlagsOneRequired("path", "models-file")
 
 	// Metadata overrides (persistent so future sub-commands inherit them)
 	aibomCmd.PersistentFlags().StringVar(&aibomOpts.Name, "name", "", "Override model name in the AIBOM output")
@@ -96,6 +301,11 @@ func init() {
 	// Output flags
 	aibomCmd.PersistentFlags().StringVar(&aibomOpts.OutputTo, "out", "", "Write AIBOM JSON to this file")
 	aibomCmd.PersistentFlags().StringVar(&aibomOpts.Format, "format", "json", `Output format: "json" or "table"`)
+	aibomCmd.PersistentFlags().BoolVar(&aibomOpts.Deterministic, "deterministic", false, "Produce byte-identical output for identical input (sorted components, content-derived serial number, timestamp from $SOURCE_DATE_EPOCH)")
+	aibomOpts.ToolVersion = version.GitSummary
+
+	// Serialization safety
+	aibomCmd.PersistentFlags().BoolVar(&aibomOpts.FailOnUnsafe, "fail-on-unsafe-weights", false, "Fail when pickle weights contain dangerous imports or have no safetensors alternative")
 
 	// Bedrock-specific flags
 	aibomBedrockCmd.Flags().StringVar(&bedrockOpts.Region, "region", "", "AWS region (e.g. us-east-1)")
@@ -104,13 +314,39 @@ func init() {
 	aibomBedrockCmd.Flags().StringVar(&bedrockOpts.AccessKeyID, "access-key-id", "", "AWS access key ID (leave empty to use default credential chain)")
 	aibomBedrockCmd.Flags().StringVar(&bedrockOpts.SecretAccessKey, "secret-access-key", "", "AWS secret access key")
 	aibomBedrockCmd.Flags().StringVar(&bedrockOpts.SessionToken, "session-token", "", "AWS session token (optional)")
-	aibomBedrockCmd.Flags().StringVar(&bedrockOpts.ModelID, "model", "", "Inventory a single Bedrock model ID (empty = all available models)")
+	aibomBedrockCmd.Flags().StringVar(&bedrockOpts.ModelID, "model", "", "Inventory a single foundation model ID or custom/imported/provisioned model name or ARN (empty = all models)")
+	aibomBedrockCmd.Flags().BoolVar(&bedrockOpts.FoundationOnly, "foundation-only", false, "Inventory foundation models only, skipping custom, imported and provisioned models and guardrails")
 
 	// Signing flags (only meaningful when --out is set)
 	aibomCmd.PersistentFlags().BoolVar(&aibomOpts.Sign.Enabled, "sign", false, "Sign the output artifact with cosign after generation")
 	aibomCmd.PersistentFlags().BoolVar(&aibomOpts.Sign.GenerateKey, "sign-generate-key", false, "Generate a new ECDSA P-256 key pair before signing")
-	aibomCmd.PersistentFlags().StringVar(&aibomOpts.Sign.KeyRef, "sign-key", "", "Path to existing cosign private key (default: cosign.key)")
+	aibomCmd.PersistentFlags().StringVar(&aibomOpts.Sign.KeyRef, "sign-key", "", "Signing key: cosign PEM file, env://VAR, or awskms://, gcpkms://, azurekms://, hashivault://, pkcs11: reference (default: cosign.key)")
 	aibomCmd.PersistentFlags().StringVar(&aibomOpts.Sign.KeyOut, "sign-key-out", "cosign", "Filename prefix for generated key pair (produces <prefix>.key / <prefix>.pub)")
-	aibomCmd.PersistentFlags().StringVar(&aibomOpts.Sign.Password, "sign-key-password", "", "Passphrase for the signing key (empty = no passphrase)")
+	aibomCmd.PersistentFlags().StringVar(&aibomOpts.Sign.Password, "sign-key-password", "", "Passphrase for a PEM signing key (default: $COSIGN_PASSWORD)")
 	aibomCmd.PersistentFlags().StringVar(&aibomOpts.Sign.SigOut, "sign-sig-out", "", "Path to write the signature (default: <out>.sig)")
+
+	// Attestation flags (use the signing key flags above)
+	aibomCmd.PersistentFlags().BoolVar(&aibomOpts.Sign.Attest, "attest", false, "Write a signed in-toto attestation (DSSE envelope) wrapping the output BOM")
+	aibomCmd.PersistentFlags().StringVar(&aibomOpts.Sign.AttestOut, "attest-out", "", "Path to write the attestation (default: <out>.intoto.jsonl)")
+	aibomCmd.PersistentFlags().StringVar(&aibomOpts.Sign.Subject, "attest-subject", "", "Attestation subject as NAME@sha256:HEX (default: local model or source tree hash, else the AIBOM file)")
+
+	// Model provider flags
+	aibomAzureOpenAICmd.Flags().StringVar(&azureOpenAIOpts.Endpoint, "endpoint", "", "Azure OpenAI resource endpoint (default: $AZURE_OPENAI_ENDPOINT)")
+	aibomAzureOpenAICmd.Flags().StringVar(&azureOpenAIOpts.APIKey, "api-key", "", "Azure OpenAI API key (default: $AZURE_OPENAI_API_KEY, then Entra ID)")
+	aibomAzureOpenAICmd.Flags().StringVar(&azureOpenAIOpts.APIVersion, "api-version", "", "Azure OpenAI REST API version for the models catalog")
+	aibomAzureOpenAICmd.Flags().StringVar(&azureOpenAIOpts.ModelID, "model", "", "Inventory a single deployment or model (empty = all deployments)")
+
+	aibomVertexCmd.Aliases = []string{"vertex"}
+	aibomVertexCmd.Flags().StringVar(&vertexOpts.Project, "project", "", "Google Cloud project ID (default: $GOOGLE_CLOUD_PROJECT)")
+	aibomVertexCmd.Flags().StringVar(&vertexOpts.Region, "region", "us-central1", "Vertex AI region")
+	aibomVertexCmd.Flags().StringVar(&vertexOpts.Token, "token", "", "OAuth access token (default: Application Default Credentials)")
+	aibomVertexCmd.Flags().StringVar(&vertexOpts.Endpoint, "endpoint", "", "Override the Vertex AI API endpoint")
+	aibomVertexCmd.Flags().StringVar(&vertexOpts.ModelID, "model", "", "Inventory a single registry model ID (empty = all models)")
+
+	aibomOllamaCmd.Flags().StringVar(&ollamaOpts.Endpoint, "endpoint", "", "Ollama server URL (default: $OLLAMA_HOST or http://localhost:11434)")
+	aibomOllamaCmd.Flags().StringVar(&ollamaOpts.ModelID, "model", "", "Inventory a single model, e.g. llama3.1:8b (empty = all models)")
+
+	aibomOpenAICmd.Flags().StringVar(&openAIOpts.Endpoint, "endpoint", "", "API base URL including /v1 (default: https://api.openai.com/v1)")
+	aibomOpenAICmd.Flags().StringVar(&openAIOpts.APIKey, "api-key", "", "API key (default: $OPENAI_API_KEY)")
+	aibomOpenAICmd.Flags().StringVar(&openAIOpts.ModelID, "model", "", "Inventory a single model ID (empty = all models)")
 }
@@ -0,0 +1,240 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/accuknox/accuknox-cli-v2/pkg/bom"
+	"github.com/accuknox/accuknox-cli-v2/pkg/onboard"
+	"github.com/spf13/cobra"
+)
+
+var bomCmd = &cobra.Command{
+	Use:   "bom",
+	Short: "Work with existing CycloneDX BOM documents",
+	Long: `Operate on CycloneDX documents produced by knoxctl — SBOMs (sbomgen /
+pkgscan), CBOMs (knoxctl cbom) and AIBOMs (knoxctl aibom).`,
+	// Skip k8s client initialisation — BOM operations are standalone.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+}
+
+// ── bom diff ──────────────────────────────────────────────────────────────────
+
+var bomDiffOpts bom.DiffOptions
+var bomDiffFailOnChange bool
+
+var bomDiffCmd = &cobra.Command{
+	Use:   "diff <old.json> <new.json>",
+	Short: "Show what changed between two BOMs",
+	Long: `Compare two CycloneDX documents and report added, removed and changed
+components. Components are matched by package URL (ignoring the version) or
+bom-ref. For changed components the report lists version, license, hash,
+property, cryptographic property (CBOM) and model card (AIBOM) differences.
+
+Examples:
+  knoxctl bom diff cbom-v1.json cbom-v2.json
+  knoxctl bom diff old.json new.json --format markdown --out diff.md
+  knoxctl bom diff old.json new.json --format json --fail-on-change`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bomDiffOpts.OldPath = args[0]
+		bomDiffOpts.NewPath = args[1]
+		report, err := bom.RunDiff(&bomDiffOpts)
+		if err != nil {
+			return err
+		}
+		if bomDiffFailOnChange && report.HasChanges() {
+			return fmt.Errorf("BOMs differ: %d added, %d removed, %d changed",
+				len(report.Added), len(report.Removed), len(report.Changed))
+		}
+		return nil
+	},
+}
+
+// ── bom merge ─────────────────────────────────────────────────────────────────
+
+var bomMergeOpts bom.MergeOptions
+
+var bomMergeCmd = &cobra.Command{
+	Use:   "merge <bom.json> <bom.json>...",
+	Short: "Combine several BOMs into one document",
+	Long: `Merge SBOM, CBOM and AIBOM documents describing the same product into a
+single CycloneDX document. Components are deduplicated, the dependency graphs
+are joined under one metadata.component and vulnerabilities are combined.
+
+The root component is taken from the first input unless --name is given.
+
+Examples:
+  knoxctl bom merge sbom.json cbom.json aibom.json --out product-bom.json
+  knoxctl bom merge sbom.json cbom.json --name myapp --group com.example --version 1.4.0`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bomMergeOpts.Inputs = args
+		merged, err := bom.RunMerge(&bomMergeOpts)
+		if err != nil {
+			return err
+		}
+		if bomMergeOpts.OutputTo != "" {
+			fmt.Printf("Merged %d document(s) into %d component(s)\n", len(args), len(*merged.Components))
+		}
+		return nil
+	},
+}
+
+// ── bom vex ───────────────────────────────────────────────────────────────────
+
+var bomVEXOpts bom.VEXOptions
+
+var bomVEXCmd = &cobra.Command{
+	Use:   "vex",
+	Short: "Attach VEX analysis to the vulnerabilities in a BOM",
+	Long: `Record the exploitability of vulnerabilities (CycloneDX VEX) from a YAML
+file of statements:
+
+  statements:
+    - vulnerability: CVE-2023-45288
+      components: ["pkg:golang/golang.org/x/net@v0.20.0"]   # bom-ref or purl; optional
+      state: not_affected
+      justification: code_not_reachable
+      response: [will_not_fix]
+      detail: HTTP/2 server is never started
+
+Valid states: resolved, resolved_with_pedigree, exploitable, in_triage,
+false_positive, not_affected.
+
+Examples:
+  knoxctl bom vex --bom sbom.json --vex vex.yaml --out sbom.vex.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, res, err := bom.RunVEX(&bomVEXOpts)
+		if err != nil {
+			return err
+		}
+		if bomVEXOpts.OutputTo != "" {
+			fmt.Printf("Updated %d vulnerability entry(s), added %d\n", res.Updated, res.Added)
+		}
+		for _, u := range res.Unmatched {
+			fmt.Fprintf(os.Stderr, "warning: VEX statement for %s matched nothing\n", u)
+		}
+		return nil
+	},
+}
+
+// ── bom attach / fetch ────────────────────────────────────────────────────────
+
+var bomOCIOpts bom.OCIOptions
+var bomOCIRegistryConfig string
+var bomOCIInsecure bool
+
+var bomAttachCmd = &cobra.Command{
+	Use:   "attach",
+	Short: "Push BOMs, signatures and attestations to an image as OCI referrers",
+	Long: `Attach CycloneDX BOMs to a container image in its registry as OCI 1.1
+referrer artifacts (artifactType application/vnd.cyclonedx+json). Each --sig
+is attached to the BOM at the same position as a cosign signature artifact;
+--attestation attaches an in-toto DSSE envelope written by --attest to the
+image. Registries without the referrers API are served through the
+referrers tag schema.
+
+Credentials are read from the docker config, as for "knoxctl onboard login".
+
+Examples:
+  knoxctl bom attach --image registry.io/myapp:v1.2 --bom cbom.json --sig cbom.json.sig
+  knoxctl bom attach --image registry.io/myapp:v1.2 --bom sbom.json --bom cbom.json
+  knoxctl bom attach --image registry.io/myapp@sha256:… --attestation cbom.json.intoto.jsonl`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := bomOCIClient(); err != nil {
+			return err
+		}
+		results, err := bom.Attach(cmd.Context(), &bomOCIOpts)
+		for _, r := range results {
+			fmt.Printf("Attached %s → %s (%s, subject %s)\n", r.Path, r.Digest, r.ArtifactType, r.Subject)
+		}
+		return err
+	},
+}
+
+var bomFetchCmd = &cobra.Command{
+	Use:   "fetch",
+	Short: "Download the BOMs, signatures and attestations attached to an image",
+	Long: `Discover the CycloneDX BOMs and in-toto attestations attached to a
+container image as OCI referrers, and the signatures attached to those BOMs,
+and download them under the file names they were attached with.
+
+Examples:
+  knoxctl bom fetch --image registry.io/myapp:v1.2
+  knoxctl bom fetch --image registry.io/myapp:v1.2 --out-dir boms/`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := bomOCIClient(); err != nil {
+			return err
+		}
+		results, err := bom.Fetch(cmd.Context(), &bomOCIOpts)
+		for _, r := range results {
+			kind := r.ArtifactType
+			if r.Kind != "" {
+				kind = r.Kind
+			}
+			fmt.Printf("Fetched %s (%s, %s)\n", r.Path, kind, r.Digest)
+		}
+		if err == nil && len(results) == 0 {
+			fmt.Printf("No BOMs attached to %s\n", bomOCIOpts.Image)
+		}
+		return err
+	},
+}
+
+// bomOCIClient sets up the registry client from the docker credential store.
+func bomOCIClient() error {
+	loginOpts := onboard.LoginOptions{
+		RegistryConfigPath: bomOCIRegistryConfig,
+		PlainHTTP:          bomOCIOpts.PlainHTTP,
+		Insecure:           bomOCIInsecure,
+	}
+	client, err := loginOpts.ORASGetAuthClient()
+	if err != nil {
+		return fmt.Errorf("loading registry credentials: %w", err)
+	}
+	bomOCIOpts.Client = client
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(bomCmd)
+	bomCmd.AddCommand(bomDiffCmd)
+	bomCmd.AddCommand(bomMergeCmd)
+	bomCmd.AddCommand(bomVEXCmd)
+	bomCmd.AddCommand(bomAttachCmd)
+	bomCmd.AddCommand(bomFetchCmd)
+
+	bomDiffCmd.Flags().StringVar(&bomDiffOpts.Format, "format", "table", `Output format: "table", "json" or "markdown"`)
+	bomDiffCmd.Flags().StringVar(&bomDiffOpts.OutputTo, "out", "", "Write the report to this file (json or markdown)")
+	bomDiffCmd.Flags().BoolVar(&bomDiffFailOnChange, "fail-on-change", false, "Exit with an error when the BOMs differ")
+
+	bomMergeCmd.Flags().StringVar(&bomMergeOpts.Name, "name", "", "Root component name (defaults to the first input's metadata.component)")
+	bomMergeCmd.Flags().StringVar(&bomMergeOpts.Group, "group", "", "Root component group (e.g. com.example)")
+	bomMergeCmd.Flags().StringVar(&bomMergeOpts.Version, "version", "", "Root component version")
+	bomMergeCmd.Flags().StringVar(&bomMergeOpts.Type, "type", "application", "Root component type (e.g. application, container, firmware)")
+	bomMergeCmd.Flags().StringVar(&bomMergeOpts.OutputTo, "out", "", "Write the merged BOM to this file")
+
+	bomVEXCmd.Flags().StringVar(&bomVEXOpts.BOMPath, "bom", "", "BOM to enrich")
+	bomVEXCmd.Flags().StringVar(&bomVEXOpts.VEXPath, "vex", "", "YAML file of VEX statements")
+	bomVEXCmd.Flags().StringVar(&bomVEXOpts.OutputTo, "out", "", "Write the enriched BOM to this file")
+	_ = bomVEXCmd.MarkFlagRequired("bom")
+	_ = bomVEXCmd.MarkFlagRequired("vex")
+
+	for _, c := range []*cobra.Command{bomAttachCmd, bomFetchCmd} {
+		c.Flags().StringVar(&bomOCIOpts.Image, "image", "", "Image reference (tag or digest) the artifacts belong to")
+		_ = c.MarkFlagRequired("image")
+		c.Flags().StringVar(&bomOCIRegistryConfig, "registry-config", "", "Path to the registry credentials file (default: docker config)")
+		c.Flags().BoolVar(&bomOCIOpts.PlainHTTP, "plain-http", false, "Use HTTP instead of HTTPS (default for localhost)")
+		c.Flags().BoolVar(&bomOCIInsecure, "insecure", false, "Skip TLS certificate verification for the registry")
+	}
+	bomAttachCmd.Flags().StringArrayVar(&bomOCIOpts.BOMs, "bom", nil, "CycloneDX JSON BOM to attach (repeatable)")
+	bomAttachCmd.Flags().StringArrayVar(&bomOCIOpts.Signatures, "sig", nil, "Signature of the --bom at the same position (repeatable)")
+	bomAttachCmd.Flags().StringArrayVar(&bomOCIOpts.Attestations, "attestation", nil, "In-toto attestation (DSSE envelope) to attach (repeatable)")
+	bomFetchCmd.Flags().StringVar(&bomOCIOpts.OutputDir, "out-dir", ".", "Directory to download the artifacts into")
+}
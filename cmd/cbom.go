@@ -7,6 +7,7 @@ import (
 	"fmt"
 
 	"github.com/accuknox/accuknox-cli-v2/pkg/cbom"
+	"github.com/accuknox/accuknox-cli-v2/pkg/version"
 	"github.com/spf13/cobra"
 )
 
@@ -17,19 +18,34 @@ var cbomCmd = &cobra.Command{
 	Short: "Generate Cryptography Bill of Materials (CBOM)",
 	Long: `Generate a CycloneDX-compliant Cryptography Bill of Materials (CBOM)
 that inventories all cryptographic algorithms, protocols, and certificates
-found in source code or a container image.`,
+found in source code or a container image.
+
+Every CBOM records its provenance in metadata: the knoxctl and scanner
+versions, the image digest or source tree hash, and the options used. With
+--deterministic components are sorted, the serial number is derived from the
+content and the timestamp is taken from $SOURCE_DATE_EPOCH (or omitted), so
+two runs over the same input produce byte-identical CBOMs.
+
+With --attest the CBOM is also wrapped in a signed in-toto attestation
+(<out>.intoto.jsonl) whose subject is the image digest or source tree hash;
+check it with "knoxctl sign verify-attestation".`,
 }
 
 var cbomSourceCmd = &cobra.Command{
 	Use:   "source",
-	Short: "Generate CBOM from Go source code",
+	Short: "Generate CBOM from Go source code and committed key material",
 	Long: `Walk a Go source tree and detect usage of cryptographic packages
-(stdlib crypto/* and golang.org/x/crypto/*). Produces a CycloneDX 1.6 CBOM.
+(stdlib crypto/* and golang.org/x/crypto/*). Certificates (PEM/DER), private
+and public keys, JKS/PKCS#12 keystores and TLS server configuration files
+(nginx, Apache httpd, HAProxy, OpenSSL) found in the tree are also inventoried;
+expired certificates and committed private keys are flagged. Produces a
+CycloneDX 1.6 CBOM.
 
 Example:
   knoxctl cbom source --path ./myapp
   knoxctl cbom source --path ./myapp --format table
-  knoxctl cbom source --path ./myapp --out cbom.json`,
+  knoxctl cbom source --path ./myapp --out cbom.json
+  knoxctl cbom source --path ./myapp --out cbom.json --attest --sign-key cosign.key`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		bom, err := cbom.GenerateFromSource(&cbomOpts)
 		if err != nil {
@@ -50,7 +66,8 @@ Example:
   knoxctl cbom image --image nginx:latest
   knoxctl cbom image --image registry.io/myapp:v1.2 --format table
   knoxctl cbom image --image nginx:latest --out cbom.json
-  knoxctl cbom image --image nginx:latest --plugins certificates,keys`,
+  knoxctl cbom image --image nginx:latest --plugins certificates,keys
+  knoxctl cbom image --image nginx:latest --out cbom.json --attest --sign-key awskms:///alias/bom`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		bom, err := cbom.GenerateFromImage(&cbomOpts)
 		if err != nil {
@@ -83,12 +100,19 @@ func init() {
 	cbomCmd.PersistentFlags().StringVar(&cbomOpts.License, "license", "", "SPDX license identifier (e.g. Apache-2.0)")
 	cbomCmd.PersistentFlags().StringVar(&cbomOpts.OutputTo, "out", "", "Write CBOM JSON to this file")
 	cbomCmd.PersistentFlags().StringVar(&cbomOpts.Format, "format", "json", `Output format: "json" or "table"`)
+	cbomCmd.PersistentFlags().BoolVar(&cbomOpts.Deterministic, "deterministic", false, "Produce byte-identical output for identical input (sorted components, content-derived serial number, timestamp from $SOURCE_DATE_EPOCH)")
+	cbomOpts.ToolVersion = version.GitSummary
 
 	// Signing flags (only meaningful when --out is set)
 	cbomCmd.PersistentFlags().BoolVar(&cbomOpts.Sign.Enabled, "sign", false, "Sign the output artifact with cosign after generation")
 	cbomCmd.PersistentFlags().BoolVar(&cbomOpts.Sign.GenerateKey, "sign-generate-key", false, "Generate a new ECDSA P-256 key pair before signing")
-	cbomCmd.PersistentFlags().StringVar(&cbomOpts.Sign.KeyRef, "sign-key", "", "Path to existing cosign private key (default: cosign.key)")
+	cbomCmd.PersistentFlags().StringVar(&cbomOpts.Sign.KeyRef, "sign-key", "", "Signing key: cosign PEM file, env://VAR, or awskms://, gcpkms://, azurekms://, hashivault://, pkcs11: reference (default: cosign.key)")
 	cbomCmd.PersistentFlags().StringVar(&cbomOpts.Sign.KeyOut, "sign-key-out", "cosign", "Filename prefix for generated key pair (produces <prefix>.key / <prefix>.pub)")
-	cbomCmd.PersistentFlags().StringVar(&cbomOpts.Sign.Password, "sign-key-password", "", "Passphrase for the signing key (empty = no passphrase)")
+	cbomCmd.PersistentFlags().StringVar(&cbomOpts.Sign.Password, "sign-key-password", "", "Passphrase for a PEM signing key (default: $COSIGN_PASSWORD)")
 	cbomCmd.PersistentFlags().StringVar(&cbomOpts.Sign.SigOut, "sign-sig-out", "", "Path to write the signature (default: <out>.sig)")
+
+	// Attestation flags (use the signing key flags above)
+	cbomCmd.PersistentFlags().BoolVar(&cbomOpts.Sign.Attest, "attest", false, "Write a signed in-toto attestation (DSSE envelope) wrapping the output BOM")
+	cbomCmd.PersistentFlags().StringVar(&cbomOpts.Sign.AttestOut, "attest-out", "", "Path to write the attestation (default: <out>.intoto.jsonl)")
+	cbomCmd.PersistentFlags().StringVar(&cbomOpts.Sign.Subject, "attest-subject", "", "Attestation subject as NAME@sha256:HEX (default: image digest or source tree hash)")
 }
@@ -3,15 +3,20 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/accuknox/accuknox-cli-v2/pkg/imagescan"
 	"github.com/accuknox/accuknox-cli-v2/pkg/logger"
 	kubesheildScanner "github.com/accuknox/kubeshield/pkg/scanner/scan"
-	"github.com/robfig/cron/v3"
+	"github.com/samber/lo"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 )
 
 var (
@@ -25,6 +30,14 @@ var (
 	imagesOnly                  bool
 	cfg                         = kubesheildScanner.ScanConfig{}
 	defaultArtifactEndpointPath = "/api/v1/artifact/"
+	localScan                   bool
+	localScanOpts               imagescan.LocalOptions
+	daemonOpts                  imagescan.DaemonOptions
+	discoveryOpts               imagescan.DiscoveryOptions
+	runtimeSockets              []string
+	includeRules                []string
+	excludeRules                []string
+	skipFile                    string
 )
 
 var imageScanCmd = &cobra.Command{
@@ -32,9 +45,77 @@ var imageScanCmd = &cobra.Command{
 	Short: "scans vm container images",
 	Long: `Scans VM container images 
 and sends back the result to saas
+
+With --schedule knoxctl runs as a scan daemon. It keeps the digest of every
+image it scanned under --state-dir and on each run rescans only images that
+are new or changed, or all of them when the vulnerability database was
+updated. Runs can be spread with --jitter and parallelised with
+--max-concurrency; "knoxctl image-scan status" reports the daemon's health
+and last run.
+
+Images are discovered from docker, containerd, CRI-O, NRI and Podman,
+rootful or rootless; "knoxctl image-scan discover" lists them without
+scanning. Add sockets with --socket runtime=path and containerd namespaces
+with --containerd-namespace.
+
+Discovered images can be filtered with --include and --exclude rules on the
+image name, registry, tag or the labels of the containers running them, in
+which "*" matches anything. Include rules on the same field are alternatives
+and rules on different fields must all match; any matching exclude rule
+drops the image. Container labels are read from docker and Podman, and from
+containerd when --containerd-namespace is set. Images already scanned
+elsewhere, such as base images scanned in CI, are skipped with --skip-image
+or --skip-file. An image found both by tag and by digest is scanned once.
+
+With --local the results are written to files instead of being uploaded,
+one report per image and format (--format json,sarif,cyclonedx,table), so
+hosts can be assessed before onboarding. No artifact endpoint, token or label
+is needed; with --offline the vulnerability database is not updated either,
+for air-gapped hosts whose scanner cache (--cache-dir) is already populated.
+
+Examples:
+  knoxctl image-scan --local --output-dir ./reports
+  knoxctl image-scan --local --image nginx:1.25 --format sarif,cyclonedx,table
+  knoxctl image-scan --local --offline --cache-dir /opt/trivy-cache --severity CRITICAL,HIGH
+  knoxctl image-scan --local --schedule "0 */6 * * *" --jitter 15m --max-concurrency 4
+  knoxctl image-scan --local --include registry=registry.example.com --exclude tag=*-debug --skip-file ci-base-images.txt
 		`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		// trivy can make use of this variable to download the trivyDB from the
+		// specified source. If it is empty, trivy will download from one of its public
+		// registries.
+		_ = os.Setenv("TRIVY_DB_REPOSITORY", vulnerabilityDB)
+		_ = os.Setenv("TRIVY_JAVA_DB_REPOSITORY", javaDB)
+
+		if schedule == "" {
+			for _, name := range []string{"jitter", "max-concurrency", "state-dir"} {
+				if cmd.Flags().Changed(name) {
+					return fmt.Errorf("--%s requires --schedule", name)
+				}
+			}
+		}
+
+		if err := setDiscoveryOptions(); err != nil {
+			return err
+		}
+		localScanOpts.HostName = HOST_NAME
+		localScanOpts.Discovery = discoveryOpts
+		if localScan {
+			if schedule != "" {
+				return runImageScanDaemon(cmd, nil)
+			}
+			_, err := imagescan.ScanLocal(localScanOpts)
+			return err
+		}
+		for _, name := range []string{"image", "output-dir", "format", "severity", "offline"} {
+			if cmd.Flags().Changed(name) {
+				return fmt.Errorf("--%s requires --local", name)
+			}
+		}
+		if schedule == "" && cmd.Flags().Changed("cache-dir") {
+			return fmt.Errorf("--cache-dir requires --local or --schedule")
+		}
 
 		if strings.HasPrefix(cfg.ArtifactConfig.ArtifactAPI, "http://") {
 			return fmt.Errorf("http scheme not supported: %s", cfg.ArtifactConfig.ArtifactAPI)
@@ -59,39 +140,190 @@ and sends back the result to saas
 			artifactEndpointPath = "/" + artifactEndpointPath
 		}
 
-		// trivy can make use of this variable to download the trivyDB from the
-		// specified source. If it is empty, trivy will download from one of its public
-		// registries.
-		_ = os.Setenv("TRIVY_DB_REPOSITORY", vulnerabilityDB)
-		_ = os.Setenv("TRIVY_JAVA_DB_REPOSITORY", javaDB)
-
 		cfg.ArtifactConfig.ArtifactAPI += artifactEndpointPath
 
 		if schedule != "" {
-			c := cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)))
+			return runImageScanDaemon(cmd, &cfg)
+		}
 
-			_, err := c.AddFunc(schedule, func() {
-				if err := imagescan.DiscoverAndScan(cfg, HOST_NAME, RUN_TIME, !allContainers, imagesOnly); err != nil {
-					logger.Error("error while executing the schedule imagescan: %v", err)
-				}
-			})
+		return imagescan.DiscoverAndScan(cfg, HOST_NAME, discoveryOpts)
+	},
+}
+
+// setDiscoveryOptions fills discoveryOpts from the discovery flags.
+func setDiscoveryOptions() error {
+	if RUN_TIME != "" && !lo.Contains(imagescan.Runtimes, RUN_TIME) {
+		return fmt.Errorf("unsupported runtime %q (supported: %s)", RUN_TIME, strings.Join(imagescan.Runtimes, ", "))
+	}
+	discoveryOpts.Runtime = RUN_TIME
+	discoveryOpts.OnlyRunningContainers = !allContainers
+	discoveryOpts.OnlyImages = imagesOnly
+	discoveryOpts.Sockets = nil
+	for _, s := range runtimeSockets {
+		sock, err := imagescan.ParseSocket(s)
+		if err != nil {
+			return err
+		}
+		discoveryOpts.Sockets = append(discoveryOpts.Sockets, sock)
+	}
+
+	filter := &discoveryOpts.Filter
+	filter.Include, filter.Exclude = nil, nil
+	for _, s := range includeRules {
+		rule, err := imagescan.ParseRule(s)
+		if err != nil {
+			return err
+		}
+		filter.Include = append(filter.Include, rule)
+	}
+	for _, s := range excludeRules {
+		rule, err := imagescan.ParseRule(s)
+		if err != nil {
+			return err
+		}
+		filter.Exclude = append(filter.Exclude, rule)
+	}
+	if skipFile != "" {
+		skip, err := imagescan.ReadSkipFile(skipFile)
+		if err != nil {
+			return err
+		}
+		filter.Skip = append(filter.Skip, skip...)
+	}
+	return nil
+}
+
+var discoverOutput string
+
+var imageScanDiscoverCmd = &cobra.Command{
+	Use:   "discover",
+	Short: "List the images image-scan would scan and the runtime they come from",
+	Long: `List the images found in the container runtimes of this host, without
+scanning them, along with the runtime, socket and containerd namespace each
+was found in. It takes the same discovery and filter flags as image-scan.
+
+Docker, containerd, CRI-O, NRI and Podman are looked up at their rootful
+socket paths and, for docker, containerd and Podman, at the rootless paths
+under $XDG_RUNTIME_DIR (or /run/user/<uid>). Other sockets can be added with
+--socket runtime=path. containerd is listed in its default namespace unless
+--containerd-namespace names others ("*" for all of them).
+
+Examples:
+  knoxctl image-scan discover
+  knoxctl image-scan discover --runtime podman --images-only
+  knoxctl image-scan discover --socket containerd=/run/k0s/containerd.sock --containerd-namespace "*"
+  knoxctl image-scan discover --exclude label=io.kubernetes.pod.namespace=kube-system
+  knoxctl image-scan discover --output json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setDiscoveryOptions(); err != nil {
+			return err
+		}
+		zapLogger, err := zap.NewProduction()
+		if err != nil {
+			return fmt.Errorf("failed to initialize logger")
+		}
+		defer func() { _ = zapLogger.Sync() }()
+
+		images := imagescan.Discover(discoveryOpts, zapLogger.Sugar())
+		if discoverOutput == "json" {
+			data, err := json.MarshalIndent(images, "", "  ")
 			if err != nil {
-				logger.Error("error while scheduling the image scanner: %v", err)
 				return err
 			}
+			fmt.Println(string(data))
+			return nil
+		}
+		if len(images) == 0 {
+			return fmt.Errorf("no images found for scanning")
+		}
+		imagescan.PrintDiscovered(images)
+		return nil
+	},
+}
 
-			if triggerSchedulescan {
-				if err := imagescan.DiscoverAndScan(cfg, HOST_NAME, RUN_TIME, !allContainers, imagesOnly); err != nil {
-					logger.Error("error while triggering the image scanner: %v", err)
-					return err
-				}
-			}
+// runImageScanDaemon runs the scan daemon until interrupted, uploading the
+// results when upload is set and writing local reports otherwise.
+func runImageScanDaemon(cmd *cobra.Command, upload *kubesheildScanner.ScanConfig) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-			c.Run()
-			return nil
+	daemonOpts.Schedule = schedule
+	daemonOpts.RunOnStart = triggerSchedulescan
+	daemonOpts.Local = localScanOpts
+	daemonOpts.Upload = upload
+	if err := imagescan.RunDaemon(ctx, daemonOpts); err != nil {
+		logger.Error("error while running the image scan daemon: %v", err)
+		return err
+	}
+	return nil
+}
+
+var (
+	statusMaxAge time.Duration
+	statusOutput string
+)
+
+var imageScanStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the health and last run of the image scan daemon",
+	Long: `Show the state of the image scan daemon started with --schedule: whether
+it is running, its next run, and the outcome of its last run. The command
+fails when the daemon is not running, its last run failed, or (with
+--max-age) finished too long ago, so it can serve as a health check.
+
+Examples:
+  knoxctl image-scan status
+  knoxctl image-scan status --max-age 25h --output json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := imagescan.ReadStatus(daemonOpts.StateDir)
+		if err != nil {
+			return err
+		}
+		health := st.Healthy(statusMaxAge)
+
+		if statusOutput == "json" {
+			out := struct {
+				*imagescan.DaemonStatus
+				Healthy bool   `json:"healthy"`
+				Problem string `json:"problem,omitempty"`
+			}{DaemonStatus: st, Healthy: health == nil}
+			if health != nil {
+				out.Problem = health.Error()
+			}
+			data, err := json.MarshalIndent(out, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return health
 		}
 
-		return imagescan.DiscoverAndScan(cfg, HOST_NAME, RUN_TIME, !allContainers, imagesOnly)
+		fmt.Printf("Daemon:      pid %d (%s mode), started %s\n", st.PID, st.Mode, st.StartedAt.Local().Format(time.RFC3339))
+		fmt.Printf("Schedule:    %s\n", st.Schedule)
+		if st.NextRun != nil {
+			fmt.Printf("Next run:    %s\n", st.NextRun.Local().Format(time.RFC3339))
+		}
+		if st.Running {
+			fmt.Println("Scanning:    in progress")
+		}
+		if r := st.LastRun; r != nil {
+			fmt.Printf("Last run:    %s (%s)\n", r.StartedAt.Local().Format(time.RFC3339), r.FinishedAt.Sub(r.StartedAt).Round(time.Second))
+			fmt.Printf("  images     %d discovered, %d scanned, %d unchanged, %d failed\n", r.Discovered, r.Scanned, r.Unchanged, r.Failed)
+			if r.DBUpdatedAt != "" {
+				fmt.Printf("  vuln DB    updated %s\n", r.DBUpdatedAt)
+			}
+			if r.Error != "" {
+				fmt.Printf("  error      %s\n", r.Error)
+			}
+		}
+		if health != nil {
+			fmt.Printf("Health:      UNHEALTHY — %v\n", health)
+			return health
+		}
+		fmt.Println("Health:      OK")
+		return nil
 	},
 }
 
@@ -107,20 +339,47 @@ func init() {
 
 	// Scan Configurations
 	imageScanCmd.Flags().StringVarP(&HOST_NAME, "hostname", "", "", "name of the host")
-	imageScanCmd.Flags().StringVarP(&RUN_TIME, "runtime", "r", "", "container runtime used in the host machine")
-	imageScanCmd.Flags().StringVarP(&schedule, "schedule", "", "", "scans the images based on the provided schedule")
+	imageScanCmd.PersistentFlags().StringVarP(&RUN_TIME, "runtime", "r", "", "container runtime used in the host machine: "+strings.Join(imagescan.Runtimes, ", "))
+	imageScanCmd.Flags().StringVarP(&schedule, "schedule", "", "", "Run as a daemon scanning on this cron schedule, rescanning only changed images")
 	imageScanCmd.Flags().BoolVar(&triggerSchedulescan, "trigger-schedule-scan", true, "If set, triggers the schdeule scan immediately without waiting for the schedule.")
+	imageScanCmd.Flags().DurationVar(&daemonOpts.Jitter, "jitter", 0, "Daemon: random delay of up to this duration before each scheduled run")
+	imageScanCmd.Flags().IntVar(&daemonOpts.MaxConcurrency, "max-concurrency", 1, "Daemon: number of images scanned in parallel")
+	imageScanCmd.PersistentFlags().StringVar(&daemonOpts.StateDir, "state-dir", "", "Daemon state and status directory (default ~/.accuknox-config/image-scan)")
 
-	imageScanCmd.Flags().BoolVar(&allContainers, "all-containers", false, "If set, discover containers in all states. By default, only running containers are discovered.")
-	imageScanCmd.Flags().BoolVar(&imagesOnly, "images-only", false, "If set, discovers and scans all images. By default, only images from running containers are scanned.")
+	imageScanCmd.PersistentFlags().BoolVar(&allContainers, "all-containers", false, "If set, discover containers in all states. By default, only running containers are discovered.")
+	imageScanCmd.PersistentFlags().BoolVar(&imagesOnly, "images-only", false, "If set, discovers and scans all images. By default, only images from running containers are scanned.")
+	imageScanCmd.PersistentFlags().StringArrayVar(&runtimeSockets, "socket", nil, "Additional runtime socket as runtime=path (repeatable)")
+	imageScanCmd.PersistentFlags().StringSliceVar(&discoveryOpts.ContainerdNamespaces, "containerd-namespace", nil, `containerd namespaces to discover images in ("*" for all)`)
+	imageScanCmd.PersistentFlags().StringArrayVar(&includeRules, "include", nil, "Only scan images matching a rule: [name|registry|tag]=glob or label=key[=glob] (repeatable)")
+	imageScanCmd.PersistentFlags().StringArrayVar(&excludeRules, "exclude", nil, "Do not scan images matching a rule, in the --include syntax (repeatable)")
+	imageScanCmd.PersistentFlags().StringSliceVar(&discoveryOpts.Filter.Skip, "skip-image", nil, "Image scanned elsewhere (e.g. a CI base image): name glob with optional tag, or digest")
+	imageScanCmd.PersistentFlags().StringVar(&skipFile, "skip-file", "", "File listing images to skip, one --skip-image entry per line")
 
 	// Trivy Configurations
 	imageScanCmd.Flags().StringVarP(&vulnerabilityDB, "db-repository", "", "", "OCI repository to retrieve vulnerability db")
 	imageScanCmd.Flags().StringVarP(&javaDB, "java-db-repository", "", "", "OCI repository to retrieve java db")
 
+	// Local report Configurations
+	imageScanCmd.Flags().BoolVar(&localScan, "local", false, "Write the results to local report files instead of uploading them")
+	imageScanCmd.Flags().StringSliceVar(&localScanOpts.Images, "image", nil, "Image to scan in local mode (repeatable); skips discovery")
+	imageScanCmd.Flags().StringVar(&localScanOpts.OutputDir, "output-dir", "image-scan-results", "Directory for local reports")
+	imageScanCmd.Flags().StringSliceVar(&localScanOpts.Formats, "format", []string{imagescan.FormatJSON}, "Local report formats: "+strings.Join(imagescan.ReportFormats, ", "))
+	imageScanCmd.Flags().StringVar(&localScanOpts.Severity, "severity", "", "Comma-separated severities to report in local mode (e.g. CRITICAL,HIGH)")
+	imageScanCmd.Flags().BoolVar(&localScanOpts.Offline, "offline", false, "Local mode: do not update the vulnerability database or contact registries")
+	imageScanCmd.Flags().StringVar(&localScanOpts.CacheDir, "cache-dir", "", "Scanner cache directory holding the vulnerability database")
+
 	// Required Flags Validation
-	imageScanCmd.MarkFlagsOneRequired("artifactEndpoint", "token", "label")
+	imageScanCmd.MarkFlagsOneRequired("artifactEndpoint", "token", "label", "local")
 	imageScanCmd.MarkFlagsRequiredTogether("artifactEndpoint", "token", "label")
-	_ = imageScanCmd.Flags().MarkHidden("schedule")
+	imageScanCmd.MarkFlagsMutuallyExclusive("local", "artifactEndpoint")
+	imageScanCmd.MarkFlagsMutuallyExclusive("local", "token")
+	imageScanCmd.MarkFlagsMutuallyExclusive("local", "label")
+
+	imageScanStatusCmd.Flags().DurationVar(&statusMaxAge, "max-age", 0, "Report unhealthy when the last run finished longer ago than this")
+	imageScanStatusCmd.Flags().StringVarP(&statusOutput, "output", "o", "text", `Output format: "text" or "json"`)
+	imageScanCmd.AddCommand(imageScanStatusCmd)
+
+	imageScanDiscoverCmd.Flags().StringVarP(&discoverOutput, "output", "o", "text", `Output format: "text" or "json"`)
+	imageScanCmd.AddCommand(imageScanDiscoverCmd)
 	rootCmd.AddCommand(imageScanCmd)
 }
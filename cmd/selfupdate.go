@@ -4,6 +4,7 @@
 package cmd
 
 import (
+	"github.com/accuknox/accuknox-cli-v2/pkg/download"
 	"github.com/accuknox/accuknox-cli-v2/pkg/update"
 	"github.com/spf13/cobra"
 )
@@ -12,7 +13,10 @@ import (
 var selfUpdateCmd = &cobra.Command{
 	Use:   "selfupdate",
 	Short: "update knoxctl",
-	Long:  `update knoxctl to sync with latest and greatest updates`,
+	Long: `update knoxctl to sync with latest and greatest updates
+
+The release is fetched through the mirrors and CA bundle set in
+~/.accuknox-config/download.yaml (or $` + download.ConfigEnv + `) and honours $HTTPS_PROXY.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := update.SelfUpdate(); err != nil {
 			return err
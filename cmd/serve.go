@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/accuknox/accuknox-cli-v2/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+// apiTokenEnv names the environment variable that may carry the API token.
+const apiTokenEnv = "KNOXCTL_API_TOKEN"
+
+var serveAddr string
+var servePort int
+var serveTokenFile string
+var serveOpts ui.Options
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run knoxctl as a local REST API service",
+	Long: `Start the knoxctl server without opening a browser, so other tools can
+call knoxctl as a local service. With --headless only the REST API is served,
+not the web UI.
+
+The API is served under /api/v1 and described by the OpenAPI document at
+/api/v1/openapi.yaml. BOM generation (sbom, cbom and aibom) answers with the
+job's result as JSON once it finishes, or streams the job's events as SSE when
+the request sends "Accept: text/event-stream"; "?detach=true" returns the job
+at once, to be followed under /api/v1/jobs/{id}. BOMs are signed with
+/api/v1/sign and published with /api/v1/{sbom,cbom,aibom}/publish, either
+given inline or by the id of the job that produced them.
+
+Every request must carry the API token as "Authorization: Bearer <token>" or
+in the X-Knoxctl-Token header. The token is read from --token-file or the
+` + apiTokenEnv + ` environment variable, and must be at least ` + fmt.Sprint(ui.MinTokenLength) + `
+characters long; otherwise a random token is generated and printed on start.
+
+Examples:
+  knoxctl serve --headless
+  ` + apiTokenEnv + `=$(cat ~/.knoxctl-token) knoxctl serve --headless --port 8080
+  knoxctl serve --headless --tls --token-file /etc/knoxctl/token
+
+  curl -H "Authorization: Bearer $TOKEN" -d '{"source":"."}' \
+    http://127.0.0.1:10100/api/v1/sbom/generate`,
+	// Override the root PersistentPreRunE to skip k8s client initialisation,
+	// which is not needed for the standalone server.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serveOpts.NoBrowser = true
+		serveOpts.Token = os.Getenv(apiTokenEnv)
+		if serveTokenFile != "" {
+			data, err := os.ReadFile(serveTokenFile) // #nosec G304 -- path given by the user
+			if err != nil {
+				return fmt.Errorf("reading --token-file: %w", err)
+			}
+			serveOpts.Token = strings.TrimSpace(string(data))
+		}
+		return startServer(cmd, serveAddr, servePort, &serveOpts)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	addServerFlags(serveCmd, &serveAddr, &servePort, &serveOpts)
+	serveCmd.Flags().BoolVar(&serveOpts.Headless, "headless", false, "Serve the REST API only, without the web UI")
+	serveCmd.Flags().StringVar(&serveTokenFile, "token-file", "", "File holding the API token (default $"+apiTokenEnv+", or a generated token)")
+}
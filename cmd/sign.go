@@ -4,6 +4,11 @@
 package cmd
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
 	"github.com/accuknox/accuknox-cli-v2/pkg/sign"
 	"github.com/spf13/cobra"
 )
@@ -12,7 +17,19 @@ var signCmd = &cobra.Command{
 	Use:   "sign",
 	Short: "Sign and verify artifacts using cosign (ECDSA P-256)",
 	Long: `Sign BOM artifacts and verify their signatures using the sigstore/cosign
-library.  Keys are stored as standard PEM files compatible with the cosign CLI.`,
+library.  Keys are stored as standard PEM files compatible with the cosign CLI.
+
+Instead of a key file, --key (and --sign-key on the bom commands) accepts a
+key reference so private keys never have to sit on the build machine:
+
+  env://VAR                           PEM private key in environment variable VAR
+  awskms://[ENDPOINT]/KEY_ID|ALIAS    AWS KMS
+  gcpkms://projects/P/locations/L/keyRings/R/cryptoKeys/K[/versions/V]
+  azurekms://VAULT.vault.azure.net/KEY
+  hashivault://KEY                    HashiCorp Vault transit engine
+  pkcs11:token=T;object=O?module-path=M   PKCS#11 token (builds with -tags pkcs11key)
+
+The passphrase of a PEM key defaults to $COSIGN_PASSWORD.`,
 	// Skip k8s client initialisation — signing is standalone.
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		return nil
@@ -49,9 +66,10 @@ var signArtifactPath string
 
 var signArtifactCmd = &cobra.Command{
 	Use:   "artifact",
-	Short: "Sign a file with a cosign private key",
-	Long: `Sign any file (e.g. a CBOM or AIBOM JSON) with an ECDSA P-256 private key.
-The base-64 encoded signature is written to <file>.sig (or --sig-out).
+	Short: "Sign a file with a cosign private key or KMS key",
+	Long: `Sign any file (e.g. a CBOM or AIBOM JSON) with an ECDSA P-256 private key
+or a KMS, Vault or PKCS#11 key. The base-64 encoded signature is written to
+<file>.sig (or --sig-out).
 
 Examples:
   # Generate a new key pair and sign
@@ -61,7 +79,13 @@ Examples:
   knoxctl sign artifact --file aibom.json --key cosign.key
 
   # Provide a key passphrase
-  knoxctl sign artifact --file bom.json --key cosign.key --key-password secret`,
+  knoxctl sign artifact --file bom.json --key cosign.key --key-password secret
+
+  # Key held in a CI secret
+  knoxctl sign artifact --file bom.json --key env://COSIGN_PRIVATE_KEY
+
+  # Key held in AWS KMS
+  knoxctl sign artifact --file bom.json --key awskms:///alias/bom-signing`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		signArtifactOpts.Enabled = true
 		return sign.Artifact(signArtifactPath, &signArtifactOpts)
@@ -72,22 +96,115 @@ Examples:
 
 var verifyArtifactPath string
 var verifySigPath string
-var verifyPubKeyPath string
+var verifyTrustOpts sign.TrustOptions
+var verifyOutput string
 
 var signVerifyCmd = &cobra.Command{
 	Use:   "verify",
-	Short: "Verify a cosign signature over a file",
-	Long: `Verify that a file's signature was produced by the private key
-corresponding to the supplied public key.
+	Short: "Verify a cosign signature over a file against trusted keys",
+	Long: `Verify that a file's signature was produced by a trusted key. The trust
+policy is any number of --pub sources, and optionally a signing certificate:
+
+  --pub FILE        PEM public key, or a bundle of keys and certificates
+  --pub DIR         every PEM public key and certificate in the directory
+  --pub REF         the KMS, PKCS#11 or env:// key reference used for signing
+  --cert FILE       signing certificate (with any intermediates appended),
+                    verified against --ca-roots and --cert-chain
+
+The signature is accepted when any trusted key verifies it, so rotated keys
+and keys of several producer teams can be trusted at once. --output json
+prints the result, including which key matched.
 
 Examples:
-  knoxctl sign verify --file cbom.json --sig cbom.json.sig --pub cosign.pub`,
+  knoxctl sign verify --file cbom.json --sig cbom.json.sig --pub cosign.pub
+  knoxctl sign verify --file bom.json --pub awskms:///alias/bom-signing
+  knoxctl sign verify --file sbom.json --pub trusted-keys/ --output json
+  knoxctl sign verify --file sbom.json --cert signer.pem --ca-roots corp-root.pem`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		sig := verifySigPath
 		if sig == "" {
 			sig = verifyArtifactPath + ".sig"
 		}
-		return sign.Verify(verifyArtifactPath, sig, verifyPubKeyPath)
+		if len(verifyTrustOpts.Keys) == 0 && verifyTrustOpts.Cert == "" {
+			verifyTrustOpts.Keys = []string{"cosign.pub"}
+		}
+
+		policy, err := sign.LoadTrustPolicy(cmd.Context(), &verifyTrustOpts)
+		if err != nil {
+			return fmt.Errorf("verify: %w", err)
+		}
+		res, err := sign.VerifyWithPolicy(verifyArtifactPath, sig, policy)
+		switch verifyOutput {
+		case "json":
+			out, jerr := json.MarshalIndent(res, "", "  ")
+			if jerr != nil {
+				return jerr
+			}
+			fmt.Println(string(out))
+			return err
+		default:
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Signature OK: %s is authentic (key %s)\n", verifyArtifactPath, res.Key)
+			return nil
+		}
+	},
+}
+
+// ── sign verify-attestation ───────────────────────────────────────────────────
+
+var verifyAttestPath string
+var verifyAttestPubKey string
+var verifyAttestImage string
+var verifyAttestDir string
+var verifyAttestDigest string
+
+var signVerifyAttestationCmd = &cobra.Command{
+	Use:   "verify-attestation",
+	Short: "Verify a signed in-toto attestation of a BOM",
+	Long: `Verify an in-toto attestation written by --attest on the cbom, aibom and
+SBOM tool commands: the DSSE envelope and its payload type, the signature
+against --pub, the in-toto Statement v1 header and CycloneDX predicate type,
+and — with --image, --path or --digest — that the attested subject digest
+matches the artifact at hand.
+
+Examples:
+  knoxctl sign verify-attestation --attestation cbom.json.intoto.jsonl --pub cosign.pub --path ./myapp
+  knoxctl sign verify-attestation --attestation sbom.json.intoto.jsonl --pub awskms:///alias/bom --image registry.io/myapp:v1.2
+  knoxctl sign verify-attestation --attestation aibom.json.intoto.jsonl --digest sha256:4f3c…`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var expected []sign.Subject
+		if verifyAttestImage != "" {
+			s, err := sign.ImageSubject(cmd.Context(), verifyAttestImage)
+			if err != nil {
+				return err
+			}
+			expected = append(expected, s)
+		}
+		if verifyAttestDir != "" {
+			// The BOM, signature and attestation may have been written
+			// into the tree; they were not part of it when it was attested.
+			bomPath := strings.TrimSuffix(verifyAttestPath, ".intoto.jsonl")
+			exclude := append(sign.OutputFiles(bomPath, &sign.Options{}), verifyAttestPath)
+			s, err := sign.PathSubject(verifyAttestDir, exclude...)
+			if err != nil {
+				return err
+			}
+			expected = append(expected, s)
+		}
+		if verifyAttestDigest != "" {
+			alg, digest, err := sign.ParseDigest(verifyAttestDigest)
+			if err != nil {
+				return err
+			}
+			expected = append(expected, sign.Subject{Name: verifyAttestDigest, Digest: map[string]string{alg: digest}})
+		}
+		if len(expected) == 0 {
+			fmt.Fprintln(os.Stderr, "Warning: no --image, --path or --digest given; the subject digest was not checked")
+		}
+		_, err := sign.VerifyAttestation(verifyAttestPath, verifyAttestPubKey, expected)
+		return err
 	},
 }
 
@@ -96,6 +213,7 @@ func init() {
 	signCmd.AddCommand(signKeygenCmd)
 	signCmd.AddCommand(signArtifactCmd)
 	signCmd.AddCommand(signVerifyCmd)
+	signCmd.AddCommand(signVerifyAttestationCmd)
 
 	// sign keygen flags
 	signKeygenCmd.Flags().StringVar(&signKeygenOpts.KeyOut, "key-out", "cosign", "Filename prefix for generated key pair (<prefix>.key / <prefix>.pub)")
@@ -105,14 +223,26 @@ func init() {
 	signArtifactCmd.Flags().StringVar(&signArtifactPath, "file", "", "Path to the file to sign")
 	_ = signArtifactCmd.MarkFlagRequired("file")
 	signArtifactCmd.Flags().BoolVar(&signArtifactOpts.GenerateKey, "generate-key", false, "Generate a new ECDSA P-256 key pair before signing")
-	signArtifactCmd.Flags().StringVar(&signArtifactOpts.KeyRef, "key", "", "Path to existing cosign private key (default: cosign.key)")
+	signArtifactCmd.Flags().StringVar(&signArtifactOpts.KeyRef, "key", "", "Signing key: cosign PEM file, env://VAR, or KMS/PKCS#11 reference (default: cosign.key)")
 	signArtifactCmd.Flags().StringVar(&signArtifactOpts.KeyOut, "key-out", "cosign", "Filename prefix for generated key pair (<prefix>.key / <prefix>.pub)")
-	signArtifactCmd.Flags().StringVar(&signArtifactOpts.Password, "key-password", "", "Passphrase for the signing key (empty = no passphrase)")
+	signArtifactCmd.Flags().StringVar(&signArtifactOpts.Password, "key-password", "", "Passphrase for a PEM signing key (default: $COSIGN_PASSWORD)")
 	signArtifactCmd.Flags().StringVar(&signArtifactOpts.SigOut, "sig-out", "", "Path to write the signature (default: <file>.sig)")
 
 	// sign verify flags
 	signVerifyCmd.Flags().StringVar(&verifyArtifactPath, "file", "", "Path to the signed file")
 	_ = signVerifyCmd.MarkFlagRequired("file")
 	signVerifyCmd.Flags().StringVar(&verifySigPath, "sig", "", "Path to the signature file (default: <file>.sig)")
-	signVerifyCmd.Flags().StringVar(&verifyPubKeyPath, "pub", "cosign.pub", "Path to the cosign public key")
+	signVerifyCmd.Flags().StringArrayVar(&verifyTrustOpts.Keys, "pub", nil, "Trusted public key file, bundle or directory, or KMS/PKCS#11/env:// key reference (repeatable; default: cosign.pub)")
+	signVerifyCmd.Flags().StringVar(&verifyTrustOpts.Cert, "cert", "", "Signing certificate (PEM, intermediates may follow the leaf)")
+	signVerifyCmd.Flags().StringArrayVar(&verifyTrustOpts.CertChain, "cert-chain", nil, "Intermediate CA certificates (PEM, repeatable)")
+	signVerifyCmd.Flags().StringArrayVar(&verifyTrustOpts.Roots, "ca-roots", nil, "Trusted root CA certificates (PEM bundle, repeatable; required with --cert)")
+	signVerifyCmd.Flags().StringVar(&verifyOutput, "output", "text", `Result format: "text" or "json"`)
+
+	// sign verify-attestation flags
+	signVerifyAttestationCmd.Flags().StringVar(&verifyAttestPath, "attestation", "", "Path to the attestation (DSSE envelope, e.g. bom.json.intoto.jsonl)")
+	_ = signVerifyAttestationCmd.MarkFlagRequired("attestation")
+	signVerifyAttestationCmd.Flags().StringVar(&verifyAttestPubKey, "pub", "cosign.pub", "Public key file, or the KMS/PKCS#11/env:// key reference used for signing")
+	signVerifyAttestationCmd.Flags().StringVar(&verifyAttestImage, "image", "", "Expect the subject to be this container image (resolved to its digest)")
+	signVerifyAttestationCmd.Flags().StringVar(&verifyAttestDir, "path", "", "Expect the subject to be this source tree or file (hashed locally)")
+	signVerifyAttestationCmd.Flags().StringVar(&verifyAttestDigest, "digest", "", "Expect the subject to have this digest (ALG:HEX, e.g. sha256:…)")
 }
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/accuknox/accuknox-cli-v2/pkg/sign"
 	"github.com/accuknox/accuknox-cli-v2/pkg/tools"
 	"github.com/spf13/cobra"
 )
@@ -27,14 +28,39 @@ func init() {
 				return nil
 			},
 			RunE: func(cmd *cobra.Command, args []string) error {
+				args, tools.InsecureSkipVerify = splitSkipVerifyArg(args, tools.InsecureSkipVerify)
+				var attest sign.Options
+				if sbomTools[t.Name] {
+					var err error
+					if args, attest, err = splitAttestArgs(args); err != nil {
+						return err
+					}
+				}
 				binPath, err := t.EnsureInstalled()
 				if err != nil {
 					return err
 				}
 				args = enrichPkgscanCycloneDXArgs(t.Name, args)
+				if attest.Attest {
+					return runAttestedTool(cmd.Context(), t.Name, binPath, args, &attest)
+				}
 				return tools.Exec(binPath, args)
 			},
 		}
 		rootCmd.AddCommand(cmd)
 	}
 }
+
+// splitSkipVerifyArg removes knoxctl's own --insecure-skip-verify flag from a
+// tool's arguments and reports whether it was given (or skip already was).
+func splitSkipVerifyArg(args []string, skip bool) ([]string, bool) {
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--insecure-skip-verify" {
+			skip = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, skip
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/accuknox/accuknox-cli-v2/pkg/sign"
+	"github.com/accuknox/accuknox-cli-v2/pkg/tools"
+)
+
+// sbomTools are the tool commands that write CycloneDX SBOMs and accept
+// knoxctl's own --attest flags ahead of the tool's arguments.
+var sbomTools = map[string]bool{"sbomgen": true, "pkgscan": true}
+
+// splitAttestArgs removes the --attest, --attest-key, --attest-out and
+// --attest-subject flags from an SBOM tool's arguments and returns the
+// remaining arguments with the attestation options they describe. The key
+// passphrase is taken from $COSIGN_PASSWORD.
+func splitAttestArgs(args []string) ([]string, sign.Options, error) {
+	var opts sign.Options
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--attest" {
+			opts.Attest = true
+			continue
+		}
+		var dst *string
+		flag, value, hasValue := strings.Cut(arg, "=")
+		switch flag {
+		case "--attest-key":
+			dst = &opts.KeyRef
+		case "--attest-out":
+			dst = &opts.AttestOut
+		case "--attest-subject":
+			dst = &opts.Subject
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, opts, fmt.Errorf("flag %s needs a value", flag)
+			}
+			i++
+			value = args[i]
+		}
+		*dst = value
+	}
+	if !opts.Attest && (opts.KeyRef != "" || opts.AttestOut != "" || opts.Subject != "") {
+		return nil, opts, fmt.Errorf("--attest-key, --attest-out and --attest-subject require --attest")
+	}
+	return rest, opts, nil
+}
+
+// toolSBOMOutput returns the CycloneDX JSON file an SBOM tool invocation
+// writes: pkgscan's -o cyclonedx-json=FILE, or sbomgen's -o FILE (default
+// bom.json).
+func toolSBOMOutput(toolName string, args []string) (string, error) {
+	for i, arg := range args {
+		var output string
+		switch {
+		case (arg == "-o" || arg == "--output") && i+1 < len(args):
+			output = args[i+1]
+		case strings.HasPrefix(arg, "-o="):
+			output = strings.TrimPrefix(arg, "-o=")
+		case strings.HasPrefix(arg, "--output="):
+			output = strings.TrimPrefix(arg, "--output=")
+		default:
+			continue
+		}
+		if toolName != "pkgscan" {
+			return output, nil
+		}
+		if file, ok := strings.CutPrefix(output, "cyclonedx-json="); ok && file != "" {
+			return file, nil
+		}
+	}
+	if toolName == "sbomgen" {
+		return "bom.json", nil
+	}
+	return "", fmt.Errorf("--attest needs %s to write CycloneDX JSON to a file (-o cyclonedx-json=FILE)", toolName)
+}
+
+// sbomgenValueFlags are sbomgen flags whose value is the next argument, so
+// that a trailing "-o bom.json" is not taken for the scan target.
+var sbomgenValueFlags = map[string]bool{"-o": true, "--output": true, "-t": true, "--type": true}
+
+// toolScanTarget returns the source an SBOM tool invocation scans: the
+// argument after pkgscan's "scan" subcommand, or sbomgen's trailing path or
+// image argument (default: the working directory).
+func toolScanTarget(toolName string, args []string) string {
+	if toolName == "pkgscan" {
+		i := 0
+		if len(args) > 0 && args[0] == "scan" {
+			i = 1
+		}
+		if i < len(args) && !strings.HasPrefix(args[i], "-") {
+			return args[i]
+		}
+		return ""
+	}
+	n := len(args)
+	if n == 0 || strings.HasPrefix(args[n-1], "-") || (n > 1 && sbomgenValueFlags[args[n-2]]) {
+		return "."
+	}
+	return args[n-1]
+}
+
+// toolTargetSubject resolves a scan target to an attestation subject. Syft
+// source schemes (dir:, docker:, registry:, …) are honoured; otherwise an
+// existing path is hashed and anything else is taken for an image reference.
+func toolTargetSubject(ctx context.Context, target string) (sign.Subject, error) {
+	if scheme, rest, ok := strings.Cut(target, ":"); ok {
+		switch scheme {
+		case "dir", "file", "oci-dir", "oci-archive", "docker-archive", "singularity":
+			return sign.PathSubject(rest)
+		case "docker", "podman", "containerd", "registry":
+			return sign.ImageSubject(ctx, rest)
+		}
+	}
+	if _, err := os.Stat(target); err == nil {
+		return sign.PathSubject(target)
+	}
+	return sign.ImageSubject(ctx, target)
+}
+
+// runAttestedTool runs an SBOM tool to completion and attests the SBOM it
+// wrote, with the scanned source as the subject unless --attest-subject is
+// given.
+func runAttestedTool(ctx context.Context, toolName, binPath string, args []string, opts *sign.Options) error {
+	out, err := toolSBOMOutput(toolName, args)
+	if err != nil {
+		return err
+	}
+	if err := tools.Run(binPath, args); err != nil {
+		return err
+	}
+
+	var subjects []sign.Subject
+	if opts.Subject == "" {
+		target := toolScanTarget(toolName, args)
+		if target == "" {
+			return fmt.Errorf("--attest could not determine what %s scanned; pass --attest-subject NAME@sha256:HEX", toolName)
+		}
+		s, err := toolTargetSubject(ctx, target)
+		if err != nil {
+			return fmt.Errorf("attest: %w", err)
+		}
+		subjects = append(subjects, s)
+	}
+	return sign.Attest(out, subjects, opts)
+}
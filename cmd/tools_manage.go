@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/accuknox/accuknox-cli-v2/pkg/download"
+	"github.com/accuknox/accuknox-cli-v2/pkg/tools"
+	"github.com/spf13/cobra"
+)
+
+var (
+	toolsOutput       string
+	toolsInstallForce bool
+	toolsCleanDryRun  bool
+)
+
+var toolsCmd = &cobra.Command{
+	Use:   "tools",
+	Short: "Manage the external tools knoxctl runs (sbomgen, imgscan, pkgscan)",
+	Long: `Show and manage the external tools knoxctl runs, as declared in its bundled
+tools.yaml.
+
+A tool is taken, in this order, from the binary embedded in knoxctl at build
+time, from next to the knoxctl executable, from the download cache under
+~/.accuknox-config/tools/<name>/<version>, or downloaded there on first use.
+
+Versions and download URLs can be overridden in a user-level tools.yaml at
+~/.accuknox-config/tools.yaml (or $` + tools.UserConfigEnv + `), with the layout of the
+bundled one. Setting only a version rewrites the bundled download URLs for it:
+
+  tools:
+    - name: pkgscan
+      version: "1.43.0"
+
+Downloads are verified against the sha256 in tools.yaml or the vendor's
+checksum file, and the vendor's cosign (keyed or keyless) or OpenPGP
+signature where one is configured; a download that cannot be verified is
+refused. Cached binaries are verified again before each run, against the
+checksums knoxctl records in ~/.accuknox-config/tool-sums.json rather than
+anything in the cache. --insecure-skip-verify, or
+$` + tools.SkipVerifyEnv + `=true, accepts unverifiable downloads; it is also
+accepted by the tool commands themselves (e.g. knoxctl pkgscan
+--insecure-skip-verify scan .).
+
+Downloads honour $HTTPS_PROXY and the mirrors and CA bundle set in
+~/.accuknox-config/download.yaml (or $` + download.ConfigEnv + `):
+
+  mirrors:
+    github.com/: artifactory.internal/artifactory/github/
+  caBundle: /etc/pki/corp-root-ca.pem`,
+	// Override the root PersistentPreRunE — tools don't need a k8s client.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+}
+
+var toolsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the tools with their version and where each is taken from",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := tools.Load()
+		if err != nil {
+			return err
+		}
+		statuses := make([]*tools.Status, 0, len(cfg.Tools))
+		for i := range cfg.Tools {
+			statuses = append(statuses, cfg.Tools[i].Locate())
+		}
+		if toolsOutput == "json" {
+			return printToolsJSON(statuses)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		defer w.Flush()
+		fmt.Fprintln(w, "NAME\tVERSION\tSOURCE\tINSTALLED\tPATH")
+		for _, st := range statuses {
+			version, source, path := st.Version, st.Source, st.Path
+			if st.Overridden {
+				version += " (user)"
+			}
+			if st.Err != nil {
+				source, path = "unavailable", st.Err.Error()
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\n", st.Name, version, source, st.Installed, path)
+		}
+		return nil
+	},
+}
+
+var toolsInstallCmd = &cobra.Command{
+	Use:   "install [tool...]",
+	Short: "Install tools ahead of time for offline use",
+	Long: `Extract the embedded tools and download the others into the cache, so later
+commands need no network access. Without arguments every tool available on
+this platform is installed. --force downloads cached tools again.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		selected, err := selectTools(args)
+		if err != nil {
+			return err
+		}
+		var failed []string
+		for _, t := range selected {
+			if st := t.Locate(); st.Err != nil && len(args) == 0 {
+				fmt.Printf("%s: skipped, %v\n", t.Name, st.Err)
+				continue
+			}
+			st, err := t.Install(toolsInstallForce)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", t.Name, err)
+				failed = append(failed, t.Name)
+				continue
+			}
+			fmt.Printf("%s %s: %s (%s)\n", st.Name, st.Version, st.Path, st.Source)
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("failed to install %s", strings.Join(failed, ", "))
+		}
+		return nil
+	},
+}
+
+var toolsUpgradeCmd = &cobra.Command{
+	Use:   "upgrade [tool...]",
+	Short: "Install the configured tool versions and drop other cached versions",
+	Long: `Install the version of each tool given by tools.yaml and the user overlay,
+then remove the other versions of it from the download cache. Tools embedded
+in knoxctl or deployed next to it are upgraded with knoxctl itself.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		selected, err := selectTools(args)
+		if err != nil {
+			return err
+		}
+		for _, t := range selected {
+			switch st := t.Locate(); {
+			case st.Err != nil && len(args) > 0:
+				return st.Err
+			case st.Err != nil:
+				fmt.Printf("%s: skipped, %v\n", t.Name, st.Err)
+				continue
+			case st.Source == tools.SourceEmbedded || st.Source == tools.SourceSideBySide:
+				fmt.Printf("%s %s: %s, upgraded with knoxctl\n", t.Name, t.Version, st.Source)
+				continue
+			}
+			removed, err := t.Upgrade()
+			if err != nil {
+				return err
+			}
+			if len(removed) > 0 {
+				fmt.Printf("%s %s: removed %s\n", t.Name, t.Version, strings.Join(removed, ", "))
+			} else {
+				fmt.Printf("%s %s: up to date\n", t.Name, t.Version)
+			}
+		}
+		return nil
+	},
+}
+
+var toolsVerifyCmd = &cobra.Command{
+	Use:   "verify [tool...]",
+	Short: "Verify the SHA256 of installed tool binaries",
+	Long: `Check the SHA256 of each installed tool binary against the binary embedded in
+knoxctl, the sha256 in tools.yaml, or the checksum recorded when its verified
+download was installed. Binaries next to the knoxctl executable that tools.yaml
+does not pin are reported as unverified. The command fails when a binary does
+not match.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		selected, err := selectTools(args)
+		if err != nil {
+			return err
+		}
+		var results []*tools.Verification
+		var mismatched []string
+		for _, t := range selected {
+			v, err := t.Verify()
+			if err != nil {
+				if len(args) > 0 {
+					return err
+				}
+				continue
+			}
+			if v.Result == tools.VerifyMismatch {
+				mismatched = append(mismatched, t.Name)
+			}
+			results = append(results, v)
+		}
+		if toolsOutput == "json" {
+			if err := printToolsJSON(results); err != nil {
+				return err
+			}
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "NAME\tVERSION\tRESULT\tAGAINST\tPATH")
+			for _, v := range results {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", v.Name, v.Version, v.Result, orDash(v.Against), orDash(v.Path))
+			}
+			w.Flush()
+		}
+		if len(mismatched) > 0 {
+			return fmt.Errorf("SHA256 mismatch for %s; reinstall with 'knoxctl tools install --force'", strings.Join(mismatched, ", "))
+		}
+		return nil
+	},
+}
+
+var toolsCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove cached tool versions that are no longer used",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := tools.Load()
+		if err != nil {
+			return err
+		}
+		stale, err := cfg.StaleCaches()
+		if err != nil {
+			return err
+		}
+		if len(stale) == 0 {
+			fmt.Println("No stale tool caches.")
+			return nil
+		}
+		for _, p := range stale {
+			fmt.Println(p)
+		}
+		if toolsCleanDryRun {
+			return nil
+		}
+		if err := tools.RemoveCaches(stale); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d stale cache entries.\n", len(stale))
+		return nil
+	},
+}
+
+// selectTools returns the named tools, or all of them when names is empty.
+func selectTools(names []string) ([]*tools.Tool, error) {
+	cfg, err := tools.Load()
+	if err != nil {
+		return nil, err
+	}
+	var selected []*tools.Tool
+	if len(names) == 0 {
+		for i := range cfg.Tools {
+			selected = append(selected, &cfg.Tools[i])
+		}
+		return selected, nil
+	}
+	for _, name := range names {
+		t := cfg.Find(name)
+		if t == nil {
+			return nil, fmt.Errorf("unknown tool %q", name)
+		}
+		selected = append(selected, t)
+	}
+	return selected, nil
+}
+
+func printToolsJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	toolsListCmd.Flags().StringVarP(&toolsOutput, "output", "o", "text", `Output format: "text" or "json"`)
+	toolsVerifyCmd.Flags().StringVarP(&toolsOutput, "output", "o", "text", `Output format: "text" or "json"`)
+	toolsInstallCmd.Flags().BoolVar(&toolsInstallForce, "force", false, "Download cached tools again")
+	for _, c := range []*cobra.Command{toolsInstallCmd, toolsUpgradeCmd} {
+		c.Flags().BoolVar(&tools.InsecureSkipVerify, "insecure-skip-verify", tools.InsecureSkipVerify, "Install tools whose downloads cannot be verified (a known checksum or signature must still match)")
+	}
+	toolsCleanCmd.Flags().BoolVar(&toolsCleanDryRun, "dry-run", false, "Only list what would be removed")
+
+	toolsCmd.AddCommand(toolsListCmd, toolsInstallCmd, toolsUpgradeCmd, toolsVerifyCmd, toolsCleanCmd)
+	rootCmd.AddCommand(toolsCmd)
+}
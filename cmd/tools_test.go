@@ -69,3 +69,90 @@ func TestEnrichPkgscanCycloneDXArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitAttestArgs(t *testing.T) {
+	args := []string{"scan", "--attest", "alpine", "--attest-key", "awskms:///alias/bom", "-o", "cyclonedx-json=sbom.json", "--attest-subject=alpine@sha256:ab"}
+	rest, opts, err := splitAttestArgs(args)
+	if err != nil {
+		t.Fatalf("splitAttestArgs() error = %v", err)
+	}
+	if want := []string{"scan", "alpine", "-o", "cyclonedx-json=sbom.json"}; !reflect.DeepEqual(rest, want) {
+		t.Fatalf("splitAttestArgs() args = %#v, want %#v", rest, want)
+	}
+	if !opts.Attest || opts.KeyRef != "awskms:///alias/bom" || opts.Subject != "alpine@sha256:ab" {
+		t.Fatalf("splitAttestArgs() opts = %+v", opts)
+	}
+
+	for _, bad := range [][]string{{"--attest", "--attest-key"}, {"--attest-key", "cosign.key"}} {
+		if _, _, err := splitAttestArgs(bad); err == nil {
+			t.Errorf("splitAttestArgs(%#v): expected error", bad)
+		}
+	}
+}
+
+func TestSplitSkipVerifyArg(t *testing.T) {
+	rest, skip := splitSkipVerifyArg([]string{"--insecure-skip-verify", "scan", "."}, false)
+	if want := []string{"scan", "."}; !skip || !reflect.DeepEqual(rest, want) {
+		t.Fatalf("splitSkipVerifyArg() = %#v, %v", rest, skip)
+	}
+	if _, skip := splitSkipVerifyArg([]string{"scan"}, true); !skip {
+		t.Error("splitSkipVerifyArg() dropped an earlier skip")
+	}
+}
+
+func TestToolSBOMOutputAndTarget(t *testing.T) {
+	tests := []struct {
+		name       string
+		toolName   string
+		args       []string
+		wantOut    string
+		wantTarget string
+		wantErr    bool
+	}{
+		{
+			name:       "pkgscan cyclonedx file",
+			toolName:   "pkgscan",
+			args:       []string{"scan", "registry:alpine:3.20", "-o", "table", "-o", "cyclonedx-json=sbom.json"},
+			wantOut:    "sbom.json",
+			wantTarget: "registry:alpine:3.20",
+		},
+		{
+			name:     "pkgscan without a cyclonedx file",
+			toolName: "pkgscan",
+			args:     []string{"scan", ".", "-o", "cyclonedx-json"},
+			wantErr:  true,
+		},
+		{
+			name:       "sbomgen defaults",
+			toolName:   "sbomgen",
+			args:       []string{"-o", "out.json"},
+			wantOut:    "out.json",
+			wantTarget: ".",
+		},
+		{
+			name:       "sbomgen image",
+			toolName:   "sbomgen",
+			args:       []string{"-t", "docker", "nginx:1.27"},
+			wantOut:    "bom.json",
+			wantTarget: "nginx:1.27",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := toolSBOMOutput(tt.toolName, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("toolSBOMOutput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if out != tt.wantOut {
+				t.Errorf("toolSBOMOutput() = %q, want %q", out, tt.wantOut)
+			}
+			if got := toolScanTarget(tt.toolName, tt.args); got != tt.wantTarget {
+				t.Errorf("toolScanTarget() = %q, want %q", got, tt.wantTarget)
+			}
+		})
+	}
+}
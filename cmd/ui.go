@@ -5,6 +5,9 @@ package cmd
 
 import (
 	"fmt"
+	"net"
+	"strconv"
+	"strings"
 
 	"github.com/accuknox/accuknox-cli-v2/pkg/ui"
 	"github.com/accuknox/accuknox-cli-v2/pkg/version"
@@ -13,32 +16,91 @@ import (
 
 var uiAddr string
 var uiPort int
+var uiOpts ui.Options
 
 var uiCmd = &cobra.Command{
 	Use:   "ui",
 	Short: "Open the knoxctl web UI in your default browser",
-	Long:  `Start an embedded web UI server and open it in the default browser. Provides a graphical interface for BOM generation (SBOM, CBOM, AIBOM), VM onboarding, security probing, and container image scanning.`,
+	Long: `Start an embedded web UI server and open it in the default browser. Provides a graphical interface for BOM generation (SBOM, CBOM, AIBOM), VM onboarding, security probing, and container image scanning.
+
+The server listens on localhost only unless --addr says otherwise. Each run
+generates a session token that every request must carry; it is part of the
+URL printed on start and opened in the browser, which then keeps it in a
+session cookie. Other clients can send it as "Authorization: Bearer <token>".
+Cross-origin requests are refused. The same handlers are also served as a
+versioned REST API under /api/v1; see "knoxctl serve".
+
+With --tls the UI is served over HTTPS using --tls-cert and --tls-key, or a
+self-signed certificate generated for the session whose SHA-256 fingerprint
+is printed on start.
+
+Every operation runs as a job that carries on when the browser is closed.
+Jobs, with their inputs, logs and the BOMs they produce, are kept under
+--jobs-dir and listed on the dashboard, where running jobs can be followed
+again or cancelled and produced files downloaded.
+
+The Reports page shows the output of "knoxctl scan" found in --reports-dir
+(process trees, network events and processed alerts) and browses the
+components of CycloneDX BOMs, with the crypto properties of CBOM assets and
+the model cards of AIBOM models.
+
+Commands the UI may run are limited to: ` + strings.Join(ui.DefaultRunCommands, ", ") + `.
+--allow-command adds others; the image scan and VM onboarding pages need
+--allow-command image-scan and --allow-command "onboard vm".
+
+Examples:
+  knoxctl ui
+  knoxctl ui --port 8443 --tls
+  knoxctl ui --addr 0.0.0.0:10100 --tls --tls-cert ui.crt --tls-key ui.key`,
 	// Override the root PersistentPreRunE to skip k8s client initialisation,
 	// which is not needed for the standalone UI server.
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		return nil
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		ver := version.GitSummary
-		if ver == "" {
-			ver = "dev"
-		}
-		addr := uiAddr
-		if cmd.Flags().Changed("port") {
-			addr = fmt.Sprintf("0.0.0.0:%d", uiPort)
-		}
-		srv := ui.NewServer(addr, ver)
-		return srv.Start()
+		return startServer(cmd, uiAddr, uiPort, &uiOpts)
 	},
 }
 
+// startServer starts the UI/API server for "knoxctl ui" and "knoxctl serve",
+// with --port overriding the port of --addr.
+func startServer(cmd *cobra.Command, addr string, port int, opts *ui.Options) error {
+	ver := version.GitSummary
+	if ver == "" {
+		ver = "dev"
+	}
+	if cmd.Flags().Changed("port") {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return fmt.Errorf("invalid --addr %q: %w", addr, err)
+		}
+		addr = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return fmt.Errorf("--tls-cert and --tls-key must be given together")
+	}
+	opts.Addr = addr
+	opts.Version = ver
+	srv, err := ui.NewServer(opts)
+	if err != nil {
+		return err
+	}
+	return srv.Start()
+}
+
+// addServerFlags registers the flags "knoxctl ui" and "knoxctl serve" share.
+func addServerFlags(cmd *cobra.Command, addr *string, port *int, opts *ui.Options) {
+	cmd.Flags().StringVar(addr, "addr", "127.0.0.1:10100", "Address and port for the server to listen on")
+	cmd.Flags().IntVar(port, "port", 10100, "Port for the server to listen on (overrides --addr port)")
+	cmd.Flags().BoolVar(&opts.TLS, "tls", false, "Serve over HTTPS (self-signed certificate unless --tls-cert/--tls-key are given)")
+	cmd.Flags().StringVar(&opts.CertFile, "tls-cert", "", "PEM certificate for HTTPS")
+	cmd.Flags().StringVar(&opts.KeyFile, "tls-key", "", "PEM private key for HTTPS")
+	cmd.Flags().StringVar(&opts.JobsDir, "jobs-dir", "", "Directory keeping the job history (default ~/.accuknox-config/ui/jobs)")
+	cmd.Flags().StringVar(&opts.ReportsDir, "reports-dir", ".", "Directory of knoxctl scan reports and BOM files to browse")
+	cmd.Flags().StringSliceVar(&opts.AllowCommands, "allow-command", nil, `Additional knoxctl command that may be run, e.g. "scan" (repeatable)`)
+}
+
 func init() {
 	rootCmd.AddCommand(uiCmd)
-	uiCmd.Flags().StringVar(&uiAddr, "addr", "0.0.0.0:10100", "Address and port for the UI server to listen on")
-	uiCmd.Flags().IntVar(&uiPort, "port", 10100, "Port for the UI server to listen on (overrides --addr port)")
+	addServerFlags(uiCmd, &uiAddr, &uiPort, &uiOpts)
 }
@@ -2,9 +2,10 @@
 // Copyright 2024 Authors of KubeArmor
 
 // Package aibom generates AI Bill of Materials (AIBOM) documents compliant
-// with CycloneDX 1.6. It fetches model metadata from the HuggingFace API and
-// produces a machine-readable inventory of AI/ML model components including
-// training datasets, architecture, licensing, and performance metrics.
+// with CycloneDX 1.6. It fetches model metadata from the HuggingFace API (or
+// reads it from model files on disk) and produces a machine-readable
+// inventory of AI/ML model components including training datasets,
+// architecture, licensing, and performance metrics.
 package aibom
 
 import (
@@ -20,6 +21,7 @@ import (
 	cdx "github.com/CycloneDX/cyclonedx-go"
 	"github.com/google/uuid"
 
+	"github.com/accuknox/accuknox-cli-v2/pkg/bom"
 	"github.com/accuknox/accuknox-cli-v2/pkg/sign"
 )
 
@@ -63,26 +65,92 @@ func Generate(opts *Options) (*cdx.BOM, error) {
 		return nil, err
 	}
 
-	return buildBOM(info, opts), nil
+	opts.models = hubSubjects(info)
+	doc := buildBOM(info, opts)
+	if opts.LineageDepth > 0 {
+		resolveLineage(doc, info, newHubFetcher(opts.Token), opts.LineageDepth)
+	}
+	serial := checkSiblings(info.Siblings)
+	annotateSerialization(&(*doc.Components)[0], serial)
+	if opts.FailOnUnsafe {
+		if err := serial.unsafeError(); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
 }
 
 // Output writes the BOM to stdout or to opts.OutputTo.
-func Output(bom *cdx.BOM, opts *Options) error {
+func Output(doc *cdx.BOM, opts *Options) error {
+	if opts.Sign.Attest && (opts.OutputTo == "" || strings.EqualFold(opts.Format, "table")) {
+		return fmt.Errorf("--attest requires JSON output written with --out")
+	}
+	if err := stamp(doc, opts); err != nil {
+		return err
+	}
 	switch strings.ToLower(opts.Format) {
 	case "table":
-		return printTable(bom, opts)
+		return printTable(doc, opts)
 	default:
-		return printJSON(bom, opts)
+		return printJSON(doc, opts)
+	}
+}
+
+// stamp records how the BOM was produced — the knoxctl version, the digest of
+// the local model or source tree, the commit of each Hub model, and the
+// options — and, with opts.Deterministic, canonicalizes it so that
+// identical inputs give byte-identical output.
+func stamp(b *cdx.BOM, opts *Options) error {
+	opts.inputs = nil
+	if opts.Path != "" {
+		var exclude []string
+		if opts.OutputTo != "" {
+			exclude = sign.OutputFiles(opts.OutputTo, &opts.Sign)
+		}
+		s, err := sign.PathSubject(opts.Path, exclude...)
+		if err != nil {
+			return fmt.Errorf("hashing %s: %w", opts.Path, err)
+		}
+		opts.inputs = append(opts.inputs, s)
+	}
+
+	err := bom.Stamp(b, &bom.Provenance{
+		ToolVersion: opts.ToolVersion,
+		Inputs:      append(append([]sign.Subject(nil), opts.inputs...), opts.models...),
+		Options: recordedOptions{
+			ModelID: opts.ModelID, Path: opts.Path, ModelsFile: opts.ModelsFile,
+			Name: opts.Name, Version: opts.Version, Manufacturer: opts.Manufacturer,
+			LineageDepth: opts.LineageDepth, FailOnUnsafe: opts.FailOnUnsafe,
+			Deterministic: opts.Deterministic,
+		},
+	})
+	if err != nil {
+		return err
 	}
+	if opts.Deterministic {
+		return bom.Canonicalize(b)
+	}
+	return nil
+}
+
+// hubSubjects pins a Hub model to the commit its metadata was read at.
+func hubSubjects(info *hfModelInfo) []sign.Subject {
+	if info.SHA == "" {
+		return nil
+	}
+	return []sign.Subject{{
+		Name:   "huggingface.co/" + info.effectiveID(),
+		Digest: map[string]string{"gitCommit": info.SHA},
+	}}
 }
 
 // ModelCount returns the number of machine-learning-model components in the BOM.
-func ModelCount(bom *cdx.BOM) int {
-	if bom.Components == nil {
+func ModelCount(doc *cdx.BOM) int {
+	if doc.Components == nil {
 		return 0
 	}
 	count := 0
-	for _, c := range *bom.Components {
+	for _, c := range *doc.Components {
 		if c.Type == cdx.ComponentTypeMachineLearningModel {
 			count++
 		}
@@ -95,17 +163,17 @@ func ModelCount(bom *cdx.BOM) int {
 // ──────────────────────────────────────────────────────────────────────────────
 
 func buildBOM(info *hfModelInfo, opts *Options) *cdx.BOM {
-	bom := cdx.NewBOM()
-	bom.SerialNumber = "urn:uuid:" + uuid.New().String()
+	doc := cdx.NewBOM()
+	doc.SerialNumber = "urn:uuid:" + uuid.New().String()
 	// Override to CycloneDX 1.7.  The library only supports up to 1.6; we
 	// set JSONSchema here and patch specVersion in the JSON output.
-	bom.JSONSchema = jsonSchema17
+	doc.JSONSchema = jsonSchema17
 
 	// Build the main ML model component first so we can reference its
 	// bom-ref and name in metadata.component.
 	modelComp := buildModelComponent(info, opts)
 
-	bom.Metadata = &cdx.Metadata{
+	doc.Metadata = &cdx.Metadata{
 		Timestamp:  time.Now().UTC().Format(time.RFC3339),
 		Lifecycles: &[]cdx.Lifecycle{{Phase: cdx.LifecyclePhaseBuild}},
 		Tools: &cdx.ToolsChoice{
@@ -121,24 +189,24 @@ func buildBOM(info *hfModelInfo, opts *Options) *cdx.BOM {
 	}
 	allComps := []cdx.Component{modelComp}
 	allComps = append(allComps, buildDataComponents(info)...)
-	bom.Components = &allComps
+	doc.Components = &allComps
 
 	// Guarantee name, bom-ref, and license are present on every component.
-	enforceRequiredFields(bom)
+	enforceRequiredFields(doc)
 
-	return bom
+	return doc
 }
 
 // enforceRequiredFields ensures that every component in the BOM carries the
 // three mandatory fields: Name, BOMRef, and Licenses.  Missing licenses are
 // filled in with "LicenseRef-unknown" so the field is never silently absent.
 // A missing BOMRef is derived from the component name as a last resort.
-func enforceRequiredFields(bom *cdx.BOM) {
-	if bom.Components == nil {
+func enforceRequiredFields(doc *cdx.BOM) {
+	if doc.Components == nil {
 		return
 	}
 	unknown := cdx.Licenses{cdx.LicenseChoice{License: &cdx.License{ID: "LicenseRef-unknown"}}}
-	comps := *bom.Components
+	comps := *doc.Components
 	for i := range comps {
 		// BOMRef — must never be empty; fall back to name if purl was not set.
 		if comps[i].BOMRef == "" {
@@ -557,8 +625,8 @@ func buildInlineDatasets(info *hfModelInfo) *[]cdx.ComponentData {
 // Output
 // ──────────────────────────────────────────────────────────────────────────────
 
-func printJSON(bom *cdx.BOM, opts *Options) error {
-	data, err := json.MarshalIndent(bom, "", "  ")
+func printJSON(doc *cdx.BOM, opts *Options) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshaling AIBOM: %w", err)
 	}
@@ -573,23 +641,36 @@ func printJSON(bom *cdx.BOM, opts *Options) error {
 			return fmt.Errorf("writing AIBOM to %s: %w", opts.OutputTo, err)
 		}
 		fmt.Printf("AIBOM written to %s\n", opts.OutputTo)
-		return sign.Artifact(opts.OutputTo, &opts.Sign)
+		if err := sign.Artifact(opts.OutputTo, &opts.Sign); err != nil {
+			return err
+		}
+		return attest(opts)
 	}
 	fmt.Println(string(data))
 	return nil
 }
 
-func printTable(bom *cdx.BOM, opts *Options) error {
+// attest writes the signed in-toto attestation for opts.OutputTo. A local
+// model or scanned source tree is the subject; hosted and cloud inventories
+// have no artifact digest, so the AIBOM itself is attested.
+func attest(opts *Options) error {
+	if !opts.Sign.Attest {
+		return nil
+	}
+	return sign.Attest(opts.OutputTo, opts.inputs, &opts.Sign)
+}
+
+func printTable(doc *cdx.BOM, opts *Options) error {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
 	defer w.Flush()
 
 	fmt.Fprintln(w, "MODEL\tGROUP\tVERSION\tTASK\tARCHITECTURE\tLICENSE")
 	fmt.Fprintln(w, "-----\t-----\t-------\t----\t------------\t-------")
 
-	if bom.Components == nil {
+	if doc.Components == nil {
 		return nil
 	}
-	for _, c := range *bom.Components {
+	for _, c := range *doc.Components {
 		if c.Type != cdx.ComponentTypeMachineLearningModel {
 			continue
 		}
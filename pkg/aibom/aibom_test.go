@@ -4,10 +4,24 @@
 package aibom
 
 import (
+	"archive/zip"
+	"bufio"
 	"bytes"
+	"context"
+	"encoding/binary"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrock"
+	brtypes "github.com/aws/aws-sdk-go-v2/service/bedrock/types"
+
 	cdx "github.com/CycloneDX/cyclonedx-go"
 )
 
@@ -209,12 +223,12 @@ func TestToolComponent_RequiredFields(t *testing.T) {
 }
 
 func TestBuildBOM_MetadataComponent(t *testing.T) {
-	bom := buildBOM(sampleModelInfo(), &Options{})
+	doc := buildBOM(sampleModelInfo(), &Options{})
 
-	if bom.Metadata == nil {
+	if doc.Metadata == nil {
 		t.Fatal("metadata must be set")
 	}
-	c := bom.Metadata.Component
+	c := doc.Metadata.Component
 	if c == nil {
 		t.Fatal("metadata.component must be set")
 	}
@@ -228,7 +242,7 @@ func TestBuildBOM_MetadataComponent(t *testing.T) {
 		t.Errorf("metadata.component: Type = %q, want machine-learning-model", c.Type)
 	}
 	// bom-ref must match the main ML model component so they cross-reference.
-	comps := *bom.Components
+	comps := *doc.Components
 	var mlBOMRef string
 	for _, comp := range comps {
 		if comp.Type == cdx.ComponentTypeMachineLearningModel {
@@ -241,7 +255,7 @@ func TestBuildBOM_MetadataComponent(t *testing.T) {
 	}
 
 	// Verify it appears in the serialised JSON.
-	raw, err := json.MarshalIndent(bom, "", "  ")
+	raw, err := json.MarshalIndent(doc, "", "  ")
 	if err != nil {
 		t.Fatalf("marshal: %v", err)
 	}
@@ -251,19 +265,19 @@ func TestBuildBOM_MetadataComponent(t *testing.T) {
 }
 
 func TestBuildBOM_ToolsComponentsSection(t *testing.T) {
-	bom := buildBOM(sampleModelInfo(), &Options{})
+	doc := buildBOM(sampleModelInfo(), &Options{})
 
-	if bom.Metadata == nil {
+	if doc.Metadata == nil {
 		t.Fatal("metadata must be set")
 	}
-	if bom.Metadata.Tools == nil {
+	if doc.Metadata.Tools == nil {
 		t.Fatal("metadata.tools must be set")
 	}
-	if bom.Metadata.Tools.Components == nil || len(*bom.Metadata.Tools.Components) == 0 {
+	if doc.Metadata.Tools.Components == nil || len(*doc.Metadata.Tools.Components) == 0 {
 		t.Fatal("metadata.tools.components must be non-empty")
 	}
 
-	tool := (*bom.Metadata.Tools.Components)[0]
+	tool := (*doc.Metadata.Tools.Components)[0]
 	if tool.BOMRef == "" {
 		t.Error("tools component: BOMRef must not be empty")
 	}
@@ -278,7 +292,7 @@ func TestBuildBOM_ToolsComponentsSection(t *testing.T) {
 	}
 
 	// Verify the section is present in the serialised JSON output.
-	raw, err := json.MarshalIndent(bom, "", "  ")
+	raw, err := json.MarshalIndent(doc, "", "  ")
 	if err != nil {
 		t.Fatalf("marshal: %v", err)
 	}
@@ -294,30 +308,30 @@ func TestBuildBOM_ToolsComponentsSection(t *testing.T) {
 }
 
 func TestBuildBOM_BasicStructure(t *testing.T) {
-	bom := buildBOM(sampleModelInfo(), &Options{})
+	doc := buildBOM(sampleModelInfo(), &Options{})
 
-	if bom.SerialNumber == "" {
+	if doc.SerialNumber == "" {
 		t.Error("SerialNumber should be set")
 	}
-	if bom.Metadata == nil || bom.Metadata.Timestamp == "" {
+	if doc.Metadata == nil || doc.Metadata.Timestamp == "" {
 		t.Error("Metadata.Timestamp should be set")
 	}
-	if bom.Metadata.Tools == nil {
+	if doc.Metadata.Tools == nil {
 		t.Error("Metadata.Tools should be set")
 	}
-	if bom.Components == nil || len(*bom.Components) == 0 {
+	if doc.Components == nil || len(*doc.Components) == 0 {
 		t.Fatal("expected at least one component")
 	}
 	// Must target the 1.7 schema.
-	if bom.JSONSchema != jsonSchema17 {
-		t.Errorf("JSONSchema = %q, want %q", bom.JSONSchema, jsonSchema17)
+	if doc.JSONSchema != jsonSchema17 {
+		t.Errorf("JSONSchema = %q, want %q", doc.JSONSchema, jsonSchema17)
 	}
 }
 
 func TestBuildBOM_SpecVersion17Patch(t *testing.T) {
 	// The JSON output must declare specVersion "1.7" after the patch.
-	bom := buildBOM(sampleModelInfo(), &Options{})
-	raw, err := json.MarshalIndent(bom, "", "  ")
+	doc := buildBOM(sampleModelInfo(), &Options{})
+	raw, err := json.MarshalIndent(doc, "", "  ")
 	if err != nil {
 		t.Fatalf("marshal: %v", err)
 	}
@@ -334,8 +348,8 @@ func TestBuildBOM_SpecVersion17Patch(t *testing.T) {
 }
 
 func TestBuildBOM_MLModelComponent(t *testing.T) {
-	bom := buildBOM(sampleModelInfo(), &Options{})
-	comps := *bom.Components
+	doc := buildBOM(sampleModelInfo(), &Options{})
+	comps := *doc.Components
 
 	var mlComp *cdx.Component
 	for i := range comps {
@@ -366,8 +380,8 @@ func TestBuildBOM_MLModelComponent(t *testing.T) {
 }
 
 func TestBuildBOM_License(t *testing.T) {
-	bom := buildBOM(sampleModelInfo(), &Options{})
-	comps := *bom.Components
+	doc := buildBOM(sampleModelInfo(), &Options{})
+	comps := *doc.Components
 	for _, c := range comps {
 		if c.Type != cdx.ComponentTypeMachineLearningModel {
 			continue
@@ -383,8 +397,8 @@ func TestBuildBOM_License(t *testing.T) {
 }
 
 func TestBuildBOM_ModelCard(t *testing.T) {
-	bom := buildBOM(sampleModelInfo(), &Options{})
-	comps := *bom.Components
+	doc := buildBOM(sampleModelInfo(), &Options{})
+	comps := *doc.Components
 	for _, c := range comps {
 		if c.Type != cdx.ComponentTypeMachineLearningModel {
 			continue
@@ -415,8 +429,8 @@ func TestBuildBOM_ModelCard(t *testing.T) {
 }
 
 func TestBuildBOM_DataComponents(t *testing.T) {
-	bom := buildBOM(sampleModelInfo(), &Options{})
-	comps := *bom.Components
+	doc := buildBOM(sampleModelInfo(), &Options{})
+	comps := *doc.Components
 
 	var dataComps []cdx.Component
 	for _, c := range comps {
@@ -435,8 +449,8 @@ func TestBuildBOM_DataComponents(t *testing.T) {
 }
 
 func TestBuildBOM_ExternalRefs(t *testing.T) {
-	bom := buildBOM(sampleModelInfo(), &Options{})
-	comps := *bom.Components
+	doc := buildBOM(sampleModelInfo(), &Options{})
+	comps := *doc.Components
 	for _, c := range comps {
 		if c.Type != cdx.ComponentTypeMachineLearningModel {
 			continue
@@ -459,8 +473,8 @@ func TestBuildBOM_ExternalRefs(t *testing.T) {
 
 func TestBuildBOM_DefaultName_IsFullModelID(t *testing.T) {
 	// When --name is not set, the component Name must be the full model ID.
-	bom := buildBOM(sampleModelInfo(), &Options{})
-	for _, c := range *bom.Components {
+	doc := buildBOM(sampleModelInfo(), &Options{})
+	for _, c := range *doc.Components {
 		if c.Type != cdx.ComponentTypeMachineLearningModel {
 			continue
 		}
@@ -477,8 +491,8 @@ func TestBuildBOM_OverrideOptions(t *testing.T) {
 		Version:      "v1.0.0",
 		Manufacturer: "MyOrg",
 	}
-	bom := buildBOM(sampleModelInfo(), opts)
-	comps := *bom.Components
+	doc := buildBOM(sampleModelInfo(), opts)
+	comps := *doc.Components
 	for _, c := range comps {
 		if c.Type != cdx.ComponentTypeMachineLearningModel {
 			continue
@@ -501,8 +515,8 @@ func TestBuildBOM_NoLicenseInCardData(t *testing.T) {
 	info.CardData.License = nil
 	info.Tags = []string{"transformers"} // no license: tag either
 
-	bom := buildBOM(info, &Options{})
-	for _, c := range *bom.Components {
+	doc := buildBOM(info, &Options{})
+	for _, c := range *doc.Components {
 		if c.Type != cdx.ComponentTypeMachineLearningModel {
 			continue
 		}
@@ -517,8 +531,8 @@ func TestBuildBOM_NoLicenseInCardData(t *testing.T) {
 }
 
 func TestModelCount(t *testing.T) {
-	bom := buildBOM(sampleModelInfo(), &Options{})
-	if got := ModelCount(bom); got != 1 {
+	doc := buildBOM(sampleModelInfo(), &Options{})
+	if got := ModelCount(doc); got != 1 {
 		t.Errorf("ModelCount = %d, want 1", got)
 	}
 }
@@ -532,11 +546,11 @@ func TestModelCount_NilComponents(t *testing.T) {
 // TestEnforceRequiredFields verifies that name, bom-ref, and license are
 // mandatory on every component, including data components.
 func TestEnforceRequiredFields_AllComponents(t *testing.T) {
-	bom := buildBOM(sampleModelInfo(), &Options{})
-	if bom.Components == nil {
+	doc := buildBOM(sampleModelInfo(), &Options{})
+	if doc.Components == nil {
 		t.Fatal("expected components")
 	}
-	for _, c := range *bom.Components {
+	for _, c := range *doc.Components {
 		if c.Name == "" {
 			t.Errorf("component type=%s: Name must not be empty", c.Type)
 		}
@@ -554,13 +568,13 @@ func TestEnforceRequiredFields_AllComponents(t *testing.T) {
 // TestEnforceRequiredFields_MissingBOMRef verifies that a component with no
 // BOMRef gets it derived from its Name.
 func TestEnforceRequiredFields_MissingBOMRef(t *testing.T) {
-	bom := &cdx.BOM{
+	doc := &cdx.BOM{
 		Components: &[]cdx.Component{
 			{Type: cdx.ComponentTypeData, Name: "my-dataset"},
 		},
 	}
-	enforceRequiredFields(bom)
-	got := (*bom.Components)[0].BOMRef
+	enforceRequiredFields(doc)
+	got := (*doc.Components)[0].BOMRef
 	if got != "my-dataset" {
 		t.Errorf("BOMRef = %q, want %q", got, "my-dataset")
 	}
@@ -569,13 +583,13 @@ func TestEnforceRequiredFields_MissingBOMRef(t *testing.T) {
 // TestEnforceRequiredFields_MissingLicense verifies that a component with no
 // license gets "LicenseRef-unknown".
 func TestEnforceRequiredFields_MissingLicense(t *testing.T) {
-	bom := &cdx.BOM{
+	doc := &cdx.BOM{
 		Components: &[]cdx.Component{
 			{Type: cdx.ComponentTypeMachineLearningModel, Name: "my-model", BOMRef: "pkg:huggingface/my-model"},
 		},
 	}
-	enforceRequiredFields(bom)
-	c := (*bom.Components)[0]
+	enforceRequiredFields(doc)
+	c := (*doc.Components)[0]
 	if c.Licenses == nil || len(*c.Licenses) == 0 {
 		t.Fatal("Licenses must be set")
 	}
@@ -588,7 +602,7 @@ func TestEnforceRequiredFields_MissingLicense(t *testing.T) {
 // existing license is not overwritten.
 func TestEnforceRequiredFields_ExistingLicensePreserved(t *testing.T) {
 	existing := cdx.Licenses{cdx.LicenseChoice{License: &cdx.License{ID: "MIT"}}}
-	bom := &cdx.BOM{
+	doc := &cdx.BOM{
 		Components: &[]cdx.Component{
 			{
 				Type:     cdx.ComponentTypeMachineLearningModel,
@@ -598,8 +612,1075 @@ func TestEnforceRequiredFields_ExistingLicensePreserved(t *testing.T) {
 			},
 		},
 	}
-	enforceRequiredFields(bom)
-	if id := (*(*bom.Components)[0].Licenses)[0].License.ID; id != "MIT" {
+	enforceRequiredFields(doc)
+	if id := (*(*doc.Components)[0].Licenses)[0].License.ID; id != "MIT" {
 		t.Errorf("existing license should not be overwritten; got %q", id)
 	}
 }
+
+// ── local model inspection ────────────────────────────────────────────────────
+
+func TestGenerateFromLocal_SafeTensorsDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "config.json", `{
+		"_name_or_path": "acme/tiny-bert",
+		"model_type": "bert",
+		"architectures": ["BertForSequenceClassification"],
+		"vocab_size": 30522,
+		"max_position_embeddings": 512
+	}`)
+	writeTestFile(t, dir, "README.md", `---
+license: apache-2.0
+pipeline_tag: text-classification
+library_name: transformers
+datasets:
+  - glue
+base_model: google-bert/bert-base-uncased
+model-index:
+  - name: tiny-bert
+    results:
+      - task: {type: text-classification}
+        dataset: {name: SST-2, type: glue}
+        metrics:
+          - type: accuracy
+            value: 0.91
+---
+# tiny-bert
+`)
+	writeTestFile(t, dir, "model.safetensors", string(testSafeTensors(t, map[string]interface{}{
+		"__metadata__": map[string]string{"format": "pt"},
+		"embeddings":   map[string]interface{}{"dtype": "BF16", "shape": []int{1000, 64}, "data_offsets": []int{0, 0}},
+		"classifier":   map[string]interface{}{"dtype": "F32", "shape": []int{64, 2}, "data_offsets": []int{0, 0}},
+	})))
+
+	doc, err := GenerateFromLocal(&Options{Path: dir})
+	if err != nil {
+		t.Fatalf("GenerateFromLocal: %v", err)
+	}
+	c := (*doc.Components)[0]
+	if c.Name != "acme/tiny-bert" || c.Group != "acme" {
+		t.Errorf("Name/Group = %q/%q, want acme/tiny-bert / acme", c.Name, c.Group)
+	}
+	if len(c.Version) != 7 || c.PackageURL != "pkg:generic/acme/tiny-bert@"+c.Version {
+		t.Errorf("PackageURL = %q, Version = %q", c.PackageURL, c.Version)
+	}
+	if doc.Metadata.Component.BOMRef != c.BOMRef {
+		t.Error("metadata.component must reference the model component")
+	}
+	if (*c.Licenses)[0].License.ID != "Apache-2.0" {
+		t.Errorf("License = %q, want Apache-2.0", (*c.Licenses)[0].License.ID)
+	}
+	mp := c.ModelCard.ModelParameters
+	if mp.Task != "text-classification" || mp.ModelArchitecture != "BertForSequenceClassification" {
+		t.Errorf("ModelParameters = %+v", mp)
+	}
+	metrics := map[string]string{}
+	for _, m := range *c.ModelCard.QuantitativeAnalysis.PerformanceMetrics {
+		metrics[m.Type] = m.Value
+	}
+	if metrics["accuracy"] != "0.91" || metrics["totalParameters"] != "64128" || metrics["vocabSize"] != "30522" {
+		t.Errorf("metrics = %v", metrics)
+	}
+	props := propertyMap(c.Properties)
+	if props["source"] != "local" || props["modelFormat"] != "safetensors" || props["precision"] != "bfloat16" {
+		t.Errorf("properties = %v", props)
+	}
+	if c.ExternalReferences == nil || len(*c.ExternalReferences) != 1 || (*c.ExternalReferences)[0].Comment != "base model" {
+		t.Errorf("only the base-model reference should remain: %+v", c.ExternalReferences)
+	}
+	if c.Hashes == nil || len((*c.Hashes)[0].Value) != 64 {
+		t.Errorf("single weight file should set the component hash: %+v", c.Hashes)
+	}
+	foundFile := false
+	for _, sub := range *c.Components {
+		if sub.Type == cdx.ComponentTypeFile && sub.Name == "model.safetensors" {
+			foundFile = true
+		}
+	}
+	if !foundFile {
+		t.Error("weight file should be listed as a nested file component")
+	}
+}
+
+func TestGenerateFromLocal_GGUF(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tinyllama.Q4_K_M.gguf")
+	if err := os.WriteFile(path, testGGUF(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := GenerateFromLocal(&Options{Path: path})
+	if err != nil {
+		t.Fatalf("GenerateFromLocal: %v", err)
+	}
+	c := (*doc.Components)[0]
+	if c.Name != "TinyLlama" {
+		t.Errorf("Name = %q, want GGUF general.name", c.Name)
+	}
+	if c.ModelCard.ModelParameters.ArchitectureFamily != "llama" {
+		t.Errorf("ArchitectureFamily = %q", c.ModelCard.ModelParameters.ArchitectureFamily)
+	}
+	props := propertyMap(c.Properties)
+	if props["quantization"] != "Q4_K_M" || props["maxInputTokens"] != "2048" ||
+		props["vocabSize"] != "3" || props["totalParameters"] != "8192" {
+		t.Errorf("properties = %v", props)
+	}
+	if (*c.Licenses)[0].License.ID != "Apache-2.0" {
+		t.Errorf("License = %q, want GGUF general.license", (*c.Licenses)[0].License.ID)
+	}
+}
+
+func TestReadONNX(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.onnx")
+	if err := os.WriteFile(path, testONNX(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	wi, err := readONNX(path)
+	if err != nil {
+		t.Fatalf("readONNX: %v", err)
+	}
+	if wi.Params != 12 || wi.DTypes["FLOAT"] != 12 {
+		t.Errorf("Params = %d, DTypes = %v; want 12 FLOAT", wi.Params, wi.DTypes)
+	}
+	md := wi.Metadata
+	if md["producer"] != "pytorch" || md["opset"] != "17" || md["operators"] != "Gemm,Relu" ||
+		md["nodes"] != "2" || md["meta:author"] != "acme" {
+		t.Errorf("Metadata = %v", md)
+	}
+}
+
+func TestGenerateFromLocal_Empty(t *testing.T) {
+	if _, err := GenerateFromLocal(&Options{Path: t.TempDir()}); err == nil {
+		t.Error("expected error for a directory without model files")
+	}
+}
+
+func TestFrontMatter(t *testing.T) {
+	fm, ok := frontMatter([]byte("---\r\nlicense: mit\r\n---\r\n# Title\r\n"))
+	if !ok || string(fm) != "license: mit\n" {
+		t.Errorf("frontMatter = %q, %v", fm, ok)
+	}
+	if _, ok := frontMatter([]byte("# No front matter\n")); ok {
+		t.Error("expected no front matter")
+	}
+}
+
+// ── unsafe serialization ──────────────────────────────────────────────────────
+
+// maliciousPickle is pickle.dumps of an object whose __reduce__ returns
+// (os.system, ("id",)) at protocol 2, as produced on Linux.
+const maliciousPickle = "\x80\x02cposix\nsystem\nq\x00X\x02\x00\x00\x00idq\x01\x85q\x02Rq\x03."
+
+// stateDictPickle is a harmless protocol 2 pickle of an empty OrderedDict.
+const stateDictPickle = "\x80\x02ccollections\nOrderedDict\nq\x00)Rq\x01."
+
+// protocol0Pickle is the protocol 0 form of maliciousPickle, which has no
+// PROTO header.
+const protocol0Pickle = "cposix\nsystem\np0\n(Vid\np1\ntp2\nRp3\n."
+
+func TestScanPickleStream(t *testing.T) {
+	// Protocol 4: names pushed via SHORT_BINUNICODE, memoised, then fetched
+	// back with BINGET before STACK_GLOBAL.
+	stackGlobal := "\x80\x04\x95\x00\x00\x00\x00\x00\x00\x00\x00" +
+		"\x8c\x08builtins\x94\x8c\x04eval\x94" +
+		"\x8c\x01x\x94h\x00h\x01\x93\x94h\x02\x85\x94R\x94."
+
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"global", maliciousPickle, []string{"posix system"}},
+		{"stack global via memo", stackGlobal, []string{"builtins eval"}},
+		{"state dict", stateDictPickle, []string{"collections OrderedDict"}},
+		{"concatenated", stateDictPickle + maliciousPickle + "raw tensor bytes", []string{"collections OrderedDict", "posix system"}},
+		{"protocol 0", protocol0Pickle, []string{"posix system"}},
+		// "x" is popped again, so the operands are "os" and "system".
+		{"stack global after pop", "\x80\x04\x8c\x02os\x8c\x06system\x8c\x01x0\x93)R.", []string{"os system"}},
+		{"stack global via escaped string", "\x80\x02S'o\\x73'\nS'system'\n\x93)R.", []string{"? ?"}},
+		{"stack global on non-string", "\x80\x04K\x01\x8c\x06system\x93)R.", []string{"? ?"}},
+	}
+	for _, tt := range tests {
+		got, err := scanPickleStream(bufio.NewReader(strings.NewReader(tt.data)))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: globals = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := scanPickleStream(bufio.NewReader(strings.NewReader("\x80\x02cposix\nsys"))); err == nil {
+		t.Error("expected error for a truncated pickle")
+	}
+	if _, err := scanPickleStream(bufio.NewReader(strings.NewReader("not a pickle"))); err == nil {
+		t.Error("expected error for data that is not a pickle")
+	}
+}
+
+func TestIsDangerousGlobal(t *testing.T) {
+	tests := []struct {
+		module, name string
+		want         bool
+	}{
+		{"posix", "system", true},
+		{"os.path", "join", true},
+		{"subprocess", "Popen", true},
+		{"builtins", "eval", true},
+		{"builtins", "set", false},
+		{"torch._utils", "_rebuild_tensor_v2", false},
+		{"collections", "OrderedDict", false},
+	}
+	for _, tt := range tests {
+		if got := isDangerousGlobal(tt.module, tt.name); got != tt.want {
+			t.Errorf("isDangerousGlobal(%q, %q) = %v, want %v", tt.module, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateFromLocal_MaliciousTorchZip(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("archive/data.pkl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write([]byte(maliciousPickle))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, dir, "model.pt", buf.String())
+	writeTestFile(t, dir, "clean.pkl", stateDictPickle)
+	writeTestFile(t, dir, "config.json", `{"model_type": "bert"}`)
+
+	doc, err := GenerateFromLocal(&Options{Path: dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	comp := (*doc.Components)[0]
+	props := propertyMap(comp.Properties)
+	if props["unsafeSerialization"] != "true" {
+		t.Errorf("unsafeSerialization = %q", props["unsafeSerialization"])
+	}
+	if props["pickleFiles"] != "clean.pkl,model.pt" {
+		t.Errorf("pickleFiles = %q", props["pickleFiles"])
+	}
+	if props["pickleDangerousImports"] != "posix.system" {
+		t.Errorf("pickleDangerousImports = %q", props["pickleDangerousImports"])
+	}
+
+	ethics := *comp.ModelCard.Considerations.EthicalConsiderations
+	var found bool
+	for _, e := range ethics {
+		if strings.Contains(e.Name, "model.pt imports posix.system") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no malicious-code consideration in %+v", ethics)
+	}
+
+	if _, err := GenerateFromLocal(&Options{Path: dir, FailOnUnsafe: true}); err == nil ||
+		!strings.Contains(err.Error(), "posix.system") {
+		t.Errorf("expected unsafe weights error, got %v", err)
+	}
+}
+
+func TestGenerateFromLocal_Protocol0Pickle(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "model.pkl", protocol0Pickle)
+	if _, err := GenerateFromLocal(&Options{Path: dir, FailOnUnsafe: true}); err == nil ||
+		!strings.Contains(err.Error(), "posix.system") {
+		t.Errorf("expected unsafe weights error, got %v", err)
+	}
+}
+
+func TestGenerateFromLocal_UnrecognisedPickle(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "pytorch_model.bin", "not a pickle")
+	doc, err := GenerateFromLocal(&Options{Path: dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if props := propertyMap((*doc.Components)[0].Properties); props["pickleUnverified"] != "pytorch_model.bin" {
+		t.Errorf("pickleUnverified = %q", props["pickleUnverified"])
+	}
+	if _, err := GenerateFromLocal(&Options{Path: dir, FailOnUnsafe: true}); err == nil {
+		t.Error("expected unsafe weights error for an unrecognised pickle file")
+	}
+}
+
+func TestGenerateFromLocal_CleanPickle(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "pytorch_model.bin", stateDictPickle)
+	if _, err := GenerateFromLocal(&Options{Path: dir, FailOnUnsafe: true}); err != nil {
+		t.Errorf("clean pickle should pass: %v", err)
+	}
+}
+
+func TestCheckSiblings(t *testing.T) {
+	u := checkSiblings([]hfFile{
+		{Rfilename: "config.json"},
+		{Rfilename: "pytorch_model.bin"},
+		{Rfilename: "optimizer.pt"},
+	})
+	if len(u.findings) != 1 || u.findings[0].File != "pytorch_model.bin" || u.findings[0].Scanned {
+		t.Errorf("findings = %+v", u.findings)
+	}
+	if err := u.unsafeError(); err == nil {
+		t.Error("expected error for pickle-only repository")
+	}
+
+	comp := cdx.Component{}
+	annotateSerialization(&comp, u)
+	props := propertyMap(comp.Properties)
+	if props["pickleUnverified"] != "pytorch_model.bin" || props["safetensorsAvailable"] != "false" {
+		t.Errorf("properties = %v", props)
+	}
+
+	u = checkSiblings([]hfFile{{Rfilename: "pytorch_model.bin"}, {Rfilename: "model.safetensors"}})
+	if err := u.unsafeError(); err != nil {
+		t.Errorf("safetensors alternative should pass: %v", err)
+	}
+
+	u = checkSiblings([]hfFile{{Rfilename: "model.safetensors"}})
+	comp = cdx.Component{}
+	annotateSerialization(&comp, u)
+	if comp.Properties != nil || comp.ModelCard != nil {
+		t.Error("expected no annotation without pickle files")
+	}
+}
+
+// ── application scan ──────────────────────────────────────────────────────────
+
+func TestCollectModelRefs_SourceTree(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "app.py", `
+from transformers import AutoModel, pipeline
+from huggingface_hub import hf_hub_download
+from langchain_openai import ChatOpenAI
+import boto3
+
+model = AutoModel.from_pretrained("google-bert/bert-base-uncased")
+local = AutoModel.from_pretrained("./checkpoints/latest")
+clf = pipeline("sentiment-analysis", model="distilbert-base-uncased-finetuned-sst-2-english")
+path = hf_hub_download(repo_id="TheBloke/Llama-2-7B-GGUF", filename="llama-2-7b.Q4_K_M.gguf")
+llm = ChatOpenAI(model="gpt-4o", temperature=0)
+client = boto3.client("bedrock-runtime")
+client.invoke_model(modelId="anthropic.claude-3-sonnet-20240229-v1:0", body=body)
+`)
+	writeTestFile(t, dir, "config.yaml", "llm:\n  model: meta-llama/Llama-2-7b-chat-hf\n  temperature: 0.2\n")
+	writeTestFile(t, dir, "notebook.ipynb", `{"cells": [{"source": ["m = AutoModel.from_pretrained(\"google-bert/bert-base-uncased\")"]}]}`)
+	if err := os.MkdirAll(filepath.Join(dir, "node_modules"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(dir, "node_modules"), "x.js", `from_pretrained("ignored/model")`)
+
+	refs, err := collectModelRefs(&Options{Path: dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := map[string]*modelRef{}
+	for _, r := range refs {
+		got[r.Provider+"|"+r.ID] = r
+	}
+	for _, want := range []string{
+		"huggingface|google-bert/bert-base-uncased",
+		"huggingface|distilbert-base-uncased-finetuned-sst-2-english",
+		"huggingface|TheBloke/Llama-2-7B-GGUF",
+		"huggingface|meta-llama/Llama-2-7b-chat-hf",
+		"openai|gpt-4o",
+		"bedrock|anthropic.claude-3-sonnet-20240229-v1:0",
+	} {
+		if got[want] == nil {
+			t.Errorf("missing reference %s", want)
+		}
+	}
+	if len(refs) != 6 {
+		t.Errorf("expected 6 references, got %d: %v", len(refs), got)
+	}
+
+	bert := got["huggingface|google-bert/bert-base-uncased"]
+	if bert != nil && len(bert.occurrences) != 2 {
+		t.Errorf("expected occurrences in app.py and notebook.ipynb, got %+v", bert.occurrences)
+	}
+	if bert != nil && (bert.occurrences[0].file != "app.py" || bert.occurrences[0].line != 7) {
+		t.Errorf("occurrence = %+v, want app.py:7", bert.occurrences[0])
+	}
+}
+
+func TestCollectModelRefs_ModelsFile(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "models.txt", `# models used in production
+openai-community/gpt2
+https://huggingface.co/google-bert/bert-base-uncased
+amazon.titan-embed-text-v2:0   # embeddings
+openai:gpt-4o-mini
+`)
+	refs, err := collectModelRefs(&Options{ModelsFile: filepath.Join(dir, "models.txt")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ids []string
+	for _, r := range refs {
+		ids = append(ids, r.Provider+"|"+r.ID)
+	}
+	want := "bedrock|amazon.titan-embed-text-v2:0,huggingface|google-bert/bert-base-uncased,huggingface|openai-community/gpt2,openai|gpt-4o-mini"
+	if strings.Join(ids, ",") != want {
+		t.Errorf("refs = %v, want %s", ids, want)
+	}
+}
+
+func TestBuildScanBOM(t *testing.T) {
+	refs := []*modelRef{
+		{Provider: providerBedrock, ID: "us.anthropic.claude-3-5-sonnet-20240620-v1:0"},
+		{Provider: providerHuggingFace, ID: "acme/sentiment-ft", occurrences: []refOccurrence{{file: "app.py", line: 3, symbol: "from_pretrained"}}},
+		{Provider: providerHuggingFace, ID: "acme/private"},
+	}
+	fetch := func(id string) (*hfModelInfo, error) {
+		if id == "acme/private" {
+			return nil, fmt.Errorf("unauthorised")
+		}
+		return &hfModelInfo{
+			ModelID:  id,
+			SHA:      "0123456789abcdef",
+			CardData: &hfCardData{BaseModel: "google-bert/bert-base-uncased", Datasets: []string{"imdb"}},
+		}, nil
+	}
+	doc := buildScanBOM(refs, &Options{Name: "review-service", Version: "2.0.0"}, hubFetcher{model: fetch})
+
+	if doc.Metadata.Component.Type != cdx.ComponentTypeApplication || doc.Metadata.Component.Name != "review-service" {
+		t.Errorf("metadata.component = %+v", doc.Metadata.Component)
+	}
+	if n := ModelCount(doc); n != 4 {
+		t.Errorf("expected 4 model components (3 referenced + 1 base), got %d", n)
+	}
+
+	deps := map[string][]string{}
+	for _, d := range *doc.Dependencies {
+		if d.Dependencies != nil {
+			deps[d.Ref] = *d.Dependencies
+		}
+	}
+	appRef := doc.Metadata.Component.BOMRef
+	if len(deps[appRef]) != 3 {
+		t.Errorf("application depends on %v, want the 3 referenced models", deps[appRef])
+	}
+	ft := "pkg:huggingface/acme/sentiment-ft@0123456"
+	if strings.Join(deps[ft], ",") != "dataset/imdb,pkg:huggingface/google-bert/bert-base-uncased" {
+		t.Errorf("fine-tune depends on %v", deps[ft])
+	}
+
+	for _, c := range *doc.Components {
+		switch c.BOMRef {
+		case ft:
+			if c.Evidence == nil || (*c.Evidence.Occurrences)[0].Location != "app.py" {
+				t.Errorf("missing evidence on %s", c.BOMRef)
+			}
+		case "pkg:huggingface/acme/private":
+			if propertyMap(c.Properties)["resolutionError"] != "unauthorised" {
+				t.Errorf("expected resolutionError on unresolved model, got %v", propertyMap(c.Properties))
+			}
+		case "pkg:generic/aws-bedrock/us.anthropic.claude-3-5-sonnet-20240620-v1:0":
+			if c.Group != "anthropic" {
+				t.Errorf("bedrock provider = %q, want anthropic", c.Group)
+			}
+		}
+	}
+}
+
+// ── model lineage ─────────────────────────────────────────────────────────────
+
+// testHub serves canned Hub responses keyed by model ID and counts lookups.
+func testHub(models map[string]*hfModelInfo, calls map[string]int) hubFetcher {
+	return hubFetcher{
+		model: func(id string) (*hfModelInfo, error) {
+			calls[id]++
+			if m, ok := models[id]; ok {
+				return m, nil
+			}
+			return nil, fmt.Errorf("model %q not found on HuggingFace", id)
+		},
+		dataset: func(id string) (*hfDatasetInfo, error) {
+			return &hfDatasetInfo{
+				ID:       id,
+				SHA:      "fedcba9876543210",
+				Author:   "stanfordnlp",
+				CardData: &hfDatasetCard{License: "other", TaskCategories: []string{"text-classification"}},
+			}, nil
+		},
+	}
+}
+
+func hubModel(id, sha string, bases ...string) *hfModelInfo {
+	m := &hfModelInfo{ModelID: id, SHA: sha, CardData: &hfCardData{}}
+	if len(bases) > 0 {
+		m.CardData.BaseModel = toInterfaceSlice(bases)
+	}
+	return m
+}
+
+func toInterfaceSlice(s []string) []interface{} {
+	out := make([]interface{}, len(s))
+	for i, v := range s {
+		out[i] = v
+	}
+	return out
+}
+
+func dependencyMap(doc *cdx.BOM) map[string][]string {
+	deps := map[string][]string{}
+	for _, d := range *doc.Dependencies {
+		if d.Dependencies != nil {
+			deps[d.Ref] = *d.Dependencies
+		}
+	}
+	return deps
+}
+
+func TestResolveLineage_Chain(t *testing.T) {
+	leaf := hubModel("acme/llama-ft", "1111111aaaa", "meta-llama/Llama-3.1-8B-Instruct")
+	leaf.CardData.Datasets = []string{"stanfordnlp/imdb"}
+	calls := map[string]int{}
+	hub := testHub(map[string]*hfModelInfo{
+		"meta-llama/Llama-3.1-8B-Instruct": hubModel("meta-llama/Llama-3.1-8B-Instruct", "2222222bbbb", "meta-llama/Llama-3.1-8B"),
+		"meta-llama/Llama-3.1-8B":          hubModel("meta-llama/Llama-3.1-8B", "3333333cccc"),
+	}, calls)
+
+	doc := buildBOM(leaf, &Options{})
+	resolveLineage(doc, leaf, hub, DefaultLineageDepth)
+
+	if n := ModelCount(doc); n != 3 {
+		t.Fatalf("expected 3 model components, got %d", n)
+	}
+	deps := dependencyMap(doc)
+	leafRef := "pkg:huggingface/acme/llama-ft@1111111"
+	instruct := "pkg:huggingface/meta-llama/Llama-3.1-8B-Instruct@2222222"
+	base := "pkg:huggingface/meta-llama/Llama-3.1-8B@3333333"
+	if strings.Join(deps[leafRef], ",") != "dataset/stanfordnlp/imdb,"+instruct {
+		t.Errorf("leaf depends on %v", deps[leafRef])
+	}
+	if strings.Join(deps[instruct], ",") != base {
+		t.Errorf("instruct depends on %v", deps[instruct])
+	}
+	if (*doc.Dependencies)[0].Ref != leafRef {
+		t.Errorf("first dependency entry = %s, want the model", (*doc.Dependencies)[0].Ref)
+	}
+
+	for _, c := range *doc.Components {
+		switch c.BOMRef {
+		case leafRef:
+			if c.Pedigree == nil || (*c.Pedigree.Ancestors)[0].PackageURL != instruct {
+				t.Errorf("leaf pedigree = %+v", c.Pedigree)
+			}
+			if (*c.Pedigree.Ancestors)[0].BOMRef != "" {
+				t.Error("pedigree ancestors must not repeat bom-refs")
+			}
+		case base:
+			if propertyMap(c.Properties)["lineageDepth"] != "2" {
+				t.Errorf("base lineageDepth = %q", propertyMap(c.Properties)["lineageDepth"])
+			}
+		case "dataset/stanfordnlp/imdb":
+			if c.Version != "fedcba9" || c.Supplier == nil || (*c.Licenses)[0].License.ID != "LicenseRef-other" {
+				t.Errorf("dataset not enriched: %+v", c)
+			}
+		}
+	}
+}
+
+func TestResolveLineage_DepthLimitAndCycle(t *testing.T) {
+	calls := map[string]int{}
+	hub := testHub(map[string]*hfModelInfo{
+		"org/b": hubModel("org/b", "bbbbbbbb", "org/c", "org/a"), // org/a closes a cycle
+		"org/c": hubModel("org/c", "cccccccc", "org/d"),
+		"org/d": hubModel("org/d", "dddddddd"),
+	}, calls)
+	leaf := hubModel("org/a", "aaaaaaaa", "org/b")
+
+	doc := buildBOM(leaf, &Options{})
+	resolveLineage(doc, leaf, hub, 2)
+
+	if calls["org/d"] != 0 {
+		t.Error("org/d is beyond the depth limit and must not be fetched")
+	}
+	props := map[string]map[string]string{}
+	for _, c := range *doc.Components {
+		props[c.Name] = propertyMap(c.Properties)
+	}
+	if props["org/d"]["lineageTruncated"] == "" {
+		t.Errorf("expected lineageTruncated on org/d, got %v", props["org/d"])
+	}
+	if props["org/b"]["lineageCycle"] != "org/a" {
+		t.Errorf("expected lineageCycle on org/b, got %v", props["org/b"])
+	}
+	deps := dependencyMap(doc)
+	for _, d := range deps["pkg:huggingface/org/b@bbbbbbb"] {
+		if strings.Contains(d, "org/a") {
+			t.Error("cycle edge back to the leaf must not be emitted")
+		}
+	}
+}
+
+// ── bedrock account resources ─────────────────────────────────────────────────
+
+const (
+	testBaseARN     = "arn:aws:bedrock:us-east-1::foundation-model/amazon.titan-text-express-v1:0:8k"
+	testCustomARN   = "arn:aws:bedrock:us-east-1:111122223333:custom-model/amazon.titan-text-express-v1:0:8k/abc123"
+	testImportedARN = "arn:aws:bedrock:us-east-1:111122223333:imported-model/xyz789"
+	testPTARN       = "arn:aws:bedrock:us-east-1:111122223333:provisioned-model/pt456"
+	testGuardARN    = "arn:aws:bedrock:us-east-1:111122223333:guardrail/gr1"
+)
+
+// fakeBedrock serves a canned account: one foundation model, a fine-tune of
+// it, an imported model, provisioned throughput for the fine-tune and a
+// guardrail. Guardrail listing is denied when denyGuardrails is set.
+type fakeBedrock struct {
+	denyGuardrails bool
+}
+
+func (fakeBedrock) ListFoundationModels(context.Context, *bedrock.ListFoundationModelsInput, ...func(*bedrock.Options)) (*bedrock.ListFoundationModelsOutput, error) {
+	return &bedrock.ListFoundationModelsOutput{ModelSummaries: []brtypes.FoundationModelSummary{{
+		ModelArn:     aws.String(testBaseARN),
+		ModelId:      aws.String("amazon.titan-text-express-v1:0:8k"),
+		ModelName:    aws.String("Titan Text G1 - Express"),
+		ProviderName: aws.String("Amazon"),
+	}}}, nil
+}
+
+func (f fakeBedrock) GetFoundationModel(_ context.Context, in *bedrock.GetFoundationModelInput, _ ...func(*bedrock.Options)) (*bedrock.GetFoundationModelOutput, error) {
+	if aws.ToString(in.ModelIdentifier) != "amazon.titan-text-express-v1:0:8k" {
+		return nil, fmt.Errorf("ValidationException: model not found")
+	}
+	out, _ := f.ListFoundationModels(context.Background(), nil)
+	s := out.ModelSummaries[0]
+	return &bedrock.GetFoundationModelOutput{ModelDetails: &brtypes.FoundationModelDetails{
+		ModelArn: s.ModelArn, ModelId: s.ModelId, ModelName: s.ModelName, ProviderName: s.ProviderName,
+	}}, nil
+}
+
+func (fakeBedrock) ListCustomModels(context.Context, *bedrock.ListCustomModelsInput, ...func(*bedrock.Options)) (*bedrock.ListCustomModelsOutput, error) {
+	return &bedrock.ListCustomModelsOutput{ModelSummaries: []brtypes.CustomModelSummary{{
+		ModelArn: aws.String(testCustomARN), ModelName: aws.String("support-bot"),
+	}}}, nil
+}
+
+func (fakeBedrock) GetCustomModel(_ context.Context, in *bedrock.GetCustomModelInput, _ ...func(*bedrock.Options)) (*bedrock.GetCustomModelOutput, error) {
+	if id := aws.ToString(in.ModelIdentifier); id != testCustomARN && id != "support-bot" {
+		return nil, fmt.Errorf("ResourceNotFoundException")
+	}
+	return &bedrock.GetCustomModelOutput{
+		ModelArn:           aws.String(testCustomARN),
+		ModelName:          aws.String("support-bot"),
+		BaseModelArn:       aws.String(testBaseARN),
+		CustomizationType:  brtypes.CustomizationTypeFineTuning,
+		JobArn:             aws.String("arn:aws:bedrock:us-east-1:111122223333:model-customization-job/j1"),
+		HyperParameters:    map[string]string{"epochCount": "2", "learningRate": "0.00001"},
+		TrainingDataConfig: &brtypes.TrainingDataConfig{S3Uri: aws.String("s3://acme-ml/train.jsonl")},
+		ValidationDataConfig: &brtypes.ValidationDataConfig{Validators: []brtypes.Validator{
+			{S3Uri: aws.String("s3://acme-ml/validation.jsonl")},
+		}},
+		TrainingMetrics: &brtypes.TrainingMetrics{TrainingLoss: aws.Float32(0.25)},
+	}, nil
+}
+
+func (fakeBedrock) ListImportedModels(context.Context, *bedrock.ListImportedModelsInput, ...func(*bedrock.Options)) (*bedrock.ListImportedModelsOutput, error) {
+	return &bedrock.ListImportedModelsOutput{ModelSummaries: []brtypes.ImportedModelSummary{{
+		ModelArn: aws.String(testImportedARN), ModelName: aws.String("llama-ft"),
+	}}}, nil
+}
+
+func (fakeBedrock) GetImportedModel(_ context.Context, in *bedrock.GetImportedModelInput, _ ...func(*bedrock.Options)) (*bedrock.GetImportedModelOutput, error) {
+	if id := aws.ToString(in.ModelIdentifier); id != testImportedARN && id != "llama-ft" {
+		return nil, fmt.Errorf("ResourceNotFoundException")
+	}
+	return &bedrock.GetImportedModelOutput{
+		ModelArn:          aws.String(testImportedARN),
+		ModelName:         aws.String("llama-ft"),
+		ModelArchitecture: aws.String("llama3"),
+		ModelDataSource: &brtypes.ModelDataSourceMemberS3DataSource{
+			Value: brtypes.S3DataSource{S3Uri: aws.String("s3://acme-ml/llama-ft/")},
+		},
+	}, nil
+}
+
+func (fakeBedrock) ListProvisionedModelThroughputs(context.Context, *bedrock.ListProvisionedModelThroughputsInput, ...func(*bedrock.Options)) (*bedrock.ListProvisionedModelThroughputsOutput, error) {
+	return &bedrock.ListProvisionedModelThroughputsOutput{ProvisionedModelSummaries: []brtypes.ProvisionedModelSummary{{
+		ProvisionedModelArn:  aws.String(testPTARN),
+		ProvisionedModelName: aws.String("support-bot-pt"),
+		ModelArn:             aws.String(testCustomARN),
+		ModelUnits:           aws.Int32(1),
+		Status:               brtypes.ProvisionedModelStatusInService,
+	}}}, nil
+}
+
+func (fakeBedrock) GetProvisionedModelThroughput(context.Context, *bedrock.GetProvisionedModelThroughputInput, ...func(*bedrock.Options)) (*bedrock.GetProvisionedModelThroughputOutput, error) {
+	return nil, fmt.Errorf("ResourceNotFoundException")
+}
+
+func (f fakeBedrock) ListGuardrails(context.Context, *bedrock.ListGuardrailsInput, ...func(*bedrock.Options)) (*bedrock.ListGuardrailsOutput, error) {
+	if f.denyGuardrails {
+		return nil, fmt.Errorf("AccessDeniedException")
+	}
+	return &bedrock.ListGuardrailsOutput{Guardrails: []brtypes.GuardrailSummary{{
+		Arn: aws.String(testGuardARN), Id: aws.String("gr1"), Name: aws.String("pii-filter"), Version: aws.String("DRAFT"),
+	}}}, nil
+}
+
+func (fakeBedrock) GetGuardrail(context.Context, *bedrock.GetGuardrailInput, ...func(*bedrock.Options)) (*bedrock.GetGuardrailOutput, error) {
+	return &bedrock.GetGuardrailOutput{
+		GuardrailArn: aws.String(testGuardARN),
+		GuardrailId:  aws.String("gr1"),
+		Name:         aws.String("pii-filter"),
+		Version:      aws.String("DRAFT"),
+		Status:       brtypes.GuardrailStatusReady,
+		ContentPolicy: &brtypes.GuardrailContentPolicy{Filters: []brtypes.GuardrailContentFilter{
+			{Type: brtypes.GuardrailContentFilterTypeViolence, InputStrength: brtypes.GuardrailFilterStrengthHigh, OutputStrength: brtypes.GuardrailFilterStrengthMedium},
+		}},
+		SensitiveInformationPolicy: &brtypes.GuardrailSensitiveInformationPolicy{PiiEntities: []brtypes.GuardrailPiiEntity{
+			{Type: brtypes.GuardrailPiiEntityTypeEmail, Action: brtypes.GuardrailSensitiveInformationActionAnonymize},
+		}},
+	}, nil
+}
+
+func TestBedrockInventory_AccountResources(t *testing.T) {
+	opts := &BedrockOptions{Region: "us-east-1"}
+	inv, err := collectBedrock(context.Background(), fakeBedrock{}, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc := buildBedrockBOM(inv, opts)
+
+	byRef := map[string]cdx.Component{}
+	for _, c := range *doc.Components {
+		byRef[c.BOMRef] = c
+	}
+	baseRef := "pkg:generic/aws-bedrock/amazon.titan-text-express-v1:0:8k"
+	for _, ref := range []string{baseRef, testCustomARN, testImportedARN, testPTARN, testGuardARN + ":DRAFT",
+		"dataset/s3://acme-ml/train.jsonl", "dataset/s3://acme-ml/validation.jsonl"} {
+		if _, ok := byRef[ref]; !ok {
+			t.Errorf("missing component %s", ref)
+		}
+	}
+
+	custom := byRef[testCustomARN]
+	props := propertyMap(custom.Properties)
+	if props["customizationType"] != "FINE_TUNING" || props["hyperParameter.epochCount"] != "2" {
+		t.Errorf("custom model properties = %v", props)
+	}
+	if custom.Pedigree == nil || (*custom.Pedigree.Ancestors)[0].Name != "Titan Text G1 - Express" {
+		t.Errorf("custom model pedigree = %+v", custom.Pedigree)
+	}
+	if custom.ModelCard == nil || custom.ModelCard.QuantitativeAnalysis == nil {
+		t.Error("custom model training loss missing")
+	}
+	if ds := byRef["dataset/s3://acme-ml/train.jsonl"]; ds.Type != cdx.ComponentTypeData || (*ds.Data)[0].Classification != "training" {
+		t.Errorf("training data component = %+v", ds)
+	}
+
+	guard := propertyMap(byRef[testGuardARN+":DRAFT"].Properties)
+	if guard["guardrail.contentFilters"] != "VIOLENCE:HIGH/MEDIUM" || guard["guardrail.piiEntities"] != "EMAIL:ANONYMIZE" {
+		t.Errorf("guardrail properties = %v", guard)
+	}
+
+	deps := dependencyMap(doc)
+	wantCustom := []string{baseRef, "dataset/s3://acme-ml/train.jsonl", "dataset/s3://acme-ml/validation.jsonl"}
+	if strings.Join(deps[testCustomARN], ",") != strings.Join(wantCustom, ",") {
+		t.Errorf("custom model dependencies = %v, want %v", deps[testCustomARN], wantCustom)
+	}
+	if strings.Join(deps[testPTARN], ",") != testCustomARN {
+		t.Errorf("provisioned throughput dependencies = %v", deps[testPTARN])
+	}
+	if len(deps["aws-bedrock-models"]) != 5 {
+		t.Errorf("root dependencies = %v", deps["aws-bedrock-models"])
+	}
+}
+
+func TestBedrockInventory_PartialPermissions(t *testing.T) {
+	opts := &BedrockOptions{Region: "us-east-1"}
+	inv, err := collectBedrock(context.Background(), fakeBedrock{denyGuardrails: true}, opts)
+	if err != nil {
+		t.Fatalf("denied guardrail listing must not fail the inventory: %v", err)
+	}
+	if len(inv.custom) != 1 || len(inv.guardrails) != 0 {
+		t.Errorf("custom=%d guardrails=%d", len(inv.custom), len(inv.guardrails))
+	}
+
+	opts.FoundationOnly = true
+	inv, _ = collectBedrock(context.Background(), fakeBedrock{}, opts)
+	if len(inv.foundation) != 1 || len(inv.custom)+len(inv.imported)+len(inv.provisioned) != 0 {
+		t.Errorf("FoundationOnly inventory = %+v", inv)
+	}
+}
+
+func TestBedrockInventory_SingleCustomModel(t *testing.T) {
+	opts := &BedrockOptions{Region: "us-east-1", ModelID: "support-bot", Name: "Support Bot"}
+	inv, err := collectBedrock(context.Background(), fakeBedrock{}, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc := buildBedrockBOM(inv, opts)
+	if doc.Metadata.Component.BOMRef != testCustomARN || doc.Metadata.Component.Name != "Support Bot" {
+		t.Errorf("subject = %+v", doc.Metadata.Component)
+	}
+	// The base foundation model is resolved so the fine-tune is linked to it.
+	if n := ModelCount(doc); n != 2 {
+		t.Errorf("expected custom and base model, got %d", n)
+	}
+	deps := dependencyMap(doc)
+	if len(deps[testCustomARN]) != 3 {
+		t.Errorf("custom model dependencies = %v", deps[testCustomARN])
+	}
+
+	opts.ModelID = "unknown-model"
+	if _, err := collectBedrock(context.Background(), fakeBedrock{}, opts); err == nil {
+		t.Error("expected error for unknown model")
+	}
+}
+
+// ── model providers ───────────────────────────────────────────────────────────
+
+func TestOllamaProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			_, _ = w.Write([]byte(`{"models": [{
+				"name": "llama3.1:8b", "model": "llama3.1:8b", "size": 4920753328,
+				"digest": "46e0c10c039e019119339687c3c1757cc81b9da49709a3b3924863ba87ca666e",
+				"details": {"format": "gguf", "family": "llama", "parameter_size": "8.0B", "quantization_level": "Q4_K_M"}
+			}]}`))
+		case "/api/show":
+			var req map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if req["model"] != "llama3.1:8b" {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write([]byte(`{
+				"license": "LLAMA 3.1 COMMUNITY LICENSE AGREEMENT\nLlama 3.1 Version Release Date: July 23, 2024",
+				"details": {"format": "gguf", "family": "llama", "parameter_size": "8.0B", "quantization_level": "Q4_K_M"},
+				"model_info": {"general.architecture": "llama", "general.parameter_count": 8030261312, "llama.context_length": 131072},
+				"capabilities": ["completion", "tools"]
+			}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	doc, err := GenerateFromProvider(&ProviderOptions{Provider: "ollama", Endpoint: srv.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := ModelCount(doc); n != 1 {
+		t.Fatalf("expected 1 model, got %d", n)
+	}
+	c := (*doc.Components)[0]
+	if c.BOMRef != "pkg:generic/ollama/llama3.1:8b@8b" || c.Name != "llama3.1" {
+		t.Errorf("component identity = %s / %s", c.BOMRef, c.Name)
+	}
+	if c.Hashes == nil || (*c.Hashes)[0].Value != "46e0c10c039e019119339687c3c1757cc81b9da49709a3b3924863ba87ca666e" {
+		t.Errorf("hashes = %+v", c.Hashes)
+	}
+	if lic := (*c.Licenses)[0].License; lic.Name != "LLAMA 3.1 COMMUNITY LICENSE AGREEMENT" {
+		t.Errorf("license = %+v", lic)
+	}
+	props := propertyMap(c.Properties)
+	if props["contextLength"] != "131072" || props["quantization"] != "Q4_K_M" || props["parameters"] != "8.0B" {
+		t.Errorf("properties = %v", props)
+	}
+	if c.ModelCard == nil || c.ModelCard.ModelParameters.ArchitectureFamily != "llama" {
+		t.Errorf("model card = %+v", c.ModelCard)
+	}
+}
+
+func TestOpenAIProvider_ListOnlyServer(t *testing.T) {
+	// vLLM implements GET /v1/models but not GET /v1/models/{id}.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sk-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/v1/models" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"object": "list", "data": [
+			{"id": "meta-llama/Llama-3.1-8B-Instruct", "object": "model", "created": 1718000000, "owned_by": "vllm", "root": "meta-llama/Llama-3.1-8B-Instruct", "max_model_len": 8192},
+			{"id": "sql-lora", "object": "model", "owned_by": "vllm", "root": "/adapters/sql", "parent": "meta-llama/Llama-3.1-8B-Instruct"}
+		]}`))
+	}))
+	defer srv.Close()
+
+	opts := &ProviderOptions{Provider: "openai", Endpoint: srv.URL + "/v1", APIKey: "sk-test"}
+	doc, err := GenerateFromProvider(opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := ModelCount(doc); n != 2 {
+		t.Fatalf("expected 2 models, got %d", n)
+	}
+
+	opts.ModelID = "sql-lora"
+	doc, err = GenerateFromProvider(opts)
+	if err != nil {
+		t.Fatalf("describe via list fallback: %v", err)
+	}
+	c := (*doc.Components)[0]
+	if propertyMap(c.Properties)["baseModel"] != "meta-llama/Llama-3.1-8B-Instruct" || c.Pedigree == nil {
+		t.Errorf("adapter not linked to its base model: %+v", c)
+	}
+	if doc.Metadata.Component.BOMRef != c.BOMRef {
+		t.Errorf("single-model BOM subject = %s, want %s", doc.Metadata.Component.BOMRef, c.BOMRef)
+	}
+
+	opts.ModelID = "missing"
+	if _, err := GenerateFromProvider(opts); err == nil {
+		t.Error("expected error for unknown model")
+	}
+}
+
+func TestAzureOpenAIProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("api-key") != "azure-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/openai/models":
+			_, _ = w.Write([]byte(`{"data": [{"id": "gpt-4o", "status": "succeeded",
+				"capabilities": {"chat_completion": true, "embeddings": false, "fine_tune": false},
+				"lifecycle_status": "generally-available", "deprecation": {"inference": 1767225600}}]}`))
+		case "/openai/deployments":
+			_, _ = w.Write([]byte(`{"data": [{"id": "chat-prod", "model": "gpt-4o", "owner": "organization-owner",
+				"status": "succeeded", "created_at": 1718000000, "scale_settings": {"scale_type": "standard"}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	doc, err := GenerateFromProvider(&ProviderOptions{Provider: "azure-openai", Endpoint: srv.URL, APIKey: "azure-key"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := (*doc.Components)[0]
+	props := propertyMap(c.Properties)
+	if c.Name != "chat-prod" || props["baseModel"] != "gpt-4o" || props["scaleType"] != "standard" {
+		t.Errorf("deployment = %s %v", c.Name, props)
+	}
+	if props["deprecation.inference"] != "2026-01-01T00:00:00Z" || props["lifecycleStatus"] != "generally-available" {
+		t.Errorf("catalog details missing: %v", props)
+	}
+	if c.Tags == nil || strings.Join(*c.Tags, ",") != "chat_completion" {
+		t.Errorf("capabilities = %v", c.Tags)
+	}
+}
+
+func TestNewProvider_Unknown(t *testing.T) {
+	if _, err := NewProvider(&ProviderOptions{Provider: "watsonx"}); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+	if _, err := NewProvider(&ProviderOptions{Provider: "azure-openai"}); err == nil && os.Getenv("AZURE_OPENAI_ENDPOINT") == "" {
+		t.Error("expected error for Azure OpenAI without an endpoint")
+	}
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func propertyMap(props *[]cdx.Property) map[string]string {
+	m := map[string]string{}
+	if props != nil {
+		for _, p := range *props {
+			m[p.Name] = p.Value
+		}
+	}
+	return m
+}
+
+// testSafeTensors encodes a safetensors header with no tensor data.
+func testSafeTensors(t *testing.T, header map[string]interface{}) []byte {
+	t.Helper()
+	js, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	_ = binary.Write(&b, binary.LittleEndian, uint64(len(js)))
+	b.Write(js)
+	return b.Bytes()
+}
+
+// testGGUF builds a GGUF v3 file with llama metadata, a 3-token vocabulary
+// and two tensors (64x64 + 64x64 = 8192 parameters).
+func testGGUF() []byte {
+	var b bytes.Buffer
+	le := func(v interface{}) { _ = binary.Write(&b, binary.LittleEndian, v) }
+	str := func(s string) { le(uint64(len(s))); b.WriteString(s) }
+	kvString := func(k, v string) { str(k); le(ggufString); str(v) }
+	kvU32 := func(k string, v uint32) { str(k); le(ggufUint32); le(v) }
+
+	b.WriteString(ggufMagic)
+	le(uint32(3)) // version
+	le(uint64(2)) // tensors
+	le(uint64(6)) // metadata entries
+	kvString("general.architecture", "llama")
+	kvString("general.name", "TinyLlama")
+	kvString("general.license", "apache-2.0")
+	kvU32("general.file_type", 15)
+	kvU32("llama.context_length", 2048)
+	str("tokenizer.ggml.tokens")
+	le(ggufArray)
+	le(ggufString)
+	le(uint64(3))
+	str("<s>")
+	str("</s>")
+	str("hello")
+
+	for _, name := range []string{"blk.0.attn_q.weight", "blk.0.attn_k.weight"} {
+		str(name)
+		le(uint32(2))
+		le(uint64(64))
+		le(uint64(64))
+		le(uint32(12)) // Q4_K
+		le(uint64(0))
+	}
+	return b.Bytes()
+}
+
+// testONNX hand-encodes a ModelProto with a Gemm+Relu graph, one 3x4 float
+// initializer, opset 17 and a metadata_props entry.
+func testONNX() []byte {
+	varint := func(v uint64) []byte { return binary.AppendUvarint(nil, v) }
+	field := func(num, wt uint64, payload []byte) []byte {
+		out := varint(num<<3 | wt)
+		if wt == pbBytes {
+			out = append(out, varint(uint64(len(payload)))...)
+		}
+		return append(out, payload...)
+	}
+	bytesF := func(num uint64, p []byte) []byte { return field(num, pbBytes, p) }
+	varF := func(num, v uint64) []byte { return field(num, pbVarint, varint(v)) }
+	cat := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+
+	node := func(op string) []byte { return bytesF(1, bytesF(4, []byte(op))) }
+	tensor := cat(varF(1, 3), varF(1, 4), varF(2, 1), bytesF(8, []byte("W")), bytesF(9, make([]byte, 48)))
+	graph := cat(node("Gemm"), node("Relu"), bytesF(2, []byte("main")), bytesF(5, tensor))
+	opset := cat(bytesF(1, []byte("")), varF(2, 17))
+	meta := cat(bytesF(1, []byte("author")), bytesF(2, []byte("acme")))
+
+	return cat(
+		varF(1, 8),
+		bytesF(2, []byte("pytorch")),
+		bytesF(3, []byte("2.1.0")),
+		bytesF(7, graph),
+		bytesF(8, opset),
+		bytesF(14, meta),
+	)
+}
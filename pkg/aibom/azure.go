@@ -0,0 +1,267 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package aibom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+)
+
+const (
+	// azureDefaultAPIVersion is used for the models endpoint.
+	azureDefaultAPIVersion = "2024-10-21"
+
+	// azureDeploymentsAPIVersion is the last data-plane API version that
+	// lists deployments.
+	azureDeploymentsAPIVersion = "2022-12-01"
+
+	azureCognitiveScope = "https://cognitiveservices.azure.com/.default"
+)
+
+// azureOpenAIProvider inventories the deployments of an Azure OpenAI
+// resource. Each deployment is reported as a model component linked to the
+// OpenAI model it serves.
+type azureOpenAIProvider struct {
+	endpoint   string
+	apiKey     string
+	apiVersion string
+	client     *http.Client
+}
+
+func newAzureOpenAIProvider(opts *ProviderOptions) (Provider, error) {
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = os.Getenv("AZURE_OPENAI_ENDPOINT")
+	}
+	if endpoint == "" {
+		return nil, fmt.Errorf("--endpoint is required for Azure OpenAI (e.g. https://<resource>.openai.azure.com)")
+	}
+	apiKey := opts.APIKey
+	if apiKey == "" {
+		apiKey = os.Getenv("AZURE_OPENAI_API_KEY")
+	}
+	apiVersion := opts.APIVersion
+	if apiVersion == "" {
+		apiVersion = azureDefaultAPIVersion
+	}
+	return &azureOpenAIProvider{
+		endpoint:   strings.TrimRight(endpoint, "/"),
+		apiKey:     apiKey,
+		apiVersion: apiVersion,
+		client:     &http.Client{Timeout: providerTimeout},
+	}, nil
+}
+
+// azureDeployment is one entry of GET /openai/deployments.
+type azureDeployment struct {
+	ID            string `json:"id"`
+	Model         string `json:"model"`
+	Owner         string `json:"owner"`
+	Status        string `json:"status"`
+	CreatedAt     int64  `json:"created_at"`
+	ScaleSettings struct {
+		ScaleType string `json:"scale_type"`
+	} `json:"scale_settings"`
+}
+
+// azureModel is one entry of GET /openai/models.
+type azureModel struct {
+	ID           string          `json:"id"`
+	Model        string          `json:"model"` // base model of a fine-tune
+	FineTune     string          `json:"fine_tune"`
+	Status       string          `json:"status"`
+	CreatedAt    int64           `json:"created_at"`
+	Capabilities map[string]bool `json:"capabilities"`
+	Lifecycle    string          `json:"lifecycle_status"`
+	Deprecation  map[string]int  `json:"deprecation"`
+}
+
+func (p *azureOpenAIProvider) Name() string { return "azure-openai" }
+
+// headers authenticates with the API key, or with a Microsoft Entra ID token
+// from the default Azure credential chain when no key is configured.
+func (p *azureOpenAIProvider) headers(ctx context.Context) (map[string]string, error) {
+	if p.apiKey != "" {
+		return map[string]string{"api-key": p.apiKey}, nil
+	}
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("no API key set and no Azure credential available: %w", err)
+	}
+	tok, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{azureCognitiveScope}})
+	if err != nil {
+		return nil, fmt.Errorf("acquiring Azure token: %w", err)
+	}
+	return map[string]string{"Authorization": "Bearer " + tok.Token}, nil
+}
+
+func (p *azureOpenAIProvider) ListModels(ctx context.Context) ([]ProviderModel, error) {
+	hdr, err := p.headers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := p.models(ctx, hdr)
+	if err != nil {
+		return nil, err
+	}
+
+	var out struct {
+		Data []azureDeployment `json:"data"`
+	}
+	err = doJSON(ctx, p.client, http.MethodGet,
+		p.endpoint+"/openai/deployments?api-version="+azureDeploymentsAPIVersion, hdr, nil, &out)
+	var status *httpStatusError
+	if errors.As(err, &status) && status.Status == http.StatusNotFound {
+		// Resources created after the deployments API was retired: fall
+		// back to the models available to the resource.
+		models := make([]ProviderModel, 0, len(catalog))
+		for _, id := range sortedModelIDs(catalog) {
+			models = append(models, p.fromModel(catalog[id]))
+		}
+		return models, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	models := make([]ProviderModel, 0, len(out.Data))
+	for _, d := range out.Data {
+		models = append(models, p.fromDeployment(d, catalog))
+	}
+	return models, nil
+}
+
+func (p *azureOpenAIProvider) DescribeModel(ctx context.Context, id string) (*ProviderModel, error) {
+	hdr, err := p.headers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := p.models(ctx, hdr)
+	if err != nil {
+		return nil, err
+	}
+
+	var d azureDeployment
+	err = doJSON(ctx, p.client, http.MethodGet,
+		p.endpoint+"/openai/deployments/"+url.PathEscape(id)+"?api-version="+azureDeploymentsAPIVersion, hdr, nil, &d)
+	if err == nil {
+		m := p.fromDeployment(d, catalog)
+		return &m, nil
+	}
+	if m, ok := catalog[id]; ok {
+		pm := p.fromModel(m)
+		return &pm, nil
+	}
+	return nil, err
+}
+
+// models returns the resource's model catalog keyed by model ID.
+func (p *azureOpenAIProvider) models(ctx context.Context, hdr map[string]string) (map[string]azureModel, error) {
+	var out struct {
+		Data []azureModel `json:"data"`
+	}
+	if err := doJSON(ctx, p.client, http.MethodGet,
+		p.endpoint+"/openai/models?api-version="+url.QueryEscape(p.apiVersion), hdr, nil, &out); err != nil {
+		return nil, err
+	}
+	catalog := make(map[string]azureModel, len(out.Data))
+	for _, m := range out.Data {
+		catalog[m.ID] = m
+	}
+	return catalog, nil
+}
+
+func (p *azureOpenAIProvider) fromDeployment(d azureDeployment, catalog map[string]azureModel) ProviderModel {
+	m := ProviderModel{
+		ID:         d.ID,
+		Owner:      d.Owner,
+		BaseModel:  d.Model,
+		Task:       openAITask(d.Model),
+		Properties: map[string]string{"endpoint": p.endpoint, "deployment": d.ID},
+	}
+	if d.CreatedAt > 0 {
+		m.Created = time.Unix(d.CreatedAt, 0)
+	}
+	if d.Status != "" {
+		m.Properties["deploymentStatus"] = d.Status
+	}
+	if d.ScaleSettings.ScaleType != "" {
+		m.Properties["scaleType"] = d.ScaleSettings.ScaleType
+	}
+	if cm, ok := catalog[d.Model]; ok {
+		p.applyCatalog(&m, cm)
+	}
+	return m
+}
+
+func (p *azureOpenAIProvider) fromModel(cm azureModel) ProviderModel {
+	m := ProviderModel{
+		ID:         cm.ID,
+		Owner:      "OpenAI",
+		BaseModel:  cm.Model,
+		Task:       openAITask(cm.ID),
+		Properties: map[string]string{"endpoint": p.endpoint},
+	}
+	if cm.CreatedAt > 0 {
+		m.Created = time.Unix(cm.CreatedAt, 0)
+	}
+	if cm.FineTune != "" {
+		m.Properties["fineTuneJob"] = cm.FineTune
+	}
+	p.applyCatalog(&m, cm)
+	return m
+}
+
+// applyCatalog adds capabilities, lifecycle and deprecation dates from the
+// model catalog entry.
+func (p *azureOpenAIProvider) applyCatalog(m *ProviderModel, cm azureModel) {
+	for _, k := range sortedBoolKeys(cm.Capabilities) {
+		if cm.Capabilities[k] {
+			m.Capabilities = append(m.Capabilities, k)
+		}
+	}
+	if cm.Capabilities["embeddings"] {
+		m.Task = "feature-extraction"
+	}
+	if cm.Lifecycle != "" {
+		m.Properties["lifecycleStatus"] = cm.Lifecycle
+	}
+	if ts := cm.Deprecation["inference"]; ts > 0 {
+		m.Properties["deprecation.inference"] = time.Unix(int64(ts), 0).UTC().Format(time.RFC3339)
+	}
+}
+
+func (p *azureOpenAIProvider) Component(m ProviderModel) cdx.Component {
+	return modelComponent(p.Name(), m)
+}
+
+func sortedModelIDs(m map[string]azureModel) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sortedBoolKeys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -34,10 +34,16 @@ type BedrockOptions struct {
 	SecretAccessKey string
 	SessionToken    string // optional
 
-	// ModelID filters to a single foundation model when set.
-	// When empty, all available foundation models are inventoried.
+	// ModelID filters to a single model when set: a foundation model ID, or
+	// the name or ARN of a custom, imported or provisioned model. When empty,
+	// all foundation models and the account's custom, imported and
+	// provisioned models and guardrails are inventoried.
 	ModelID string
 
+	// FoundationOnly skips the account's custom, imported and provisioned
+	// models and guardrails.
+	FoundationOnly bool
+
 	// Metadata overrides
 	Name         string
 	Version      string
@@ -49,7 +55,8 @@ type BedrockOptions struct {
 }
 
 // GenerateFromBedrock calls the AWS Bedrock API to list/describe foundation
-// models and returns a CycloneDX BOM inventorying them as ML model components.
+// models and the account's custom, imported and provisioned models and
+// guardrails, and returns a CycloneDX BOM inventorying them.
 func GenerateFromBedrock(opts *BedrockOptions) (*cdx.BOM, error) {
 	if opts.Region == "" {
 		return nil, fmt.Errorf("--region is required for AWS Bedrock")
@@ -76,78 +83,106 @@ func GenerateFromBedrock(opts *BedrockOptions) (*cdx.BOM, error) {
 
 	client := bedrock.NewFromConfig(awsCfg)
 
-	// Fetch models.
-	var models []types.FoundationModelSummary
-	if opts.ModelID != "" {
-		// Single model — use GetFoundationModel.
-		out, err := client.GetFoundationModel(ctx, &bedrock.GetFoundationModelInput{
-			ModelIdentifier: aws.String(opts.ModelID),
-		})
-		if err != nil {
-			return nil, fmt.Errorf("fetching Bedrock model %q: %w", opts.ModelID, err)
+	inv, err := collectBedrock(ctx, client, opts)
+	if err != nil {
+		return nil, err
+	}
+	return buildBedrockBOM(inv, opts), nil
+}
+
+// buildBedrockBOM constructs a CycloneDX BOM from a Bedrock inventory. Custom
+// models depend on their base model and training data, provisioned
+// throughputs on the model they serve.
+func buildBedrockBOM(inv *bedrockInventory, opts *BedrockOptions) *cdx.BOM {
+	doc := cdx.NewBOM()
+	doc.SerialNumber = "urn:uuid:" + uuid.New().String()
+	doc.JSONSchema = jsonSchema17
+
+	g := newBOMGraph("aws-bedrock-models")
+	refs := map[string]string{} // ARN resource ("custom-model/...") → bom-ref
+	var top []string
+	add := func(resource string, c cdx.Component) string {
+		ref := g.add(c)
+		refs[resource] = ref
+		top = append(top, ref)
+		return ref
+	}
+
+	for _, m := range inv.foundation {
+		add("foundation-model/"+aws.ToString(m.ModelId), bedrockModelComponent(m, opts))
+	}
+	for _, m := range inv.custom {
+		add(arnResource(aws.ToString(m.ModelArn)), bedrockCustomComponent(m, opts))
+	}
+	for _, m := range inv.imported {
+		add(arnResource(aws.ToString(m.ModelArn)), bedrockImportedComponent(m, opts))
+	}
+	for _, p := range inv.provisioned {
+		add(arnResource(aws.ToString(p.ProvisionedModelArn)), bedrockProvisionedComponent(p, opts))
+	}
+	for _, gr := range inv.guardrails {
+		add(arnResource(aws.ToString(gr.GuardrailArn)), bedrockGuardrailComponent(gr))
+	}
+
+	// modelRef returns the bom-ref of the model identified by arn, adding a
+	// stub when it was not inventoried (e.g. a base model in another region).
+	modelRef := func(arn string) string {
+		if ref, ok := refs[arnResource(arn)]; ok {
+			return ref
 		}
-		d := out.ModelDetails
-		models = []types.FoundationModelSummary{{
-			ModelId:                    d.ModelId,
-			ModelName:                  d.ModelName,
-			ProviderName:               d.ProviderName,
-			InputModalities:            d.InputModalities,
-			OutputModalities:           d.OutputModalities,
-			CustomizationsSupported:    d.CustomizationsSupported,
-			InferenceTypesSupported:    d.InferenceTypesSupported,
-			ResponseStreamingSupported: d.ResponseStreamingSupported,
-			ModelLifecycle:             d.ModelLifecycle,
-		}}
-	} else {
-		// All models — use ListFoundationModels.
-		out, err := client.ListFoundationModels(ctx, &bedrock.ListFoundationModelsInput{})
-		if err != nil {
-			return nil, fmt.Errorf("listing Bedrock foundation models: %w", err)
+		ref := g.add(bedrockStubComponent(arn, opts))
+		refs[arnResource(arn)] = ref
+		return ref
+	}
+
+	for _, m := range inv.custom {
+		ref := refs[arnResource(aws.ToString(m.ModelArn))]
+		if base := aws.ToString(m.BaseModelArn); base != "" {
+			baseRef := modelRef(base)
+			g.link(ref, baseRef)
+			ancestor := ancestorOf(g.component(baseRef))
+			g.component(ref).Pedigree = &cdx.Pedigree{Ancestors: &[]cdx.Component{ancestor}}
+		}
+		for _, ds := range customModelData(m) {
+			g.link(ref, g.add(bedrockDataComponent(ds.uri, ds.classification)))
 		}
-		models = out.ModelSummaries
+	}
+	for _, p := range inv.provisioned {
+		g.link(refs[arnResource(aws.ToString(p.ProvisionedModelArn))], modelRef(aws.ToString(p.ModelArn)))
 	}
 
-	return buildBedrockBOM(models, opts), nil
-}
-
-// buildBedrockBOM constructs a CycloneDX BOM from a slice of Bedrock model summaries.
-func buildBedrockBOM(models []types.FoundationModelSummary, opts *BedrockOptions) *cdx.BOM {
-	bom := cdx.NewBOM()
-	bom.SerialNumber = "urn:uuid:" + uuid.New().String()
-	bom.JSONSchema = jsonSchema17
-
-	// Use first model (or the only model) as the BOM subject when there's one.
-	subjectName := "AWS Bedrock Foundation Models"
-	subjectRef := "aws-bedrock-models"
-	if len(models) == 1 && models[0].ModelId != nil {
-		subjectName = aws.ToString(models[0].ModelName)
-		if subjectName == "" {
-			subjectName = aws.ToString(models[0].ModelId)
+	// A single requested model is the subject of the BOM; a full inventory
+	// hangs every resource off a synthetic root.
+	subjectName := "AWS Bedrock Models"
+	if ref, ok := refs[inv.subject]; ok && inv.subject != "" {
+		g.root = ref
+		c := g.component(ref)
+		if opts.Name != "" {
+			c.Name = opts.Name
+		}
+		subjectName = c.Name
+	} else {
+		for _, ref := range top {
+			g.link(g.root, ref)
 		}
-		subjectRef = aws.ToString(models[0].ModelId)
 	}
 
-	bom.Metadata = &cdx.Metadata{
+	doc.Metadata = &cdx.Metadata{
 		Timestamp:  time.Now().UTC().Format(time.RFC3339),
 		Lifecycles: &[]cdx.Lifecycle{{Phase: cdx.LifecyclePhaseBuild}},
 		Tools: &cdx.ToolsChoice{
 			Components: &[]cdx.Component{toolComponent},
 		},
 		Component: &cdx.Component{
-			BOMRef: subjectRef,
+			BOMRef: g.root,
 			Type:   cdx.ComponentTypeMachineLearningModel,
 			Name:   subjectName,
 		},
 	}
-
-	comps := make([]cdx.Component, 0, len(models))
-	for _, m := range models {
-		comps = append(comps, bedrockModelComponent(m, opts))
-	}
-
-	bom.Components = &comps
-	enforceRequiredFields(bom)
-	return bom
+	doc.Components = &g.comps
+	doc.Dependencies = g.dependencies()
+	enforceRequiredFields(doc)
+	return doc
 }
 
 // bedrockModelComponent converts a single Bedrock FoundationModelSummary into a
@@ -158,19 +193,13 @@ func bedrockModelComponent(m types.FoundationModelSummary, opts *BedrockOptions)
 	if name == "" {
 		name = modelID
 	}
-	if opts.Name != "" {
-		name = opts.Name
-	}
 	provider := aws.ToString(m.ProviderName)
 	if opts.Manufacturer != "" {
 		provider = opts.Manufacturer
 	}
 	version := opts.Version // optional override; Bedrock models have no concept of git SHA
 
-	purl := fmt.Sprintf("pkg:generic/aws-bedrock/%s", modelID)
-	if version != "" {
-		purl += "@" + version
-	}
+	purl := bedrockFoundationRef(modelID, opts)
 	bomRef := purl
 
 	comp := cdx.Component{
@@ -209,6 +238,7 @@ func bedrockModelComponent(m types.FoundationModelSummary, opts *BedrockOptions)
 			modelProps = append(modelProps, cdx.Property{Name: name, Value: value})
 		}
 	}
+	addProp("bedrockResourceType", "foundation-model")
 	if m.ResponseStreamingSupported != nil {
 		addProp("responseStreamingSupported", fmt.Sprintf("%v", *m.ResponseStreamingSupported))
 	}
@@ -254,6 +284,32 @@ func bedrockModelComponent(m types.FoundationModelSummary, opts *BedrockOptions)
 	return comp
 }
 
+// bedrockFoundationRef returns the purl (and bom-ref) of a foundation model.
+func bedrockFoundationRef(modelID string, opts *BedrockOptions) string {
+	purl := fmt.Sprintf("pkg:generic/aws-bedrock/%s", modelID)
+	if opts.Version != "" {
+		purl += "@" + opts.Version
+	}
+	return purl
+}
+
+// foundationSummary converts GetFoundationModel details to the summary shape
+// returned by ListFoundationModels.
+func foundationSummary(d *types.FoundationModelDetails) types.FoundationModelSummary {
+	return types.FoundationModelSummary{
+		ModelArn:                   d.ModelArn,
+		ModelId:                    d.ModelId,
+		ModelName:                  d.ModelName,
+		ProviderName:               d.ProviderName,
+		InputModalities:            d.InputModalities,
+		OutputModalities:           d.OutputModalities,
+		CustomizationsSupported:    d.CustomizationsSupported,
+		InferenceTypesSupported:    d.InferenceTypesSupported,
+		ResponseStreamingSupported: d.ResponseStreamingSupported,
+		ModelLifecycle:             d.ModelLifecycle,
+	}
+}
+
 // bedrockDescription builds a human-readable description for a Bedrock model.
 func bedrockDescription(m types.FoundationModelSummary) string {
 	var parts []string
@@ -0,0 +1,681 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package aibom
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrock"
+	"github.com/aws/aws-sdk-go-v2/service/bedrock/types"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+)
+
+// bedrockAPI is the subset of the Bedrock control-plane client used to build
+// an inventory. It is satisfied by *bedrock.Client.
+type bedrockAPI interface {
+	ListFoundationModels(ctx context.Context, in *bedrock.ListFoundationModelsInput, optFns ...func(*bedrock.Options)) (*bedrock.ListFoundationModelsOutput, error)
+	GetFoundationModel(ctx context.Context, in *bedrock.GetFoundationModelInput, optFns ...func(*bedrock.Options)) (*bedrock.GetFoundationModelOutput, error)
+	ListCustomModels(ctx context.Context, in *bedrock.ListCustomModelsInput, optFns ...func(*bedrock.Options)) (*bedrock.ListCustomModelsOutput, error)
+	GetCustomModel(ctx context.Context, in *bedrock.GetCustomModelInput, optFns ...func(*bedrock.Options)) (*bedrock.GetCustomModelOutput, error)
+	ListImportedModels(ctx context.Context, in *bedrock.ListImportedModelsInput, optFns ...func(*bedrock.Options)) (*bedrock.ListImportedModelsOutput, error)
+	GetImportedModel(ctx context.Context, in *bedrock.GetImportedModelInput, optFns ...func(*bedrock.Options)) (*bedrock.GetImportedModelOutput, error)
+	ListProvisionedModelThroughputs(ctx context.Context, in *bedrock.ListProvisionedModelThroughputsInput, optFns ...func(*bedrock.Options)) (*bedrock.ListProvisionedModelThroughputsOutput, error)
+	GetProvisionedModelThroughput(ctx context.Context, in *bedrock.GetProvisionedModelThroughputInput, optFns ...func(*bedrock.Options)) (*bedrock.GetProvisionedModelThroughputOutput, error)
+	ListGuardrails(ctx context.Context, in *bedrock.ListGuardrailsInput, optFns ...func(*bedrock.Options)) (*bedrock.ListGuardrailsOutput, error)
+	GetGuardrail(ctx context.Context, in *bedrock.GetGuardrailInput, optFns ...func(*bedrock.Options)) (*bedrock.GetGuardrailOutput, error)
+}
+
+// bedrockInventory is everything collected from one account and region.
+type bedrockInventory struct {
+	foundation  []types.FoundationModelSummary
+	custom      []*bedrock.GetCustomModelOutput
+	imported    []*bedrock.GetImportedModelOutput
+	provisioned []types.ProvisionedModelSummary
+	guardrails  []*bedrock.GetGuardrailOutput
+
+	// subject is the ARN resource ("foundation-model/<id>",
+	// "custom-model/...") of the model requested with ModelID; empty for a
+	// full inventory.
+	subject string
+}
+
+// collectBedrock gathers the models (and guardrails) to inventory.
+func collectBedrock(ctx context.Context, api bedrockAPI, opts *BedrockOptions) (*bedrockInventory, error) {
+	inv := &bedrockInventory{}
+	if opts.ModelID != "" {
+		subject, err := inv.describe(ctx, api, opts.ModelID)
+		if err != nil {
+			return nil, err
+		}
+		inv.subject = subject
+		return inv, nil
+	}
+
+	out, err := api.ListFoundationModels(ctx, &bedrock.ListFoundationModelsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("listing Bedrock foundation models: %w", err)
+	}
+	inv.foundation = out.ModelSummaries
+	if opts.FoundationOnly {
+		return inv, nil
+	}
+
+	// Account resources need further IAM permissions; a principal that may
+	// only list foundation models still gets a partial inventory.
+	steps := []struct {
+		what string
+		list func(context.Context, bedrockAPI) error
+	}{
+		{"custom models", inv.listCustom},
+		{"imported models", inv.listImported},
+		{"provisioned throughputs", inv.listProvisioned},
+		{"guardrails", inv.listGuardrails},
+	}
+	for _, s := range steps {
+		if err := s.list(ctx, api); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: listing Bedrock %s: %v\n", s.what, err)
+		}
+	}
+	return inv, nil
+}
+
+func (inv *bedrockInventory) listCustom(ctx context.Context, api bedrockAPI) error {
+	in := &bedrock.ListCustomModelsInput{}
+	for {
+		out, err := api.ListCustomModels(ctx, in)
+		if err != nil {
+			return err
+		}
+		for _, s := range out.ModelSummaries {
+			m, err := api.GetCustomModel(ctx, &bedrock.GetCustomModelInput{ModelIdentifier: s.ModelArn})
+			if err != nil {
+				// The summary still names the model and its base model.
+				fmt.Fprintf(os.Stderr, "Warning: describing Bedrock custom model %s: %v\n", aws.ToString(s.ModelName), err)
+				m = &bedrock.GetCustomModelOutput{
+					ModelArn:          s.ModelArn,
+					ModelName:         s.ModelName,
+					BaseModelArn:      s.BaseModelArn,
+					CustomizationType: s.CustomizationType,
+					ModelStatus:       s.ModelStatus,
+					CreationTime:      s.CreationTime,
+				}
+			}
+			inv.custom = append(inv.custom, m)
+		}
+		if aws.ToString(out.NextToken) == "" {
+			return nil
+		}
+		in.NextToken = out.NextToken
+	}
+}
+
+func (inv *bedrockInventory) listImported(ctx context.Context, api bedrockAPI) error {
+	in := &bedrock.ListImportedModelsInput{}
+	for {
+		out, err := api.ListImportedModels(ctx, in)
+		if err != nil {
+			return err
+		}
+		for _, s := range out.ModelSummaries {
+			m, err := api.GetImportedModel(ctx, &bedrock.GetImportedModelInput{ModelIdentifier: s.ModelArn})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: describing Bedrock imported model %s: %v\n", aws.ToString(s.ModelName), err)
+				m = &bedrock.GetImportedModelOutput{
+					ModelArn:          s.ModelArn,
+					ModelName:         s.ModelName,
+					ModelArchitecture: s.ModelArchitecture,
+					InstructSupported: s.InstructSupported,
+					CreationTime:      s.CreationTime,
+				}
+			}
+			inv.imported = append(inv.imported, m)
+		}
+		if aws.ToString(out.NextToken) == "" {
+			return nil
+		}
+		in.NextToken = out.NextToken
+	}
+}
+
+func (inv *bedrockInventory) listProvisioned(ctx context.Context, api bedrockAPI) error {
+	in := &bedrock.ListProvisionedModelThroughputsInput{}
+	for {
+		out, err := api.ListProvisionedModelThroughputs(ctx, in)
+		if err != nil {
+			return err
+		}
+		inv.provisioned = append(inv.provisioned, out.ProvisionedModelSummaries...)
+		if aws.ToString(out.NextToken) == "" {
+			return nil
+		}
+		in.NextToken = out.NextToken
+	}
+}
+
+func (inv *bedrockInventory) listGuardrails(ctx context.Context, api bedrockAPI) error {
+	in := &bedrock.ListGuardrailsInput{}
+	for {
+		out, err := api.ListGuardrails(ctx, in)
+		if err != nil {
+			return err
+		}
+		for _, s := range out.Guardrails {
+			gr, err := api.GetGuardrail(ctx, &bedrock.GetGuardrailInput{
+				GuardrailIdentifier: s.Id,
+				GuardrailVersion:    s.Version,
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: describing Bedrock guardrail %s: %v\n", aws.ToString(s.Name), err)
+				gr = &bedrock.GetGuardrailOutput{
+					GuardrailArn: s.Arn,
+					GuardrailId:  s.Id,
+					Name:         s.Name,
+					Version:      s.Version,
+					Status:       s.Status,
+					Description:  s.Description,
+					CreatedAt:    s.CreatedAt,
+					UpdatedAt:    s.UpdatedAt,
+				}
+			}
+			inv.guardrails = append(inv.guardrails, gr)
+		}
+		if aws.ToString(out.NextToken) == "" {
+			return nil
+		}
+		in.NextToken = out.NextToken
+	}
+}
+
+// describe fetches the model identified by id and, for custom and
+// provisioned models, the models it derives from. It returns the ARN
+// resource of the requested model.
+func (inv *bedrockInventory) describe(ctx context.Context, api bedrockAPI, id string) (string, error) {
+	switch {
+	case strings.Contains(id, ":provisioned-model/"):
+		return inv.describeProvisioned(ctx, api, id)
+	case strings.Contains(id, ":custom-model/"):
+		return inv.describeCustom(ctx, api, id)
+	case strings.Contains(id, ":imported-model/"):
+		return inv.describeImported(ctx, api, id)
+	}
+
+	// Bare identifiers are foundation model IDs or custom, imported or
+	// provisioned model names.
+	resource, err := inv.describeFoundation(ctx, api, id)
+	if err == nil {
+		return resource, nil
+	}
+	if resource, cerr := inv.describeCustom(ctx, api, id); cerr == nil {
+		return resource, nil
+	}
+	if resource, ierr := inv.describeImported(ctx, api, id); ierr == nil {
+		return resource, nil
+	}
+	if resource, perr := inv.describeProvisioned(ctx, api, id); perr == nil {
+		return resource, nil
+	}
+	return "", fmt.Errorf("fetching Bedrock model %q: %w", id, err)
+}
+
+func (inv *bedrockInventory) describeFoundation(ctx context.Context, api bedrockAPI, id string) (string, error) {
+	out, err := api.GetFoundationModel(ctx, &bedrock.GetFoundationModelInput{ModelIdentifier: aws.String(id)})
+	if err != nil {
+		return "", err
+	}
+	m := foundationSummary(out.ModelDetails)
+	inv.foundation = append(inv.foundation, m)
+	return "foundation-model/" + aws.ToString(m.ModelId), nil
+}
+
+func (inv *bedrockInventory) describeCustom(ctx context.Context, api bedrockAPI, id string) (string, error) {
+	m, err := api.GetCustomModel(ctx, &bedrock.GetCustomModelInput{ModelIdentifier: aws.String(id)})
+	if err != nil {
+		return "", err
+	}
+	inv.custom = append(inv.custom, m)
+	inv.describeBase(ctx, api, aws.ToString(m.BaseModelArn))
+	return arnResource(aws.ToString(m.ModelArn)), nil
+}
+
+func (inv *bedrockInventory) describeImported(ctx context.Context, api bedrockAPI, id string) (string, error) {
+	m, err := api.GetImportedModel(ctx, &bedrock.GetImportedModelInput{ModelIdentifier: aws.String(id)})
+	if err != nil {
+		return "", err
+	}
+	inv.imported = append(inv.imported, m)
+	return arnResource(aws.ToString(m.ModelArn)), nil
+}
+
+func (inv *bedrockInventory) describeProvisioned(ctx context.Context, api bedrockAPI, id string) (string, error) {
+	p, err := api.GetProvisionedModelThroughput(ctx, &bedrock.GetProvisionedModelThroughputInput{ProvisionedModelId: aws.String(id)})
+	if err != nil {
+		return "", err
+	}
+	inv.provisioned = append(inv.provisioned, types.ProvisionedModelSummary{
+		ProvisionedModelArn:      p.ProvisionedModelArn,
+		ProvisionedModelName:     p.ProvisionedModelName,
+		ModelArn:                 p.ModelArn,
+		DesiredModelArn:          p.DesiredModelArn,
+		FoundationModelArn:       p.FoundationModelArn,
+		ModelUnits:               p.ModelUnits,
+		DesiredModelUnits:        p.DesiredModelUnits,
+		Status:                   p.Status,
+		CommitmentDuration:       p.CommitmentDuration,
+		CommitmentExpirationTime: p.CommitmentExpirationTime,
+		CreationTime:             p.CreationTime,
+		LastModifiedTime:         p.LastModifiedTime,
+	})
+	inv.describeBase(ctx, api, aws.ToString(p.ModelArn))
+	return arnResource(aws.ToString(p.ProvisionedModelArn)), nil
+}
+
+// describeBase fetches the model a custom model or provisioned throughput
+// derives from. Failures leave a stub in the BOM rather than failing it.
+func (inv *bedrockInventory) describeBase(ctx context.Context, api bedrockAPI, arn string) {
+	if arn == "" {
+		return
+	}
+	var err error
+	switch resource := arnResource(arn); {
+	case strings.HasPrefix(resource, "foundation-model/"):
+		_, err = inv.describeFoundation(ctx, api, strings.TrimPrefix(resource, "foundation-model/"))
+	case strings.HasPrefix(resource, "custom-model/"):
+		_, err = inv.describeCustom(ctx, api, arn)
+	case strings.HasPrefix(resource, "imported-model/"):
+		_, err = inv.describeImported(ctx, api, arn)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: resolving base model %s: %v\n", arn, err)
+	}
+}
+
+// arnResource returns the resource part of an ARN
+// (arn:partition:service:region:account:resource), or s unchanged when it is
+// not an ARN.
+func arnResource(s string) string {
+	parts := strings.SplitN(s, ":", 6)
+	if len(parts) < 6 || parts[0] != "arn" {
+		return s
+	}
+	return parts[5]
+}
+
+// arnAccount returns the account ID of an ARN.
+func arnAccount(s string) string {
+	parts := strings.SplitN(s, ":", 6)
+	if len(parts) < 6 || parts[0] != "arn" {
+		return ""
+	}
+	return parts[4]
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Account resource components
+// ──────────────────────────────────────────────────────────────────────────────
+
+// bedrockAccountComponent returns the fields shared by every account-owned
+// resource: bom-ref is the ARN, which is unique where names are not.
+func bedrockAccountComponent(arn, kind, name string, opts *BedrockOptions) cdx.Component {
+	purl := fmt.Sprintf("pkg:generic/aws-bedrock/%s/%s", kind, name)
+	if opts.Version != "" {
+		purl += "@" + opts.Version
+	}
+	comp := cdx.Component{
+		BOMRef:     arn,
+		Type:       cdx.ComponentTypeMachineLearningModel,
+		Name:       name,
+		Version:    opts.Version,
+		PackageURL: purl,
+	}
+	supplier := opts.Manufacturer
+	if supplier == "" && arnAccount(arn) != "" {
+		supplier = "AWS account " + arnAccount(arn)
+	}
+	if supplier != "" {
+		comp.Supplier = &cdx.OrganizationalEntity{Name: supplier}
+	}
+	return comp
+}
+
+// bedrockCustomComponent maps a fine-tuned, continued-pretraining or distilled
+// model. Base model and training data edges are added by buildBedrockBOM.
+func bedrockCustomComponent(m *bedrock.GetCustomModelOutput, opts *BedrockOptions) cdx.Component {
+	arn := aws.ToString(m.ModelArn)
+	comp := bedrockAccountComponent(arn, "custom-model", aws.ToString(m.ModelName), opts)
+
+	customization := strings.ToLower(string(m.CustomizationType))
+	base := arnResource(aws.ToString(m.BaseModelArn))
+	base = base[strings.Index(base, "/")+1:]
+	comp.Description = "AWS Bedrock custom model"
+	if base != "" {
+		comp.Description = fmt.Sprintf("AWS Bedrock custom model (%s) of %s",
+			strings.ReplaceAll(customization, "_", " "), base)
+	}
+	if customization != "" {
+		comp.Tags = &[]string{"customization:" + customization}
+	}
+	// Custom models remain subject to the base model's EULA.
+	comp.Licenses = &cdx.Licenses{{License: &cdx.License{ID: "LicenseRef-AWS-Bedrock-EULA"}}}
+
+	var props []cdx.Property
+	addProp := func(name, value string) {
+		if value != "" {
+			props = append(props, cdx.Property{Name: name, Value: value})
+		}
+	}
+	addProp("bedrockResourceType", "custom-model")
+	addProp("arn", arn)
+	addProp("customizationType", string(m.CustomizationType))
+	addProp("modelStatus", string(m.ModelStatus))
+	addProp("baseModelArn", aws.ToString(m.BaseModelArn))
+	addProp("jobName", aws.ToString(m.JobName))
+	addProp("jobArn", aws.ToString(m.JobArn))
+	addProp("modelKmsKeyArn", aws.ToString(m.ModelKmsKeyArn))
+	if m.CreationTime != nil {
+		addProp("createdAt", m.CreationTime.UTC().Format(time.RFC3339))
+	}
+	if m.OutputDataConfig != nil {
+		addProp("outputDataUri", aws.ToString(m.OutputDataConfig.S3Uri))
+	}
+	addProp("failureMessage", aws.ToString(m.FailureMessage))
+	for _, k := range sortedStringKeys(m.HyperParameters) {
+		addProp("hyperParameter."+k, m.HyperParameters[k])
+	}
+	comp.Properties = &props
+
+	params := &cdx.MLModelParameters{}
+	switch m.CustomizationType {
+	case types.CustomizationTypeFineTuning, types.CustomizationTypeDistillation:
+		params.Approach = &cdx.MLModelParametersApproach{Type: cdx.MLModelParametersApproachTypeSupervised}
+	case types.CustomizationTypeContinuedPreTraining:
+		params.Approach = &cdx.MLModelParametersApproach{Type: cdx.MLModelParametersApproachTypeSelfSupervised}
+	case types.CustomizationTypeReinforcementFineTuning:
+		params.Approach = &cdx.MLModelParametersApproach{Type: cdx.MLModelParametersApproachTypeReinforcementLearning}
+	}
+	var metrics []cdx.MLPerformanceMetric
+	if m.TrainingMetrics != nil && m.TrainingMetrics.TrainingLoss != nil {
+		metrics = append(metrics, cdx.MLPerformanceMetric{
+			Type: "trainingLoss", Value: fmt.Sprint(*m.TrainingMetrics.TrainingLoss),
+		})
+	}
+	for i, v := range m.ValidationMetrics {
+		if v.ValidationLoss != nil {
+			metrics = append(metrics, cdx.MLPerformanceMetric{
+				Type: "validationLoss", Value: fmt.Sprint(*v.ValidationLoss), Slice: fmt.Sprintf("validator-%d", i),
+			})
+		}
+	}
+	card := &cdx.MLModelCard{}
+	if params.Approach != nil {
+		card.ModelParameters = params
+	}
+	if len(metrics) > 0 {
+		card.QuantitativeAnalysis = &cdx.MLQuantitativeAnalysis{PerformanceMetrics: &metrics}
+	}
+	if card.ModelParameters != nil || card.QuantitativeAnalysis != nil {
+		comp.ModelCard = card
+	}
+	return comp
+}
+
+// bedrockData is an S3 data source used to customise a model.
+type bedrockData struct {
+	uri            string
+	classification string // "training" or "validation"
+}
+
+// customModelData returns the training and validation data sources of a
+// custom model.
+func customModelData(m *bedrock.GetCustomModelOutput) []bedrockData {
+	var out []bedrockData
+	if m.TrainingDataConfig != nil && aws.ToString(m.TrainingDataConfig.S3Uri) != "" {
+		out = append(out, bedrockData{uri: aws.ToString(m.TrainingDataConfig.S3Uri), classification: "training"})
+	}
+	if m.ValidationDataConfig != nil {
+		for _, v := range m.ValidationDataConfig.Validators {
+			if uri := aws.ToString(v.S3Uri); uri != "" {
+				out = append(out, bedrockData{uri: uri, classification: "validation"})
+			}
+		}
+	}
+	return out
+}
+
+// bedrockDataComponent maps an S3 data source to a data component.
+func bedrockDataComponent(uri, classification string) cdx.Component {
+	return cdx.Component{
+		BOMRef:      "dataset/" + uri,
+		Type:        cdx.ComponentTypeData,
+		Name:        uri,
+		Description: "Model customization " + classification + " data",
+		Data: &[]cdx.ComponentData{{
+			Type:           cdx.ComponentDataTypeDataset,
+			Name:           uri,
+			Classification: classification,
+			Contents:       &cdx.ComponentDataContents{URL: uri},
+		}},
+		ExternalReferences: &[]cdx.ExternalReference{{Type: cdx.ERTypeDistribution, URL: uri}},
+	}
+}
+
+// bedrockImportedComponent maps a model imported with Custom Model
+// Import. Its weights come from the customer, so no license is implied.
+func bedrockImportedComponent(m *bedrock.GetImportedModelOutput, opts *BedrockOptions) cdx.Component {
+	arn := aws.ToString(m.ModelArn)
+	comp := bedrockAccountComponent(arn, "imported-model", aws.ToString(m.ModelName), opts)
+	comp.Description = "AWS Bedrock imported model"
+
+	var props []cdx.Property
+	addProp := func(name, value string) {
+		if value != "" {
+			props = append(props, cdx.Property{Name: name, Value: value})
+		}
+	}
+	addProp("bedrockResourceType", "imported-model")
+	addProp("arn", arn)
+	addProp("modelArchitecture", aws.ToString(m.ModelArchitecture))
+	if m.InstructSupported != nil {
+		addProp("instructSupported", fmt.Sprint(*m.InstructSupported))
+	}
+	addProp("jobName", aws.ToString(m.JobName))
+	addProp("jobArn", aws.ToString(m.JobArn))
+	addProp("modelKmsKeyArn", aws.ToString(m.ModelKmsKeyArn))
+	if m.CustomModelUnits != nil && m.CustomModelUnits.CustomModelUnitsPerModelCopy != nil {
+		addProp("customModelUnitsPerCopy", fmt.Sprint(*m.CustomModelUnits.CustomModelUnitsPerModelCopy))
+	}
+	if m.CreationTime != nil {
+		addProp("createdAt", m.CreationTime.UTC().Format(time.RFC3339))
+	}
+	if src, ok := m.ModelDataSource.(*types.ModelDataSourceMemberS3DataSource); ok {
+		uri := aws.ToString(src.Value.S3Uri)
+		addProp("modelDataSource", uri)
+		if uri != "" {
+			comp.ExternalReferences = &[]cdx.ExternalReference{{
+				Type: cdx.ERTypeDistribution, URL: uri, Comment: "Imported model weights",
+			}}
+		}
+	}
+	comp.Properties = &props
+
+	if arch := aws.ToString(m.ModelArchitecture); arch != "" {
+		comp.ModelCard = &cdx.MLModelCard{ModelParameters: &cdx.MLModelParameters{ModelArchitecture: arch}}
+	}
+	return comp
+}
+
+// bedrockProvisionedComponent maps a Provisioned Throughput, the dedicated
+// capacity through which a model is invoked.
+func bedrockProvisionedComponent(p types.ProvisionedModelSummary, opts *BedrockOptions) cdx.Component {
+	arn := aws.ToString(p.ProvisionedModelArn)
+	comp := bedrockAccountComponent(arn, "provisioned-model", aws.ToString(p.ProvisionedModelName), opts)
+	served := arnResource(aws.ToString(p.ModelArn))
+	comp.Description = "AWS Bedrock provisioned throughput for " + served[strings.Index(served, "/")+1:]
+
+	var props []cdx.Property
+	addProp := func(name, value string) {
+		if value != "" {
+			props = append(props, cdx.Property{Name: name, Value: value})
+		}
+	}
+	addProp("bedrockResourceType", "provisioned-throughput")
+	addProp("arn", arn)
+	addProp("modelArn", aws.ToString(p.ModelArn))
+	addProp("foundationModelArn", aws.ToString(p.FoundationModelArn))
+	if d := aws.ToString(p.DesiredModelArn); d != aws.ToString(p.ModelArn) {
+		addProp("desiredModelArn", d)
+	}
+	addProp("status", string(p.Status))
+	if p.ModelUnits != nil {
+		addProp("modelUnits", fmt.Sprint(*p.ModelUnits))
+	}
+	addProp("commitmentDuration", string(p.CommitmentDuration))
+	if p.CommitmentExpirationTime != nil {
+		addProp("commitmentExpiration", p.CommitmentExpirationTime.UTC().Format(time.RFC3339))
+	}
+	if p.CreationTime != nil {
+		addProp("createdAt", p.CreationTime.UTC().Format(time.RFC3339))
+	}
+	comp.Properties = &props
+	return comp
+}
+
+// bedrockStubComponent stands in for a model referenced by ARN that is not
+// part of the inventory.
+func bedrockStubComponent(arn string, opts *BedrockOptions) cdx.Component {
+	resource := arnResource(arn)
+	kind, id, _ := strings.Cut(resource, "/")
+	if kind == "foundation-model" {
+		return cdx.Component{
+			BOMRef:     bedrockFoundationRef(id, opts),
+			Type:       cdx.ComponentTypeMachineLearningModel,
+			Name:       id,
+			PackageURL: bedrockFoundationRef(id, opts),
+			Properties: &[]cdx.Property{{Name: "bedrockResourceType", Value: kind}, {Name: "arn", Value: arn}},
+		}
+	}
+	return cdx.Component{
+		BOMRef:     arn,
+		Type:       cdx.ComponentTypeMachineLearningModel,
+		Name:       resource,
+		Properties: &[]cdx.Property{{Name: "bedrockResourceType", Value: kind}, {Name: "arn", Value: arn}},
+	}
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Guardrails
+// ──────────────────────────────────────────────────────────────────────────────
+
+// bedrockGuardrailComponent maps a guardrail to a configuration data
+// component whose policies are recorded as properties.
+func bedrockGuardrailComponent(gr *bedrock.GetGuardrailOutput) cdx.Component {
+	arn := aws.ToString(gr.GuardrailArn)
+	name := aws.ToString(gr.Name)
+	version := aws.ToString(gr.Version)
+	ref := arn
+	if version != "" {
+		ref += ":" + version
+	}
+	comp := cdx.Component{
+		BOMRef:      ref,
+		Type:        cdx.ComponentTypeData,
+		Name:        name,
+		Version:     version,
+		Description: aws.ToString(gr.Description),
+		Data: &[]cdx.ComponentData{{
+			Type: cdx.ComponentDataTypeConfiguration,
+			Name: name,
+		}},
+	}
+	if comp.Description == "" {
+		comp.Description = "AWS Bedrock guardrail"
+	}
+
+	var props []cdx.Property
+	addProp := func(name, value string) {
+		if value != "" {
+			props = append(props, cdx.Property{Name: name, Value: value})
+		}
+	}
+	addProp("bedrockResourceType", "guardrail")
+	addProp("arn", arn)
+	addProp("guardrailId", aws.ToString(gr.GuardrailId))
+	addProp("status", string(gr.Status))
+	addProp("kmsKeyArn", aws.ToString(gr.KmsKeyArn))
+	if gr.UpdatedAt != nil {
+		addProp("updatedAt", gr.UpdatedAt.UTC().Format(time.RFC3339))
+	}
+	for _, p := range guardrailPolicies(gr) {
+		addProp(p.Name, p.Value)
+	}
+	addProp("blockedInputMessaging", aws.ToString(gr.BlockedInputMessaging))
+	addProp("blockedOutputsMessaging", aws.ToString(gr.BlockedOutputsMessaging))
+	comp.Properties = &props
+	return comp
+}
+
+// guardrailPolicies summarises a guardrail's policies as properties, one per
+// policy type, e.g. contentFilters = "HATE:HIGH/HIGH,VIOLENCE:MEDIUM/MEDIUM".
+func guardrailPolicies(gr *bedrock.GetGuardrailOutput) []cdx.Property {
+	var props []cdx.Property
+	add := func(name string, values []string) {
+		if len(values) > 0 {
+			sort.Strings(values)
+			props = append(props, cdx.Property{Name: name, Value: strings.Join(values, ",")})
+		}
+	}
+
+	if p := gr.ContentPolicy; p != nil {
+		var filters []string
+		for _, f := range p.Filters {
+			filters = append(filters, fmt.Sprintf("%s:%s/%s", f.Type, f.InputStrength, f.OutputStrength))
+		}
+		add("guardrail.contentFilters", filters)
+	}
+	if p := gr.TopicPolicy; p != nil {
+		var topics []string
+		for _, t := range p.Topics {
+			topics = append(topics, aws.ToString(t.Name))
+		}
+		add("guardrail.deniedTopics", topics)
+	}
+	if p := gr.WordPolicy; p != nil {
+		var lists []string
+		for _, l := range p.ManagedWordLists {
+			lists = append(lists, string(l.Type))
+		}
+		add("guardrail.managedWordLists", lists)
+		if len(p.Words) > 0 {
+			props = append(props, cdx.Property{Name: "guardrail.customWords", Value: fmt.Sprint(len(p.Words))})
+		}
+	}
+	if p := gr.SensitiveInformationPolicy; p != nil {
+		var pii, regexes []string
+		for _, e := range p.PiiEntities {
+			pii = append(pii, fmt.Sprintf("%s:%s", e.Type, e.Action))
+		}
+		for _, r := range p.Regexes {
+			regexes = append(regexes, fmt.Sprintf("%s:%s", aws.ToString(r.Name), r.Action))
+		}
+		add("guardrail.piiEntities", pii)
+		add("guardrail.regexes", regexes)
+	}
+	if p := gr.ContextualGroundingPolicy; p != nil {
+		var filters []string
+		for _, f := range p.Filters {
+			filters = append(filters, fmt.Sprintf("%s:%v", f.Type, aws.ToFloat64(f.Threshold)))
+		}
+		add("guardrail.contextualGrounding", filters)
+	}
+	if gr.AutomatedReasoningPolicy != nil {
+		props = append(props, cdx.Property{Name: "guardrail.automatedReasoning", Value: "true"})
+	}
+	return props
+}
@@ -13,9 +13,10 @@ import (
 )
 
 const (
-	hfAPIBase    = "https://huggingface.co/api/models"
-	hfWebBase    = "https://huggingface.co"
-	hfAPITimeout = 30 * time.Second
+	hfAPIBase        = "https://huggingface.co/api/models"
+	hfDatasetAPIBase = "https://huggingface.co/api/datasets"
+	hfWebBase        = "https://huggingface.co"
+	hfAPITimeout     = 30 * time.Second
 )
 
 // fetchModel retrieves model metadata from the HuggingFace API.
@@ -27,8 +28,50 @@ func fetchModel(modelID, token string) (*hfModelInfo, error) {
 		return nil, fmt.Errorf("model ID must not be empty")
 	}
 
-	url := fmt.Sprintf("%s/%s", hfAPIBase, modelID)
+	body, err := hubGet(fmt.Sprintf("%s/%s", hfAPIBase, modelID), token, "model", modelID)
+	if err != nil {
+		return nil, err
+	}
+
+	var info hfModelInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("parsing HuggingFace API response: %w", err)
+	}
+
+	// Ensure effectiveID is always set even if the API omits modelId.
+	if info.ModelID == "" {
+		info.ModelID = modelID
+	}
+
+	return &info, nil
+}
+
+// fetchDataset retrieves dataset metadata from the HuggingFace API.
+func fetchDataset(datasetID, token string) (*hfDatasetInfo, error) {
+	datasetID = strings.TrimPrefix(datasetID, "https://huggingface.co/datasets/")
+	datasetID = strings.Trim(datasetID, "/")
+	if datasetID == "" {
+		return nil, fmt.Errorf("dataset ID must not be empty")
+	}
+
+	body, err := hubGet(fmt.Sprintf("%s/%s", hfDatasetAPIBase, datasetID), token, "dataset", datasetID)
+	if err != nil {
+		return nil, err
+	}
 
+	var info hfDatasetInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("parsing HuggingFace API response: %w", err)
+	}
+	if info.ID == "" {
+		info.ID = datasetID
+	}
+	return &info, nil
+}
+
+// hubGet performs an authenticated GET against the HuggingFace API and maps
+// error statuses to messages naming the requested kind ("model", "dataset").
+func hubGet(url, token, kind, id string) ([]byte, error) {
 	client := &http.Client{Timeout: hfAPITimeout}
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
@@ -51,26 +94,15 @@ func fetchModel(modelID, token string) (*hfModelInfo, error) {
 	}
 
 	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("HuggingFace API: unauthorised — pass --token for private models")
+		return nil, fmt.Errorf("HuggingFace API: unauthorised — pass --token for private %ss", kind)
 	}
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("model %q not found on HuggingFace", modelID)
+		return nil, fmt.Errorf("%s %q not found on HuggingFace", kind, id)
 	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("HuggingFace API returned HTTP %d: %s", resp.StatusCode, string(body))
 	}
-
-	var info hfModelInfo
-	if err := json.Unmarshal(body, &info); err != nil {
-		return nil, fmt.Errorf("parsing HuggingFace API response: %w", err)
-	}
-
-	// Ensure effectiveID is always set even if the API omits modelId.
-	if info.ModelID == "" {
-		info.ModelID = modelID
-	}
-
-	return &info, nil
+	return body, nil
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
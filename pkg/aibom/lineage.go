@@ -0,0 +1,216 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package aibom
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+)
+
+// DefaultLineageDepth is the default number of base_model generations
+// resolved from the Hub.
+const DefaultLineageDepth = 5
+
+// hubFetcher retrieves Hub metadata. It is a struct of functions so tests
+// can substitute canned responses.
+type hubFetcher struct {
+	model   func(id string) (*hfModelInfo, error)
+	dataset func(id string) (*hfDatasetInfo, error) // nil: datasets are not enriched
+}
+
+// newHubFetcher returns a fetcher backed by the HuggingFace API.
+func newHubFetcher(token string) hubFetcher {
+	return hubFetcher{
+		model:   func(id string) (*hfModelInfo, error) { return fetchModel(id, token) },
+		dataset: func(id string) (*hfDatasetInfo, error) { return fetchDataset(id, token) },
+	}
+}
+
+// lineage resolves the provenance of Hub models: training datasets and the
+// chain of base models (fine-tune → base → original). Every ancestor becomes
+// a component linked through the dependency graph and listed in the
+// descendant's pedigree.
+type lineage struct {
+	g        *bomGraph
+	hub      hubFetcher
+	maxDepth int
+	enriched map[string]bool // dataset bom-refs already resolved
+}
+
+func newLineage(g *bomGraph, hub hubFetcher, maxDepth int) *lineage {
+	return &lineage{g: g, hub: hub, maxDepth: maxDepth, enriched: map[string]bool{}}
+}
+
+// resolveLineage adds the lineage of the model described by info (the first
+// component of doc) to doc, replacing its components and dependencies.
+func resolveLineage(doc *cdx.BOM, info *hfModelInfo, hub hubFetcher, maxDepth int) {
+	comps := *doc.Components
+	g := newBOMGraph(comps[0].BOMRef)
+	for _, c := range comps {
+		g.add(c)
+	}
+	key := modelKey(providerHuggingFace, info.effectiveID())
+	g.byModel[key] = comps[0].BOMRef
+
+	newLineage(g, hub, maxDepth).expand(comps[0].BOMRef, info, 0, []string{key})
+
+	doc.Components = &g.comps
+	doc.Dependencies = g.dependencies()
+	enforceRequiredFields(doc)
+}
+
+// expand links the component ref, described by info, to its datasets and
+// base models. path holds the model keys from the leaf down to ref; a base
+// model already on the path is a cycle and is recorded instead of linked.
+func (l *lineage) expand(ref string, info *hfModelInfo, depth int, path []string) {
+	for _, ds := range buildDataComponents(info) {
+		l.g.link(ref, l.addDataset(ds))
+	}
+	if info.CardData == nil {
+		return
+	}
+
+	var ancestors []cdx.Component
+	var cycles []string
+	for _, base := range info.CardData.baseModels() {
+		key := modelKey(providerHuggingFace, base)
+		if containsString(path, key) {
+			cycles = append(cycles, base)
+			continue
+		}
+		baseRef, seen := l.g.byModel[key]
+		if !seen {
+			baseRef = l.addBase(base, depth+1, append(path[:len(path):len(path)], key))
+		}
+		l.g.link(ref, baseRef)
+		ancestors = append(ancestors, ancestorOf(l.g.component(baseRef)))
+	}
+
+	// Look the component up only now: adding ancestors may have grown (and
+	// moved) the component slice.
+	comp := l.g.component(ref)
+	if len(ancestors) > 0 {
+		if comp.Pedigree == nil {
+			comp.Pedigree = &cdx.Pedigree{}
+		}
+		if comp.Pedigree.Ancestors != nil {
+			ancestors = append(*comp.Pedigree.Ancestors, ancestors...)
+		}
+		comp.Pedigree.Ancestors = &ancestors
+	}
+	if len(cycles) > 0 {
+		addProperty(comp, "lineageCycle", strings.Join(cycles, ","))
+	}
+}
+
+// addBase adds the base model id, found depth generations above the leaf,
+// and resolves its own lineage while within maxDepth. Beyond the limit, or
+// when the Hub lookup fails, a stub component keeps the chain visible.
+func (l *lineage) addBase(id string, depth int, path []string) string {
+	key := modelKey(providerHuggingFace, id)
+
+	if depth > l.maxDepth {
+		comp := hubStubComponent(id)
+		if l.maxDepth > 0 {
+			addProperty(&comp, "lineageTruncated", fmt.Sprintf("depth limit %d reached", l.maxDepth))
+		}
+		ref := l.g.add(comp)
+		l.g.byModel[key] = ref
+		return ref
+	}
+
+	info, err := l.hub.model(id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: resolving base model %s: %v\n", id, err)
+		comp := hubStubComponent(id)
+		addProperty(&comp, "resolutionError", err.Error())
+		ref := l.g.add(comp)
+		l.g.byModel[key] = ref
+		return ref
+	}
+
+	comp := buildModelComponent(info, &Options{})
+	annotateSerialization(&comp, checkSiblings(info.Siblings))
+	addProperty(&comp, "lineageDepth", fmt.Sprint(depth))
+	ref := l.g.add(comp)
+	l.g.byModel[key] = ref
+	l.expand(ref, info, depth, path)
+	return ref
+}
+
+// addDataset adds a dataset component (once) and, when a dataset fetcher is
+// configured, fills in its license, revision and description from the Hub.
+func (l *lineage) addDataset(ds cdx.Component) string {
+	ref := l.g.add(ds)
+	if l.hub.dataset == nil || l.enriched[ref] {
+		return ref
+	}
+	l.enriched[ref] = true
+
+	comp := l.g.component(ref)
+	info, err := l.hub.dataset(ds.Name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: resolving dataset %s: %v\n", ds.Name, err)
+		addProperty(comp, "resolutionError", err.Error())
+		return ref
+	}
+
+	comp.Version = shortSHA(info.SHA)
+	if info.Author != "" {
+		comp.Supplier = &cdx.OrganizationalEntity{Name: info.Author}
+	}
+	if desc := strings.TrimSpace(info.Description); desc != "" {
+		comp.Description = desc
+	}
+	if info.CardData != nil {
+		if ids := toStringSlice(info.CardData.License); len(ids) > 0 {
+			lics := make(cdx.Licenses, 0, len(ids))
+			for _, id := range ids {
+				lics = append(lics, cdx.LicenseChoice{License: &cdx.License{ID: normaliseLicense(id)}})
+			}
+			comp.Licenses = &lics
+		}
+		if len(info.CardData.TaskCategories) > 0 {
+			addProperty(comp, "taskCategories", strings.Join(info.CardData.TaskCategories, ","))
+		}
+	}
+	if isGated(info.Gated) {
+		addProperty(comp, "gated", "true")
+	}
+	return ref
+}
+
+// ancestorOf returns the identity of c for use in a pedigree. The bom-ref is
+// omitted: the ancestor is also a top-level component and bom-refs must be
+// unique within the document.
+func ancestorOf(c *cdx.Component) cdx.Component {
+	return cdx.Component{
+		Type:       c.Type,
+		Name:       c.Name,
+		Group:      c.Group,
+		Version:    c.Version,
+		PackageURL: c.PackageURL,
+	}
+}
+
+func addProperty(c *cdx.Component, name, value string) {
+	var props []cdx.Property
+	if c.Properties != nil {
+		props = *c.Properties
+	}
+	props = append(props, cdx.Property{Name: name, Value: value})
+	c.Properties = &props
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
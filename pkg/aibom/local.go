@@ -0,0 +1,510 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package aibom
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"gopkg.in/yaml.v3"
+)
+
+// weightExts lists file extensions treated as model weights. Weight files are
+// hashed and recorded as nested file components; the formats with a parser
+// (safetensors, GGUF, ONNX) also contribute parameter counts and metadata.
+var weightExts = map[string]bool{
+	".safetensors": true, ".gguf": true, ".onnx": true,
+	".bin": true, ".pt": true, ".pth": true, ".ckpt": true, ".pkl": true, ".pickle": true,
+	".h5": true, ".keras": true, ".tflite": true, ".msgpack": true, ".mlmodel": true,
+}
+
+// localModel is the result of inspecting a model directory.
+type localModel struct {
+	path    string // path as given by the user
+	info    *hfModelInfo
+	weights []localWeight
+	serial  unsafeSerialization
+}
+
+// localWeight is one weight file found on disk.
+type localWeight struct {
+	rel    string
+	size   int64
+	sha256 string
+	parsed *weightInfo // nil for formats without a header parser
+}
+
+// GenerateFromLocal inspects model artifacts under opts.Path and returns a
+// CycloneDX BOM. No network access is performed.
+func GenerateFromLocal(opts *Options) (*cdx.BOM, error) {
+	if opts.Path == "" {
+		return nil, fmt.Errorf("--path is required for local model inspection")
+	}
+	lm, err := inspectLocal(opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	if len(lm.weights) == 0 && lm.info.Config == nil && lm.info.CardData == nil {
+		return nil, fmt.Errorf("no model files (weights, config.json or README.md) found under %s", opts.Path)
+	}
+
+	doc := buildBOM(lm.info, opts)
+	localiseModelComponent(doc, lm, opts)
+	annotateSerialization(&(*doc.Components)[0], &lm.serial)
+	if opts.FailOnUnsafe {
+		if err := lm.serial.unsafeError(); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// inspectLocal walks path (a directory or a single weight file) and builds an
+// hfModelInfo from config.json, README.md front-matter and weight headers so
+// the HuggingFace model-card builders can be reused unchanged.
+func inspectLocal(path string) (*localModel, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading model path: %w", err)
+	}
+	root := path
+	if !st.IsDir() {
+		root = filepath.Dir(path)
+	}
+
+	lm := &localModel{path: path, info: &hfModelInfo{}}
+	var nameOrPath string
+
+	visit := func(p string, size int64) error {
+		var err error
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+		lm.info.Siblings = append(lm.info.Siblings, hfFile{Rfilename: rel, Size: size})
+
+		switch {
+		case rel == "config.json":
+			cfg, name, err := readConfigJSON(p)
+			if err != nil {
+				return err
+			}
+			lm.info.Config, nameOrPath = cfg, name
+			return nil
+		case strings.EqualFold(rel, "README.md"):
+			card, err := readCardData(p)
+			if err != nil {
+				return err
+			}
+			lm.info.CardData = card
+			return nil
+		}
+
+		ext := strings.ToLower(filepath.Ext(p))
+		if !weightExts[ext] {
+			return nil
+		}
+		w := localWeight{rel: rel, size: size}
+		if w.sha256, err = fileSHA256(p); err != nil {
+			return err
+		}
+		if pickleExts[ext] {
+			lm.serial.findings = append(lm.serial.findings, scanPickleFile(p, rel))
+		}
+		switch ext {
+		case ".safetensors":
+			lm.serial.hasSafetensors = true
+			w.parsed, err = readSafeTensors(p)
+		case ".gguf":
+			w.parsed, err = readGGUF(p)
+		case ".onnx":
+			w.parsed, err = readONNX(p)
+		}
+		if err != nil {
+			return fmt.Errorf("inspecting %s: %w", rel, err)
+		}
+		lm.weights = append(lm.weights, w)
+		return nil
+	}
+
+	if !st.IsDir() {
+		err = visit(path, st.Size())
+	} else {
+		err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				// Skip VCS and cache directories (.git, .cache/huggingface).
+				if p != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			fi, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return visit(p, fi.Size())
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lm.fillInfo(nameOrPath)
+	return lm, nil
+}
+
+// fillInfo derives the model identifier, architecture, tags and parameter
+// counts from what was found on disk.
+func (lm *localModel) fillInfo(nameOrPath string) {
+	info := lm.info
+
+	var total int64
+	dtypes := map[string]int64{}
+	formats := map[string]bool{}
+	for _, w := range lm.weights {
+		if w.parsed == nil {
+			continue
+		}
+		formats[w.parsed.Format] = true
+		total += w.parsed.Params
+		for dt, n := range w.parsed.DTypes {
+			dtypes[dt] += n
+		}
+		if w.parsed.ggufConfig != nil && info.Config == nil {
+			info.Config = w.parsed.ggufConfig
+		}
+		if lic := w.parsed.Metadata["general.license"]; lic != "" {
+			if info.CardData == nil {
+				info.CardData = &hfCardData{}
+			}
+			if len(info.CardData.licenses()) == 0 {
+				info.CardData.License = lic
+			}
+		}
+	}
+
+	// The parameter total is stored in the same shape as the Hub's
+	// "safetensors" field so parseSafeTensors picks it up.
+	if total > 0 {
+		params := map[string]interface{}{}
+		for dt, n := range dtypes {
+			params[dt] = float64(n)
+		}
+		info.SafeTensors = map[string]interface{}{"total": float64(total), "parameters": params}
+	}
+	if info.Config != nil && info.Config.TorchDtype == "" {
+		info.Config.TorchDtype = torchDtype(dominantDType(dtypes))
+	}
+
+	// Tags drive framework detection (buildSoftwareDependencies) the same way
+	// Hub tags do.
+	if info.CardData != nil {
+		info.Tags = append(info.Tags, info.CardData.Tags...)
+	}
+	for _, f := range sortedSet(formats) {
+		info.Tags = append(info.Tags, f)
+	}
+
+	info.ModelID = localModelID(lm, nameOrPath)
+}
+
+// localModelID picks the most meaningful identifier available: a Hub-style
+// _name_or_path from config.json, the GGUF general.name, or the directory
+// (or file) name.
+func localModelID(lm *localModel, nameOrPath string) string {
+	if nameOrPath != "" && !filepath.IsAbs(nameOrPath) && !strings.HasPrefix(nameOrPath, ".") &&
+		strings.Count(nameOrPath, "/") <= 1 {
+		return nameOrPath
+	}
+	for _, w := range lm.weights {
+		if w.parsed != nil && w.parsed.Metadata["general.name"] != "" {
+			return w.parsed.Metadata["general.name"]
+		}
+	}
+	abs, err := filepath.Abs(lm.path)
+	if err != nil {
+		abs = lm.path
+	}
+	base := filepath.Base(abs)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// localiseModelComponent replaces the Hub-specific parts of the model
+// component produced by buildBOM (huggingface purl and links) with details of
+// the files on disk.
+func localiseModelComponent(doc *cdx.BOM, lm *localModel, opts *Options) {
+	comp := &(*doc.Components)[0]
+
+	primary := lm.primaryWeight()
+	version := opts.Version
+	if version == "" && primary != nil {
+		// Digest of the main weight file stands in for the Hub commit SHA.
+		version = shortSHA(primary.sha256)
+	}
+	comp.Version = version
+	comp.PackageURL = buildGenericPURL(comp.Group, comp.Name, version)
+	comp.BOMRef = buildBOMRef(comp.PackageURL, comp.Name, version)
+	doc.Metadata.Component.BOMRef = comp.BOMRef
+
+	if strings.HasPrefix(comp.Description, "HuggingFace model: ") {
+		comp.Description = "Local model: " + lm.info.effectiveID()
+	}
+
+	// Only the base-model back-references are meaningful offline.
+	var refs []cdx.ExternalReference
+	if comp.ExternalReferences != nil {
+		for _, r := range *comp.ExternalReferences {
+			if r.Comment == "base model" {
+				refs = append(refs, r)
+			}
+		}
+	}
+	comp.ExternalReferences = nil
+	if len(refs) > 0 {
+		comp.ExternalReferences = &refs
+	}
+
+	if len(lm.weights) == 1 {
+		comp.Hashes = &[]cdx.Hash{{Algorithm: cdx.HashAlgoSHA256, Value: primary.sha256}}
+	}
+
+	var files []cdx.Component
+	if comp.Components != nil {
+		files = *comp.Components
+	}
+	for _, w := range lm.weights {
+		fc := cdx.Component{
+			BOMRef: "file/" + w.rel,
+			Type:   cdx.ComponentTypeFile,
+			Name:   w.rel,
+			Hashes: &[]cdx.Hash{{Algorithm: cdx.HashAlgoSHA256, Value: w.sha256}},
+		}
+		fprops := []cdx.Property{{Name: "size", Value: fmt.Sprint(w.size)}}
+		if w.parsed != nil {
+			fprops = append(fprops, cdx.Property{Name: "format", Value: w.parsed.Format})
+			if w.parsed.Params > 0 {
+				fprops = append(fprops, cdx.Property{Name: "parameters", Value: formatParamCount(w.parsed.Params)})
+			}
+		}
+		fc.Properties = &fprops
+		files = append(files, fc)
+	}
+	if len(files) > 0 {
+		comp.Components = &files
+	}
+
+	var props []cdx.Property
+	if comp.Properties != nil {
+		props = *comp.Properties
+	}
+	props = append(props, cdx.Property{Name: "source", Value: "local"})
+	props = append(props, localWeightProperties(lm)...)
+	comp.Properties = &props
+}
+
+// localWeightProperties summarises format-specific metadata of the weights.
+func localWeightProperties(lm *localModel) []cdx.Property {
+	var props []cdx.Property
+	add := func(name, value string) {
+		if value != "" {
+			props = append(props, cdx.Property{Name: name, Value: value})
+		}
+	}
+
+	formats := map[string]bool{}
+	dtypes := map[string]int64{}
+	for _, w := range lm.weights {
+		if w.parsed == nil {
+			formats[strings.TrimPrefix(filepath.Ext(w.rel), ".")] = true
+			continue
+		}
+		formats[w.parsed.Format] = true
+		for dt, n := range w.parsed.DTypes {
+			dtypes[dt] += n
+		}
+		md := w.parsed.Metadata
+		switch w.parsed.Format {
+		case "gguf":
+			add("quantization", md["quantization"])
+			add("ggufVersion", md["ggufVersion"])
+		case "onnx":
+			add("onnxOpset", md["opset"])
+			add("onnxIRVersion", md["irVersion"])
+			if md["producer"] != "" {
+				add("onnxProducer", strings.TrimSpace(md["producer"]+" "+md["producerVersion"]))
+			}
+			add("onnxOperators", md["operators"])
+		}
+	}
+	add("modelFormat", strings.Join(sortedSet(formats), ","))
+
+	if len(dtypes) > 0 {
+		parts := make([]string, 0, len(dtypes))
+		for _, dt := range sortedKeys(dtypes) {
+			parts = append(parts, dt+":"+formatParamCount(dtypes[dt]))
+		}
+		add("parameterDTypes", strings.Join(parts, ","))
+	}
+	return props
+}
+
+// primaryWeight returns the largest weight file, or nil.
+func (lm *localModel) primaryWeight() *localWeight {
+	var best *localWeight
+	for i := range lm.weights {
+		if best == nil || lm.weights[i].size > best.size {
+			best = &lm.weights[i]
+		}
+	}
+	return best
+}
+
+// readConfigJSON parses a transformers config.json and returns it together
+// with its _name_or_path entry.
+func readConfigJSON(path string) (*hfConfig, string, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path comes from walking the user-supplied model directory
+	if err != nil {
+		return nil, "", err
+	}
+	var cfg hfConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, "", fmt.Errorf("parsing config.json: %w", err)
+	}
+	var extra struct {
+		NameOrPath string `json:"_name_or_path"`
+	}
+	_ = json.Unmarshal(data, &extra)
+	return &cfg, extra.NameOrPath, nil
+}
+
+// readCardData parses the YAML front-matter of a model card README. A README
+// without front-matter yields nil.
+func readCardData(path string) (*hfCardData, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path comes from walking the user-supplied model directory
+	if err != nil {
+		return nil, err
+	}
+	front, ok := frontMatter(data)
+	if !ok {
+		return nil, nil
+	}
+	// Round-trip through JSON so the json tags of hfCardData (model-index,
+	// pipeline_tag, ...) apply exactly as they do for Hub responses.
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(front, &raw); err != nil {
+		return nil, fmt.Errorf("parsing README.md front-matter: %w", err)
+	}
+	js, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parsing README.md front-matter: %w", err)
+	}
+	var card hfCardData
+	if err := json.Unmarshal(js, &card); err != nil {
+		return nil, fmt.Errorf("parsing README.md front-matter: %w", err)
+	}
+	return &card, nil
+}
+
+// frontMatter returns the YAML between the leading "---" delimiters.
+func frontMatter(data []byte) ([]byte, bool) {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	if !bytes.HasPrefix(data, []byte("---\n")) {
+		return nil, false
+	}
+	rest := data[4:]
+	end := bytes.Index(rest, []byte("\n---"))
+	if end < 0 {
+		return nil, false
+	}
+	return rest[:end+1], true
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path) // #nosec G304 -- path comes from walking the user-supplied model directory
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hashing %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// buildGenericPURL builds a pkg:generic Package URL for a model that is not
+// tied to a registry.
+func buildGenericPURL(group, name, version string) string {
+	purl := "pkg:generic/"
+	if group != "" {
+		purl += group + "/"
+	}
+	// The name may still carry an "owner/" prefix from a Hub-style ID.
+	purl += name[strings.LastIndex(name, "/")+1:]
+	if version != "" {
+		purl += "@" + version
+	}
+	return purl
+}
+
+// dominantDType returns the dtype holding the most parameters.
+func dominantDType(dtypes map[string]int64) string {
+	best, bestN := "", int64(-1)
+	for _, dt := range sortedKeys(dtypes) {
+		if dtypes[dt] > bestN {
+			best, bestN = dt, dtypes[dt]
+		}
+	}
+	return best
+}
+
+// torchDtype maps safetensors/ONNX/GGUF dtype names to the torch_dtype
+// spelling used in config.json.
+func torchDtype(dt string) string {
+	switch strings.ToUpper(dt) {
+	case "F32", "FLOAT":
+		return "float32"
+	case "F16", "FLOAT16":
+		return "float16"
+	case "BF16", "BFLOAT16":
+		return "bfloat16"
+	case "I8", "INT8", "Q8_0":
+		return "int8"
+	case "Q4_0", "Q4_1", "Q4_K":
+		return "int4"
+	}
+	return ""
+}
+
+func sortedSet(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sortedKeys(m map[string]int64) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
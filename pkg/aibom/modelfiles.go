@@ -0,0 +1,584 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package aibom
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// On-disk model file parsers
+//
+// Only headers and metadata are read; tensor data is skipped, so multi-GB
+// weight files are inspected without being loaded into memory.
+// ──────────────────────────────────────────────────────────────────────────────
+
+// weightInfo summarises one weight file.
+type weightInfo struct {
+	Format     string           // "safetensors", "gguf" or "onnx"
+	Params     int64            // total parameter (tensor element) count
+	DTypes     map[string]int64 // parameter count per tensor dtype
+	Metadata   map[string]string
+	ggufConfig *hfConfig // architecture hints recovered from GGUF metadata
+}
+
+const (
+	// maxHeaderSize bounds the safetensors JSON header and individual GGUF
+	// strings so a corrupt file cannot trigger a huge allocation.
+	maxHeaderSize = 100 << 20
+	maxGGUFString = 1 << 20
+)
+
+// ── safetensors ──────────────────────────────────────────────────────────────
+
+// readSafeTensors parses the JSON header of a .safetensors file: an 8-byte
+// little-endian length followed by {"tensor": {"dtype", "shape", ...}, ...}.
+func readSafeTensors(path string) (*weightInfo, error) {
+	f, err := os.Open(path) // #nosec G304 -- path comes from walking the user-supplied model directory
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var n uint64
+	if err := binary.Read(f, binary.LittleEndian, &n); err != nil {
+		return nil, fmt.Errorf("reading safetensors header length: %w", err)
+	}
+	if n == 0 || n > maxHeaderSize {
+		return nil, fmt.Errorf("invalid safetensors header length %d", n)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(f, buf); err != nil {
+		return nil, fmt.Errorf("reading safetensors header: %w", err)
+	}
+
+	var header map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &header); err != nil {
+		return nil, fmt.Errorf("parsing safetensors header: %w", err)
+	}
+
+	wi := &weightInfo{Format: "safetensors", DTypes: map[string]int64{}, Metadata: map[string]string{}}
+	for name, raw := range header {
+		if name == "__metadata__" {
+			_ = json.Unmarshal(raw, &wi.Metadata)
+			continue
+		}
+		var t struct {
+			DType string  `json:"dtype"`
+			Shape []int64 `json:"shape"`
+		}
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return nil, fmt.Errorf("parsing tensor %q: %w", name, err)
+		}
+		count := shapeProduct(t.Shape)
+		wi.Params += count
+		wi.DTypes[t.DType] += count
+	}
+	return wi, nil
+}
+
+// ── GGUF ─────────────────────────────────────────────────────────────────────
+
+const ggufMagic = "GGUF"
+
+// GGUF metadata value types.
+const (
+	ggufUint8 uint32 = iota
+	ggufInt8
+	ggufUint16
+	ggufInt16
+	ggufUint32
+	ggufInt32
+	ggufFloat32
+	ggufBool
+	ggufString
+	ggufArray
+	ggufUint64
+	ggufInt64
+	ggufFloat64
+)
+
+// ggufFileTypes maps general.file_type to the llama.cpp quantisation name.
+var ggufFileTypes = map[int64]string{
+	0: "F32", 1: "F16", 2: "Q4_0", 3: "Q4_1", 7: "Q8_0", 8: "Q5_0", 9: "Q5_1",
+	10: "Q2_K", 11: "Q3_K_S", 12: "Q3_K_M", 13: "Q3_K_L", 14: "Q4_K_S",
+	15: "Q4_K_M", 16: "Q5_K_S", 17: "Q5_K_M", 18: "Q6_K", 32: "BF16",
+}
+
+// ggufTensorTypes maps the ggml tensor type to its name.
+var ggufTensorTypes = map[uint32]string{
+	0: "F32", 1: "F16", 2: "Q4_0", 3: "Q4_1", 6: "Q5_0", 7: "Q5_1", 8: "Q8_0",
+	9: "Q8_1", 10: "Q2_K", 11: "Q3_K", 12: "Q4_K", 13: "Q5_K", 14: "Q6_K",
+	15: "Q8_K", 30: "BF16",
+}
+
+// readGGUF parses the metadata key/value section and tensor descriptors of a
+// GGUF file (versions 2 and 3).
+func readGGUF(path string) (*weightInfo, error) {
+	f, err := os.Open(path) // #nosec G304 -- path comes from walking the user-supplied model directory
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := &ggufReader{r: bufio.NewReaderSize(f, 1<<16)}
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r.r, magic); err != nil || string(magic) != ggufMagic {
+		return nil, fmt.Errorf("not a GGUF file")
+	}
+	version := r.u32()
+	if version < 2 {
+		return nil, fmt.Errorf("unsupported GGUF version %d", version)
+	}
+	tensorCount, kvCount := r.u64(), r.u64()
+	if r.err != nil {
+		return nil, fmt.Errorf("reading GGUF header: %w", r.err)
+	}
+
+	wi := &weightInfo{Format: "gguf", DTypes: map[string]int64{}, Metadata: map[string]string{}}
+	kv := map[string]interface{}{}
+	for i := uint64(0); i < kvCount && r.err == nil; i++ {
+		key := r.str()
+		vt := r.u32()
+		kv[key] = r.value(vt, key == "tokenizer.ggml.tokens")
+	}
+	for i := uint64(0); i < tensorCount && r.err == nil; i++ {
+		r.str() // tensor name
+		nDims := r.u32()
+		count := int64(1)
+		for d := uint32(0); d < nDims && r.err == nil; d++ {
+			count *= int64(r.u64()) // #nosec G115 -- dimension sizes fit in int64
+		}
+		typ := r.u32()
+		r.u64() // data offset
+		wi.Params += count
+		name, ok := ggufTensorTypes[typ]
+		if !ok {
+			name = fmt.Sprintf("type%d", typ)
+		}
+		wi.DTypes[name] += count
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("reading GGUF metadata: %w", r.err)
+	}
+
+	wi.Metadata["ggufVersion"] = fmt.Sprint(version)
+	arch, _ := kv["general.architecture"].(string)
+	for _, k := range []string{"general.architecture", "general.name", "general.license", "general.basename", "general.organization"} {
+		if s, ok := kv[k].(string); ok && s != "" {
+			wi.Metadata[k] = s
+		}
+	}
+	if ft, ok := toInt64(kv["general.file_type"]); ok {
+		if q, known := ggufFileTypes[ft]; known {
+			wi.Metadata["quantization"] = q
+		}
+	}
+
+	cfg := &hfConfig{ModelType: arch}
+	if v, ok := toInt64(kv[arch+".context_length"]); ok {
+		cfg.MaxPositionEmbeddings = int(v)
+	}
+	if v, ok := toInt64(kv[arch+".embedding_length"]); ok {
+		cfg.HiddenSize = int(v)
+	}
+	if v, ok := toInt64(kv[arch+".block_count"]); ok {
+		cfg.NumHiddenLayers = int(v)
+	}
+	if v, ok := toInt64(kv[arch+".attention.head_count"]); ok {
+		cfg.NumAttentionHeads = int(v)
+	}
+	if n, ok := kv["tokenizer.ggml.tokens"].(int64); ok {
+		cfg.VocabSize = int(n)
+	}
+	wi.ggufConfig = cfg
+	return wi, nil
+}
+
+// ggufReader is a sticky-error reader for GGUF's little-endian encoding.
+type ggufReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (g *ggufReader) read(v interface{}) {
+	if g.err == nil {
+		g.err = binary.Read(g.r, binary.LittleEndian, v)
+	}
+}
+
+func (g *ggufReader) u32() uint32 {
+	var v uint32
+	g.read(&v)
+	return v
+}
+
+func (g *ggufReader) u64() uint64 {
+	var v uint64
+	g.read(&v)
+	return v
+}
+
+func (g *ggufReader) str() string {
+	n := g.u64()
+	if g.err != nil {
+		return ""
+	}
+	if n > maxGGUFString {
+		g.err = fmt.Errorf("string length %d exceeds limit", n)
+		return ""
+	}
+	buf := make([]byte, n)
+	_, g.err = io.ReadFull(g.r, buf)
+	return string(buf)
+}
+
+func (g *ggufReader) skip(n uint64) {
+	if g.err == nil {
+		_, g.err = g.r.Discard(int(n)) // #nosec G115 -- element sizes are at most 8 bytes per item
+	}
+}
+
+// value decodes a metadata value of type vt. Arrays are skipped; when
+// countOnly is set the array length is returned instead (used for the
+// tokenizer vocabulary, which can hold 100k+ strings).
+func (g *ggufReader) value(vt uint32, countOnly bool) interface{} {
+	switch vt {
+	case ggufUint8, ggufInt8, ggufBool:
+		var v uint8
+		g.read(&v)
+		return int64(v)
+	case ggufUint16, ggufInt16:
+		var v uint16
+		g.read(&v)
+		return int64(v)
+	case ggufUint32:
+		return int64(g.u32())
+	case ggufInt32:
+		return int64(int32(g.u32())) // #nosec G115 -- reinterpreting the signed value
+	case ggufFloat32:
+		var v float32
+		g.read(&v)
+		return float64(v)
+	case ggufUint64, ggufInt64:
+		return int64(g.u64()) // #nosec G115 -- metadata integers fit in int64
+	case ggufFloat64:
+		var v float64
+		g.read(&v)
+		return v
+	case ggufString:
+		return g.str()
+	case ggufArray:
+		et := g.u32()
+		n := g.u64()
+		for i := uint64(0); i < n && g.err == nil; i++ {
+			if size := ggufScalarSize(et); size > 0 {
+				g.skip(size * (n - i))
+				break
+			}
+			g.value(et, false)
+		}
+		if countOnly {
+			return int64(n) // #nosec G115 -- array lengths fit in int64
+		}
+		return nil
+	default:
+		if g.err == nil {
+			g.err = fmt.Errorf("unknown GGUF value type %d", vt)
+		}
+		return nil
+	}
+}
+
+func ggufScalarSize(vt uint32) uint64 {
+	switch vt {
+	case ggufUint8, ggufInt8, ggufBool:
+		return 1
+	case ggufUint16, ggufInt16:
+		return 2
+	case ggufUint32, ggufInt32, ggufFloat32:
+		return 4
+	case ggufUint64, ggufInt64, ggufFloat64:
+		return 8
+	}
+	return 0
+}
+
+// ── ONNX ─────────────────────────────────────────────────────────────────────
+
+// onnxDataTypes maps TensorProto.DataType to a dtype name.
+var onnxDataTypes = map[uint64]string{
+	1: "FLOAT", 2: "UINT8", 3: "INT8", 4: "UINT16", 5: "INT16", 6: "INT32",
+	7: "INT64", 9: "BOOL", 10: "FLOAT16", 11: "DOUBLE", 12: "UINT32",
+	13: "UINT64", 16: "BFLOAT16",
+}
+
+// readONNX walks the ModelProto protobuf stream and collects the producer,
+// opset, metadata_props and the shapes of the graph initializers (weights).
+// Tensor payloads are skipped without being read into memory.
+func readONNX(path string) (*weightInfo, error) {
+	f, err := os.Open(path) // #nosec G304 -- path comes from walking the user-supplied model directory
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	wi := &weightInfo{Format: "onnx", DTypes: map[string]int64{}, Metadata: map[string]string{}}
+	p := &pbReader{r: bufio.NewReaderSize(f, 1<<16)}
+	ops := map[string]bool{}
+	nodes := 0
+
+	// ModelProto
+	err = p.fields(uint64(st.Size()), func(field, wt uint64, n uint64) error { // #nosec G115 -- file sizes are non-negative
+		switch {
+		case field == 1 && wt == pbVarint:
+			wi.Metadata["irVersion"] = fmt.Sprint(n)
+		case field == 2 && wt == pbBytes:
+			wi.Metadata["producer"] = p.string(n)
+		case field == 3 && wt == pbBytes:
+			wi.Metadata["producerVersion"] = p.string(n)
+		case field == 5 && wt == pbVarint:
+			wi.Metadata["modelVersion"] = fmt.Sprint(n)
+		case field == 7 && wt == pbBytes: // GraphProto
+			return p.fields(n, func(field, wt, n uint64) error {
+				switch {
+				case field == 1 && wt == pbBytes: // NodeProto
+					nodes++
+					return p.fields(n, func(field, wt, n uint64) error {
+						if field == 4 && wt == pbBytes {
+							ops[p.string(n)] = true
+							return nil
+						}
+						return p.skipField(wt, n)
+					})
+				case field == 2 && wt == pbBytes:
+					wi.Metadata["graph"] = p.string(n)
+				case field == 5 && wt == pbBytes: // TensorProto initializer
+					count, dtype := int64(1), ""
+					err := p.fields(n, func(field, wt, n uint64) error {
+						switch {
+						case field == 1 && wt == pbVarint:
+							count *= int64(n) // #nosec G115 -- dimension sizes fit in int64
+						case field == 1 && wt == pbBytes: // packed dims
+							return p.packedVarints(n, func(v uint64) { count *= int64(v) }) // #nosec G115 -- dimension sizes fit in int64
+						case field == 2 && wt == pbVarint:
+							dtype = onnxDataTypes[n]
+						default:
+							return p.skipField(wt, n)
+						}
+						return nil
+					})
+					if dtype == "" {
+						dtype = "UNKNOWN"
+					}
+					wi.Params += count
+					wi.DTypes[dtype] += count
+					return err
+				default:
+					return p.skipField(wt, n)
+				}
+				return nil
+			})
+		case field == 8 && wt == pbBytes: // OperatorSetIdProto
+			domain, version := "", uint64(0)
+			err := p.fields(n, func(field, wt, n uint64) error {
+				switch {
+				case field == 1 && wt == pbBytes:
+					domain = p.string(n)
+				case field == 2 && wt == pbVarint:
+					version = n
+				default:
+					return p.skipField(wt, n)
+				}
+				return nil
+			})
+			if domain == "" || domain == "ai.onnx" {
+				wi.Metadata["opset"] = fmt.Sprint(version)
+			}
+			return err
+		case field == 14 && wt == pbBytes: // StringStringEntryProto
+			key, val := "", ""
+			err := p.fields(n, func(field, wt, n uint64) error {
+				switch {
+				case field == 1 && wt == pbBytes:
+					key = p.string(n)
+				case field == 2 && wt == pbBytes:
+					val = p.string(n)
+				default:
+					return p.skipField(wt, n)
+				}
+				return nil
+			})
+			if key != "" {
+				wi.Metadata["meta:"+key] = val
+			}
+			return err
+		default:
+			return p.skipField(wt, n)
+		}
+		return p.err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parsing ONNX model: %w", err)
+	}
+	if nodes == 0 && wi.Metadata["producer"] == "" && wi.Metadata["irVersion"] == "" {
+		return nil, fmt.Errorf("not an ONNX model")
+	}
+
+	wi.Metadata["nodes"] = fmt.Sprint(nodes)
+	opList := make([]string, 0, len(ops))
+	for op := range ops {
+		opList = append(opList, op)
+	}
+	sort.Strings(opList)
+	wi.Metadata["operators"] = strings.Join(opList, ",")
+	return wi, nil
+}
+
+// Protobuf wire types.
+const (
+	pbVarint = 0
+	pb64Bit  = 1
+	pbBytes  = 2
+	pb32Bit  = 5
+)
+
+// pbReader is a minimal streaming protobuf decoder: just enough to walk the
+// ONNX ModelProto without generated code or loading the file into memory.
+type pbReader struct {
+	r   *bufio.Reader
+	pos uint64
+	err error
+}
+
+func (p *pbReader) varint() uint64 {
+	if p.err != nil {
+		return 0
+	}
+	var v uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		b, err := p.r.ReadByte()
+		if err != nil {
+			p.err = err
+			return 0
+		}
+		p.pos++
+		v |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return v
+		}
+	}
+	p.err = errors.New("varint overflow")
+	return 0
+}
+
+func (p *pbReader) discard(n uint64) {
+	for n > 0 && p.err == nil {
+		chunk := n
+		if chunk > 1<<30 {
+			chunk = 1 << 30
+		}
+		d, err := p.r.Discard(int(chunk)) // #nosec G115 -- chunk is bounded above
+		p.pos += uint64(d)                // #nosec G115 -- d is non-negative
+		n -= uint64(d)                    // #nosec G115 -- d is non-negative
+		p.err = err
+	}
+}
+
+func (p *pbReader) string(n uint64) string {
+	if p.err != nil {
+		return ""
+	}
+	if n > maxGGUFString {
+		p.discard(n)
+		return ""
+	}
+	buf := make([]byte, n)
+	_, p.err = io.ReadFull(p.r, buf)
+	p.pos += n
+	return string(buf)
+}
+
+// skipField skips the payload of a field whose tag and (for varint and
+// length-delimited fields) value or length have already been read.
+func (p *pbReader) skipField(wt, n uint64) error {
+	switch wt {
+	case pbVarint:
+	case pb64Bit:
+		p.discard(8)
+	case pbBytes:
+		p.discard(n)
+	case pb32Bit:
+		p.discard(4)
+	default:
+		p.err = fmt.Errorf("unsupported wire type %d", wt)
+	}
+	return p.err
+}
+
+// fields reads the fields of a message of length size and calls fn for each.
+// For varint fields n is the value; for length-delimited fields n is the
+// length and fn must consume exactly that many bytes (or call skipField).
+func (p *pbReader) fields(size uint64, fn func(field, wt, n uint64) error) error {
+	end := p.pos + size
+	for p.pos < end && p.err == nil {
+		tag := p.varint()
+		if p.err == io.EOF && tag == 0 {
+			p.err = nil
+			return nil
+		}
+		field, wt := tag>>3, tag&7
+		var n uint64
+		if wt == pbVarint || wt == pbBytes {
+			n = p.varint()
+		}
+		if p.err != nil {
+			break
+		}
+		if err := fn(field, wt, n); err != nil {
+			return err
+		}
+	}
+	return p.err
+}
+
+func (p *pbReader) packedVarints(size uint64, fn func(uint64)) error {
+	end := p.pos + size
+	for p.pos < end && p.err == nil {
+		fn(p.varint())
+	}
+	return p.err
+}
+
+// ── helpers ──────────────────────────────────────────────────────────────────
+
+func shapeProduct(shape []int64) int64 {
+	n := int64(1)
+	for _, d := range shape {
+		n *= d
+	}
+	return n
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package aibom
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+)
+
+const ollamaDefaultEndpoint = "http://localhost:11434"
+
+// ollamaProvider inventories the models pulled into a local Ollama server.
+type ollamaProvider struct {
+	endpoint string
+	client   *http.Client
+}
+
+func newOllamaProvider(opts *ProviderOptions) (Provider, error) {
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = os.Getenv("OLLAMA_HOST")
+	}
+	if endpoint == "" {
+		endpoint = ollamaDefaultEndpoint
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	return &ollamaProvider{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		client:   &http.Client{Timeout: providerTimeout},
+	}, nil
+}
+
+// ollamaDetails is the "details" object shared by /api/tags and /api/show.
+type ollamaDetails struct {
+	ParentModel       string   `json:"parent_model"`
+	Format            string   `json:"format"`
+	Family            string   `json:"family"`
+	Families          []string `json:"families"`
+	ParameterSize     string   `json:"parameter_size"`
+	QuantizationLevel string   `json:"quantization_level"`
+}
+
+// ollamaTag is one entry of GET /api/tags.
+type ollamaTag struct {
+	Name       string        `json:"name"`
+	Model      string        `json:"model"`
+	ModifiedAt time.Time     `json:"modified_at"`
+	Size       int64         `json:"size"`
+	Digest     string        `json:"digest"`
+	Details    ollamaDetails `json:"details"`
+}
+
+// ollamaShow is the response of POST /api/show.
+type ollamaShow struct {
+	License      string                 `json:"license"`
+	Details      ollamaDetails          `json:"details"`
+	ModelInfo    map[string]interface{} `json:"model_info"`
+	Capabilities []string               `json:"capabilities"`
+	ModifiedAt   time.Time              `json:"modified_at"`
+}
+
+func (p *ollamaProvider) Name() string { return "ollama" }
+
+func (p *ollamaProvider) ListModels(ctx context.Context) ([]ProviderModel, error) {
+	var out struct {
+		Models []ollamaTag `json:"models"`
+	}
+	if err := doJSON(ctx, p.client, http.MethodGet, p.endpoint+"/api/tags", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	models := make([]ProviderModel, 0, len(out.Models))
+	for _, t := range out.Models {
+		m, err := p.DescribeModel(ctx, t.Name)
+		if err != nil {
+			// /api/show failing for one model should not hide the rest;
+			// the listing alone still identifies it.
+			m = p.fromDetails(t.Name, t.Details)
+		}
+		m.Digest = t.Digest
+		m.Properties["size"] = fmt.Sprint(t.Size)
+		if m.Created.IsZero() {
+			m.Created = t.ModifiedAt
+		}
+		models = append(models, *m)
+	}
+	return models, nil
+}
+
+func (p *ollamaProvider) DescribeModel(ctx context.Context, id string) (*ProviderModel, error) {
+	var show ollamaShow
+	if err := doJSON(ctx, p.client, http.MethodPost, p.endpoint+"/api/show", nil,
+		map[string]string{"model": id}, &show); err != nil {
+		return nil, err
+	}
+	m := p.fromDetails(id, show.Details)
+	m.License = show.License
+	m.Capabilities = show.Capabilities
+	m.Created = show.ModifiedAt
+
+	arch, _ := show.ModelInfo["general.architecture"].(string)
+	if arch != "" {
+		m.Architecture = arch
+		if n, ok := show.ModelInfo[arch+".context_length"].(float64); ok {
+			m.ContextLength = int64(n)
+		}
+	}
+	if n, ok := show.ModelInfo["general.parameter_count"].(float64); ok && n > 0 {
+		m.Properties["parameters"] = formatParamCount(int64(n))
+	}
+	if base, ok := show.ModelInfo["general.basename"].(string); ok && m.BaseModel == "" {
+		m.BaseModel = base
+	}
+	return m, nil
+}
+
+func (p *ollamaProvider) fromDetails(id string, d ollamaDetails) *ProviderModel {
+	name, tag, _ := strings.Cut(id, ":")
+	m := &ProviderModel{
+		ID:            id,
+		Name:          name,
+		Version:       tag,
+		Family:        d.Family,
+		ParameterSize: d.ParameterSize,
+		Quantization:  d.QuantizationLevel,
+		Format:        d.Format,
+		BaseModel:     d.ParentModel,
+		Task:          "text-generation",
+		URL:           "https://ollama.com/library/" + name,
+		Properties:    map[string]string{"endpoint": p.endpoint},
+	}
+	if owner, _, ok := strings.Cut(name, "/"); ok {
+		// Community models are namespaced; library models are not.
+		m.Owner = owner
+		m.URL = "https://ollama.com/" + name
+	}
+	if len(d.Families) > 1 {
+		m.Properties["families"] = strings.Join(d.Families, ",")
+	}
+	return m
+}
+
+func (p *ollamaProvider) Component(m ProviderModel) cdx.Component {
+	return modelComponent(p.Name(), m)
+}
@@ -0,0 +1,144 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package aibom
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+)
+
+const openAIDefaultEndpoint = "https://api.openai.com/v1"
+
+// openAIProvider inventories models served through the OpenAI REST API or
+// any server implementing its /models endpoint (vLLM, LM Studio, LocalAI,
+// llama.cpp server, ...).
+type openAIProvider struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+func newOpenAIProvider(opts *ProviderOptions) (Provider, error) {
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = openAIDefaultEndpoint
+	}
+	apiKey := opts.APIKey
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+	return &openAIProvider{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		apiKey:   apiKey,
+		client:   &http.Client{Timeout: providerTimeout},
+	}, nil
+}
+
+// openAIModel is one entry of GET /models. vLLM adds root, parent and
+// max_model_len.
+type openAIModel struct {
+	ID          string `json:"id"`
+	Created     int64  `json:"created"`
+	OwnedBy     string `json:"owned_by"`
+	Root        string `json:"root"`
+	Parent      string `json:"parent"`
+	MaxModelLen int64  `json:"max_model_len"`
+}
+
+func (p *openAIProvider) Name() string { return "openai" }
+
+func (p *openAIProvider) headers() map[string]string {
+	if p.apiKey == "" {
+		return nil
+	}
+	return map[string]string{"Authorization": "Bearer " + p.apiKey}
+}
+
+func (p *openAIProvider) ListModels(ctx context.Context) ([]ProviderModel, error) {
+	var out struct {
+		Data []openAIModel `json:"data"`
+	}
+	if err := doJSON(ctx, p.client, http.MethodGet, p.endpoint+"/models", p.headers(), nil, &out); err != nil {
+		return nil, err
+	}
+	models := make([]ProviderModel, 0, len(out.Data))
+	for _, m := range out.Data {
+		models = append(models, p.toModel(m))
+	}
+	return models, nil
+}
+
+func (p *openAIProvider) DescribeModel(ctx context.Context, id string) (*ProviderModel, error) {
+	var m openAIModel
+	err := doJSON(ctx, p.client, http.MethodGet, p.endpoint+"/models/"+url.PathEscape(id), p.headers(), nil, &m)
+	var status *httpStatusError
+	if errors.As(err, &status) && (status.Status == http.StatusNotFound || status.Status == http.StatusMethodNotAllowed) {
+		// Several compatible servers only implement the list endpoint.
+		models, lerr := p.ListModels(ctx)
+		if lerr != nil {
+			return nil, lerr
+		}
+		for i := range models {
+			if models[i].ID == id {
+				return &models[i], nil
+			}
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	pm := p.toModel(m)
+	return &pm, nil
+}
+
+func (p *openAIProvider) toModel(m openAIModel) ProviderModel {
+	pm := ProviderModel{
+		ID:            m.ID,
+		Owner:         m.OwnedBy,
+		ContextLength: m.MaxModelLen,
+		Task:          openAITask(m.ID),
+		Properties:    map[string]string{"endpoint": p.endpoint},
+	}
+	if m.Created > 0 {
+		pm.Created = time.Unix(m.Created, 0)
+	}
+	// vLLM reports the served weights as root and LoRA adapters' base
+	// model as parent.
+	switch {
+	case m.Parent != "":
+		pm.BaseModel = m.Parent
+	case m.Root != "" && m.Root != m.ID:
+		pm.BaseModel = m.Root
+	}
+	return pm
+}
+
+// openAITask infers the task from well-known OpenAI model name prefixes.
+func openAITask(id string) string {
+	switch {
+	case strings.Contains(id, "embedding"):
+		return "feature-extraction"
+	case strings.HasPrefix(id, "whisper"):
+		return "automatic-speech-recognition"
+	case strings.HasPrefix(id, "tts"):
+		return "text-to-speech"
+	case strings.HasPrefix(id, "dall-e"), strings.HasPrefix(id, "gpt-image"):
+		return "text-to-image"
+	case strings.Contains(id, "moderation"):
+		return "text-classification"
+	}
+	return "text-generation"
+}
+
+func (p *openAIProvider) Component(m ProviderModel) cdx.Component {
+	return modelComponent(p.Name(), m)
+}
@@ -0,0 +1,832 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package aibom
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Unsafe serialization detection
+//
+// Pickle-based checkpoints execute arbitrary callables on load. Local files
+// are scanned statically: the opcode stream is walked (never unpickled) and
+// every imported global is checked against a deny-list. Hub repositories are
+// only flagged by file name, as scanning would require downloading them.
+// ──────────────────────────────────────────────────────────────────────────────
+
+// pickleExts lists extensions of pickle-based weight formats. PyTorch .bin,
+// .pt and .pth files are either raw pickles or zip archives wrapping one.
+var pickleExts = map[string]bool{
+	".bin": true, ".pt": true, ".pth": true, ".pkl": true, ".pickle": true, ".ckpt": true,
+}
+
+// dangerousGlobals maps a module to the callables that can run code or touch
+// the system when invoked by the unpickler. "*" matches every name.
+var dangerousGlobals = map[string][]string{
+	"os":             {"*"},
+	"posix":          {"*"},
+	"nt":             {"*"},
+	"subprocess":     {"*"},
+	"sys":            {"*"},
+	"socket":         {"*"},
+	"shutil":         {"*"},
+	"runpy":          {"*"},
+	"pty":            {"*"},
+	"commands":       {"*"},
+	"webbrowser":     {"*"},
+	"importlib":      {"*"},
+	"ctypes":         {"*"},
+	"requests":       {"*"},
+	"httplib":        {"*"},
+	"http.client":    {"*"},
+	"urllib":         {"*"},
+	"urllib2":        {"*"},
+	"urllib.request": {"*"},
+	"pip":            {"*"},
+	"marshal":        {"loads", "load"},
+	"pickle":         {"loads", "load"},
+	"_pickle":        {"loads", "load"},
+	"types":          {"CodeType", "FunctionType"},
+	"operator":       {"attrgetter", "methodcaller"},
+	"functools":      {"partial"},
+	"torch.hub":      {"*"},
+	"builtins":       {"eval", "exec", "compile", "open", "__import__", "getattr", "setattr", "globals", "breakpoint", "input", "execfile"},
+	"__builtin__":    {"eval", "exec", "compile", "open", "__import__", "getattr", "setattr", "globals", "breakpoint", "input", "execfile", "file"},
+}
+
+// pickleFinding records the result of inspecting one pickle-format file.
+type pickleFinding struct {
+	File      string
+	Scanned   bool     // false when only the file name was available (Hub)
+	IsPickle  bool     // false when a scanned file turned out not to be a pickle
+	Imports   []string // every module.name global referenced
+	Dangerous []string // subset of Imports on the deny-list
+	Err       string   // parse error; the file is treated as unverified
+}
+
+// unsafeSerialization is the outcome of checking all weights of a model.
+type unsafeSerialization struct {
+	findings       []pickleFinding
+	hasSafetensors bool
+}
+
+// dangerous returns the findings with deny-listed imports.
+func (u *unsafeSerialization) dangerous() []pickleFinding {
+	var out []pickleFinding
+	for _, f := range u.findings {
+		if len(f.Dangerous) > 0 {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// pickles returns the findings that are (or may be) pickle data.
+func (u *unsafeSerialization) pickles() []pickleFinding {
+	var out []pickleFinding
+	for _, f := range u.findings {
+		if !f.Scanned || f.IsPickle || f.Err != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// checkSiblings flags pickle-format files in a Hub repository listing.
+func checkSiblings(siblings []hfFile) *unsafeSerialization {
+	u := &unsafeSerialization{}
+	for _, s := range siblings {
+		ext := strings.ToLower(filepath.Ext(s.Rfilename))
+		if ext == ".safetensors" {
+			u.hasSafetensors = true
+		}
+		if pickleExts[ext] && !isAuxiliaryPickle(s.Rfilename) {
+			u.findings = append(u.findings, pickleFinding{File: s.Rfilename})
+		}
+	}
+	return u
+}
+
+// isAuxiliaryPickle reports whether name is a training-state file shipped
+// next to the weights (optimizer, scheduler, RNG state). These are still
+// pickles but are not loaded for inference, so they are not reported for
+// Hub repositories where they cannot be scanned.
+func isAuxiliaryPickle(name string) bool {
+	base := strings.ToLower(filepath.Base(name))
+	return containsAny(base, "optimizer", "scheduler", "rng_state", "training_args")
+}
+
+// scanPickleFile statically scans a local pickle-format file.
+func scanPickleFile(path, rel string) pickleFinding {
+	f := pickleFinding{File: rel, Scanned: true}
+	imports, isPickle, err := scanPickleFileImports(path)
+	f.IsPickle = isPickle
+	if err != nil {
+		f.Err = err.Error()
+	}
+	f.Imports = imports
+	for _, imp := range imports {
+		mod, name, _ := strings.Cut(imp, " ")
+		if isDangerousGlobal(mod, name) {
+			f.Dangerous = append(f.Dangerous, mod+"."+name)
+		}
+	}
+	for i, imp := range f.Imports {
+		f.Imports[i] = strings.Replace(imp, " ", ".", 1)
+	}
+	return f
+}
+
+// notPickleMagics are headers of weight formats that share the .bin
+// extension with PyTorch checkpoints but are not pickles (the GGML family).
+var notPickleMagics = []string{"GGUF", "ggml", "ggjt", "ggmf", "ggla"}
+
+// scanPickleFileImports returns the "module name" globals referenced by the
+// pickle(s) in path. Zip archives (PyTorch >= 1.6) are opened and every
+// *.pkl member scanned; any other file is scanned as a raw pickle of any
+// protocol. A file that does not parse as a pickle is returned with an
+// error, so that it is treated as unverified rather than clean.
+func scanPickleFileImports(path string) ([]string, bool, error) {
+	fh, err := os.Open(path) // #nosec G304 -- path comes from walking the user-supplied model directory
+	if err != nil {
+		return nil, false, err
+	}
+	defer fh.Close()
+
+	magic := make([]byte, 4)
+	n, err := io.ReadFull(fh, magic)
+	if n == 0 {
+		return nil, false, nil
+	}
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, false, err
+	}
+	magic = magic[:n]
+
+	switch {
+	case string(magic) == "PK\x03\x04":
+		st, err := fh.Stat()
+		if err != nil {
+			return nil, true, err
+		}
+		zr, err := zip.NewReader(fh, st.Size())
+		if err != nil {
+			return nil, true, fmt.Errorf("opening zip archive: %w", err)
+		}
+		seen := map[string]bool{}
+		var all []string
+		found := false
+		for _, zf := range zr.File {
+			if !strings.HasSuffix(zf.Name, ".pkl") {
+				continue
+			}
+			found = true
+			rc, err := zf.Open()
+			if err != nil {
+				return all, true, fmt.Errorf("opening %s: %w", zf.Name, err)
+			}
+			imports, err := scanPickleStream(bufio.NewReader(rc))
+			_ = rc.Close()
+			for _, imp := range imports {
+				if !seen[imp] {
+					seen[imp] = true
+					all = append(all, imp)
+				}
+			}
+			if err != nil {
+				return all, true, fmt.Errorf("%s: %w", zf.Name, err)
+			}
+		}
+		if !found {
+			return nil, true, errors.New("zip archive contains no pickle")
+		}
+		return all, true, nil
+	case slices.Contains(notPickleMagics, string(magic)):
+		return nil, false, nil
+	}
+
+	if _, err := fh.Seek(0, io.SeekStart); err != nil {
+		return nil, true, err
+	}
+	imports, err := scanPickleStream(bufio.NewReader(fh))
+	return imports, true, err
+}
+
+// unresolvedGlobal is recorded as the module and name of a global the
+// scanner cannot resolve: a STACK_GLOBAL whose operands are not strings of
+// known value, or an extension-registry reference. It is always dangerous.
+const unresolvedGlobal = "?"
+
+// isDangerousGlobal reports whether module.name is on the deny-list.
+func isDangerousGlobal(module, name string) bool {
+	if module == unresolvedGlobal {
+		return true
+	}
+	for mod := module; mod != ""; {
+		for _, n := range dangerousGlobals[mod] {
+			if n == "*" || n == name {
+				return true
+			}
+		}
+		// "os.path" is covered by "os"; "urllib.request" by "urllib".
+		i := strings.LastIndex(mod, ".")
+		if i < 0 {
+			break
+		}
+		mod = mod[:i]
+	}
+	return false
+}
+
+// ── pickle opcode walker ─────────────────────────────────────────────────────
+
+// Pickle opcodes, protocols 0 to 5.
+const (
+	opMark           = '('
+	opStop           = '.'
+	opPop            = '0'
+	opPopMark        = '1'
+	opDup            = '2'
+	opFloat          = 'F'
+	opInt            = 'I'
+	opBinInt         = 'J'
+	opBinInt1        = 'K'
+	opLong           = 'L'
+	opBinInt2        = 'M'
+	opNone           = 'N'
+	opPersID         = 'P'
+	opBinPersID      = 'Q'
+	opReduce         = 'R'
+	opString         = 'S'
+	opBinString      = 'T'
+	opShortBinString = 'U'
+	opUnicode        = 'V'
+	opBinUnicode     = 'X'
+	opAppend         = 'a'
+	opBuild          = 'b'
+	opGlobal         = 'c'
+	opDict           = 'd'
+	opEmptyDict      = '}'
+	opAppends        = 'e'
+	opGet            = 'g'
+	opBinGet         = 'h'
+	opInst           = 'i'
+	opLongBinGet     = 'j'
+	opList           = 'l'
+	opEmptyList      = ']'
+	opObj            = 'o'
+	opPut            = 'p'
+	opBinPut         = 'q'
+	opLongBinPut     = 'r'
+	opSetItem        = 's'
+	opTuple          = 't'
+	opEmptyTuple     = ')'
+	opSetItems       = 'u'
+	opBinFloat       = 'G'
+	opBinBytes       = 'B'
+	opShortBinBytes  = 'C'
+	opProto          = 0x80
+	opNewObj         = 0x81
+	opExt1           = 0x82
+	opExt2           = 0x83
+	opExt4           = 0x84
+	opTuple1         = 0x85
+	opTuple2         = 0x86
+	opTuple3         = 0x87
+	opNewTrue        = 0x88
+	opNewFalse       = 0x89
+	opLong1          = 0x8a
+	opLong4          = 0x8b
+	opShortBinUni    = 0x8c
+	opBinUnicode8    = 0x8d
+	opBinBytes8      = 0x8e
+	opEmptySet       = 0x8f
+	opAddItems       = 0x90
+	opFrozenSet      = 0x91
+	opNewObjEx       = 0x92
+	opStackGlobal    = 0x93
+	opMemoize        = 0x94
+	opFrame          = 0x95
+	opByteArray8     = 0x96
+	opNextBuffer     = 0x97
+	opReadonlyBuffer = 0x98
+)
+
+// maxPickleArg bounds a single inline argument to avoid huge allocations
+// from corrupt length prefixes; larger payloads are skipped, not read.
+const maxPickleArg = 1 << 20
+
+// pickleValue is the walker's view of a stack or memo entry: a mark, a
+// string whose exact value is known, or anything else.
+type pickleValue struct {
+	kind int
+	s    string
+}
+
+const (
+	valOther = iota
+	valMark
+	valString
+)
+
+var errPickleStack = errors.New("pickle stack underflow")
+
+// pickleWalker models the unpickler's stack and memo closely enough to know
+// which strings STACK_GLOBAL takes as module and name.
+type pickleWalker struct {
+	stack   []pickleValue
+	memo    map[uint64]pickleValue
+	globals []string
+	seen    map[string]bool
+}
+
+// scanPickleStream walks the opcodes of one or more consecutive pickles
+// (legacy PyTorch files concatenate several) and returns every global as
+// "module name". Nothing is executed. An error means the first pickle did
+// not parse; data that fails to parse after it is taken to be the raw
+// tensor storage of a legacy file, but globals found in it are still
+// returned.
+func scanPickleStream(r *bufio.Reader) ([]string, error) {
+	w := &pickleWalker{seen: map[string]bool{}}
+	w.reset()
+	trailing, started := false, false
+	for {
+		op, err := r.ReadByte()
+		if err == io.EOF {
+			if started && !trailing {
+				return w.globals, errors.New("truncated pickle: no STOP opcode")
+			}
+			return w.globals, nil
+		}
+		if err != nil {
+			return w.globals, err
+		}
+		started = true
+		stop, err := w.step(r, op)
+		if err != nil {
+			if trailing {
+				return w.globals, nil
+			}
+			return w.globals, err
+		}
+		if stop {
+			if _, err := r.Peek(1); err != nil {
+				return w.globals, nil
+			}
+			trailing = true
+			w.reset()
+		}
+	}
+}
+
+func (w *pickleWalker) reset() {
+	w.stack = w.stack[:0]
+	w.memo = map[uint64]pickleValue{}
+}
+
+func (w *pickleWalker) addGlobal(module, name string) {
+	g := module + " " + name
+	if !w.seen[g] {
+		w.seen[g] = true
+		w.globals = append(w.globals, g)
+	}
+}
+
+func (w *pickleWalker) push(v pickleValue) {
+	w.stack = append(w.stack, v)
+}
+
+// pop removes n entries and, if result is set, pushes a value in their place.
+func (w *pickleWalker) pop(n int, result bool) error {
+	if len(w.stack) < n {
+		return errPickleStack
+	}
+	w.stack = w.stack[:len(w.stack)-n]
+	if result {
+		w.push(pickleValue{})
+	}
+	return nil
+}
+
+// popMark removes the entries up to and including the topmost mark and, if
+// result is set, pushes a value in their place.
+func (w *pickleWalker) popMark(result bool) error {
+	for i := len(w.stack) - 1; i >= 0; i-- {
+		if w.stack[i].kind == valMark {
+			w.stack = w.stack[:i]
+			if result {
+				w.push(pickleValue{})
+			}
+			return nil
+		}
+	}
+	return errors.New("pickle MARK not found")
+}
+
+func (w *pickleWalker) top() (pickleValue, error) {
+	if len(w.stack) == 0 {
+		return pickleValue{}, errPickleStack
+	}
+	return w.stack[len(w.stack)-1], nil
+}
+
+func (w *pickleWalker) put(idx uint64) error {
+	v, err := w.top()
+	w.memo[idx] = v
+	return err
+}
+
+func (w *pickleWalker) get(idx uint64) error {
+	v, ok := w.memo[idx]
+	if !ok {
+		return fmt.Errorf("pickle memo key %d not found", idx)
+	}
+	w.push(v)
+	return nil
+}
+
+// pushString pushes s when its value is known exactly, or an opaque value
+// for payloads that were skipped or need unescaping.
+func (w *pickleWalker) pushString(s string, known bool) {
+	if known {
+		w.push(pickleValue{kind: valString, s: s})
+	} else {
+		w.push(pickleValue{})
+	}
+}
+
+// step executes the stack effect of op, reading its argument from r. It
+// reports whether op was STOP.
+func (w *pickleWalker) step(r *bufio.Reader, op byte) (bool, error) {
+	switch op {
+	case opStop:
+		return true, w.pop(1, false)
+	case opMark:
+		w.push(pickleValue{kind: valMark})
+	case opPop:
+		return false, w.pop(1, false)
+	case opPopMark:
+		return false, w.popMark(false)
+	case opDup:
+		v, err := w.top()
+		if err != nil {
+			return false, err
+		}
+		w.push(v)
+	case opNone, opNewTrue, opNewFalse, opEmptyDict, opEmptyList, opEmptyTuple, opEmptySet, opNextBuffer:
+		w.push(pickleValue{})
+	case opBinPersID, opTuple1, opReadonlyBuffer:
+		return false, w.pop(1, true)
+	case opAppend, opBuild:
+		return false, w.pop(1, false)
+	case opReduce, opNewObj, opTuple2:
+		return false, w.pop(2, true)
+	case opNewObjEx, opTuple3:
+		return false, w.pop(3, true)
+	case opSetItem:
+		return false, w.pop(2, false)
+	case opDict, opList, opTuple, opFrozenSet, opObj:
+		return false, w.popMark(true)
+	case opAppends, opSetItems, opAddItems:
+		return false, w.popMark(false)
+
+	case opGlobal, opInst:
+		module, err1 := readLine(r)
+		name, err2 := readLine(r)
+		if err := errors.Join(err1, err2); err != nil {
+			return false, err
+		}
+		if !isDottedName(module) || !isDottedName(name) {
+			return false, fmt.Errorf("invalid pickle global %q %q", module, name)
+		}
+		w.addGlobal(module, name)
+		if op == opInst {
+			return false, w.popMark(true)
+		}
+		w.push(pickleValue{})
+	case opStackGlobal:
+		if len(w.stack) < 2 {
+			w.addGlobal(unresolvedGlobal, unresolvedGlobal)
+			return false, errPickleStack
+		}
+		module, name := w.stack[len(w.stack)-2], w.stack[len(w.stack)-1]
+		if module.kind == valString && name.kind == valString {
+			w.addGlobal(module.s, name.s)
+		} else {
+			w.addGlobal(unresolvedGlobal, unresolvedGlobal)
+		}
+		return false, w.pop(2, true)
+	case opExt1, opExt2, opExt4:
+		size := map[byte]int{opExt1: 1, opExt2: 2, opExt4: 4}[op]
+		if _, err := r.Discard(size); err != nil {
+			return false, fmt.Errorf("truncated pickle: %w", err)
+		}
+		// The copyreg extension registry can name any global.
+		w.addGlobal(unresolvedGlobal, unresolvedGlobal)
+		w.push(pickleValue{})
+
+	case opString, opUnicode:
+		s, err := readLine(r)
+		if err != nil {
+			return false, err
+		}
+		// Escapes are not decoded, so such strings are of unknown value.
+		known := !strings.Contains(s, `\`)
+		if op == opString {
+			unquoted := strings.Trim(s, `'"`)
+			known = known && len(unquoted) == len(s)-2
+			s = unquoted
+		}
+		w.pushString(s, known)
+	case opShortBinString, opShortBinUni:
+		s, known, err := readCounted(r, 1)
+		if err != nil {
+			return false, err
+		}
+		w.pushString(s, known)
+	case opBinString, opBinUnicode:
+		s, known, err := readCounted(r, 4)
+		if err != nil {
+			return false, err
+		}
+		w.pushString(s, known)
+	case opBinUnicode8:
+		s, known, err := readCounted(r, 8)
+		if err != nil {
+			return false, err
+		}
+		w.pushString(s, known)
+	case opShortBinBytes, opLong1:
+		if _, _, err := readCounted(r, 1); err != nil {
+			return false, err
+		}
+		w.push(pickleValue{})
+	case opBinBytes, opLong4:
+		if _, _, err := readCounted(r, 4); err != nil {
+			return false, err
+		}
+		w.push(pickleValue{})
+	case opBinBytes8, opByteArray8:
+		if _, _, err := readCounted(r, 8); err != nil {
+			return false, err
+		}
+		w.push(pickleValue{})
+
+	case opFloat, opInt, opLong, opPersID:
+		if _, err := readLine(r); err != nil {
+			return false, err
+		}
+		w.push(pickleValue{})
+	case opBinInt1, opBinInt2, opBinInt, opBinFloat:
+		size := map[byte]int{opBinInt1: 1, opBinInt2: 2, opBinInt: 4, opBinFloat: 8}[op]
+		if _, err := r.Discard(size); err != nil {
+			return false, fmt.Errorf("truncated pickle: %w", err)
+		}
+		w.push(pickleValue{})
+
+	case opGet:
+		s, err := readLine(r)
+		if err != nil {
+			return false, err
+		}
+		idx, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("invalid pickle memo key %q", s)
+		}
+		return false, w.get(idx)
+	case opPut:
+		s, err := readLine(r)
+		if err != nil {
+			return false, err
+		}
+		idx, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("invalid pickle memo key %q", s)
+		}
+		return false, w.put(idx)
+	case opBinGet, opBinPut:
+		b, err := r.ReadByte()
+		if err != nil {
+			return false, fmt.Errorf("truncated pickle: %w", err)
+		}
+		if op == opBinGet {
+			return false, w.get(uint64(b))
+		}
+		return false, w.put(uint64(b))
+	case opLongBinGet, opLongBinPut:
+		n, err := readUint(r, 4)
+		if err != nil {
+			return false, err
+		}
+		if op == opLongBinGet {
+			return false, w.get(n)
+		}
+		return false, w.put(n)
+	case opMemoize:
+		return false, w.put(uint64(len(w.memo)))
+
+	case opProto:
+		if _, err := r.Discard(1); err != nil {
+			return false, fmt.Errorf("truncated pickle: %w", err)
+		}
+	case opFrame:
+		if _, err := r.Discard(8); err != nil {
+			return false, fmt.Errorf("truncated pickle: %w", err)
+		}
+	default:
+		return false, fmt.Errorf("unknown pickle opcode 0x%02x", op)
+	}
+	return false, nil
+}
+
+// isDottedName reports whether s is a dotted Python identifier such as
+// "torch._utils" or "OrderedDict".
+func isDottedName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, part := range strings.Split(s, ".") {
+		if part == "" {
+			return false
+		}
+		for i, c := range part {
+			if c != '_' && !unicode.IsLetter(c) && (i == 0 || !unicode.IsDigit(c)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// readLine reads a newline-terminated argument of at most maxPickleArg
+// bytes.
+func readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > maxPickleArg {
+			return "", errors.New("pickle line argument too long")
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("truncated pickle: %w", err)
+		}
+		return strings.TrimSuffix(string(line), "\n"), nil
+	}
+}
+
+func readUint(r *bufio.Reader, size int) (uint64, error) {
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(r, buf[:size]); err != nil {
+		return 0, fmt.Errorf("truncated pickle: %w", err)
+	}
+	return binary.LittleEndian.Uint64(buf), nil
+}
+
+// readCounted reads a length-prefixed argument. Payloads larger than
+// maxPickleArg are skipped and reported as not read.
+func readCounted(r *bufio.Reader, lenSize int) (string, bool, error) {
+	n, err := readUint(r, lenSize)
+	if err != nil {
+		return "", false, err
+	}
+	if n > maxPickleArg {
+		for n > 0 {
+			chunk := n
+			if chunk > 1<<30 {
+				chunk = 1 << 30
+			}
+			d, err := r.Discard(int(chunk)) // #nosec G115 -- chunk is bounded above
+			if err != nil {
+				return "", false, fmt.Errorf("truncated pickle: %w", err)
+			}
+			n -= uint64(d) // #nosec G115 -- d is non-negative
+		}
+		return "", false, nil
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", false, fmt.Errorf("truncated pickle: %w", err)
+	}
+	return string(buf), true, nil
+}
+
+// ── BOM annotation ───────────────────────────────────────────────────────────
+
+// annotateSerialization records pickle findings on the model component as
+// properties and model-card ethical considerations (the CycloneDX risk list).
+func annotateSerialization(comp *cdx.Component, u *unsafeSerialization) {
+	pickles := u.pickles()
+	if len(pickles) == 0 {
+		return
+	}
+
+	var props []cdx.Property
+	if comp.Properties != nil {
+		props = *comp.Properties
+	}
+	files := make([]string, 0, len(pickles))
+	for _, f := range pickles {
+		files = append(files, f.File)
+	}
+	props = append(props,
+		cdx.Property{Name: "unsafeSerialization", Value: "true"},
+		cdx.Property{Name: "pickleFiles", Value: strings.Join(files, ",")},
+		cdx.Property{Name: "safetensorsAvailable", Value: fmt.Sprint(u.hasSafetensors)},
+	)
+
+	dangerous := map[string]bool{}
+	for _, f := range u.dangerous() {
+		for _, d := range f.Dangerous {
+			dangerous[d] = true
+		}
+	}
+	if len(dangerous) > 0 {
+		props = append(props, cdx.Property{Name: "pickleDangerousImports", Value: strings.Join(sortedSet(dangerous), ",")})
+	}
+	var unverified []string
+	for _, f := range pickles {
+		if !f.Scanned || f.Err != "" {
+			unverified = append(unverified, f.File)
+		}
+	}
+	if len(unverified) > 0 {
+		props = append(props, cdx.Property{Name: "pickleUnverified", Value: strings.Join(unverified, ",")})
+	}
+	comp.Properties = &props
+
+	if comp.ModelCard == nil {
+		comp.ModelCard = &cdx.MLModelCard{}
+	}
+	if comp.ModelCard.Considerations == nil {
+		comp.ModelCard.Considerations = &cdx.MLModelCardConsiderations{}
+	}
+	cons := comp.ModelCard.Considerations
+	var ethics []cdx.MLModelCardEthicalConsideration
+	if cons.EthicalConsiderations != nil {
+		ethics = *cons.EthicalConsiderations
+	}
+
+	mitigation := "Convert the weights to safetensors, or load with torch.load(weights_only=True) in an isolated environment"
+	if u.hasSafetensors {
+		mitigation = "Load the safetensors weights instead of the pickle files"
+	}
+	ethics = append(ethics, cdx.MLModelCardEthicalConsideration{
+		Name:               "Unsafe Serialization Format: pickle-based weights can execute arbitrary code when loaded (" + strings.Join(files, ", ") + ")",
+		MitigationStrategy: mitigation,
+	})
+	for _, f := range u.dangerous() {
+		ethics = append(ethics, cdx.MLModelCardEthicalConsideration{
+			Name:               fmt.Sprintf("Malicious Code in Model Weights: %s imports %s", f.File, strings.Join(f.Dangerous, ", ")),
+			MitigationStrategy: "Do not load this file; obtain the model from a trusted source and verify its integrity",
+		})
+	}
+	cons.EthicalConsiderations = &ethics
+}
+
+// unsafeError returns an error describing why generation should fail when
+// --fail-on-unsafe is set: deny-listed imports in a scanned pickle, or
+// unverifiable pickle weights with no safetensors alternative.
+func (u *unsafeSerialization) unsafeError() error {
+	if d := u.dangerous(); len(d) > 0 {
+		msgs := make([]string, 0, len(d))
+		for _, f := range d {
+			msgs = append(msgs, f.File+" ("+strings.Join(f.Dangerous, ", ")+")")
+		}
+		sort.Strings(msgs)
+		return fmt.Errorf("unsafe model weights: dangerous pickle imports in %s", strings.Join(msgs, "; "))
+	}
+	if u.hasSafetensors {
+		return nil
+	}
+	var unverified []string
+	for _, f := range u.pickles() {
+		if !f.Scanned || f.Err != "" {
+			unverified = append(unverified, f.File)
+		}
+	}
+	if len(unverified) > 0 {
+		return fmt.Errorf("unsafe model weights: pickle files %s could not be verified and no safetensors alternative exists",
+			strings.Join(unverified, ", "))
+	}
+	return nil
+}
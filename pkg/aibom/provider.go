@@ -0,0 +1,371 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package aibom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"github.com/google/uuid"
+)
+
+// providerTimeout bounds every request made to a model provider API.
+const providerTimeout = 30 * time.Second
+
+// Provider is a model-serving backend whose models can be inventoried.
+// Implementations translate the backend's API into ProviderModel values; the
+// AIBOM is then assembled the same way for every provider.
+type Provider interface {
+	// Name is the provider identifier used in purls and component groups.
+	Name() string
+
+	// ListModels returns every model (or deployment) available.
+	ListModels(ctx context.Context) ([]ProviderModel, error)
+
+	// DescribeModel returns details of a single model.
+	DescribeModel(ctx context.Context, id string) (*ProviderModel, error)
+
+	// Component maps a model to a CycloneDX machine-learning-model component.
+	Component(m ProviderModel) cdx.Component
+}
+
+// ProviderOptions holds configuration for AIBOM generation from a model
+// provider (Azure OpenAI, Vertex AI, Ollama, OpenAI-compatible servers).
+type ProviderOptions struct {
+	// Provider selects the backend: "azure-openai", "vertexai", "ollama" or
+	// "openai".
+	Provider string
+
+	// Endpoint is the API base URL. Defaults depend on the provider.
+	Endpoint string
+
+	// APIKey authenticates against Azure OpenAI and OpenAI-compatible
+	// servers; Token is an OAuth access token for Vertex AI. When empty the
+	// provider falls back to its environment variable or default
+	// credentials.
+	APIKey string
+	Token  string
+
+	// APIVersion is the Azure OpenAI REST API version.
+	APIVersion string
+
+	// Project and Region locate Vertex AI resources.
+	Project string
+	Region  string
+
+	// ModelID inventories a single model when set.
+	ModelID string
+
+	// Metadata overrides
+	Name         string
+	Version      string
+	Manufacturer string
+}
+
+// ProviderModel is the provider-neutral description of a model.
+type ProviderModel struct {
+	ID            string // identifier used to call the model
+	Name          string // display name; defaults to ID
+	Owner         string // publisher or owning organisation
+	Version       string
+	Description   string
+	Created       time.Time
+	Family        string // architecture family, e.g. "llama"
+	Architecture  string
+	Task          string
+	ParameterSize string // human-readable, e.g. "8.0B"
+	Quantization  string
+	Format        string
+	ContextLength int64
+	Digest        string // sha256 of the model content, when known
+	License       string // SPDX identifier or license text
+	Capabilities  []string
+	BaseModel     string // model a deployment or fine-tune derives from
+	URL           string // console or API URL
+	Properties    map[string]string
+}
+
+// providerFactories maps provider names to constructors.
+var providerFactories = map[string]func(opts *ProviderOptions) (Provider, error){
+	"azure-openai": newAzureOpenAIProvider,
+	"vertexai":     newVertexProvider,
+	"ollama":       newOllamaProvider,
+	"openai":       newOpenAIProvider,
+}
+
+// ProviderNames returns the supported provider names.
+func ProviderNames() []string {
+	names := make([]string, 0, len(providerFactories))
+	for n := range providerFactories {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewProvider returns the provider selected by opts.Provider.
+func NewProvider(opts *ProviderOptions) (Provider, error) {
+	factory, ok := providerFactories[opts.Provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q (supported: %s)", opts.Provider, strings.Join(ProviderNames(), ", "))
+	}
+	return factory(opts)
+}
+
+// GenerateFromProvider lists (or describes) the models of the provider
+// selected by opts and returns a CycloneDX BOM inventorying them.
+func GenerateFromProvider(opts *ProviderOptions) (*cdx.BOM, error) {
+	p, err := NewProvider(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*providerTimeout)
+	defer cancel()
+
+	var models []ProviderModel
+	if opts.ModelID != "" {
+		m, err := p.DescribeModel(ctx, opts.ModelID)
+		if err != nil {
+			return nil, fmt.Errorf("describing %s model %q: %w", p.Name(), opts.ModelID, err)
+		}
+		models = []ProviderModel{*m}
+	} else {
+		models, err = p.ListModels(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing %s models: %w", p.Name(), err)
+		}
+	}
+	return buildProviderBOM(p, models, opts), nil
+}
+
+// buildProviderBOM constructs a CycloneDX BOM from provider models, mirroring
+// buildBedrockBOM.
+func buildProviderBOM(p Provider, models []ProviderModel, opts *ProviderOptions) *cdx.BOM {
+	doc := cdx.NewBOM()
+	doc.SerialNumber = "urn:uuid:" + uuid.New().String()
+	doc.JSONSchema = jsonSchema17
+
+	comps := make([]cdx.Component, 0, len(models))
+	for _, m := range models {
+		c := p.Component(m)
+		if opts.Name != "" && len(models) == 1 {
+			c.Name = opts.Name
+		}
+		if opts.Manufacturer != "" {
+			c.Supplier = &cdx.OrganizationalEntity{Name: opts.Manufacturer}
+		}
+		if opts.Version != "" {
+			c.Version = opts.Version
+		}
+		comps = append(comps, c)
+	}
+
+	subjectName := p.Name() + " models"
+	subjectRef := p.Name() + "-models"
+	if len(comps) == 1 {
+		subjectName = comps[0].Name
+		subjectRef = comps[0].BOMRef
+	}
+
+	doc.Metadata = &cdx.Metadata{
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		Lifecycles: &[]cdx.Lifecycle{{Phase: cdx.LifecyclePhaseBuild}},
+		Tools: &cdx.ToolsChoice{
+			Components: &[]cdx.Component{toolComponent},
+		},
+		Component: &cdx.Component{
+			BOMRef: subjectRef,
+			Type:   cdx.ComponentTypeMachineLearningModel,
+			Name:   subjectName,
+		},
+	}
+	doc.Components = &comps
+	enforceRequiredFields(doc)
+	return doc
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Component mapping
+// ──────────────────────────────────────────────────────────────────────────────
+
+// modelComponent is the shared ProviderModel → component mapping used by
+// every provider's Component method.
+func modelComponent(provider string, m ProviderModel) cdx.Component {
+	name := m.Name
+	if name == "" {
+		name = m.ID
+	}
+	purl := fmt.Sprintf("pkg:generic/%s/%s", provider, m.ID)
+	if m.Version != "" {
+		purl += "@" + m.Version
+	}
+
+	comp := cdx.Component{
+		BOMRef:      purl,
+		Type:        cdx.ComponentTypeMachineLearningModel,
+		Name:        name,
+		Group:       provider,
+		Version:     m.Version,
+		PackageURL:  purl,
+		Description: m.Description,
+	}
+	if comp.Description == "" {
+		comp.Description = "Model served by " + provider
+	}
+	supplier := m.Owner
+	if supplier == "" {
+		supplier = provider
+	}
+	comp.Supplier = &cdx.OrganizationalEntity{Name: supplier}
+
+	if m.Digest != "" {
+		comp.Hashes = &[]cdx.Hash{{Algorithm: cdx.HashAlgoSHA256, Value: strings.TrimPrefix(m.Digest, "sha256:")}}
+	}
+	if lic := providerLicense(m.License); lic != nil {
+		comp.Licenses = &cdx.Licenses{{License: lic}}
+	}
+	if len(m.Capabilities) > 0 {
+		tags := append([]string(nil), m.Capabilities...)
+		comp.Tags = &tags
+	}
+	if m.URL != "" {
+		comp.ExternalReferences = &[]cdx.ExternalReference{{Type: cdx.ERTypeWebsite, URL: m.URL}}
+	}
+	if m.BaseModel != "" {
+		comp.Pedigree = &cdx.Pedigree{Ancestors: &[]cdx.Component{{
+			Type: cdx.ComponentTypeMachineLearningModel,
+			Name: m.BaseModel,
+		}}}
+	}
+
+	var props []cdx.Property
+	add := func(name, value string) {
+		if value != "" {
+			props = append(props, cdx.Property{Name: name, Value: value})
+		}
+	}
+	add("provider", provider)
+	add("baseModel", m.BaseModel)
+	add("parameterSize", m.ParameterSize)
+	add("quantization", m.Quantization)
+	add("modelFormat", m.Format)
+	if m.ContextLength > 0 {
+		add("contextLength", fmt.Sprint(m.ContextLength))
+	}
+	if !m.Created.IsZero() {
+		add("createdAt", m.Created.UTC().Format(time.RFC3339))
+	}
+	for _, k := range sortedStringKeys(m.Properties) {
+		add(k, m.Properties[k])
+	}
+	comp.Properties = &props
+
+	params := &cdx.MLModelParameters{
+		Task:               m.Task,
+		ArchitectureFamily: m.Family,
+		ModelArchitecture:  m.Architecture,
+	}
+	if params.Task != "" || params.ArchitectureFamily != "" || params.ModelArchitecture != "" {
+		comp.ModelCard = &cdx.MLModelCard{ModelParameters: params}
+	}
+	return comp
+}
+
+// providerLicense maps a provider license field to a CycloneDX license. Some
+// providers (Ollama) return the full license text; only its first line is
+// kept, as a license name.
+func providerLicense(raw string) *cdx.License {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	if !strings.ContainsAny(raw, " \n\t") && len(raw) <= 64 {
+		return &cdx.License{ID: normaliseLicense(raw)}
+	}
+	first, _, _ := strings.Cut(raw, "\n")
+	first = strings.TrimSpace(first)
+	if len(first) > 100 {
+		first = first[:100]
+	}
+	return &cdx.License{Name: first}
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// HTTP helpers
+// ──────────────────────────────────────────────────────────────────────────────
+
+// doJSON sends a request with an optional JSON body and decodes a JSON
+// response into out.
+func doJSON(ctx context.Context, client *http.Client, method, url string, headers map[string]string, body, out interface{}) error {
+	var rd io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		rd = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, rd)
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("querying %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return fmt.Errorf("reading response from %s: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &httpStatusError{URL: url, Status: resp.StatusCode, Body: strings.TrimSpace(string(data))}
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("parsing response from %s: %w", url, err)
+	}
+	return nil
+}
+
+// httpStatusError is returned by doJSON for non-200 responses.
+type httpStatusError struct {
+	URL    string
+	Status int
+	Body   string
+}
+
+func (e *httpStatusError) Error() string {
+	msg := e.Body
+	if len(msg) > 200 {
+		msg = msg[:200] + "..."
+	}
+	return fmt.Sprintf("%s returned HTTP %d: %s", e.URL, e.Status, msg)
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
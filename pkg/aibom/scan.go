@@ -0,0 +1,643 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package aibom
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrock/types"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"github.com/google/uuid"
+)
+
+// Model providers recognised by the scanner.
+const (
+	providerHuggingFace = "huggingface"
+	providerBedrock     = "bedrock"
+)
+
+// maxScanFileSize bounds the size of source and config files read by Scan.
+const maxScanFileSize = 2 << 20
+
+// modelRef is a model referenced by the application, with every place it was
+// found.
+type modelRef struct {
+	Provider    string
+	ID          string
+	occurrences []refOccurrence
+}
+
+type refOccurrence struct {
+	file   string
+	line   int
+	symbol string
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Reference patterns
+// ──────────────────────────────────────────────────────────────────────────────
+
+// codePattern matches a model reference in source code. An empty provider
+// means the captured ID is classified by its shape (Hub ID vs Bedrock ID).
+type codePattern struct {
+	symbol   string
+	provider string
+	re       *regexp.Regexp
+}
+
+var codePatterns = []codePattern{
+	{"from_pretrained", providerHuggingFace,
+		regexp.MustCompile(`\bfrom_pretrained\(\s*(?:pretrained_model_name_or_path\s*=\s*)?["']([^"'\s]+)["']`)},
+	{"pipeline", providerHuggingFace,
+		regexp.MustCompile(`\bpipeline\([^)]*?\bmodel\s*=\s*["']([^"'\s]+)["']`)},
+	{"hf_hub_download", providerHuggingFace,
+		regexp.MustCompile(`\b(?:hf_hub_download|snapshot_download)\(\s*(?:repo_id\s*=\s*)?["']([^"'\s]+)["']`)},
+	{"hf_hub_download", providerHuggingFace,
+		regexp.MustCompile(`\b(?:hf_hub_download|snapshot_download)\([^)]*?\brepo_id\s*=\s*["']([^"'\s]+)["']`)},
+	{"SentenceTransformer", providerHuggingFace,
+		regexp.MustCompile(`\bSentenceTransformer\(\s*(?:model_name_or_path\s*=\s*)?["']([^"'\s]+)["']`)},
+	// boto3 invoke_model / converse and the JavaScript SDK commands.
+	{"modelId", "",
+		regexp.MustCompile(`\bmodel(?:Id|ID|_id)\s*[=:]\s*["']([^"'\s]+)["']`)},
+}
+
+// frameworkClasses maps LangChain and LlamaIndex model classes to the
+// provider serving the model named in their model/model_name/model_id/repo_id
+// argument.
+var frameworkClasses = map[string]string{
+	// LangChain
+	"ChatOpenAI": "openai", "OpenAI": "openai", "OpenAIEmbeddings": "openai",
+	"AzureChatOpenAI": "azure-openai", "AzureOpenAIEmbeddings": "azure-openai",
+	"ChatAnthropic": "anthropic",
+	"ChatOllama":    "ollama", "OllamaLLM": "ollama", "OllamaEmbeddings": "ollama",
+	"ChatBedrock": providerBedrock, "ChatBedrockConverse": providerBedrock,
+	"BedrockLLM": providerBedrock, "BedrockEmbeddings": providerBedrock,
+	"ChatVertexAI": "vertexai", "VertexAI": "vertexai", "VertexAIEmbeddings": "vertexai",
+	"ChatGoogleGenerativeAI": "google", "ChatMistralAI": "mistral", "ChatCohere": "cohere",
+	"HuggingFaceEmbeddings": providerHuggingFace, "HuggingFaceEndpoint": providerHuggingFace,
+	"HuggingFacePipeline": providerHuggingFace, "HuggingFaceHub": providerHuggingFace,
+	"from_model_id": providerHuggingFace,
+	// LlamaIndex
+	"HuggingFaceEmbedding": providerHuggingFace, "HuggingFaceLLM": providerHuggingFace,
+	"HuggingFaceInferenceAPI": providerHuggingFace,
+	"Bedrock":                 providerBedrock, "BedrockConverse": providerBedrock, "BedrockEmbedding": providerBedrock,
+	"Ollama": "ollama", "OpenAIEmbedding": "openai", "AzureOpenAI": "azure-openai",
+	"Anthropic": "anthropic", "Gemini": "google", "Vertex": "vertexai",
+}
+
+var (
+	frameworkCallRe = frameworkCallPattern()
+	frameworkArgRe  = regexp.MustCompile(`\b(?:model|model_name|model_id|repo_id|llm_model|embed_model)\s*=\s*["']([^"'\s]+)["']`)
+
+	// configKeyRe matches model settings in YAML, JSON, TOML, INI and .env
+	// files. Only values shaped like a Hub or Bedrock ID are kept.
+	configKeyRe = regexp.MustCompile(`(?im)["']?\b(?:model|model_name|model_id|modelid|base_model|repo_id|llm_model|embedding_model)["']?\s*[:=]\s*["']?([A-Za-z0-9][\w.:/-]*)`)
+
+	hfIDRe      = regexp.MustCompile(`^[A-Za-z0-9][\w.-]*/[A-Za-z0-9][\w.-]*$`)
+	hfNameRe    = regexp.MustCompile(`^[A-Za-z0-9][\w.-]*(?:/[A-Za-z0-9][\w.-]*)?$`)
+	bedrockIDRe = regexp.MustCompile(`^(?:(?:us|eu|apac|us-gov|global)\.)?(?:amazon|anthropic|ai21|cohere|meta|mistral|stability|deepseek|writer|luma|twelvelabs)\.[A-Za-z0-9][\w.-]*(?::\d+(?::[\w.]+)?)?$|^arn:aws[\w-]*:bedrock:`)
+)
+
+// frameworkCallPattern matches a call to one of frameworkClasses and
+// captures the class and its (single-level) argument list.
+func frameworkCallPattern() *regexp.Regexp {
+	names := make([]string, 0, len(frameworkClasses))
+	for n := range frameworkClasses {
+		names = append(names, regexp.QuoteMeta(n))
+	}
+	sort.Strings(names)
+	return regexp.MustCompile(`\b(` + strings.Join(names, "|") + `)\s*\(([^)]*)\)`)
+}
+
+// sourceExts and configExts select the files searched for model references.
+var (
+	sourceExts = map[string]bool{
+		".py": true, ".ipynb": true, ".js": true, ".mjs": true, ".cjs": true, ".ts": true,
+		".tsx": true, ".go": true, ".java": true, ".kt": true, ".rb": true,
+	}
+	configExts = map[string]bool{
+		".yaml": true, ".yml": true, ".json": true, ".toml": true, ".env": true, ".cfg": true, ".ini": true,
+	}
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Scan
+// ──────────────────────────────────────────────────────────────────────────────
+
+// Scan discovers model references under opts.Path and in opts.ModelsFile and
+// returns a single AIBOM with the application as metadata.component, every
+// model as a component and a dependency graph from the application to each
+// model and its base models.
+func Scan(opts *Options) (*cdx.BOM, error) {
+	if opts.Path == "" && opts.ModelsFile == "" {
+		return nil, fmt.Errorf("--path or --models-file is required")
+	}
+	refs, err := collectModelRefs(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("no model references found")
+	}
+	hub := newHubFetcher(opts.Token)
+	if opts.LineageDepth <= 0 {
+		hub.dataset = nil
+	}
+	opts.models = nil
+	return buildScanBOM(refs, opts, hub), nil
+}
+
+// collectModelRefs merges the references found in the source tree with those
+// listed in the models file, deduplicated by provider and ID.
+func collectModelRefs(opts *Options) ([]*modelRef, error) {
+	byKey := map[string]*modelRef{}
+	add := func(provider, id string, occ refOccurrence) {
+		key := provider + "|" + id
+		if provider == providerHuggingFace {
+			// Hub IDs are case-insensitive.
+			key = strings.ToLower(key)
+		}
+		r, ok := byKey[key]
+		if !ok {
+			r = &modelRef{Provider: provider, ID: id}
+			byKey[key] = r
+		}
+		for _, o := range r.occurrences {
+			if o.file == occ.file && o.line == occ.line {
+				return
+			}
+		}
+		r.occurrences = append(r.occurrences, occ)
+	}
+
+	if opts.Path != "" {
+		if err := discoverModelRefs(opts.Path, add); err != nil {
+			return nil, err
+		}
+	}
+	if opts.ModelsFile != "" {
+		if err := readModelsFile(opts.ModelsFile, add); err != nil {
+			return nil, err
+		}
+	}
+
+	refs := make([]*modelRef, 0, len(byKey))
+	for _, r := range byKey {
+		refs = append(refs, r)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Provider != refs[j].Provider {
+			return refs[i].Provider < refs[j].Provider
+		}
+		return refs[i].ID < refs[j].ID
+	})
+	return refs, nil
+}
+
+// discoverModelRefs walks root and reports every model reference found in
+// source and config files.
+func discoverModelRefs(root string, add func(provider, id string, occ refOccurrence)) error {
+	st, err := os.Stat(root)
+	if err != nil {
+		return fmt.Errorf("reading scan path: %w", err)
+	}
+	base := root
+	if !st.IsDir() {
+		base = filepath.Dir(root)
+	}
+
+	visit := func(p string, size int64) error {
+		ext := strings.ToLower(filepath.Ext(p))
+		isSource, isConfig := sourceExts[ext], configExts[ext] || filepath.Base(p) == ".env"
+		if (!isSource && !isConfig) || size > maxScanFileSize {
+			return nil
+		}
+		data, err := os.ReadFile(p) // #nosec G304 -- path comes from walking the user-supplied source tree
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(base, p)
+		rel = filepath.ToSlash(rel)
+		if ext == ".ipynb" {
+			// Notebook cells are JSON strings; unescape quotes so the source
+			// patterns match. Line numbers still refer to the .ipynb file.
+			data = bytes.ReplaceAll(data, []byte(`\"`), []byte(`"`))
+		}
+		if isSource {
+			scanSourceRefs(rel, data, add)
+		} else {
+			scanConfigRefs(rel, data, add)
+		}
+		return nil
+	}
+
+	if !st.IsDir() {
+		return visit(root, st.Size())
+	}
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p == root {
+				return nil
+			}
+			switch name := d.Name(); {
+			case strings.HasPrefix(name, "."), name == "node_modules", name == "vendor",
+				name == "__pycache__", name == "site-packages", name == "venv":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return visit(p, fi.Size())
+	})
+}
+
+// scanSourceRefs applies the code patterns and framework class patterns to
+// one source file.
+func scanSourceRefs(rel string, data []byte, add func(provider, id string, occ refOccurrence)) {
+	src := string(data)
+	report := func(provider, id, symbol string, offset int) {
+		if isLocalPath(id) {
+			return
+		}
+		switch {
+		case provider == "":
+			var ok bool
+			if provider, ok = classifyModelID(id); !ok {
+				return
+			}
+		case provider == providerHuggingFace:
+			// Explicit Hub calls also accept legacy un-namespaced IDs ("gpt2").
+			if !hfNameRe.MatchString(id) || hasFileExt(id) {
+				return
+			}
+		}
+		add(provider, id, refOccurrence{file: rel, line: lineAt(src, offset), symbol: symbol})
+	}
+
+	for _, p := range codePatterns {
+		for _, m := range p.re.FindAllStringSubmatchIndex(src, -1) {
+			report(p.provider, src[m[2]:m[3]], p.symbol, m[0])
+		}
+	}
+	for _, m := range frameworkCallRe.FindAllStringSubmatchIndex(src, -1) {
+		class := src[m[2]:m[3]]
+		provider, ok := frameworkClasses[class]
+		if !ok {
+			continue
+		}
+		args := src[m[4]:m[5]]
+		for _, a := range frameworkArgRe.FindAllStringSubmatchIndex(args, -1) {
+			report(provider, args[a[2]:a[3]], class, m[0])
+		}
+	}
+}
+
+// scanConfigRefs reports model settings in a config file whose value looks
+// like a Hub or Bedrock model ID.
+func scanConfigRefs(rel string, data []byte, add func(provider, id string, occ refOccurrence)) {
+	src := string(data)
+	for _, m := range configKeyRe.FindAllStringSubmatchIndex(src, -1) {
+		id := src[m[2]:m[3]]
+		provider, ok := classifyModelID(id)
+		if !ok || isLocalPath(id) {
+			continue
+		}
+		add(provider, id, refOccurrence{file: rel, line: lineAt(src, m[0]), symbol: "config"})
+	}
+}
+
+// readModelsFile reads one model per line. Blank lines and "#" comments are
+// ignored. A line may carry an explicit "provider:" prefix (e.g.
+// "openai:gpt-4o"); otherwise Bedrock IDs are recognised by shape and
+// everything else is taken to be a HuggingFace model ID.
+func readModelsFile(path string, add func(provider, id string, occ refOccurrence)) error {
+	f, err := os.Open(path) // #nosec G304 -- user-supplied models file
+	if err != nil {
+		return fmt.Errorf("reading models file: %w", err)
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	line := 0
+	for s.Scan() {
+		line++
+		text := strings.TrimSpace(s.Text())
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = strings.TrimSpace(text[:i])
+		}
+		if text == "" {
+			continue
+		}
+		provider, id := splitProvider(text)
+		if provider == "" {
+			if provider, _ = classifyModelID(id); provider == "" {
+				provider = providerHuggingFace
+			}
+		}
+		id = strings.TrimPrefix(id, hfWebBase+"/")
+		add(provider, id, refOccurrence{file: filepath.ToSlash(path), line: line, symbol: "models-file"})
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("reading models file: %w", err)
+	}
+	return nil
+}
+
+// splitProvider splits an optional "provider:" prefix off a models-file
+// entry. Only known providers are recognised so Bedrock version suffixes
+// ("anthropic.claude-v2:1") are left alone.
+func splitProvider(s string) (provider, id string) {
+	p, rest, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", s
+	}
+	switch strings.ToLower(p) {
+	case "hf", providerHuggingFace:
+		return providerHuggingFace, rest
+	case providerBedrock:
+		return providerBedrock, rest
+	}
+	for _, known := range frameworkClasses {
+		if strings.EqualFold(p, known) {
+			return known, rest
+		}
+	}
+	return "", s
+}
+
+// classifyModelID infers the provider from the shape of a model ID.
+func classifyModelID(id string) (string, bool) {
+	switch {
+	case bedrockIDRe.MatchString(id):
+		return providerBedrock, true
+	case isHubID(id):
+		return providerHuggingFace, true
+	}
+	return "", false
+}
+
+// isHubID reports whether id looks like an "owner/name" Hub model ID rather
+// than a file path.
+func isHubID(id string) bool {
+	return hfIDRe.MatchString(id) && !hasFileExt(id)
+}
+
+// hasFileExt reports whether id ends in a weight, config or source file
+// extension, i.e. names a file rather than a model.
+func hasFileExt(id string) bool {
+	ext := strings.ToLower(filepath.Ext(id))
+	return weightExts[ext] || configExts[ext] || sourceExts[ext] || ext == ".txt"
+}
+
+func isLocalPath(id string) bool {
+	return strings.HasPrefix(id, ".") || strings.HasPrefix(id, "/") || strings.HasPrefix(id, "~") ||
+		strings.ContainsAny(id, "{}$\\")
+}
+
+func lineAt(src string, offset int) int {
+	return strings.Count(src[:offset], "\n") + 1
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// BOM construction
+// ──────────────────────────────────────────────────────────────────────────────
+
+// buildScanBOM builds the application AIBOM. Hub models are resolved with
+// hub, together with opts.LineageDepth generations of base models; a model
+// that cannot be fetched is still listed, with the error recorded as a
+// property, so one private or renamed model does not hide the rest of the
+// inventory.
+func buildScanBOM(refs []*modelRef, opts *Options, hub hubFetcher) *cdx.BOM {
+	doc := cdx.NewBOM()
+	doc.SerialNumber = "urn:uuid:" + uuid.New().String()
+	doc.JSONSchema = jsonSchema17
+
+	app := scanApplication(opts)
+	doc.Metadata = &cdx.Metadata{
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		Lifecycles: &[]cdx.Lifecycle{{Phase: cdx.LifecyclePhaseBuild}},
+		Tools: &cdx.ToolsChoice{
+			Components: &[]cdx.Component{toolComponent},
+		},
+		Component: &app,
+	}
+
+	g := newBOMGraph(app.BOMRef)
+	l := newLineage(g, hub, opts.LineageDepth)
+
+	// Directly referenced models first, so a base model that is also used
+	// directly links to the fully resolved component.
+	type resolvedModel struct {
+		ref, key string
+		info     *hfModelInfo
+	}
+	var resolved []resolvedModel
+	for _, r := range refs {
+		var comp cdx.Component
+		switch r.Provider {
+		case providerHuggingFace:
+			info, err := hub.model(r.ID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: resolving %s: %v\n", r.ID, err)
+				comp = hubStubComponent(r.ID)
+				comp.Properties = &[]cdx.Property{{Name: "resolutionError", Value: err.Error()}}
+				break
+			}
+			comp = buildModelComponent(info, &Options{})
+			annotateSerialization(&comp, checkSiblings(info.Siblings))
+			resolved = append(resolved, resolvedModel{comp.BOMRef, modelKey(r.Provider, r.ID), info})
+			opts.models = append(opts.models, hubSubjects(info)...)
+		case providerBedrock:
+			comp = bedrockRefComponent(r.ID)
+		default:
+			comp = modelComponent(r.Provider, ProviderModel{ID: r.ID})
+		}
+		comp.Evidence = refEvidence(r)
+		ref := g.add(comp)
+		g.byModel[modelKey(r.Provider, r.ID)] = ref
+		g.link(app.BOMRef, ref)
+	}
+
+	// Then the datasets and base-model lineage of each Hub model.
+	for _, m := range resolved {
+		l.expand(m.ref, m.info, 0, []string{m.key})
+	}
+
+	doc.Components = &g.comps
+	doc.Dependencies = g.dependencies()
+	enforceRequiredFields(doc)
+	return doc
+}
+
+// scanApplication builds the metadata.component describing the scanned
+// application.
+func scanApplication(opts *Options) cdx.Component {
+	name := opts.Name
+	if name == "" && opts.Path != "" {
+		if abs, err := filepath.Abs(opts.Path); err == nil {
+			name = filepath.Base(abs)
+		}
+	}
+	if name == "" {
+		name = "application"
+	}
+	app := cdx.Component{
+		BOMRef:  "application/" + name,
+		Type:    cdx.ComponentTypeApplication,
+		Name:    name,
+		Version: opts.Version,
+	}
+	if opts.Version != "" {
+		app.BOMRef += "@" + opts.Version
+	}
+	if opts.Manufacturer != "" {
+		app.Supplier = &cdx.OrganizationalEntity{Name: opts.Manufacturer}
+	}
+	return app
+}
+
+// hubStubComponent describes a Hub model that was not (or could not be)
+// fetched.
+func hubStubComponent(id string) cdx.Component {
+	owner, name := splitModelID(id)
+	purl := buildPURL(owner, name, "")
+	return cdx.Component{
+		BOMRef:     buildBOMRef(purl, id, ""),
+		Type:       cdx.ComponentTypeMachineLearningModel,
+		Name:       id,
+		Group:      owner,
+		PackageURL: purl,
+		ExternalReferences: &[]cdx.ExternalReference{
+			{Type: cdx.ERTypeWebsite, URL: fmt.Sprintf("%s/%s", hfWebBase, id)},
+		},
+	}
+}
+
+// bedrockRefComponent describes a Bedrock model referenced by ID. Only the
+// ID is known without calling the Bedrock API; the provider is its prefix.
+func bedrockRefComponent(id string) cdx.Component {
+	m := types.FoundationModelSummary{ModelId: aws.String(id)}
+	if !strings.HasPrefix(id, "arn:") {
+		parts := strings.Split(id, ".")
+		provider := parts[0]
+		if len(parts) > 2 && bedrockIDRe.MatchString(strings.Join(parts[1:], ".")) {
+			provider = parts[1] // cross-region inference profile, e.g. "us.anthropic..."
+		}
+		m.ProviderName = aws.String(provider)
+	}
+	return bedrockModelComponent(m, &BedrockOptions{})
+}
+
+// refEvidence records where a model was referenced.
+func refEvidence(r *modelRef) *cdx.Evidence {
+	occs := make([]cdx.EvidenceOccurrence, 0, len(r.occurrences))
+	for _, o := range r.occurrences {
+		line := o.line
+		occs = append(occs, cdx.EvidenceOccurrence{Location: o.file, Line: &line, Symbol: o.symbol})
+	}
+	return &cdx.Evidence{Occurrences: &occs}
+}
+
+func modelKey(provider, id string) string {
+	if provider == providerHuggingFace {
+		return provider + "|" + strings.ToLower(id)
+	}
+	return provider + "|" + id
+}
+
+// bomGraph accumulates components and dependency edges. The root is the
+// subject of the BOM (the scanned application, or the model for a single
+// model AIBOM).
+type bomGraph struct {
+	root    string
+	comps   []cdx.Component
+	byRef   map[string]bool
+	byModel map[string]string // modelKey → bom-ref
+	deps    map[string][]string
+	order   []string
+}
+
+func newBOMGraph(root string) *bomGraph {
+	return &bomGraph{
+		root:    root,
+		byRef:   map[string]bool{},
+		byModel: map[string]string{},
+		deps:    map[string][]string{},
+	}
+}
+
+// add appends c unless a component with the same bom-ref exists, and returns
+// the bom-ref.
+func (g *bomGraph) add(c cdx.Component) string {
+	if !g.byRef[c.BOMRef] {
+		g.byRef[c.BOMRef] = true
+		g.comps = append(g.comps, c)
+		g.order = append(g.order, c.BOMRef)
+	}
+	return c.BOMRef
+}
+
+// component returns the component with the given bom-ref. The pointer is
+// only valid until the next add.
+func (g *bomGraph) component(ref string) *cdx.Component {
+	for i := range g.comps {
+		if g.comps[i].BOMRef == ref {
+			return &g.comps[i]
+		}
+	}
+	return nil
+}
+
+func (g *bomGraph) link(from, to string) {
+	if from == "" || to == "" || from == to {
+		return
+	}
+	for _, d := range g.deps[from] {
+		if d == to {
+			return
+		}
+	}
+	g.deps[from] = append(g.deps[from], to)
+}
+
+// dependencies returns the graph with the root first, followed by every
+// component in insertion order.
+func (g *bomGraph) dependencies() *[]cdx.Dependency {
+	refs := []string{g.root}
+	for _, ref := range g.order {
+		if ref != g.root {
+			refs = append(refs, ref)
+		}
+	}
+	deps := make([]cdx.Dependency, 0, len(refs))
+	for _, ref := range refs {
+		d := cdx.Dependency{Ref: ref}
+		if on := g.deps[ref]; len(on) > 0 {
+			on := append([]string(nil), on...)
+			d.Dependencies = &on
+		}
+		deps = append(deps, d)
+	}
+	return &deps
+}
@@ -13,17 +13,60 @@ type Options struct {
 	// HuggingFace API token (optional; required for private models)
 	Token string
 
+	// Local model directory or weight file (aibom local), or the source tree
+	// searched for model references (aibom scan)
+	Path string
+
+	// File listing model IDs, one per line (aibom scan)
+	ModelsFile string
+
 	// Metadata overrides
 	Name         string // override model name
 	Version      string // override model version
 	Manufacturer string // override manufacturer / supplier name
 
+	// LineageDepth is the number of base_model generations resolved from the
+	// Hub (aibom generate / scan); 0 disables lineage resolution.
+	LineageDepth int
+
+	// Fail generation when pickle-based weights are unsafe: dangerous
+	// imports were found, or no safetensors alternative exists for weights
+	// that could not be scanned.
+	FailOnUnsafe bool
+
 	// Output
 	OutputTo string // write AIBOM JSON to this file instead of stdout
 	Format   string // "json" or "table"
 
+	// Provenance recorded in metadata (see bom.Stamp)
+	ToolVersion   string // knoxctl version (version.GitSummary)
+	Deterministic bool   // reproducible output: sorted components, content-derived serial number
+
 	// Signing options — sign the output artifact with cosign.
 	Sign sign.Options
+
+	// models are the Hub models described, pinned by commit SHA; set during
+	// generation.
+	models []sign.Subject
+
+	// inputs are the digests of the local model or scanned source tree,
+	// resolved once by Output for both the provenance block and the
+	// attestation.
+	inputs []sign.Subject
+}
+
+// recordedOptions are the generation options recorded in the provenance
+// block. The Hub token and the output and signing settings are left out.
+type recordedOptions struct {
+	ModelID       string `json:"model,omitempty"`
+	Path          string `json:"path,omitempty"`
+	ModelsFile    string `json:"modelsFile,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Version       string `json:"version,omitempty"`
+	Manufacturer  string `json:"manufacturer,omitempty"`
+	LineageDepth  int    `json:"lineageDepth,omitempty"`
+	FailOnUnsafe  bool   `json:"failOnUnsafeWeights,omitempty"`
+	Deterministic bool   `json:"deterministic,omitempty"`
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -140,6 +183,26 @@ type hfFile struct {
 	Size      int64  `json:"size"`
 }
 
+// hfDatasetInfo is the response from GET https://huggingface.co/api/datasets/{id}.
+type hfDatasetInfo struct {
+	ID           string         `json:"id"`
+	Author       string         `json:"author"`
+	SHA          string         `json:"sha"`
+	LastModified string         `json:"lastModified"`
+	Description  string         `json:"description"`
+	Tags         []string       `json:"tags"`
+	CardData     *hfDatasetCard `json:"cardData"`
+	Gated        interface{}    `json:"gated"`
+}
+
+// hfDatasetCard is the parsed YAML front-matter from the dataset card README.
+type hfDatasetCard struct {
+	License        interface{} `json:"license"`
+	PrettyName     string      `json:"pretty_name"`
+	TaskCategories []string    `json:"task_categories"`
+	SizeCategories []string    `json:"size_categories"`
+}
+
 // hfConfig is the parsed config.json for the model.
 type hfConfig struct {
 	ModelType             string   `json:"model_type"`
@@ -0,0 +1,215 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package aibom
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2/google"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+)
+
+const vertexScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// vertexProvider inventories the models registered in a Vertex AI Model
+// Registry location.
+type vertexProvider struct {
+	endpoint string // e.g. https://us-central1-aiplatform.googleapis.com/v1
+	project  string
+	region   string
+	token    string
+	client   *http.Client
+}
+
+func newVertexProvider(opts *ProviderOptions) (Provider, error) {
+	project := opts.Project
+	if project == "" {
+		project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	if project == "" {
+		return nil, fmt.Errorf("--project is required for Vertex AI")
+	}
+	region := opts.Region
+	if region == "" {
+		region = "us-central1"
+	}
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s-aiplatform.googleapis.com/v1", region)
+	}
+	return &vertexProvider{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		project:  project,
+		region:   region,
+		token:    opts.Token,
+		client:   &http.Client{Timeout: providerTimeout},
+	}, nil
+}
+
+// vertexModel is a Model resource of the Vertex AI REST API.
+type vertexModel struct {
+	Name           string            `json:"name"` // projects/{p}/locations/{l}/models/{id}
+	DisplayName    string            `json:"displayName"`
+	Description    string            `json:"description"`
+	VersionID      string            `json:"versionId"`
+	VersionAliases []string          `json:"versionAliases"`
+	CreateTime     time.Time         `json:"createTime"`
+	Labels         map[string]string `json:"labels"`
+	ArtifactURI    string            `json:"artifactUri"`
+	MetadataSchema string            `json:"metadataSchemaUri"`
+	ContainerSpec  *struct {
+		ImageURI string `json:"imageUri"`
+	} `json:"containerSpec"`
+	DeployedModels []struct {
+		Endpoint string `json:"endpoint"`
+	} `json:"deployedModels"`
+	ModelSourceInfo *struct {
+		SourceType string `json:"sourceType"`
+	} `json:"modelSourceInfo"`
+	BaseModelSource *struct {
+		ModelGardenSource *struct {
+			PublicModelName string `json:"publicModelName"`
+		} `json:"modelGardenSource"`
+		GenieSource *struct {
+			BaseModelURI string `json:"baseModelUri"`
+		} `json:"genieSource"`
+	} `json:"baseModelSource"`
+}
+
+func (p *vertexProvider) Name() string { return "vertexai" }
+
+// headers authenticates with the configured access token or Application
+// Default Credentials.
+func (p *vertexProvider) headers(ctx context.Context) (map[string]string, error) {
+	token := p.token
+	if token == "" {
+		ts, err := google.DefaultTokenSource(ctx, vertexScope)
+		if err != nil {
+			return nil, fmt.Errorf("no access token set and no Google credentials available: %w", err)
+		}
+		tok, err := ts.Token()
+		if err != nil {
+			return nil, fmt.Errorf("acquiring Google access token: %w", err)
+		}
+		token = tok.AccessToken
+	}
+	return map[string]string{"Authorization": "Bearer " + token}, nil
+}
+
+func (p *vertexProvider) parent() string {
+	return fmt.Sprintf("%s/projects/%s/locations/%s", p.endpoint, url.PathEscape(p.project), url.PathEscape(p.region))
+}
+
+func (p *vertexProvider) ListModels(ctx context.Context) ([]ProviderModel, error) {
+	hdr, err := p.headers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var models []ProviderModel
+	pageToken := ""
+	for {
+		u := p.parent() + "/models?pageSize=100"
+		if pageToken != "" {
+			u += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		var out struct {
+			Models        []vertexModel `json:"models"`
+			NextPageToken string        `json:"nextPageToken"`
+		}
+		if err := doJSON(ctx, p.client, http.MethodGet, u, hdr, nil, &out); err != nil {
+			return nil, err
+		}
+		for _, m := range out.Models {
+			models = append(models, p.toModel(m))
+		}
+		if out.NextPageToken == "" {
+			return models, nil
+		}
+		pageToken = out.NextPageToken
+	}
+}
+
+func (p *vertexProvider) DescribeModel(ctx context.Context, id string) (*ProviderModel, error) {
+	hdr, err := p.headers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Accept the bare model ID or the full resource name.
+	if i := strings.LastIndex(id, "/models/"); i >= 0 {
+		id = id[i+len("/models/"):]
+	}
+	var m vertexModel
+	if err := doJSON(ctx, p.client, http.MethodGet, p.parent()+"/models/"+url.PathEscape(id), hdr, nil, &m); err != nil {
+		return nil, err
+	}
+	pm := p.toModel(m)
+	return &pm, nil
+}
+
+func (p *vertexProvider) toModel(m vertexModel) ProviderModel {
+	id := m.Name[strings.LastIndex(m.Name, "/")+1:]
+	pm := ProviderModel{
+		ID:          id,
+		Name:        m.DisplayName,
+		Owner:       p.project,
+		Version:     m.VersionID,
+		Description: m.Description,
+		Created:     m.CreateTime,
+		URL: fmt.Sprintf("https://console.cloud.google.com/vertex-ai/models/locations/%s/models/%s?project=%s",
+			p.region, id, p.project),
+		Properties: map[string]string{"resourceName": m.Name, "region": p.region},
+	}
+	add := func(k, v string) {
+		if v != "" {
+			pm.Properties[k] = v
+		}
+	}
+	add("artifactUri", m.ArtifactURI)
+	add("metadataSchemaUri", m.MetadataSchema)
+	add("versionAliases", strings.Join(m.VersionAliases, ","))
+	if m.ContainerSpec != nil {
+		add("servingContainer", m.ContainerSpec.ImageURI)
+	}
+	if m.ModelSourceInfo != nil {
+		add("sourceType", m.ModelSourceInfo.SourceType)
+	}
+	if len(m.DeployedModels) > 0 {
+		endpoints := make([]string, 0, len(m.DeployedModels))
+		for _, d := range m.DeployedModels {
+			endpoints = append(endpoints, d.Endpoint)
+		}
+		add("deployedEndpoints", strings.Join(endpoints, ","))
+	}
+	for _, k := range sortedStringKeys(m.Labels) {
+		add("label."+k, m.Labels[k])
+	}
+	if b := m.BaseModelSource; b != nil {
+		switch {
+		case b.ModelGardenSource != nil:
+			pm.BaseModel = b.ModelGardenSource.PublicModelName
+		case b.GenieSource != nil:
+			pm.BaseModel = b.GenieSource.BaseModelURI
+		}
+	}
+	return pm
+}
+
+func (p *vertexProvider) Component(m ProviderModel) cdx.Component {
+	comp := modelComponent(p.Name(), m)
+	// Registry model IDs are numeric; qualify the purl with the project so
+	// components from different projects do not collide.
+	comp.PackageURL = fmt.Sprintf("pkg:generic/vertexai/%s/%s", p.project, m.ID)
+	if m.Version != "" {
+		comp.PackageURL += "@" + m.Version
+	}
+	comp.BOMRef = comp.PackageURL
+	return comp
+}
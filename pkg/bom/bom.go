@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+// Package bom provides operations over existing CycloneDX documents — SBOMs
+// from the sbomgen/pkgscan tools, CBOMs from pkg/cbom and AIBOMs from
+// pkg/aibom: comparing two revisions of a document, merging documents that
+// describe the same product, and attaching VEX analysis to vulnerabilities.
+package bom
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+)
+
+// Load reads and decodes a CycloneDX JSON document from path.
+//
+// Documents declaring a specVersion newer than the library supports (the
+// AIBOM is emitted as 1.7) are decoded as 1.6; the 1.7 additions are a
+// superset, so nothing the diff relies on is lost.
+func Load(path string) (*cdx.BOM, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is supplied by the user
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return Decode(data)
+}
+
+// Decode decodes a CycloneDX JSON document.
+func Decode(data []byte) (*cdx.BOM, error) {
+	var head struct {
+		BOMFormat   string `json:"bomFormat"`
+		SpecVersion string `json:"specVersion"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, fmt.Errorf("parsing BOM: %w", err)
+	}
+	if head.BOMFormat != cdx.BOMFormat {
+		return nil, fmt.Errorf("not a CycloneDX document (bomFormat %q)", head.BOMFormat)
+	}
+	if !supportedSpecVersion(head.SpecVersion) {
+		// Decode newer documents as the latest version the library knows.
+		var doc map[string]json.RawMessage
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parsing BOM: %w", err)
+		}
+		doc["specVersion"] = json.RawMessage(strconv.Quote(cdx.SpecVersion1_6.String()))
+		var err error
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, fmt.Errorf("parsing BOM: %w", err)
+		}
+	}
+
+	bom := new(cdx.BOM)
+	if err := cdx.NewBOMDecoder(bytes.NewReader(data), cdx.BOMFileFormatJSON).Decode(bom); err != nil {
+		return nil, fmt.Errorf("decoding BOM: %w", err)
+	}
+	return bom, nil
+}
+
+// supportedSpecVersion reports whether the CycloneDX library can decode v.
+func supportedSpecVersion(v string) bool {
+	for sv := cdx.SpecVersion1_0; sv <= cdx.SpecVersion1_6; sv++ {
+		if sv.String() == v {
+			return true
+		}
+	}
+	return false
+}
+
+// writeOutput writes data to path, or to stdout when path is empty.
+func writeOutput(data []byte, path, what string) error {
+	if path == "" {
+		fmt.Print(string(data))
+		return nil
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("writing %s to %s: %w", what, path, err)
+	}
+	fmt.Printf("%s written to %s\n", what, path)
+	return nil
+}
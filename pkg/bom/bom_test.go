@@ -0,0 +1,592 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package bom
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/registry/remote"
+
+	"github.com/accuknox/accuknox-cli-v2/pkg/sign"
+)
+
+// ── Load ──────────────────────────────────────────────────────────────────────
+
+func TestDecode_SpecVersion17(t *testing.T) {
+	doc := `{"bomFormat": "CycloneDX", "specVersion": "1.7", "version": 1,
+		"components": [{"type": "machine-learning-model", "name": "gpt2"}]}`
+	bom, err := Decode([]byte(doc))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if bom.Components == nil || len(*bom.Components) != 1 {
+		t.Fatalf("expected 1 component, got %+v", bom.Components)
+	}
+	if bom.SpecVersion != cdx.SpecVersion1_6 {
+		t.Errorf("SpecVersion = %v, want 1.6", bom.SpecVersion)
+	}
+}
+
+func TestDecode_SpecVersion17Compact(t *testing.T) {
+	doc := `{"specVersion":"1.7","bomFormat":"CycloneDX","version":1,` +
+		`"metadata":{"component":{"type":"application","name":"app","description":"\"specVersion\": \"1.7\""}}}`
+	bom, err := Decode([]byte(doc))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if bom.Metadata == nil || bom.Metadata.Component == nil ||
+		bom.Metadata.Component.Description != `"specVersion": "1.7"` {
+		t.Errorf("metadata = %+v", bom.Metadata)
+	}
+}
+
+func TestDecode_NotCycloneDX(t *testing.T) {
+	if _, err := Decode([]byte(`{"spdxVersion": "SPDX-2.3"}`)); err == nil {
+		t.Error("expected error for non-CycloneDX document")
+	}
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+// ── purlBase ──────────────────────────────────────────────────────────────────
+
+func TestPurlBase(t *testing.T) {
+	cases := map[string]string{
+		"pkg:golang/golang.org/x/crypto@v0.51.0":          "pkg:golang/golang.org/x/crypto",
+		"pkg:npm/%40angular/core@17.0.0?arch=x64":         "pkg:npm/%40angular/core",
+		"pkg:huggingface/openai-community/gpt2@607a30d7":  "pkg:huggingface/openai-community/gpt2",
+		"pkg:deb/debian/openssl@3.0.11-1~deb12u2#sub/dir": "pkg:deb/debian/openssl",
+		"pkg:generic/noversion":                           "pkg:generic/noversion",
+	}
+	for in, want := range cases {
+		if got := purlBase(in); got != want {
+			t.Errorf("purlBase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// ── Diff ──────────────────────────────────────────────────────────────────────
+
+func TestDiff_AddedRemovedChanged(t *testing.T) {
+	oldBOM := testBOM(
+		cdx.Component{Type: cdx.ComponentTypeLibrary, Name: "crypto", Version: "v0.50.0",
+			PackageURL: "pkg:golang/golang.org/x/crypto@v0.50.0", Licenses: licenses("BSD-3-Clause")},
+		cdx.Component{Type: cdx.ComponentTypeLibrary, Name: "yaml", Version: "v3.0.0",
+			PackageURL: "pkg:golang/gopkg.in/yaml.v3@v3.0.0"},
+	)
+	newBOM := testBOM(
+		cdx.Component{Type: cdx.ComponentTypeLibrary, Name: "crypto", Version: "v0.51.0",
+			PackageURL: "pkg:golang/golang.org/x/crypto@v0.51.0", Licenses: licenses("MIT")},
+		cdx.Component{Type: cdx.ComponentTypeLibrary, Name: "zap", Version: "v1.27.0",
+			PackageURL: "pkg:golang/go.uber.org/zap@v1.27.0"},
+	)
+
+	r := Diff(oldBOM, newBOM)
+	if len(r.Added) != 1 || r.Added[0].Name != "zap" {
+		t.Errorf("Added = %+v, want [zap]", r.Added)
+	}
+	if len(r.Removed) != 1 || r.Removed[0].Name != "yaml" {
+		t.Errorf("Removed = %+v, want [yaml]", r.Removed)
+	}
+	if len(r.Changed) != 1 {
+		t.Fatalf("Changed = %+v, want 1 entry", r.Changed)
+	}
+	got := map[string]FieldChange{}
+	for _, c := range r.Changed[0].Changes {
+		got[c.Category+":"+c.Field] = c
+	}
+	if c := got["component:version"]; c.Old != "v0.50.0" || c.New != "v0.51.0" {
+		t.Errorf("version change = %+v", c)
+	}
+	if c := got["license:licenses"]; c.Old != "BSD-3-Clause" || c.New != "MIT" {
+		t.Errorf("license change = %+v", c)
+	}
+	if len(got) != 2 {
+		t.Errorf("unexpected extra changes: %+v", got)
+	}
+}
+
+func TestDiff_DuplicatePurlReordered(t *testing.T) {
+	v1 := cdx.Component{Type: cdx.ComponentTypeLibrary, Name: "lodash", Version: "4.17.20",
+		PackageURL: "pkg:npm/lodash@4.17.20"}
+	v2 := cdx.Component{Type: cdx.ComponentTypeLibrary, Name: "lodash", Version: "4.17.21",
+		PackageURL: "pkg:npm/lodash@4.17.21"}
+
+	r := Diff(testBOM(v1, v2), testBOM(v2, v1))
+	if len(r.Added) != 0 || len(r.Removed) != 0 || len(r.Changed) != 0 {
+		t.Errorf("reordered duplicates: %+v", r)
+	}
+
+	v3 := v2
+	v3.Version, v3.PackageURL = "4.17.22", "pkg:npm/lodash@4.17.22"
+	r = Diff(testBOM(v1, v2), testBOM(v3, v1))
+	if len(r.Added) != 1 || r.Added[0].Version != "4.17.22" {
+		t.Errorf("Added = %+v, want [4.17.22]", r.Added)
+	}
+	if len(r.Removed) != 1 || r.Removed[0].Version != "4.17.21" {
+		t.Errorf("Removed = %+v, want [4.17.21]", r.Removed)
+	}
+	if len(r.Changed) != 0 {
+		t.Errorf("Changed = %+v, want none", r.Changed)
+	}
+
+	r = Diff(testBOM(v1), testBOM(v2, v1))
+	if len(r.Added) != 1 || r.Added[0].Version != "4.17.21" || len(r.Removed) != 0 || len(r.Changed) != 0 {
+		t.Errorf("second version added: %+v", r)
+	}
+}
+
+func TestDiff_IdenticalDuplicates(t *testing.T) {
+	c := cdx.Component{Type: cdx.ComponentTypeLibrary, Name: "lodash", Version: "4.17.21",
+		PackageURL: "pkg:npm/lodash@4.17.21"}
+
+	all := flattenComponents(testBOM(c, c, c))
+	if got := len(indexComponents(all, duplicateKeys(all))); got != 3 {
+		t.Errorf("indexComponents kept %d of 3 identical components", got)
+	}
+	r := Diff(testBOM(c), testBOM(c, c))
+	if len(r.Added) != 1 || len(r.Removed) != 0 || len(r.Changed) != 0 {
+		t.Errorf("one more identical component: %+v", r)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate(strings.Repeat("é", 70)); got != strings.Repeat("é", 57)+"..." {
+		t.Errorf("truncate split a character: %q", got)
+	}
+	if got := truncate("short\nvalue"); got != "short value" {
+		t.Errorf("truncate = %q", got)
+	}
+}
+
+func TestDiff_CryptoProperties(t *testing.T) {
+	cert := func(notAfter string) cdx.Component {
+		return cdx.Component{
+			Type:   cdx.ComponentTypeCryptographicAsset,
+			Name:   "example.com",
+			BOMRef: "crypto/certificate/abc",
+			CryptoProperties: &cdx.CryptoProperties{
+				AssetType: cdx.CryptoAssetTypeCertificate,
+				CertificateProperties: &cdx.CertificateProperties{
+					SubjectName:   "CN=example.com",
+					NotValidAfter: notAfter,
+				},
+			},
+		}
+	}
+	r := Diff(testBOM(cert("2025-01-01T00:00:00Z")), testBOM(cert("2026-01-01T00:00:00Z")))
+	if len(r.Changed) != 1 || len(r.Changed[0].Changes) != 1 {
+		t.Fatalf("Changed = %+v, want exactly one field change", r.Changed)
+	}
+	c := r.Changed[0].Changes[0]
+	if c.Category != CategoryCrypto || c.Field != "certificateProperties.notValidAfter" {
+		t.Errorf("change = %+v", c)
+	}
+}
+
+func TestDiff_ModelCard(t *testing.T) {
+	model := func(task string) cdx.Component {
+		return cdx.Component{
+			Type:       cdx.ComponentTypeMachineLearningModel,
+			Name:       "gpt2",
+			PackageURL: "pkg:huggingface/openai-community/gpt2@abc",
+			ModelCard: &cdx.MLModelCard{
+				ModelParameters: &cdx.MLModelParameters{Task: task},
+			},
+		}
+	}
+	r := Diff(testBOM(model("text-generation")), testBOM(model("text-classification")))
+	if len(r.Changed) != 1 {
+		t.Fatalf("Changed = %+v, want 1 entry", r.Changed)
+	}
+	c := r.Changed[0].Changes[0]
+	if c.Category != CategoryModel || c.Field != "modelParameters.task" || c.New != "text-classification" {
+		t.Errorf("change = %+v", c)
+	}
+}
+
+func TestDiff_Identical(t *testing.T) {
+	comp := cdx.Component{Type: cdx.ComponentTypeLibrary, Name: "a", Version: "1",
+		Licenses: licenses("MIT", "Apache-2.0")}
+	reordered := comp
+	reordered.Licenses = licenses("Apache-2.0", "MIT")
+	if r := Diff(testBOM(comp), testBOM(reordered)); r.HasChanges() {
+		t.Errorf("expected no changes, got %+v", r)
+	}
+}
+
+// ── Output ────────────────────────────────────────────────────────────────────
+
+func TestOutputDiff_Markdown(t *testing.T) {
+	r := Diff(testBOM(), testBOM(cdx.Component{Type: cdx.ComponentTypeLibrary, Name: "a|b", Version: "1"}))
+	out := filepath.Join(t.TempDir(), "diff.md")
+	if err := OutputDiff(r, &DiffOptions{Format: "markdown", OutputTo: out}); err != nil {
+		t.Fatalf("OutputDiff: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := string(data)
+	if !strings.Contains(md, "### Added") || !strings.Contains(md, `a\|b`) {
+		t.Errorf("unexpected markdown:\n%s", md)
+	}
+}
+
+func TestOutputDiff_UnknownFormat(t *testing.T) {
+	if err := OutputDiff(&DiffReport{}, &DiffOptions{Format: "xml"}); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
+// ── Merge ─────────────────────────────────────────────────────────────────────
+
+func TestMerge_DeduplicatesAndUnifiesGraph(t *testing.T) {
+	sbom := testBOM(
+		cdx.Component{Type: cdx.ComponentTypeLibrary, Name: "crypto", Version: "v0.51.0", BOMRef: "lib-crypto",
+			PackageURL: "pkg:golang/golang.org/x/crypto@v0.51.0", Licenses: licenses("unknown")},
+	)
+	sbom.Metadata = &cdx.Metadata{Component: &cdx.Component{Type: cdx.ComponentTypeApplication, Name: "app", BOMRef: "app-ref"}}
+	sbom.Dependencies = &[]cdx.Dependency{{Ref: "app-ref", Dependencies: &[]string{"lib-crypto"}}}
+
+	cbom := testBOM(
+		cdx.Component{Type: cdx.ComponentTypeLibrary, Name: "crypto", Version: "v0.51.0", BOMRef: "other-ref",
+			PackageURL: "pkg:golang/golang.org/x/crypto@v0.51.0", Licenses: licenses("BSD-3-Clause")},
+		// Same bom-ref as the SBOM library but a different component.
+		cdx.Component{Type: cdx.ComponentTypeCryptographicAsset, Name: "RSA", BOMRef: "lib-crypto"},
+	)
+	cbom.Metadata = &cdx.Metadata{Component: &cdx.Component{Type: cdx.ComponentTypeApplication, Name: "./app", BOMRef: "./app"}}
+
+	merged, err := Merge([]*cdx.BOM{sbom, cbom}, &MergeOptions{})
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if merged.Metadata.Component.BOMRef != "app-ref" {
+		t.Errorf("root = %q, want first input's metadata.component", merged.Metadata.Component.BOMRef)
+	}
+	comps := *merged.Components
+	if len(comps) != 2 {
+		t.Fatalf("expected 2 components after dedup, got %d", len(comps))
+	}
+	if got := licenseString(comps[0].Licenses); got != "BSD-3-Clause" {
+		t.Errorf("merged licenses = %q, want the unknown placeholder replaced", got)
+	}
+	if comps[1].BOMRef == "lib-crypto" {
+		t.Error("colliding bom-ref should have been renamed")
+	}
+
+	deps := *merged.Dependencies
+	if len(deps) != 1 || deps[0].Ref != "app-ref" {
+		t.Fatalf("dependencies = %+v, want a single root entry", deps)
+	}
+	on := *deps[0].Dependencies
+	want := []string{"lib-crypto", comps[1].BOMRef}
+	if len(on) != 2 || !contains(on, want[0]) || !contains(on, want[1]) {
+		t.Errorf("root dependsOn = %v, want %v", on, want)
+	}
+}
+
+func TestMerge_NameOverride(t *testing.T) {
+	merged, err := Merge([]*cdx.BOM{testBOM(), testBOM()},
+		&MergeOptions{Name: "product", Group: "com.example", Version: "1.0"})
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if got := merged.Metadata.Component.BOMRef; got != "pkg:generic/com.example/product@1.0" {
+		t.Errorf("root bom-ref = %q", got)
+	}
+}
+
+func TestMerge_NoRoot(t *testing.T) {
+	if _, err := Merge([]*cdx.BOM{testBOM(), testBOM()}, &MergeOptions{}); err == nil {
+		t.Error("expected error when no input has a metadata.component")
+	}
+}
+
+// ── VEX ───────────────────────────────────────────────────────────────────────
+
+func TestApplyVEX_SplitsAndAdds(t *testing.T) {
+	bom := testBOM(
+		cdx.Component{Type: cdx.ComponentTypeLibrary, Name: "net", BOMRef: "net", PackageURL: "pkg:golang/golang.org/x/net@v0.20.0"},
+		cdx.Component{Type: cdx.ComponentTypeLibrary, Name: "grpc", BOMRef: "grpc"},
+	)
+	bom.Vulnerabilities = &[]cdx.Vulnerability{
+		{ID: "CVE-2023-45288", Affects: &[]cdx.Affects{{Ref: "net"}, {Ref: "grpc"}}},
+	}
+	vex := &VEXFile{Statements: []VEXStatement{
+		{Vulnerability: "CVE-2023-45288", Components: []string{"pkg:golang/golang.org/x/net@v0.20.0"},
+			State: "not_affected", Justification: "code_not_reachable", Response: []string{"will_not_fix"}},
+		{Vulnerability: "CVE-2024-0001", Components: []string{"grpc"}, State: "in_triage"},
+		{Vulnerability: "CVE-2024-9999", State: "false_positive"},
+	}}
+
+	res, err := ApplyVEX(bom, vex, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("ApplyVEX: %v", err)
+	}
+	if res.Updated != 1 || res.Added != 1 || len(res.Unmatched) != 1 {
+		t.Errorf("result = %+v", res)
+	}
+
+	vulns := *bom.Vulnerabilities
+	if len(vulns) != 3 {
+		t.Fatalf("expected 3 vulnerability entries, got %d", len(vulns))
+	}
+	if vulns[0].Analysis != nil || len(*vulns[0].Affects) != 1 || (*vulns[0].Affects)[0].Ref != "grpc" {
+		t.Errorf("original entry should keep only grpc without analysis: %+v", vulns[0])
+	}
+	a := vulns[1].Analysis
+	if a == nil || a.State != cdx.IASNotAffected || a.Justification != cdx.IAJCodeNotReachable ||
+		(*vulns[1].Affects)[0].Ref != "net" || a.LastUpdated != "2025-01-02T00:00:00Z" {
+		t.Errorf("split entry = %+v", vulns[1])
+	}
+	if vulns[2].ID != "CVE-2024-0001" || vulns[2].Analysis.State != cdx.IASInTriage {
+		t.Errorf("added entry = %+v", vulns[2])
+	}
+}
+
+func TestLoadVEX_InvalidState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vex.yaml")
+	doc := "statements:\n  - vulnerability: CVE-1\n    state: fixed\n"
+	if err := os.WriteFile(path, []byte(doc), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadVEX(path); err == nil || !strings.Contains(err.Error(), "invalid state") {
+		t.Errorf("expected invalid state error, got %v", err)
+	}
+}
+
+// ── OCI referrers ─────────────────────────────────────────────────────────────
+
+func TestAttachFetch_Referrers(t *testing.T) {
+	for name, opts := range map[string][]registry.Option{
+		"referrers API": {registry.WithReferrersSupport(true)},
+		"tag schema":    nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			image := pushTestImage(t, opts...)
+			dir := t.TempDir()
+			cbomPath := writeTestFile(t, dir, "cbom.json", `{"bomFormat":"CycloneDX","specVersion":"1.6","version":1,
+				"components":[{"type":"cryptographic-asset","name":"AES-256-GCM"}]}`)
+			sigPath := writeTestFile(t, dir, "cbom.json.sig", "MEUCIQ==\n")
+			attPath := writeTestFile(t, dir, "cbom.json.intoto.jsonl", `{"payloadType":"application/vnd.in-toto+json","payload":"","signatures":[]}`)
+
+			attached, err := Attach(context.Background(), &OCIOptions{
+				Image:        image,
+				BOMs:         []string{cbomPath},
+				Signatures:   []string{sigPath},
+				Attestations: []string{attPath},
+			})
+			if err != nil {
+				t.Fatalf("Attach: %v", err)
+			}
+			if len(attached) != 3 || attached[1].Subject != attached[0].Digest {
+				t.Fatalf("expected BOM, signature on the BOM and attestation, got %+v", attached)
+			}
+
+			out := t.TempDir()
+			fetched, err := Fetch(context.Background(), &OCIOptions{Image: image, OutputDir: out})
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			if len(fetched) != 3 {
+				t.Fatalf("expected 3 artifacts, got %+v", fetched)
+			}
+			if fetched[0].Kind != "cbom" || fetched[1].ArtifactType != ArtifactTypeSignature || fetched[2].ArtifactType != MediaTypeDSSE {
+				t.Errorf("unexpected artifacts %+v", fetched)
+			}
+			for _, src := range []string{cbomPath, sigPath, attPath} {
+				want, _ := os.ReadFile(src)
+				got, err := os.ReadFile(filepath.Join(out, filepath.Base(src)))
+				if err != nil || string(got) != string(want) {
+					t.Errorf("%s: fetched %q (%v), want %q", filepath.Base(src), got, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAttach_Errors(t *testing.T) {
+	image := pushTestImage(t)
+	dir := t.TempDir()
+	bomPath := writeTestFile(t, dir, "sbom.json", `{"bomFormat":"CycloneDX","specVersion":"1.6","version":1}`)
+	notBOM := writeTestFile(t, dir, "spdx.json", `{"spdxVersion":"SPDX-2.3"}`)
+
+	cases := map[string]*OCIOptions{
+		"nothing":       {Image: image},
+		"extra sig":     {Image: image, BOMs: []string{bomPath}, Signatures: []string{"a.sig", "b.sig"}},
+		"not cyclonedx": {Image: image, BOMs: []string{notBOM}},
+		"missing image": {Image: strings.Replace(image, ":v1", ":missing", 1), BOMs: []string{bomPath}},
+		"bad reference": {Image: "UPPER/case", BOMs: []string{bomPath}},
+	}
+	for name, opts := range cases {
+		if _, err := Attach(context.Background(), opts); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+// pushTestImage starts an in-process registry and pushes a minimal image
+// manifest to it, returning its tag reference.
+func pushTestImage(t *testing.T, opts ...registry.Option) string {
+	t.Helper()
+	opts = append(opts, registry.Logger(log.New(io.Discard, "", 0)))
+	srv := httptest.NewServer(registry.New(opts...))
+	t.Cleanup(srv.Close)
+
+	ref := strings.TrimPrefix(srv.URL, "http://") + "/acme/app"
+	repo, err := remote.NewRepository(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.PlainHTTP = true
+	ctx := context.Background()
+	desc, err := oras.PackManifest(ctx, repo, oras.PackManifestVersion1_1, "application/vnd.example.image", oras.PackManifestOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Tag(ctx, desc, "v1"); err != nil {
+		t.Fatal(err)
+	}
+	return ref + ":v1"
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// ── Provenance ────────────────────────────────────────────────────────────────
+
+func TestStamp_RecordsProvenance(t *testing.T) {
+	b := cdx.NewBOM()
+	b.Metadata = &cdx.Metadata{Properties: &[]cdx.Property{{Name: "keep", Value: "1"}}}
+	p := &Provenance{
+		ToolVersion: "v1.2.3",
+		Scanners:    []Scanner{{Name: "scanner", Version: "0.9", SHA256: "abcd"}},
+		Inputs:      []sign.Subject{{Name: "app", Digest: map[string]string{"sha256": "ef01"}}},
+		Options:     map[string]string{"path": "./app"},
+	}
+	// Stamping twice must not duplicate properties or tools.
+	for i := 0; i < 2; i++ {
+		if err := Stamp(b, p); err != nil {
+			t.Fatalf("Stamp: %v", err)
+		}
+	}
+
+	props := map[string]string{}
+	for _, prop := range *b.Metadata.Properties {
+		if _, dup := props[prop.Name]; dup {
+			t.Errorf("duplicate property %s", prop.Name)
+		}
+		props[prop.Name] = prop.Value
+	}
+	want := map[string]string{
+		"keep":                              "1",
+		ProvenancePrefix + "knoxctlVersion": "v1.2.3",
+		ProvenancePrefix + "input":          "app@sha256:ef01",
+		ProvenancePrefix + "options":        `{"path":"./app"}`,
+	}
+	for k, v := range want {
+		if props[k] != v {
+			t.Errorf("property %s = %q, want %q", k, props[k], v)
+		}
+	}
+	tools := *b.Metadata.Tools.Components
+	if len(tools) != 1 || tools[0].Version != "0.9" || (*tools[0].Hashes)[0].Value != "abcd" {
+		t.Errorf("scanner tool = %+v", tools)
+	}
+}
+
+func TestCanonicalize_Reproducible(t *testing.T) {
+	build := func(order []string) []byte {
+		b := cdx.NewBOM()
+		b.SerialNumber = "urn:uuid:" + order[0]
+		b.Metadata = &cdx.Metadata{Timestamp: time.Now().String()}
+		var comps []cdx.Component
+		for _, n := range order {
+			comps = append(comps, cdx.Component{BOMRef: n, Name: n, Type: cdx.ComponentTypeLibrary})
+		}
+		b.Components = &comps
+		deps := []cdx.Dependency{{Ref: "b", Dependencies: &[]string{"c", "a"}}, {Ref: "a"}}
+		if order[0] != "a" {
+			deps = []cdx.Dependency{{Ref: "a"}, {Ref: "b", Dependencies: &[]string{"a", "c"}}}
+		}
+		b.Dependencies = &deps
+		if err := Canonicalize(b); err != nil {
+			t.Fatalf("Canonicalize: %v", err)
+		}
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	t.Setenv(SourceDateEpochEnv, "")
+	a, b := build([]string{"a", "b", "c"}), build([]string{"c", "a", "b"})
+	if string(a) != string(b) {
+		t.Fatalf("canonical BOMs differ:\n%s\n%s", a, b)
+	}
+	if strings.Contains(string(a), `"timestamp"`) {
+		t.Error("timestamp should be omitted without $SOURCE_DATE_EPOCH")
+	}
+	if !strings.Contains(string(a), `"serialNumber":"urn:uuid:`) {
+		t.Error("serial number missing")
+	}
+
+	t.Setenv(SourceDateEpochEnv, "1700000000")
+	if c := build([]string{"a", "b", "c"}); !strings.Contains(string(c), `"timestamp":"2023-11-14T22:13:20Z"`) {
+		t.Errorf("timestamp not taken from $SOURCE_DATE_EPOCH: %s", c)
+	}
+	t.Setenv(SourceDateEpochEnv, "yesterday")
+	if err := Canonicalize(&cdx.BOM{Metadata: &cdx.Metadata{}}); err == nil {
+		t.Error("expected error for invalid $SOURCE_DATE_EPOCH")
+	}
+}
+
+// ── helpers ───────────────────────────────────────────────────────────────────
+
+func testBOM(comps ...cdx.Component) *cdx.BOM {
+	bom := cdx.NewBOM()
+	bom.Components = &comps
+	return bom
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func licenses(ids ...string) *cdx.Licenses {
+	ls := cdx.Licenses{}
+	for _, id := range ids {
+		ls = append(ls, cdx.LicenseChoice{License: &cdx.License{ID: id}})
+	}
+	return &ls
+}
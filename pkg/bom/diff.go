@@ -0,0 +1,511 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package bom
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+)
+
+// RunDiff loads the two BOMs named in opts, compares them and writes the
+// report in the requested format.
+func RunDiff(opts *DiffOptions) (*DiffReport, error) {
+	oldBOM, err := Load(opts.OldPath)
+	if err != nil {
+		return nil, err
+	}
+	newBOM, err := Load(opts.NewPath)
+	if err != nil {
+		return nil, err
+	}
+	report := Diff(oldBOM, newBOM)
+	report.Old.Path = opts.OldPath
+	report.New.Path = opts.NewPath
+	return report, OutputDiff(report, opts)
+}
+
+// Diff compares two BOMs. Components are matched by package URL (ignoring the
+// version, so upgrades show up as changes rather than remove/add pairs), then
+// by bom-ref, then by type/group/name. A package URL that occurs more than
+// once in either document is matched with its version.
+func Diff(oldBOM, newBOM *cdx.BOM) *DiffReport {
+	oldAll, newAll := flattenComponents(oldBOM), flattenComponents(newBOM)
+	dups := duplicateKeys(oldAll, newAll)
+	oldComps := indexComponents(oldAll, dups)
+	newComps := indexComponents(newAll, dups)
+
+	report := &DiffReport{
+		Old:      summarise(oldBOM, len(oldComps)),
+		New:      summarise(newBOM, len(newComps)),
+		Metadata: diffFields(metadataComponent(oldBOM), metadataComponent(newBOM)),
+		Added:    []ComponentRef{},
+		Removed:  []ComponentRef{},
+		Changed:  []ComponentChange{},
+	}
+
+	for _, key := range sortedKeys(oldComps) {
+		oc := oldComps[key]
+		nc, ok := newComps[key]
+		if !ok {
+			report.Removed = append(report.Removed, refOf(key, oc))
+			continue
+		}
+		if changes := diffFields(oc, nc); len(changes) > 0 {
+			report.Changed = append(report.Changed, ComponentChange{
+				ComponentRef: refOf(key, nc),
+				Changes:      changes,
+			})
+		}
+	}
+	for _, key := range sortedKeys(newComps) {
+		if _, ok := oldComps[key]; !ok {
+			report.Added = append(report.Added, refOf(key, newComps[key]))
+		}
+	}
+	return report
+}
+
+// OutputDiff writes the report to stdout or opts.OutputTo in opts.Format.
+func OutputDiff(r *DiffReport, opts *DiffOptions) error {
+	var data []byte
+	switch strings.ToLower(opts.Format) {
+	case "json":
+		b, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshaling diff: %w", err)
+		}
+		data = append(b, '\n')
+	case "markdown", "md":
+		data = []byte(renderMarkdown(r))
+	case "", "table":
+		if opts.OutputTo == "" {
+			return printDiffTable(r)
+		}
+		return fmt.Errorf("table output cannot be written to a file; use --format json or markdown")
+	default:
+		return fmt.Errorf("unsupported format %q: use table, json or markdown", opts.Format)
+	}
+	return writeOutput(data, opts.OutputTo, "BOM diff")
+}
+
+// ── Component indexing ───────────────────────────────────────────────────────
+
+// flattenComponents returns the (possibly nested) component tree of bom in
+// document order.
+func flattenComponents(bom *cdx.BOM) []*cdx.Component {
+	if bom == nil || bom.Components == nil {
+		return nil
+	}
+	var all []*cdx.Component
+	var walk func(comps []cdx.Component)
+	walk = func(comps []cdx.Component) {
+		for i := range comps {
+			all = append(all, &comps[i])
+			if comps[i].Components != nil {
+				walk(*comps[i].Components)
+			}
+		}
+	}
+	walk(*bom.Components)
+	return all
+}
+
+// duplicateKeys returns the componentKeys, and componentKeys with version,
+// shared by more than one component of any of the documents.
+func duplicateKeys(docs ...[]*cdx.Component) map[string]bool {
+	dups := map[string]bool{}
+	for _, comps := range docs {
+		count := map[string]int{}
+		for _, c := range comps {
+			count[componentKey(c)]++
+			count[componentKey(c)+"@"+c.Version]++
+		}
+		for key, n := range count {
+			if n > 1 {
+				dups[key] = true
+			}
+		}
+	}
+	return dups
+}
+
+// indexComponents maps comps by componentKey. A key in dups is extended by
+// version (and by bom-ref if that is in dups too), so the result does not
+// depend on the order of the components. Components that remain
+// indistinguishable are numbered in document order, so that none is dropped
+// and a change in how many there are shows up in the diff.
+func indexComponents(comps []*cdx.Component, dups map[string]bool) map[string]*cdx.Component {
+	out := map[string]*cdx.Component{}
+	for _, c := range comps {
+		key := componentKey(c)
+		if dups[key] {
+			key += "@" + c.Version
+			if dups[key] && c.BOMRef != "" {
+				key += "#" + c.BOMRef
+			}
+		}
+		if _, dup := out[key]; dup {
+			for n := 2; ; n++ {
+				if k := key + "#" + strconv.Itoa(n); out[k] == nil {
+					key = k
+					break
+				}
+			}
+		}
+		out[key] = c
+	}
+	return out
+}
+
+// componentKey returns the identity used to match c across documents.
+func componentKey(c *cdx.Component) string {
+	if c.PackageURL != "" {
+		return purlBase(c.PackageURL)
+	}
+	if c.BOMRef != "" {
+		return c.BOMRef
+	}
+	parts := []string{string(c.Type)}
+	if c.Group != "" {
+		parts = append(parts, c.Group)
+	}
+	return strings.Join(append(parts, c.Name), "/")
+}
+
+// purlBase strips the version, qualifiers and subpath from a package URL.
+func purlBase(purl string) string {
+	if i := strings.IndexAny(purl, "?#"); i >= 0 {
+		purl = purl[:i]
+	}
+	// The version separator is the last "@" after the final "/"; an "@" in
+	// the namespace (e.g. pkg:npm/%40scope) is always percent-encoded.
+	if slash := strings.LastIndex(purl, "/"); slash >= 0 {
+		if at := strings.Index(purl[slash:], "@"); at >= 0 {
+			purl = purl[:slash+at]
+		}
+	}
+	return purl
+}
+
+func refOf(key string, c *cdx.Component) ComponentRef {
+	return ComponentRef{
+		Key:     key,
+		BOMRef:  c.BOMRef,
+		Type:    string(c.Type),
+		Name:    c.Name,
+		Version: c.Version,
+		PURL:    c.PackageURL,
+	}
+}
+
+func summarise(bom *cdx.BOM, count int) DocumentSummary {
+	s := DocumentSummary{
+		SerialNumber: bom.SerialNumber,
+		SpecVersion:  bom.SpecVersion.String(),
+		Components:   count,
+	}
+	if mc := metadataComponent(bom); mc != nil {
+		s.Name = mc.Name
+		s.Version = mc.Version
+	}
+	return s
+}
+
+func metadataComponent(bom *cdx.BOM) *cdx.Component {
+	if bom.Metadata == nil {
+		return nil
+	}
+	return bom.Metadata.Component
+}
+
+// ── Field comparison ─────────────────────────────────────────────────────────
+
+// diffFields returns the field-level differences between two components,
+// sorted by category and field. Either side may be nil.
+func diffFields(oc, nc *cdx.Component) []FieldChange {
+	oldF := componentFields(oc)
+	newF := componentFields(nc)
+
+	var changes []FieldChange
+	for k, ov := range oldF {
+		if nv := newF[k]; nv != ov {
+			changes = append(changes, FieldChange{Category: k.category, Field: k.name, Old: ov, New: nv})
+		}
+	}
+	for k, nv := range newF {
+		if _, ok := oldF[k]; !ok {
+			changes = append(changes, FieldChange{Category: k.category, Field: k.name, New: nv})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Category != changes[j].Category {
+			return changes[i].Category < changes[j].Category
+		}
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
+type fieldKey struct {
+	category string
+	name     string
+}
+
+// componentFields flattens the comparable parts of c into category/field
+// pairs. Identity fields (bom-ref, purl) are excluded: they are either the
+// match key or derived from the version.
+func componentFields(c *cdx.Component) map[fieldKey]string {
+	f := map[fieldKey]string{}
+	if c == nil {
+		return f
+	}
+	set := func(cat, name, v string) {
+		if v != "" {
+			f[fieldKey{cat, name}] = v
+		}
+	}
+
+	set(CategoryComponent, "name", c.Name)
+	set(CategoryComponent, "type", string(c.Type))
+	set(CategoryComponent, "group", c.Group)
+	set(CategoryComponent, "version", c.Version)
+	if c.Supplier != nil {
+		set(CategoryComponent, "supplier", c.Supplier.Name)
+	}
+
+	set(CategoryLicense, "licenses", licenseString(c.Licenses))
+
+	if c.Hashes != nil {
+		for _, h := range *c.Hashes {
+			set(CategoryHash, string(h.Algorithm), h.Value)
+		}
+	}
+
+	if c.Properties != nil {
+		props := map[string][]string{}
+		for _, p := range *c.Properties {
+			props[p.Name] = append(props[p.Name], p.Value)
+		}
+		for name, vals := range props {
+			sort.Strings(vals)
+			set(CategoryProperty, name, strings.Join(vals, ", "))
+		}
+	}
+
+	flattenInto(f, CategoryCrypto, c.CryptoProperties)
+	flattenInto(f, CategoryModel, c.ModelCard)
+	return f
+}
+
+// licenseString renders licenses as a sorted, comma-separated list so that
+// reordering alone is not reported as a change.
+func licenseString(ls *cdx.Licenses) string {
+	if ls == nil {
+		return ""
+	}
+	var ids []string
+	for _, lc := range *ls {
+		switch {
+		case lc.Expression != "":
+			ids = append(ids, lc.Expression)
+		case lc.License != nil && lc.License.ID != "":
+			ids = append(ids, lc.License.ID)
+		case lc.License != nil:
+			ids = append(ids, lc.License.Name)
+		}
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ", ")
+}
+
+// flattenInto marshals v to JSON and records every leaf value under a dotted
+// path (e.g. "certificateProperties.notValidAfter"). Arrays of scalars are
+// joined; arrays of objects are indexed.
+func flattenInto(f map[fieldKey]string, category string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return
+	}
+	var walk func(path string, node interface{})
+	walk = func(path string, node interface{}) {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			for k, child := range n {
+				p := k
+				if path != "" {
+					p = path + "." + k
+				}
+				walk(p, child)
+			}
+		case []interface{}:
+			scalars := make([]string, 0, len(n))
+			for i, child := range n {
+				if _, isObj := child.(map[string]interface{}); isObj {
+					walk(path+"["+strconv.Itoa(i)+"]", child)
+					continue
+				}
+				if _, isArr := child.([]interface{}); isArr {
+					walk(path+"["+strconv.Itoa(i)+"]", child)
+					continue
+				}
+				scalars = append(scalars, scalarString(child))
+			}
+			if len(scalars) > 0 {
+				f[fieldKey{category, path}] = strings.Join(scalars, ", ")
+			}
+		default:
+			if s := scalarString(n); s != "" {
+				f[fieldKey{category, path}] = s
+			}
+		}
+	}
+	walk("", generic)
+}
+
+func scalarString(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	default:
+		return fmt.Sprint(s)
+	}
+}
+
+func sortedKeys(m map[string]*cdx.Component) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ── Rendering ────────────────────────────────────────────────────────────────
+
+func printDiffTable(r *DiffReport) error {
+	fmt.Printf("%s (%d components) -> %s (%d components)\n",
+		docLabel(r.Old), r.Old.Components, docLabel(r.New), r.New.Components)
+	fmt.Printf("%d added, %d removed, %d changed\n\n", len(r.Added), len(r.Removed), len(r.Changed))
+	if !r.HasChanges() {
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "STATUS\tCOMPONENT\tVERSION\tFIELD\tOLD\tNEW")
+	fmt.Fprintln(w, "------\t---------\t-------\t-----\t---\t---")
+	for _, c := range r.Metadata {
+		fmt.Fprintf(w, "metadata\t%s\t%s\t%s:%s\t%s\t%s\n",
+			r.New.Name, r.New.Version, c.Category, c.Field, truncate(c.Old), truncate(c.New))
+	}
+	for _, c := range r.Added {
+		fmt.Fprintf(w, "added\t%s\t%s\t\t\t\n", c.Name, c.Version)
+	}
+	for _, c := range r.Removed {
+		fmt.Fprintf(w, "removed\t%s\t%s\t\t\t\n", c.Name, c.Version)
+	}
+	for _, cc := range r.Changed {
+		for _, c := range cc.Changes {
+			fmt.Fprintf(w, "changed\t%s\t%s\t%s:%s\t%s\t%s\n",
+				cc.Name, cc.Version, c.Category, c.Field, truncate(c.Old), truncate(c.New))
+		}
+	}
+	return nil
+}
+
+// renderMarkdown produces a report suitable for posting as a PR comment.
+func renderMarkdown(r *DiffReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## BOM diff: `%s` → `%s`\n\n", docLabel(r.Old), docLabel(r.New))
+	fmt.Fprintf(&b, "| | Components |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Before | %d |\n| After | %d |\n", r.Old.Components, r.New.Components)
+	fmt.Fprintf(&b, "| Added | %d |\n| Removed | %d |\n| Changed | %d |\n\n",
+		len(r.Added), len(r.Removed), len(r.Changed))
+
+	if !r.HasChanges() {
+		b.WriteString("No changes.\n")
+		return b.String()
+	}
+
+	if len(r.Metadata) > 0 {
+		b.WriteString("### Metadata\n\n| Field | Old | New |\n|---|---|---|\n")
+		for _, c := range r.Metadata {
+			fmt.Fprintf(&b, "| %s:%s | %s | %s |\n", c.Category, c.Field, mdCell(c.Old), mdCell(c.New))
+		}
+		b.WriteString("\n")
+	}
+	writeRefs := func(title string, refs []ComponentRef) {
+		if len(refs) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "### %s\n\n| Component | Version | Type | Reference |\n|---|---|---|---|\n", title)
+		for _, c := range refs {
+			ref := c.PURL
+			if ref == "" {
+				ref = c.BOMRef
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", mdCell(c.Name), mdCell(c.Version), c.Type, mdCell(ref))
+		}
+		b.WriteString("\n")
+	}
+	writeRefs("Added", r.Added)
+	writeRefs("Removed", r.Removed)
+
+	if len(r.Changed) > 0 {
+		b.WriteString("### Changed\n\n| Component | Field | Old | New |\n|---|---|---|---|\n")
+		for _, cc := range r.Changed {
+			for _, c := range cc.Changes {
+				fmt.Fprintf(&b, "| %s | %s:%s | %s | %s |\n",
+					mdCell(cc.Name), c.Category, c.Field, mdCell(c.Old), mdCell(c.New))
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func docLabel(d DocumentSummary) string {
+	switch {
+	case d.Path != "":
+		return d.Path
+	case d.Version != "":
+		return d.Name + "@" + d.Version
+	default:
+		return d.Name
+	}
+}
+
+// mdCell escapes characters that would break a markdown table cell.
+func mdCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+// truncate shortens long values (model descriptions, fingerprints) so the
+// table stays readable; JSON and markdown output keep the full value.
+func truncate(s string) string {
+	const max = 60
+	s = strings.ReplaceAll(s, "\n", " ")
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max-3]) + "..."
+}
@@ -0,0 +1,446 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package bom
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"github.com/google/uuid"
+)
+
+// toolComponent is stamped into metadata.tools of every document rewritten
+// by this package, alongside the tools of the input documents.
+var toolComponent = cdx.Component{
+	Type:      cdx.ComponentTypeApplication,
+	Publisher: "AccuKnox",
+	Name:      "knoxctl-bom",
+}
+
+// RunMerge loads opts.Inputs, merges them and writes the result.
+func RunMerge(opts *MergeOptions) (*cdx.BOM, error) {
+	if len(opts.Inputs) < 2 {
+		return nil, fmt.Errorf("at least two BOMs are required to merge")
+	}
+	boms := make([]*cdx.BOM, 0, len(opts.Inputs))
+	for _, p := range opts.Inputs {
+		b, err := Load(p)
+		if err != nil {
+			return nil, err
+		}
+		boms = append(boms, b)
+	}
+	merged, err := Merge(boms, opts)
+	if err != nil {
+		return nil, err
+	}
+	return merged, writeBOM(merged, opts.OutputTo, "Merged BOM")
+}
+
+// Merge combines boms into a single document.
+//
+// Components are deduplicated by package URL, then bom-ref, then
+// type/group/name@version; when the same component appears more than once
+// the first occurrence wins and licenses, hashes, properties and external
+// references from later occurrences are added to it. Distinct components
+// whose bom-refs collide are renamed and every reference to them rewritten.
+//
+// The metadata.component of each input is replaced by a single root, so the
+// dependency graphs of the inputs hang off the same node. Inputs without a
+// dependency graph (e.g. CBOMs) get a root → component edge for each of
+// their top-level components.
+func Merge(boms []*cdx.BOM, opts *MergeOptions) (*cdx.BOM, error) {
+	root, err := mergedRoot(boms, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &merger{
+		root:     root.BOMRef,
+		byKey:    map[string]int{},
+		refOwner: map[string]string{root.BOMRef: "root"},
+		deps:     map[string]map[string]bool{},
+		vulnByID: map[string]int{},
+	}
+	var tools []cdx.Component
+	var sources []cdx.Property
+	for i, b := range boms {
+		m.add(i, b)
+		tools = append(tools, inputTools(b)...)
+		if b.SerialNumber != "" {
+			sources = append(sources, cdx.Property{Name: "mergedFrom", Value: b.SerialNumber})
+		}
+	}
+
+	out := cdx.NewBOM()
+	out.SerialNumber = "urn:uuid:" + uuid.New().String()
+	out.Metadata = &cdx.Metadata{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Tools: &cdx.ToolsChoice{
+			Components: dedupTools(append([]cdx.Component{toolComponent}, tools...)),
+		},
+		Component: root,
+	}
+	if len(sources) > 0 {
+		out.Metadata.Properties = &sources
+	}
+	out.Components = &m.comps
+	if deps := m.dependencies(); len(deps) > 0 {
+		out.Dependencies = &deps
+	}
+	if len(m.vulns) > 0 {
+		out.Vulnerabilities = &m.vulns
+	}
+	return out, nil
+}
+
+// mergedRoot returns the metadata.component for the merged document.
+func mergedRoot(boms []*cdx.BOM, opts *MergeOptions) (*cdx.Component, error) {
+	if opts.Name != "" {
+		compType := cdx.ComponentType(opts.Type)
+		if compType == "" {
+			compType = cdx.ComponentTypeApplication
+		}
+		c := &cdx.Component{
+			Type:    compType,
+			Name:    opts.Name,
+			Group:   opts.Group,
+			Version: opts.Version,
+		}
+		switch {
+		case opts.Group != "" && opts.Version != "":
+			c.PackageURL = fmt.Sprintf("pkg:generic/%s/%s@%s", opts.Group, opts.Name, opts.Version)
+			c.BOMRef = c.PackageURL
+		case opts.Version != "":
+			c.PackageURL = fmt.Sprintf("pkg:generic/%s@%s", opts.Name, opts.Version)
+			c.BOMRef = c.PackageURL
+		default:
+			c.BOMRef = opts.Name
+		}
+		return c, nil
+	}
+
+	for _, b := range boms {
+		if mc := metadataComponent(b); mc != nil && mc.Name != "" {
+			c := *mc
+			c.Components = nil
+			if c.BOMRef == "" {
+				c.BOMRef = c.Name
+				if c.Version != "" {
+					c.BOMRef += "@" + c.Version
+				}
+			}
+			return &c, nil
+		}
+	}
+	return nil, fmt.Errorf("no input has a metadata.component; pass --name to set one")
+}
+
+type merger struct {
+	root     string
+	comps    []cdx.Component
+	byKey    map[string]int    // identity key → index in comps
+	refOwner map[string]string // bom-ref → identity key of the component using it
+
+	deps     map[string]map[string]bool
+	depOrder []string
+
+	vulns    []cdx.Vulnerability
+	vulnByID map[string]int
+}
+
+// add merges one input document.
+func (m *merger) add(input int, b *cdx.BOM) {
+	remap := map[string]string{}
+	if mc := metadataComponent(b); mc != nil && mc.BOMRef != "" {
+		remap[mc.BOMRef] = m.root
+	}
+
+	start := len(m.comps)
+	var topLevel []string
+	if b.Components != nil {
+		for _, c := range *b.Components {
+			ref := m.addComponent(input, c, remap)
+			if ref != "" {
+				topLevel = append(topLevel, ref)
+			}
+		}
+	}
+	rewrite := func(ref string) string {
+		if r, ok := remap[ref]; ok {
+			return r
+		}
+		return ref
+	}
+
+	if b.Dependencies != nil && len(*b.Dependencies) > 0 {
+		for _, d := range *b.Dependencies {
+			from := rewrite(d.Ref)
+			m.addDependency(from)
+			if d.Dependencies == nil {
+				continue
+			}
+			for _, to := range *d.Dependencies {
+				m.addDependency(from, rewrite(to))
+			}
+		}
+	} else {
+		for _, ref := range topLevel {
+			m.addDependency(m.root, ref)
+		}
+	}
+
+	if b.Vulnerabilities != nil {
+		for _, v := range *b.Vulnerabilities {
+			m.addVulnerability(v, rewrite)
+		}
+	}
+
+	// Crypto assets reference each other by bom-ref (certificate →
+	// signature algorithm, key → algorithm); follow any renames.
+	for i := start; i < len(m.comps); i++ {
+		rewriteCryptoRefs(&m.comps[i], rewrite)
+	}
+}
+
+// addComponent adds c (with its nested components) and returns the bom-ref
+// it ends up with in the merged document.
+func (m *merger) addComponent(input int, c cdx.Component, remap map[string]string) string {
+	key := mergeKey(&c)
+	if idx, ok := m.byKey[key]; ok {
+		mergeInto(&m.comps[idx], &c)
+		if c.BOMRef != "" {
+			remap[c.BOMRef] = m.comps[idx].BOMRef
+		}
+		return m.comps[idx].BOMRef
+	}
+
+	if c.BOMRef != "" {
+		if owner, taken := m.refOwner[c.BOMRef]; taken && owner != key {
+			renamed := fmt.Sprintf("%s-%d", c.BOMRef, input+1)
+			remap[c.BOMRef] = renamed
+			c.BOMRef = renamed
+		}
+		m.refOwner[c.BOMRef] = key
+	}
+	if c.Components != nil {
+		for _, nc := range *c.Components {
+			if nc.BOMRef != "" {
+				m.refOwner[nc.BOMRef] = mergeKey(&nc)
+			}
+		}
+	}
+
+	m.byKey[key] = len(m.comps)
+	m.comps = append(m.comps, c)
+	return c.BOMRef
+}
+
+// mergeKey is the identity used for deduplication. Unlike componentKey it
+// keeps the version: two versions of a library are two components.
+func mergeKey(c *cdx.Component) string {
+	switch {
+	case c.PackageURL != "":
+		return c.PackageURL
+	case c.BOMRef != "":
+		return c.BOMRef
+	default:
+		return componentKey(c) + "@" + c.Version
+	}
+}
+
+// mergeInto copies information from dup into c without overwriting anything
+// c already has.
+func mergeInto(c, dup *cdx.Component) {
+	if c.Description == "" {
+		c.Description = dup.Description
+	}
+	if c.Supplier == nil {
+		c.Supplier = dup.Supplier
+	}
+	if c.CryptoProperties == nil {
+		c.CryptoProperties = dup.CryptoProperties
+	}
+	if c.ModelCard == nil {
+		c.ModelCard = dup.ModelCard
+	}
+	if c.Evidence == nil {
+		c.Evidence = dup.Evidence
+	}
+
+	if dup.Licenses != nil {
+		key := func(l cdx.LicenseChoice) string { return licenseString(&cdx.Licenses{l}) }
+		var base []cdx.LicenseChoice
+		if c.Licenses != nil {
+			base = *c.Licenses
+		}
+		ls := appendUnique(&base, *dup.Licenses, key)
+		// Drop the "unknown" placeholder once a real license is known.
+		known := ls[:0]
+		for _, l := range ls {
+			if key(l) != "unknown" {
+				known = append(known, l)
+			}
+		}
+		if len(known) > 0 {
+			ls = known
+		}
+		licenses := cdx.Licenses(ls)
+		c.Licenses = &licenses
+	}
+
+	if dup.Hashes != nil {
+		hs := appendUnique(c.Hashes, *dup.Hashes, func(h cdx.Hash) string { return string(h.Algorithm) })
+		c.Hashes = &hs
+	}
+	if dup.Properties != nil {
+		ps := appendUnique(c.Properties, *dup.Properties, func(p cdx.Property) string { return p.Name + "=" + p.Value })
+		c.Properties = &ps
+	}
+	if dup.ExternalReferences != nil {
+		rs := appendUnique(c.ExternalReferences, *dup.ExternalReferences, func(r cdx.ExternalReference) string { return string(r.Type) + " " + r.URL })
+		c.ExternalReferences = &rs
+	}
+}
+
+// appendUnique appends the elements of extra whose key is not already in
+// base.
+func appendUnique[T any](base *[]T, extra []T, key func(T) string) []T {
+	var out []T
+	if base != nil {
+		out = append(out, *base...)
+	}
+	seen := map[string]bool{}
+	for _, v := range out {
+		seen[key(v)] = true
+	}
+	for _, v := range extra {
+		if k := key(v); !seen[k] {
+			out = append(out, v)
+			seen[k] = true
+		}
+	}
+	return out
+}
+
+func rewriteCryptoRefs(c *cdx.Component, rewrite func(string) string) {
+	cp := c.CryptoProperties
+	if cp == nil {
+		return
+	}
+	if p := cp.CertificateProperties; p != nil {
+		p.SignatureAlgorithmRef = cdx.BOMReference(rewrite(string(p.SignatureAlgorithmRef)))
+		p.SubjectPublicKeyRef = cdx.BOMReference(rewrite(string(p.SubjectPublicKeyRef)))
+	}
+	if p := cp.RelatedCryptoMaterialProperties; p != nil {
+		p.AlgorithmRef = cdx.BOMReference(rewrite(string(p.AlgorithmRef)))
+	}
+}
+
+func (m *merger) addDependency(from string, to ...string) {
+	set, ok := m.deps[from]
+	if !ok {
+		set = map[string]bool{}
+		m.deps[from] = set
+		m.depOrder = append(m.depOrder, from)
+	}
+	for _, t := range to {
+		if t != "" && t != from {
+			set[t] = true
+		}
+	}
+}
+
+// dependencies returns the unified graph with the root first and each
+// dependsOn list sorted.
+func (m *merger) dependencies() []cdx.Dependency {
+	var out []cdx.Dependency
+	emit := func(ref string) {
+		set := m.deps[ref]
+		d := cdx.Dependency{Ref: ref}
+		if len(set) > 0 {
+			on := make([]string, 0, len(set))
+			for t := range set {
+				on = append(on, t)
+			}
+			sort.Strings(on)
+			d.Dependencies = &on
+		}
+		out = append(out, d)
+	}
+	if _, ok := m.deps[m.root]; ok {
+		emit(m.root)
+	}
+	for _, ref := range m.depOrder {
+		if ref != m.root {
+			emit(ref)
+		}
+	}
+	return out
+}
+
+// addVulnerability merges v by ID, combining the affected components.
+func (m *merger) addVulnerability(v cdx.Vulnerability, rewrite func(string) string) {
+	var affects []cdx.Affects
+	if v.Affects != nil {
+		for _, a := range *v.Affects {
+			a.Ref = rewrite(a.Ref)
+			affects = append(affects, a)
+		}
+	}
+	if idx, ok := m.vulnByID[v.ID]; ok {
+		existing := &m.vulns[idx]
+		merged := appendUnique(existing.Affects, affects, func(a cdx.Affects) string { return a.Ref })
+		existing.Affects = &merged
+		if existing.Analysis == nil {
+			existing.Analysis = v.Analysis
+		}
+		return
+	}
+	if affects != nil {
+		v.Affects = &affects
+	}
+	m.vulnByID[v.ID] = len(m.vulns)
+	m.vulns = append(m.vulns, v)
+}
+
+// inputTools returns the tools recorded in b, converting the deprecated
+// metadata.tools array form to components.
+func inputTools(b *cdx.BOM) []cdx.Component {
+	if b.Metadata == nil || b.Metadata.Tools == nil {
+		return nil
+	}
+	var out []cdx.Component
+	if b.Metadata.Tools.Components != nil {
+		out = append(out, *b.Metadata.Tools.Components...)
+	}
+	if b.Metadata.Tools.Tools != nil {
+		for _, t := range *b.Metadata.Tools.Tools {
+			out = append(out, cdx.Component{
+				Type:      cdx.ComponentTypeApplication,
+				Publisher: t.Vendor,
+				Name:      t.Name,
+				Version:   t.Version,
+			})
+		}
+	}
+	return out
+}
+
+func dedupTools(tools []cdx.Component) *[]cdx.Component {
+	out := appendUnique(nil, tools, func(c cdx.Component) string { return c.Name + "@" + c.Version })
+	return &out
+}
+
+// writeBOM marshals bom as indented JSON to path or stdout.
+func writeBOM(bom *cdx.BOM, path, what string) error {
+	data, err := json.MarshalIndent(bom, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling %s: %w", what, err)
+	}
+	return writeOutput(append(data, '\n'), path, what)
+}
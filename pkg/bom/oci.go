@@ -0,0 +1,336 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package bom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"github.com/google/go-containerregistry/pkg/name"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+
+	"github.com/accuknox/accuknox-cli-v2/pkg/sign"
+)
+
+// OCI artifact and media types of the referrers pushed by Attach.
+const (
+	// MediaTypeCycloneDX is the artifactType and layer media type of a BOM.
+	MediaTypeCycloneDX = "application/vnd.cyclonedx+json"
+
+	// ArtifactTypeSignature is the artifactType of a detached cosign
+	// signature over a BOM; its subject is the BOM artifact.
+	ArtifactTypeSignature = "application/vnd.dev.cosign.artifact.sig.v1+json"
+
+	// MediaTypeSignature is the layer media type of the base-64 signature.
+	MediaTypeSignature = "text/plain"
+
+	// MediaTypeDSSE is the artifactType and layer media type of an in-toto
+	// attestation written by --attest.
+	MediaTypeDSSE = "application/vnd.dsse.envelope.v1+json"
+)
+
+// Annotations set on attached artifacts.
+const (
+	AnnotationBOMKind       = "dev.accuknox.bom.kind" // sbom, cbom or aibom
+	AnnotationSignature     = "dev.cosignproject.cosign/signature"
+	AnnotationPredicateType = "predicateType"
+)
+
+// AttachResult describes one artifact pushed by Attach.
+type AttachResult struct {
+	Path         string // local file that was attached
+	ArtifactType string
+	Digest       string // manifest digest of the referrer artifact
+	Subject      string // digest of the manifest it refers to
+}
+
+// FetchResult describes one artifact downloaded by Fetch.
+type FetchResult struct {
+	Path         string // file written under OutputDir
+	ArtifactType string
+	Kind         string // BOM kind (sbom, cbom, aibom) for BOM artifacts
+	Digest       string // manifest digest of the referrer artifact
+}
+
+// Attach pushes the BOMs, signatures and attestations in opts to the
+// registry as OCI 1.1 referrers of opts.Image. BOMs and attestations refer
+// to the image; the signature opts.Signatures[i] refers to the artifact of
+// opts.BOMs[i]. Registries without the referrers API are served through
+// the referrers tag schema.
+func Attach(ctx context.Context, opts *OCIOptions) ([]AttachResult, error) {
+	if len(opts.BOMs) == 0 && len(opts.Attestations) == 0 {
+		return nil, fmt.Errorf("nothing to attach: give at least one BOM or attestation")
+	}
+	if len(opts.Signatures) > len(opts.BOMs) {
+		return nil, fmt.Errorf("%d signature(s) given for %d BOM(s): each signature belongs to the BOM at the same position", len(opts.Signatures), len(opts.BOMs))
+	}
+
+	repo, image, err := resolveImage(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []AttachResult
+	for i, path := range opts.BOMs {
+		data, err := os.ReadFile(path) // #nosec G304 -- path is supplied by the user
+		if err != nil {
+			return results, fmt.Errorf("reading %s: %w", path, err)
+		}
+		doc, err := Decode(data)
+		if err != nil {
+			return results, fmt.Errorf("%s: %w", path, err)
+		}
+		annotations := map[string]string{AnnotationBOMKind: kindOf(doc)}
+		bomDesc, err := pushReferrer(ctx, repo, image, MediaTypeCycloneDX, MediaTypeCycloneDX, path, data, annotations)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, AttachResult{Path: path, ArtifactType: MediaTypeCycloneDX, Digest: bomDesc.Digest.String(), Subject: image.Digest.String()})
+
+		if i >= len(opts.Signatures) || opts.Signatures[i] == "" {
+			continue
+		}
+		sigPath := opts.Signatures[i]
+		sig, err := os.ReadFile(sigPath) // #nosec G304 -- path is supplied by the user
+		if err != nil {
+			return results, fmt.Errorf("reading %s: %w", sigPath, err)
+		}
+		sigAnnotations := map[string]string{AnnotationSignature: strings.TrimSpace(string(sig))}
+		sigDesc, err := pushReferrer(ctx, repo, bomDesc, ArtifactTypeSignature, MediaTypeSignature, sigPath, sig, sigAnnotations)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, AttachResult{Path: sigPath, ArtifactType: ArtifactTypeSignature, Digest: sigDesc.Digest.String(), Subject: bomDesc.Digest.String()})
+	}
+
+	for _, path := range opts.Attestations {
+		data, err := os.ReadFile(path) // #nosec G304 -- path is supplied by the user
+		if err != nil {
+			return results, fmt.Errorf("reading %s: %w", path, err)
+		}
+		if !json.Valid(data) {
+			return results, fmt.Errorf("%s is not a DSSE envelope", path)
+		}
+		annotations := map[string]string{AnnotationPredicateType: sign.PredicateCycloneDX}
+		desc, err := pushReferrer(ctx, repo, image, MediaTypeDSSE, MediaTypeDSSE, path, data, annotations)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, AttachResult{Path: path, ArtifactType: MediaTypeDSSE, Digest: desc.Digest.String(), Subject: image.Digest.String()})
+	}
+	return results, nil
+}
+
+// Fetch discovers the BOMs and attestations attached to opts.Image, and the
+// signatures attached to those BOMs, and downloads them into opts.OutputDir
+// under the file names they were attached with.
+func Fetch(ctx context.Context, opts *OCIOptions) ([]FetchResult, error) {
+	repo, image, err := resolveImage(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	outDir := opts.OutputDir
+	if outDir == "" {
+		outDir = "."
+	}
+	if err := os.MkdirAll(outDir, 0750); err != nil {
+		return nil, fmt.Errorf("creating %s: %w", outDir, err)
+	}
+
+	// Referrers are listed unfiltered and classified by their manifests:
+	// some registries report artifactType from the config media type, which
+	// is empty for OCI 1.1 artifacts.
+	referrers, err := referrerManifests(ctx, repo, image)
+	if err != nil {
+		return nil, fmt.Errorf("listing referrers of %s: %w", opts.Image, err)
+	}
+
+	var results []FetchResult
+	for _, artifactType := range []string{MediaTypeCycloneDX, MediaTypeDSSE} {
+		for _, r := range referrers {
+			if r.manifest.ArtifactType != artifactType {
+				continue
+			}
+			res, err := fetchReferrer(ctx, repo, r, outDir)
+			if err != nil {
+				return results, err
+			}
+			results = append(results, res)
+			if artifactType != MediaTypeCycloneDX {
+				continue
+			}
+
+			sigs, err := referrerManifests(ctx, repo, r.desc)
+			if err != nil {
+				return results, fmt.Errorf("listing signatures of %s: %w", res.Path, err)
+			}
+			for _, sig := range sigs {
+				if sig.manifest.ArtifactType != ArtifactTypeSignature {
+					continue
+				}
+				sigRes, err := fetchReferrer(ctx, repo, sig, outDir)
+				if err != nil {
+					return results, err
+				}
+				results = append(results, sigRes)
+			}
+		}
+	}
+	return results, nil
+}
+
+// resolveImage opens the repository of opts.Image and resolves the image
+// manifest the referrers attach to.
+func resolveImage(ctx context.Context, opts *OCIOptions) (*remote.Repository, ocispec.Descriptor, error) {
+	// go-containerregistry expands Docker Hub short names (nginx:latest)
+	// that oras expects fully qualified.
+	ref, err := name.ParseReference(opts.Image)
+	if err != nil {
+		return nil, ocispec.Descriptor{}, fmt.Errorf("parsing image reference %s: %w", opts.Image, err)
+	}
+	repo, err := remote.NewRepository(ref.Context().Name())
+	if err != nil {
+		return nil, ocispec.Descriptor{}, fmt.Errorf("opening repository for %s: %w", opts.Image, err)
+	}
+	repo.PlainHTTP = opts.PlainHTTP || isLocalRegistry(ref.Context().RegistryStr())
+	if opts.Client != nil {
+		repo.Client = opts.Client
+	}
+
+	image, err := repo.Resolve(ctx, ref.Identifier())
+	if err != nil {
+		return nil, ocispec.Descriptor{}, fmt.Errorf("resolving %s: %w", opts.Image, err)
+	}
+	return repo, image, nil
+}
+
+// pushReferrer pushes data as the single layer of an OCI 1.1 artifact
+// manifest whose subject is subject.
+func pushReferrer(ctx context.Context, repo *remote.Repository, subject ocispec.Descriptor, artifactType, mediaType, path string, data []byte, annotations map[string]string) (ocispec.Descriptor, error) {
+	layer, err := oras.PushBytes(ctx, repo, mediaType, data)
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("pushing %s: %w", path, err)
+	}
+	layer.Annotations = map[string]string{ocispec.AnnotationTitle: filepath.Base(path)}
+
+	desc, err := oras.PackManifest(ctx, repo, oras.PackManifestVersion1_1, artifactType, oras.PackManifestOptions{
+		Subject:             &subject,
+		Layers:              []ocispec.Descriptor{layer},
+		ManifestAnnotations: annotations,
+	})
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("pushing manifest for %s: %w", path, err)
+	}
+	return desc, nil
+}
+
+// referrer is a referrer artifact with its decoded manifest.
+type referrer struct {
+	desc     ocispec.Descriptor
+	manifest ocispec.Manifest
+}
+
+// referrerManifests lists the referrers of subject and fetches their
+// manifests, ordered by digest so results are stable.
+func referrerManifests(ctx context.Context, repo *remote.Repository, subject ocispec.Descriptor) ([]referrer, error) {
+	descs, err := registry.Referrers(ctx, repo, subject, "")
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(descs, func(i, j int) bool { return descs[i].Digest < descs[j].Digest })
+
+	refs := make([]referrer, 0, len(descs))
+	for _, desc := range descs {
+		raw, err := content.FetchAll(ctx, repo, desc)
+		if err != nil {
+			return nil, fmt.Errorf("fetching manifest %s: %w", desc.Digest, err)
+		}
+		r := referrer{desc: desc}
+		if err := json.Unmarshal(raw, &r.manifest); err != nil {
+			return nil, fmt.Errorf("parsing manifest %s: %w", desc.Digest, err)
+		}
+		refs = append(refs, r)
+	}
+	return refs, nil
+}
+
+// fetchReferrer downloads the first layer of a referrer artifact into
+// outDir, named by its title annotation (or its digest when untitled).
+func fetchReferrer(ctx context.Context, repo *remote.Repository, r referrer, outDir string) (FetchResult, error) {
+	if len(r.manifest.Layers) == 0 {
+		return FetchResult{}, fmt.Errorf("artifact %s has no layers", r.desc.Digest)
+	}
+	layer := r.manifest.Layers[0]
+	data, err := content.FetchAll(ctx, repo, layer)
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("fetching %s: %w", layer.Digest, err)
+	}
+
+	// Only the base name of the title is used so a crafted annotation
+	// cannot write outside outDir.
+	name := filepath.Base(layer.Annotations[ocispec.AnnotationTitle])
+	if name == "." || name == "/" || name == ".." {
+		name = r.desc.Digest.Encoded() + extensionFor(r.manifest.ArtifactType)
+	}
+	path := filepath.Join(outDir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return FetchResult{}, fmt.Errorf("writing %s: %w", path, err)
+	}
+	return FetchResult{
+		Path:         path,
+		ArtifactType: r.manifest.ArtifactType,
+		Kind:         r.manifest.Annotations[AnnotationBOMKind],
+		Digest:       r.desc.Digest.String(),
+	}, nil
+}
+
+// kindOf classifies a BOM by its components: any cryptographic asset makes
+// it a CBOM, any machine-learning model an AIBOM, otherwise an SBOM.
+func kindOf(doc *cdx.BOM) string {
+	kind := "sbom"
+	if doc.Components == nil {
+		return kind
+	}
+	for _, c := range *doc.Components {
+		switch c.Type {
+		case cdx.ComponentTypeCryptographicAsset:
+			return "cbom"
+		case cdx.ComponentTypeMachineLearningModel:
+			kind = "aibom"
+		}
+	}
+	return kind
+}
+
+func extensionFor(artifactType string) string {
+	switch artifactType {
+	case ArtifactTypeSignature:
+		return ".sig"
+	case MediaTypeDSSE:
+		return ".intoto.jsonl"
+	default:
+		return ".json"
+	}
+}
+
+// isLocalRegistry reports whether registry is on localhost, which is
+// served over plain HTTP unless stated otherwise (as for onboard login).
+func isLocalRegistry(registry string) bool {
+	host, _, err := net.SplitHostPort(registry)
+	if err != nil {
+		host = registry
+	}
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
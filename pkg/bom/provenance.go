@@ -0,0 +1,212 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package bom
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"github.com/google/uuid"
+
+	"github.com/accuknox/accuknox-cli-v2/pkg/sign"
+)
+
+// ProvenancePrefix prefixes the metadata properties written by Stamp.
+const ProvenancePrefix = "knoxctl:provenance:"
+
+// SourceDateEpochEnv fixes the timestamp of deterministic BOMs, following
+// the reproducible-builds convention (seconds since the Unix epoch).
+const SourceDateEpochEnv = "SOURCE_DATE_EPOCH"
+
+// serialNamespace is the UUID namespace of content-derived serial numbers.
+var serialNamespace = uuid.MustParse("6f1c3f7e-9a43-4d0e-8a7b-1c0e6d7b2f51")
+
+// Provenance records how a BOM was produced.
+type Provenance struct {
+	ToolVersion string         // knoxctl version (version.GitSummary)
+	Scanners    []Scanner      // external scanners the BOM was produced with
+	Inputs      []sign.Subject // digests of what was scanned
+	Options     interface{}    // generation options, recorded as JSON; must hold no secrets
+}
+
+// Scanner identifies an external scanner by version and binary digest.
+type Scanner struct {
+	Name    string
+	Version string
+	SHA256  string // hex digest of the scanner binary
+}
+
+// Stamp records p in the BOM: scanners as metadata.tools components and the
+// rest as knoxctl:provenance:* metadata properties. Earlier provenance
+// properties are replaced, so stamping is idempotent.
+func Stamp(b *cdx.BOM, p *Provenance) error {
+	if b.Metadata == nil {
+		b.Metadata = &cdx.Metadata{}
+	}
+	md := b.Metadata
+
+	var props []cdx.Property
+	if md.Properties != nil {
+		for _, prop := range *md.Properties {
+			if !strings.HasPrefix(prop.Name, ProvenancePrefix) {
+				props = append(props, prop)
+			}
+		}
+	}
+	add := func(name, value string) {
+		if value != "" {
+			props = append(props, cdx.Property{Name: ProvenancePrefix + name, Value: value})
+		}
+	}
+
+	version := p.ToolVersion
+	if version == "" {
+		version = "dev"
+	}
+	add("knoxctlVersion", version)
+	for _, in := range p.Inputs {
+		add("input", in.String())
+	}
+	if p.Options != nil {
+		opts, err := json.Marshal(p.Options)
+		if err != nil {
+			return fmt.Errorf("recording options: %w", err)
+		}
+		add("options", string(opts))
+	}
+	md.Properties = &props
+
+	if len(p.Scanners) > 0 {
+		if md.Tools == nil {
+			md.Tools = &cdx.ToolsChoice{}
+		}
+		var tools []cdx.Component
+		if md.Tools.Components != nil {
+			tools = *md.Tools.Components
+		}
+		for _, s := range p.Scanners {
+			tools = appendScanner(tools, s)
+		}
+		md.Tools.Components = &tools
+	}
+	return nil
+}
+
+func appendScanner(tools []cdx.Component, s Scanner) []cdx.Component {
+	for _, t := range tools {
+		if t.Name == s.Name {
+			return tools
+		}
+	}
+	c := cdx.Component{Type: cdx.ComponentTypeApplication, Name: s.Name, Version: s.Version}
+	if s.SHA256 != "" {
+		c.Hashes = &[]cdx.Hash{{Algorithm: cdx.HashAlgoSHA256, Value: s.SHA256}}
+	}
+	return append(tools, c)
+}
+
+// Canonicalize makes a BOM reproducible: components (recursively), their
+// properties and evidence, and the dependency graph are sorted; the
+// timestamp is taken from $SOURCE_DATE_EPOCH (or dropped when unset); and
+// the serial number is derived from the content, so two runs over the same
+// input produce byte-identical documents.
+func Canonicalize(b *cdx.BOM) error {
+	if b.Components != nil {
+		sortComponents(*b.Components)
+	}
+	if b.Dependencies != nil {
+		deps := *b.Dependencies
+		for _, d := range deps {
+			if d.Dependencies != nil {
+				sort.Strings(*d.Dependencies)
+			}
+		}
+		sort.Slice(deps, func(i, j int) bool { return deps[i].Ref < deps[j].Ref })
+	}
+	if b.Metadata != nil {
+		ts, err := sourceDateEpoch()
+		if err != nil {
+			return err
+		}
+		b.Metadata.Timestamp = ts
+		if b.Metadata.Component != nil && b.Metadata.Component.Properties != nil {
+			sortProperties(*b.Metadata.Component.Properties)
+		}
+	}
+
+	b.SerialNumber = ""
+	data, err := json.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("serialising BOM: %w", err)
+	}
+	b.SerialNumber = "urn:uuid:" + uuid.NewSHA1(serialNamespace, data).String()
+	return nil
+}
+
+func sortComponents(comps []cdx.Component) {
+	for i := range comps {
+		c := &comps[i]
+		if c.Components != nil {
+			sortComponents(*c.Components)
+		}
+		if c.Properties != nil {
+			sortProperties(*c.Properties)
+		}
+		if c.Evidence != nil && c.Evidence.Occurrences != nil {
+			occ := *c.Evidence.Occurrences
+			sort.SliceStable(occ, func(i, j int) bool {
+				if occ[i].Location != occ[j].Location {
+					return occ[i].Location < occ[j].Location
+				}
+				return lineOf(occ[i].Line) < lineOf(occ[j].Line)
+			})
+		}
+	}
+	sort.SliceStable(comps, func(i, j int) bool {
+		a, b := &comps[i], &comps[j]
+		if a.BOMRef != b.BOMRef {
+			return a.BOMRef < b.BOMRef
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Version < b.Version
+	})
+}
+
+func sortProperties(props []cdx.Property) {
+	sort.SliceStable(props, func(i, j int) bool {
+		if props[i].Name != props[j].Name {
+			return props[i].Name < props[j].Name
+		}
+		return props[i].Value < props[j].Value
+	})
+}
+
+func lineOf(l *int) int {
+	if l == nil {
+		return 0
+	}
+	return *l
+}
+
+// sourceDateEpoch returns $SOURCE_DATE_EPOCH as an RFC 3339 timestamp, or
+// "" when it is unset.
+func sourceDateEpoch() (string, error) {
+	v := os.Getenv(SourceDateEpochEnv)
+	if v == "" {
+		return "", nil
+	}
+	secs, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid $%s %q: %w", SourceDateEpochEnv, v, err)
+	}
+	return time.Unix(secs, 0).UTC().Format(time.RFC3339), nil
+}
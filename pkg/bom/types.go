@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package bom
+
+import "oras.land/oras-go/v2/registry/remote"
+
+// DiffOptions holds configuration for comparing two BOMs.
+type DiffOptions struct {
+	OldPath  string // path to the baseline BOM
+	NewPath  string // path to the BOM being compared against the baseline
+	Format   string // output format: "table" (default), "json" or "markdown"
+	OutputTo string // write the report to this file instead of stdout
+}
+
+// Change categories reported by Diff.
+const (
+	CategoryComponent = "component" // version, type, group, supplier
+	CategoryLicense   = "license"
+	CategoryHash      = "hash"
+	CategoryCrypto    = "crypto" // cryptoProperties (CBOM)
+	CategoryModel     = "model"  // modelCard (AIBOM)
+	CategoryProperty  = "property"
+)
+
+// DiffReport is the result of comparing two BOMs.
+type DiffReport struct {
+	Old      DocumentSummary   `json:"old"`
+	New      DocumentSummary   `json:"new"`
+	Metadata []FieldChange     `json:"metadata,omitempty"` // changes to metadata.component
+	Added    []ComponentRef    `json:"added"`
+	Removed  []ComponentRef    `json:"removed"`
+	Changed  []ComponentChange `json:"changed"`
+}
+
+// DocumentSummary identifies one side of a diff.
+type DocumentSummary struct {
+	Path         string `json:"path,omitempty"`
+	SerialNumber string `json:"serialNumber,omitempty"`
+	SpecVersion  string `json:"specVersion,omitempty"`
+	Name         string `json:"name,omitempty"` // metadata.component name
+	Version      string `json:"version,omitempty"`
+	Components   int    `json:"components"`
+}
+
+// ComponentRef identifies a component that was added or removed.
+type ComponentRef struct {
+	Key     string `json:"key"` // identity used to match components across documents
+	BOMRef  string `json:"bomRef,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	PURL    string `json:"purl,omitempty"`
+}
+
+// ComponentChange lists the field-level differences of a component present in
+// both documents.
+type ComponentChange struct {
+	ComponentRef
+	Changes []FieldChange `json:"changes"`
+}
+
+// FieldChange is a single field difference. An empty Old or New means the
+// field was absent on that side.
+type FieldChange struct {
+	Category string `json:"category"`
+	Field    string `json:"field"`
+	Old      string `json:"old,omitempty"`
+	New      string `json:"new,omitempty"`
+}
+
+// HasChanges reports whether the two documents differ in any component.
+func (r *DiffReport) HasChanges() bool {
+	return len(r.Metadata)+len(r.Added)+len(r.Removed)+len(r.Changed) > 0
+}
+
+// MergeOptions holds configuration for combining several BOMs into one.
+type MergeOptions struct {
+	Inputs []string // paths of the BOMs to merge, in priority order
+
+	// Root component for metadata.component. When Name is empty the
+	// metadata.component of the first input is used.
+	Name    string
+	Group   string
+	Version string
+	Type    string // CycloneDX component type of the root (default "application")
+
+	OutputTo string // write the merged BOM to this file instead of stdout
+}
+
+// VEXOptions holds configuration for attaching VEX analysis to a BOM.
+type VEXOptions struct {
+	BOMPath  string // BOM to enrich
+	VEXPath  string // YAML file of VEX statements
+	OutputTo string // write the enriched BOM to this file instead of stdout
+}
+
+// OCIOptions holds configuration for attaching BOMs to, and fetching them
+// from, a container image in an OCI registry.
+type OCIOptions struct {
+	Image string // image reference (tag or digest) the artifacts describe
+
+	// Attach
+	BOMs         []string // CycloneDX JSON BOMs
+	Signatures   []string // detached signatures; Signatures[i] signs BOMs[i]
+	Attestations []string // in-toto DSSE envelopes (bom.json.intoto.jsonl)
+
+	// Fetch
+	OutputDir string // directory to download into (default ".")
+
+	PlainHTTP bool          // use HTTP instead of HTTPS (default for localhost)
+	Client    remote.Client // registry client carrying credentials; nil = anonymous
+}
+
+// VEXFile is the YAML document accepted by "bom vex".
+//
+//	statements:
+//	  - vulnerability: CVE-2023-45288
+//	    components: ["pkg:golang/golang.org/x/net@v0.20.0"]
+//	    state: not_affected
+//	    justification: code_not_reachable
+//	    response: [will_not_fix]
+//	    detail: HTTP/2 server is never started
+type VEXFile struct {
+	Statements []VEXStatement `yaml:"statements"`
+}
+
+// VEXStatement records the analysis of one vulnerability. Components holds
+// bom-refs or package URLs; when empty the statement applies to every
+// component the vulnerability affects.
+type VEXStatement struct {
+	Vulnerability string   `yaml:"vulnerability"`
+	Components    []string `yaml:"components,omitempty"`
+	State         string   `yaml:"state"`
+	Justification string   `yaml:"justification,omitempty"`
+	Response      []string `yaml:"response,omitempty"`
+	Detail        string   `yaml:"detail,omitempty"`
+}
@@ -0,0 +1,263 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package bom
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"gopkg.in/yaml.v3"
+)
+
+// VEXResult summarises what ApplyVEX changed.
+type VEXResult struct {
+	Updated   int      // existing vulnerabilities whose analysis was set
+	Added     int      // vulnerabilities created because the BOM had no entry
+	Unmatched []string // statements that matched nothing, as "ID (reason)"
+}
+
+var (
+	vexStates = map[string]cdx.ImpactAnalysisState{}
+	vexJust   = map[string]cdx.ImpactAnalysisJustification{}
+	vexResp   = map[string]cdx.ImpactAnalysisResponse{}
+)
+
+func init() {
+	for _, s := range []cdx.ImpactAnalysisState{
+		cdx.IASResolved, cdx.IASResolvedWithPedigree, cdx.IASExploitable,
+		cdx.IASInTriage, cdx.IASFalsePositive, cdx.IASNotAffected,
+	} {
+		vexStates[string(s)] = s
+	}
+	for _, j := range []cdx.ImpactAnalysisJustification{
+		cdx.IAJCodeNotPresent, cdx.IAJCodeNotReachable, cdx.IAJRequiresConfiguration,
+		cdx.IAJRequiresDependency, cdx.IAJRequiresEnvironment, cdx.IAJProtectedByCompiler,
+		cdx.IAJProtectedAtRuntime, cdx.IAJProtectedAtPerimeter, cdx.IAJProtectedByMitigatingControl,
+	} {
+		vexJust[string(j)] = j
+	}
+	for _, r := range []cdx.ImpactAnalysisResponse{
+		cdx.IARCanNotFix, cdx.IARWillNotFix, cdx.IARUpdate, cdx.IARRollback, cdx.IARWorkaroundAvailable,
+	} {
+		vexResp[string(r)] = r
+	}
+}
+
+// RunVEX loads the BOM and VEX statements named in opts, applies the
+// statements and writes the enriched BOM.
+func RunVEX(opts *VEXOptions) (*cdx.BOM, *VEXResult, error) {
+	bom, err := Load(opts.BOMPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	vex, err := LoadVEX(opts.VEXPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := ApplyVEX(bom, vex, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	return bom, res, writeBOM(bom, opts.OutputTo, "VEX-enriched BOM")
+}
+
+// LoadVEX reads and validates a VEX statement file.
+func LoadVEX(path string) (*VEXFile, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is supplied by the user
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	var vex VEXFile
+	if err := yaml.Unmarshal(data, &vex); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for i, s := range vex.Statements {
+		if err := s.validate(); err != nil {
+			return nil, fmt.Errorf("%s: statement %d: %w", path, i+1, err)
+		}
+	}
+	return &vex, nil
+}
+
+func (s *VEXStatement) validate() error {
+	if s.Vulnerability == "" {
+		return fmt.Errorf("vulnerability is required")
+	}
+	if _, ok := vexStates[s.State]; !ok {
+		return fmt.Errorf("%s: invalid state %q (one of %s)", s.Vulnerability, s.State, keysOf(vexStates))
+	}
+	if s.Justification != "" {
+		if _, ok := vexJust[s.Justification]; !ok {
+			return fmt.Errorf("%s: invalid justification %q (one of %s)", s.Vulnerability, s.Justification, keysOf(vexJust))
+		}
+	}
+	for _, r := range s.Response {
+		if _, ok := vexResp[r]; !ok {
+			return fmt.Errorf("%s: invalid response %q (one of %s)", s.Vulnerability, r, keysOf(vexResp))
+		}
+	}
+	return nil
+}
+
+// ApplyVEX sets the analysis of the vulnerabilities in bom from vex.
+//
+// A statement without components applies to every entry for the
+// vulnerability. A statement naming components applies only to those: an
+// entry that also affects other components is split so the analysis does not
+// leak to them, and a new entry is created when the BOM does not yet list
+// the vulnerability for the named components. Components that are not in
+// the BOM are ignored so one VEX file can be reused across releases.
+func ApplyVEX(bom *cdx.BOM, vex *VEXFile, now time.Time) (*VEXResult, error) {
+	res := &VEXResult{}
+	refs := componentRefIndex(bom)
+	ts := now.UTC().Format(time.RFC3339)
+
+	var vulns []cdx.Vulnerability
+	if bom.Vulnerabilities != nil {
+		vulns = *bom.Vulnerabilities
+	}
+
+	for _, s := range vex.Statements {
+		if err := s.validate(); err != nil {
+			return nil, err
+		}
+
+		if len(s.Components) == 0 {
+			matched := false
+			for i := range vulns {
+				if strings.EqualFold(vulns[i].ID, s.Vulnerability) {
+					setAnalysis(&vulns[i], &s, ts)
+					res.Updated++
+					matched = true
+				}
+			}
+			if !matched {
+				res.Unmatched = append(res.Unmatched, s.Vulnerability+" (not in BOM)")
+			}
+			continue
+		}
+
+		targets := map[string]bool{}
+		for _, c := range s.Components {
+			if ref, ok := refs[c]; ok {
+				targets[ref] = true
+			}
+		}
+		if len(targets) == 0 {
+			res.Unmatched = append(res.Unmatched, s.Vulnerability+" (components not in BOM)")
+			continue
+		}
+
+		covered := map[string]bool{}
+		for i, n := 0, len(vulns); i < n; i++ {
+			v := &vulns[i]
+			if !strings.EqualFold(v.ID, s.Vulnerability) || v.Affects == nil {
+				continue
+			}
+			var in, out []cdx.Affects
+			for _, a := range *v.Affects {
+				if targets[a.Ref] {
+					in = append(in, a)
+					covered[a.Ref] = true
+				} else {
+					out = append(out, a)
+				}
+			}
+			switch {
+			case len(in) == 0:
+				continue
+			case len(out) > 0:
+				split := *v
+				split.BOMRef = ""
+				split.Affects = &in
+				v.Affects = &out
+				setAnalysis(&split, &s, ts)
+				vulns = append(vulns, split)
+			default:
+				setAnalysis(v, &s, ts)
+			}
+			res.Updated++
+		}
+
+		var missing []cdx.Affects
+		for _, c := range s.Components {
+			if ref, ok := refs[c]; ok && !covered[ref] {
+				missing = append(missing, cdx.Affects{Ref: ref})
+				covered[ref] = true
+			}
+		}
+		if len(missing) > 0 {
+			v := cdx.Vulnerability{ID: s.Vulnerability, Affects: &missing}
+			setAnalysis(&v, &s, ts)
+			vulns = append(vulns, v)
+			res.Added++
+		}
+	}
+
+	if len(vulns) > 0 {
+		bom.Vulnerabilities = &vulns
+	}
+	return res, nil
+}
+
+func setAnalysis(v *cdx.Vulnerability, s *VEXStatement, ts string) {
+	a := &cdx.VulnerabilityAnalysis{
+		State:         vexStates[s.State],
+		Justification: vexJust[s.Justification],
+		Detail:        s.Detail,
+		FirstIssued:   ts,
+		LastUpdated:   ts,
+	}
+	if v.Analysis != nil && v.Analysis.FirstIssued != "" {
+		a.FirstIssued = v.Analysis.FirstIssued
+	}
+	if len(s.Response) > 0 {
+		resp := make([]cdx.ImpactAnalysisResponse, 0, len(s.Response))
+		for _, r := range s.Response {
+			resp = append(resp, vexResp[r])
+		}
+		a.Response = &resp
+	}
+	v.Analysis = a
+}
+
+// componentRefIndex maps every bom-ref and package URL in bom to the
+// component's bom-ref.
+func componentRefIndex(bom *cdx.BOM) map[string]string {
+	idx := map[string]string{}
+	var walk func(comps []cdx.Component)
+	walk = func(comps []cdx.Component) {
+		for _, c := range comps {
+			if c.BOMRef != "" {
+				idx[c.BOMRef] = c.BOMRef
+				if c.PackageURL != "" {
+					idx[c.PackageURL] = c.BOMRef
+				}
+			}
+			if c.Components != nil {
+				walk(*c.Components)
+			}
+		}
+	}
+	if bom.Metadata != nil && bom.Metadata.Component != nil {
+		walk([]cdx.Component{*bom.Metadata.Component})
+	}
+	if bom.Components != nil {
+		walk(*bom.Components)
+	}
+	return idx
+}
+
+func keysOf[V any](m map[string]V) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
@@ -5,13 +5,16 @@
 // compliant with CycloneDX 1.6. It supports two modes:
 //
 //   - Source scanning: walks Go source files and detects usage of known
-//     cryptographic packages (stdlib crypto/* and golang.org/x/crypto/*).
+//     cryptographic packages (stdlib crypto/* and golang.org/x/crypto/*), and
+//     inventories certificates, keys, keystores and TLS configuration files
+//     committed alongside the source.
 //
 //   - Image scanning: scans container images for certificates, keys, TLS
 //     configuration, and secrets.
 package cbom
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -22,6 +25,7 @@ import (
 	cdx "github.com/CycloneDX/cyclonedx-go"
 	"github.com/google/uuid"
 
+	"github.com/accuknox/accuknox-cli-v2/pkg/bom"
 	"github.com/accuknox/accuknox-cli-v2/pkg/sign"
 )
 
@@ -64,6 +68,12 @@ func GenerateFromImage(opts *Options) (*cdx.BOM, error) {
 // Output writes the BOM to stdout or to opts.OutputTo, in the format
 // specified by opts.Format ("json" or "table").
 func Output(bom *cdx.BOM, opts *Options) error {
+	if opts.Sign.Attest && (opts.OutputTo == "" || strings.EqualFold(opts.Format, "table")) {
+		return fmt.Errorf("--attest requires JSON output written with --out")
+	}
+	if err := stamp(bom, opts); err != nil {
+		return err
+	}
 	switch strings.ToLower(opts.Format) {
 	case "table":
 		return printTable(bom, opts)
@@ -72,6 +82,57 @@ func Output(bom *cdx.BOM, opts *Options) error {
 	}
 }
 
+// stamp records how bom was produced — knoxctl and scanner versions, the
+// digest of the scanned image or source tree, and the options — and, with
+// opts.Deterministic, canonicalizes it so that identical inputs give
+// byte-identical output.
+func stamp(b *cdx.BOM, opts *Options) error {
+	p := &bom.Provenance{
+		ToolVersion: opts.ToolVersion,
+		Options: recordedOptions{
+			Path: opts.Path, Image: opts.Image,
+			Name: opts.Name, Group: opts.Group, Version: opts.Version,
+			Description: opts.Description, License: opts.License,
+			BOMFile: opts.BOMFile, Plugins: opts.Plugins, Ignore: opts.Ignore,
+			Deterministic: opts.Deterministic,
+		},
+	}
+
+	opts.inputs = nil
+	switch {
+	case opts.Image != "":
+		p.Scanners = embeddedScanners()
+		s, err := sign.ImageSubject(context.Background(), opts.Image)
+		if err != nil {
+			if opts.Sign.Attest && opts.Sign.Subject == "" {
+				return fmt.Errorf("attest: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "Warning: image digest not recorded: %v\n", err)
+			break
+		}
+		opts.inputs = append(opts.inputs, s)
+	case opts.Path != "":
+		var exclude []string
+		if opts.OutputTo != "" {
+			exclude = sign.OutputFiles(opts.OutputTo, &opts.Sign)
+		}
+		s, err := sign.PathSubject(opts.Path, exclude...)
+		if err != nil {
+			return fmt.Errorf("hashing source tree: %w", err)
+		}
+		opts.inputs = append(opts.inputs, s)
+	}
+	p.Inputs = opts.inputs
+
+	if err := bom.Stamp(b, p); err != nil {
+		return err
+	}
+	if opts.Deterministic {
+		return bom.Canonicalize(b)
+	}
+	return nil
+}
+
 // toolComponent is the CycloneDX tool entry stamped into every CBOM produced
 // by knoxctl, regardless of the underlying scanner used.
 var toolComponent = cdx.Component{
@@ -189,12 +250,24 @@ func printJSON(bom *cdx.BOM, opts *Options) error {
 			return fmt.Errorf("writing CBOM to %s: %w", opts.OutputTo, err)
 		}
 		fmt.Printf("CBOM written to %s\n", opts.OutputTo)
-		return sign.Artifact(opts.OutputTo, &opts.Sign)
+		if err := sign.Artifact(opts.OutputTo, &opts.Sign); err != nil {
+			return err
+		}
+		return attest(opts)
 	}
 	fmt.Println(string(data))
 	return nil
 }
 
+// attest writes the signed in-toto attestation for opts.OutputTo. Its
+// subject is the scanned image, by manifest digest, or the source tree.
+func attest(opts *Options) error {
+	if !opts.Sign.Attest {
+		return nil
+	}
+	return sign.Attest(opts.OutputTo, opts.inputs, &opts.Sign)
+}
+
 // printTable renders a human-readable summary of the cryptographic components.
 func printTable(bom *cdx.BOM, opts *Options) error {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
@@ -231,6 +304,15 @@ func printTable(bom *cdx.BOM, opts *Options) error {
 			if cp.ProtocolProperties.Version != "" {
 				params += " " + cp.ProtocolProperties.Version
 			}
+		} else if cp.CertificateProperties != nil {
+			primitive = "certificate"
+			params = "expires " + cp.CertificateProperties.NotValidAfter
+		} else if rp := cp.RelatedCryptoMaterialProperties; rp != nil {
+			primitive = string(rp.Type)
+			params = rp.Format
+			if rp.Size != nil {
+				params = fmt.Sprintf("%d bits %s", *rp.Size, rp.Format)
+			}
 		}
 
 		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
@@ -4,9 +4,19 @@
 package cbom
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"math/big"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	cdx "github.com/CycloneDX/cyclonedx-go"
 )
@@ -458,6 +468,204 @@ var _ = sha256.New
 	}
 }
 
+func TestOutput_DeterministicProvenance(t *testing.T) {
+	dir := t.TempDir()
+	src := "package foo\nimport (\n\t\"crypto/aes\"\n\t\"crypto/sha256\"\n)\nvar _ = aes.NewCipher\nvar _ = sha256.New\n"
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte(src), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+
+	// The CBOM is written into the scanned tree: it must not feed back into
+	// the recorded tree hash of the next run.
+	out := filepath.Join(dir, "cbom.json")
+	run := func() []byte {
+		opts := &Options{Path: dir, Name: "app", OutputTo: out, ToolVersion: "v9.9.9", Deterministic: true}
+		bom, err := GenerateFromSource(opts)
+		if err != nil {
+			t.Fatalf("GenerateFromSource: %v", err)
+		}
+		if err := Output(bom, opts); err != nil {
+			t.Fatalf("Output: %v", err)
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	first, second := run(), run()
+	if !bytes.Equal(first, second) {
+		t.Fatalf("deterministic CBOMs differ:\n%s\n---\n%s", first, second)
+	}
+	for _, want := range []string{`"knoxctl:provenance:knoxctlVersion"`, `"v9.9.9"`, `"knoxctl:provenance:input"`, `"knoxctl:provenance:options"`} {
+		if !bytes.Contains(first, []byte(want)) {
+			t.Errorf("CBOM is missing %s", want)
+		}
+	}
+}
+
+// ----- certificate and key material tests -----
+
+func TestScanSource_CertificateAndPrivateKey(t *testing.T) {
+	dir := t.TempDir()
+	certDER, keyDER := testCertificate(t, "expired.example.com", time.Now().Add(-time.Hour))
+
+	var buf bytes.Buffer
+	_ = pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	_ = pem.Encode(&buf, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(filepath.Join(dir, "server.pem"), buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := ScanSource(dir)
+	if err != nil {
+		t.Fatalf("ScanSource: %v", err)
+	}
+
+	var cert, priv *cdx.Component
+	for i := range components {
+		cp := components[i].CryptoProperties
+		switch {
+		case cp.AssetType == cdx.CryptoAssetTypeCertificate:
+			cert = &components[i]
+		case cp.RelatedCryptoMaterialProperties != nil &&
+			cp.RelatedCryptoMaterialProperties.Type == cdx.RelatedCryptoMaterialTypePrivateKey:
+			priv = &components[i]
+		}
+	}
+
+	if cert == nil {
+		t.Fatal("expected a certificate component")
+	}
+	props := cert.CryptoProperties.CertificateProperties
+	if props.SubjectName != "CN=expired.example.com" {
+		t.Errorf("SubjectName = %q", props.SubjectName)
+	}
+	if props.NotValidAfter == "" || props.SignatureAlgorithmRef == "" || props.SubjectPublicKeyRef == "" {
+		t.Errorf("certificate properties incomplete: %+v", props)
+	}
+	if !hasProperty(cert, "certificateExpired", "true") {
+		t.Error("expired certificate should be flagged")
+	}
+	if occ := *cert.Evidence.Occurrences; len(occ) != 1 || occ[0].Line == nil || *occ[0].Line != 1 {
+		t.Errorf("certificate evidence = %+v, want line 1", occ)
+	}
+
+	if priv == nil {
+		t.Fatal("expected a private-key component")
+	}
+	if !hasProperty(priv, "privateKeyCommitted", "true") {
+		t.Error("committed private key should be flagged")
+	}
+	rp := priv.CryptoProperties.RelatedCryptoMaterialProperties
+	if rp.Size == nil || *rp.Size != 256 {
+		t.Errorf("private key size = %v, want 256", rp.Size)
+	}
+	if rp.AlgorithmRef != "crypto/algorithm/ECDSA-P-256" {
+		t.Errorf("private key algorithmRef = %q", rp.AlgorithmRef)
+	}
+}
+
+func TestScanSource_ValidCertificateNotFlagged(t *testing.T) {
+	dir := t.TempDir()
+	certDER, _ := testCertificate(t, "valid.example.com", time.Now().Add(24*time.Hour))
+	if err := os.WriteFile(filepath.Join(dir, "ca.der"), certDER, 0600); err != nil {
+		t.Fatal(err)
+	}
+	components, err := ScanSource(dir)
+	if err != nil {
+		t.Fatalf("ScanSource: %v", err)
+	}
+	found := false
+	for i := range components {
+		if components[i].CryptoProperties.AssetType == cdx.CryptoAssetTypeCertificate {
+			found = true
+			if hasProperty(&components[i], "certificateExpired", "true") {
+				t.Error("valid certificate must not be flagged as expired")
+			}
+		}
+	}
+	if !found {
+		t.Error("expected DER certificate to be discovered")
+	}
+}
+
+func TestScanSource_JKSKeystore(t *testing.T) {
+	dir := t.TempDir()
+	certDER, _ := testCertificate(t, "trusted.example.com", time.Now().Add(time.Hour))
+	if err := os.WriteFile(filepath.Join(dir, "truststore.jks"), testJKS(certDER), 0600); err != nil {
+		t.Fatal(err)
+	}
+	components, err := ScanSource(dir)
+	if err != nil {
+		t.Fatalf("ScanSource: %v", err)
+	}
+	var keystore, cert bool
+	for _, c := range components {
+		cp := c.CryptoProperties
+		if cp.AssetType == cdx.CryptoAssetTypeCertificate && c.Name == "trusted.example.com" {
+			cert = true
+		}
+		if cp.RelatedCryptoMaterialProperties != nil && cp.RelatedCryptoMaterialProperties.Format == "JKS" {
+			keystore = true
+		}
+	}
+	if !keystore || !cert {
+		t.Errorf("keystore found = %v, certificate found = %v; want both", keystore, cert)
+	}
+}
+
+func TestScanSource_TLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	conf := `server {
+    listen 443 ssl;
+    ssl_protocols TLSv1 TLSv1.2;
+    ssl_ciphers ECDHE-RSA-AES128-GCM-SHA256:!aNULL;
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "nginx.conf"), []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+	components, err := ScanSource(dir)
+	if err != nil {
+		t.Fatalf("ScanSource: %v", err)
+	}
+	versions := map[string]*cdx.Component{}
+	for i := range components {
+		if pp := components[i].CryptoProperties.ProtocolProperties; pp != nil {
+			versions[pp.Version] = &components[i]
+		}
+	}
+	if len(versions) != 2 || versions["1.0"] == nil || versions["1.2"] == nil {
+		t.Fatalf("expected TLS 1.0 and 1.2 components, got %v", versions)
+	}
+	if !hasProperty(versions["1.0"], "deprecatedProtocol", "true") {
+		t.Error("TLS 1.0 should be flagged as deprecated")
+	}
+	if hasProperty(versions["1.2"], "deprecatedProtocol", "true") {
+		t.Error("TLS 1.2 must not be flagged as deprecated")
+	}
+	suites := versions["1.2"].CryptoProperties.ProtocolProperties.CipherSuites
+	if suites == nil || len(*suites) != 1 || (*suites)[0].Name != "ECDHE-RSA-AES128-GCM-SHA256" {
+		t.Errorf("cipher suites = %+v", suites)
+	}
+}
+
+func TestScanSource_IgnoresUnrelatedConfig(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.conf"), []byte("port = 8080\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	components, err := ScanSource(dir)
+	if err != nil {
+		t.Fatalf("ScanSource: %v", err)
+	}
+	if len(components) != 0 {
+		t.Errorf("expected 0 components, got %d", len(components))
+	}
+}
+
 // ----- knownPackages coverage test -----
 
 func TestKnownPackages_AlgorithmPropertiesNotNil(t *testing.T) {
@@ -477,3 +685,60 @@ func keys(m map[string]bool) []string {
 	}
 	return out
 }
+
+func hasProperty(c *cdx.Component, name, value string) bool {
+	if c.Properties == nil {
+		return false
+	}
+	for _, p := range *c.Properties {
+		if p.Name == name && p.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// testCertificate returns a self-signed ECDSA P-256 certificate and its SEC 1
+// private key, both DER encoded.
+func testCertificate(t *testing.T, cn string, notAfter time.Time) (certDER, keyDER []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    notAfter.Add(-48 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	certDER, err = x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err = x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certDER, keyDER
+}
+
+// testJKS builds a version-2 JKS keystore holding a single trusted certificate.
+// The trailing integrity digest is omitted; the scanner does not check it.
+func testJKS(certDER []byte) []byte {
+	var b bytes.Buffer
+	utf := func(s string) {
+		_ = binary.Write(&b, binary.BigEndian, uint16(len(s)))
+		b.WriteString(s)
+	}
+	b.Write([]byte{0xFE, 0xED, 0xFE, 0xED})
+	_ = binary.Write(&b, binary.BigEndian, uint32(2)) // version
+	_ = binary.Write(&b, binary.BigEndian, uint32(1)) // entry count
+	_ = binary.Write(&b, binary.BigEndian, uint32(2)) // trusted certificate entry
+	utf("trusted")
+	_ = binary.Write(&b, binary.BigEndian, int64(0)) // timestamp
+	utf("X.509")
+	_ = binary.Write(&b, binary.BigEndian, uint32(len(certDER)))
+	b.Write(certDER)
+	return b.Bytes()
+}
@@ -7,6 +7,8 @@ package cbom
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -16,6 +18,8 @@ import (
 	"os/exec"
 
 	cdx "github.com/CycloneDX/cyclonedx-go"
+
+	"github.com/accuknox/accuknox-cli-v2/pkg/bom"
 )
 
 //go:embed cbomkit-theia-bin
@@ -24,6 +28,17 @@ var cbomkitBinary []byte
 // scannerBin is the filename used when extracting the embedded image scanner.
 const scannerBin = "knoxctl-cbom-scanner"
 
+// scannerVersion is the version of the embedded scanner, set at build time
+// with -ldflags "-X github.com/accuknox/accuknox-cli-v2/pkg/cbom.scannerVersion=…".
+var scannerVersion = ""
+
+// embeddedScanners identifies the embedded image scanner for the provenance
+// block; its digest pins the exact binary even when no version was set.
+func embeddedScanners() []bom.Scanner {
+	sum := sha256.Sum256(cbomkitBinary)
+	return []bom.Scanner{{Name: "cbomkit-theia", Version: scannerVersion, SHA256: hex.EncodeToString(sum[:])}}
+}
+
 // ScanImage extracts the embedded image scanner, runs it against a container
 // image reference, and returns the parsed CycloneDX BOM.
 func ScanImage(opts *Options) (*cdx.BOM, error) {
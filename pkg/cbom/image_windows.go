@@ -9,6 +9,8 @@ import (
 	"fmt"
 
 	cdx "github.com/CycloneDX/cyclonedx-go"
+
+	"github.com/accuknox/accuknox-cli-v2/pkg/bom"
 )
 
 // ScanImage is not supported on Windows.
@@ -20,3 +22,8 @@ func ScanImage(opts *Options) (*cdx.BOM, error) {
 func ScanDir(opts *Options) (*cdx.BOM, error) {
 	return nil, fmt.Errorf("directory scanning is not supported on Windows")
 }
+
+// embeddedScanners returns nil: no scanner is embedded on Windows.
+func embeddedScanners() []bom.Scanner {
+	return nil
+}
@@ -0,0 +1,907 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package cbom
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/dsa" // #nosec G505 -- only used to identify committed DSA keys
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"golang.org/x/crypto/pkcs12"
+	"golang.org/x/crypto/ssh"
+)
+
+// maxMaterialFileSize caps how much of a candidate file is read. Certificates,
+// keys and keystores are small; anything larger is almost certainly not one.
+const maxMaterialFileSize = 4 << 20
+
+// materialExts lists file extensions that commonly hold certificates, keys or
+// keystores committed to a repository.
+var materialExts = map[string]bool{
+	".pem":        true,
+	".crt":        true,
+	".cer":        true,
+	".cert":       true,
+	".der":        true,
+	".key":        true,
+	".pub":        true,
+	".p8":         true,
+	".p12":        true,
+	".pfx":        true,
+	".jks":        true,
+	".keystore":   true,
+	".truststore": true,
+}
+
+// materialNames lists well-known extensionless key file names.
+var materialNames = map[string]bool{
+	"id_rsa":     true,
+	"id_dsa":     true,
+	"id_ecdsa":   true,
+	"id_ed25519": true,
+}
+
+// tlsConfigNames lists TLS-bearing server configuration files; any other
+// *.conf / *.cnf file is also inspected for TLS directives.
+var tlsConfigNames = map[string]bool{
+	"nginx.conf":   true,
+	"httpd.conf":   true,
+	"apache2.conf": true,
+	"ssl.conf":     true,
+	"haproxy.cfg":  true,
+	"openssl.cnf":  true,
+}
+
+// JKS and JCEKS keystore magic numbers.
+var (
+	jksMagic   = []byte{0xFE, 0xED, 0xFE, 0xED}
+	jceksMagic = []byte{0xCE, 0xCE, 0xCE, 0xCE}
+)
+
+// isMaterialCandidate reports whether a file name looks like it may contain
+// certificates, keys, keystores or TLS configuration.
+func isMaterialCandidate(name string) bool {
+	lower := strings.ToLower(name)
+	ext := filepath.Ext(lower)
+	return materialExts[ext] || materialNames[lower] || isTLSConfig(lower)
+}
+
+// isTLSConfig reports whether a (lower-cased) file name is a server
+// configuration file that may carry TLS protocol and cipher directives.
+func isTLSConfig(lower string) bool {
+	ext := filepath.Ext(lower)
+	return tlsConfigNames[lower] || ext == ".conf" || ext == ".cnf"
+}
+
+// materialCollector accumulates certificate, key and protocol components
+// discovered in non-Go files, de-duplicating by bom-ref so the same material
+// committed in several places is reported once with multiple occurrences.
+type materialCollector struct {
+	comps map[string]*cdx.Component
+	order []string
+	now   time.Time
+}
+
+func newMaterialCollector() *materialCollector {
+	return &materialCollector{
+		comps: map[string]*cdx.Component{},
+		now:   time.Now(),
+	}
+}
+
+// components returns the collected components in discovery order.
+func (c *materialCollector) components() []cdx.Component {
+	out := make([]cdx.Component, 0, len(c.order))
+	for _, ref := range c.order {
+		out = append(out, *c.comps[ref])
+	}
+	return out
+}
+
+// add records comp, or merges the occurrence (and any cipher suites) into an
+// already-recorded component with the same bom-ref.
+func (c *materialCollector) add(comp cdx.Component, occ occurrence) {
+	existing, ok := c.comps[comp.BOMRef]
+	if !ok {
+		existing = &comp
+		existing.Evidence = &cdx.Evidence{Occurrences: &[]cdx.EvidenceOccurrence{}}
+		c.comps[comp.BOMRef] = existing
+		c.order = append(c.order, comp.BOMRef)
+	} else if pp := protocolProps(existing); pp != nil {
+		mergeCipherSuites(pp, protocolProps(&comp))
+	}
+	if occ.file == "" {
+		return
+	}
+	ev := cdx.EvidenceOccurrence{Location: occ.file}
+	if occ.line > 0 {
+		line := occ.line
+		ev.Line = &line
+	}
+	for _, o := range *existing.Evidence.Occurrences {
+		if o.Location == ev.Location && equalLine(o.Line, ev.Line) {
+			return
+		}
+	}
+	*existing.Evidence.Occurrences = append(*existing.Evidence.Occurrences, ev)
+}
+
+// scanFile inspects a single candidate file and records any material found.
+// Unreadable or unparsable files are skipped, mirroring how the Go scanner
+// ignores files that fail to parse.
+func (c *materialCollector) scanFile(path string) {
+	data, err := readLimited(path)
+	if err != nil || len(data) == 0 {
+		return
+	}
+	lower := strings.ToLower(filepath.Base(path))
+	ext := filepath.Ext(lower)
+
+	switch {
+	case bytes.HasPrefix(data, jksMagic) || bytes.HasPrefix(data, jceksMagic):
+		c.scanJKS(path, data)
+	case ext == ".p12" || ext == ".pfx":
+		c.scanPKCS12(path, data)
+	case bytes.Contains(data, []byte("-----BEGIN ")):
+		c.scanPEM(path, data)
+	case isTLSConfig(lower):
+		c.scanTLSConfig(path, data)
+	case ext == ".pub":
+		c.scanSSHPublicKey(path, data)
+	default:
+		c.scanDER(path, data)
+	}
+}
+
+// readLimited reads at most maxMaterialFileSize bytes; larger files are rejected.
+func readLimited(path string) ([]byte, error) {
+	f, err := os.Open(path) // #nosec G304 -- path comes from walking the user-supplied scan root
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxMaterialFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxMaterialFileSize {
+		return nil, fmt.Errorf("%s exceeds %d bytes", path, maxMaterialFileSize)
+	}
+	return data, nil
+}
+
+// ── PEM / DER ────────────────────────────────────────────────────────────────
+
+// scanPEM walks every PEM block in data, recording certificates, public keys
+// and private keys along with the line of each BEGIN marker.
+func (c *materialCollector) scanPEM(path string, data []byte) {
+	rest := data
+	for {
+		start := bytes.Index(rest, []byte("-----BEGIN "))
+		if start < 0 {
+			return
+		}
+		line := bytes.Count(data[:len(data)-len(rest)+start], []byte("\n")) + 1
+		block, next := pem.Decode(rest[start:])
+		if block == nil {
+			// Malformed block: skip past this marker and keep looking.
+			rest = rest[start+len("-----BEGIN "):]
+			continue
+		}
+		rest = next
+		occ := occurrence{file: path, line: line}
+
+		switch {
+		case block.Type == "CERTIFICATE" || block.Type == "TRUSTED CERTIFICATE":
+			if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+				c.addCertificate(cert, "PEM", occ)
+			}
+		case block.Type == "PUBLIC KEY":
+			if pub, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+				c.addPublicKey(pub, "PEM", "", occ)
+			}
+		case block.Type == "RSA PUBLIC KEY":
+			if pub, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+				c.addPublicKey(pub, "PEM", "", occ)
+			}
+		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+			c.addPrivateKeyBlock(block, occ)
+		}
+	}
+}
+
+// scanDER tries to interpret a binary file as a DER certificate or key.
+func (c *materialCollector) scanDER(path string, data []byte) {
+	occ := occurrence{file: path}
+	if certs, err := x509.ParseCertificates(data); err == nil {
+		for _, cert := range certs {
+			c.addCertificate(cert, "DER", occ)
+		}
+		return
+	}
+	if pub, err := x509.ParsePKIXPublicKey(data); err == nil {
+		c.addPublicKey(pub, "DER", "", occ)
+		return
+	}
+	if key, err := parsePrivateKey(data); err == nil {
+		c.addPrivateKey(key, "DER", sha256Hex(data), false, occ)
+	}
+}
+
+// scanSSHPublicKey parses an OpenSSH authorized_keys style public key.
+func (c *materialCollector) scanSSHPublicKey(path string, data []byte) {
+	pk, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return
+	}
+	cpk, ok := pk.(ssh.CryptoPublicKey)
+	if !ok {
+		return
+	}
+	c.addPublicKey(cpk.CryptoPublicKey(), "OpenSSH", "", occurrence{file: path, line: 1})
+}
+
+// addPrivateKeyBlock records a PEM private key. Encrypted keys cannot be
+// parsed without their passphrase but are still reported.
+func (c *materialCollector) addPrivateKeyBlock(block *pem.Block, occ occurrence) {
+	id := sha256Hex(block.Bytes)
+	encrypted := block.Type == "ENCRYPTED PRIVATE KEY" ||
+		strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED")
+	if encrypted {
+		c.addPrivateKey(nil, "PEM", id, true, occ)
+		return
+	}
+	var key interface{}
+	var err error
+	if block.Type == "OPENSSH PRIVATE KEY" {
+		key, err = ssh.ParseRawPrivateKey(pem.EncodeToMemory(block))
+		if _, ok := err.(*ssh.PassphraseMissingError); ok {
+			c.addPrivateKey(nil, "OpenSSH", id, true, occ)
+			return
+		}
+	} else {
+		key, err = parsePrivateKey(block.Bytes)
+	}
+	if err != nil {
+		key = nil
+	}
+	c.addPrivateKey(key, "PEM", id, false, occ)
+}
+
+// parsePrivateKey tries PKCS#8, PKCS#1 and SEC 1 encodings in turn.
+func parsePrivateKey(der []byte) (interface{}, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unrecognised private key encoding")
+}
+
+// ── Keystores ────────────────────────────────────────────────────────────────
+
+// scanPKCS12 records a PKCS#12 keystore. Unprotected stores are opened and
+// their certificates and keys recorded individually.
+func (c *materialCollector) scanPKCS12(path string, data []byte) {
+	occ := occurrence{file: path}
+	blocks, err := pkcs12.ToPEM(data, "")
+	c.addKeystore(path, "PKCS#12", err != nil, occ)
+	if err != nil {
+		return
+	}
+	for _, block := range blocks {
+		switch block.Type {
+		case "CERTIFICATE":
+			if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+				c.addCertificate(cert, "PKCS#12", occ)
+			}
+		case "PRIVATE KEY":
+			key, err := parsePrivateKey(block.Bytes)
+			if err != nil {
+				key = nil
+			}
+			c.addPrivateKey(key, "PKCS#12", sha256Hex(block.Bytes), false, occ)
+		}
+	}
+}
+
+// scanJKS records a Java (JKS / JCEKS) keystore. Certificates are stored in
+// the clear and are parsed; private key entries are password-protected and
+// are reported without algorithm details.
+func (c *materialCollector) scanJKS(path string, data []byte) {
+	format := "JKS"
+	if bytes.HasPrefix(data, jceksMagic) {
+		format = "JCEKS"
+	}
+	occ := occurrence{file: path}
+	c.addKeystore(path, format, true, occ)
+
+	entries, err := parseJKS(data)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.privateKey != nil {
+			c.addPrivateKey(nil, format, sha256Hex(e.privateKey), true, occ)
+		}
+		for _, der := range e.certs {
+			if cert, err := x509.ParseCertificate(der); err == nil {
+				c.addCertificate(cert, format, occ)
+			}
+		}
+	}
+}
+
+// jksEntry is a single entry of a JKS / JCEKS keystore.
+type jksEntry struct {
+	alias      string
+	privateKey []byte   // encrypted key blob (private key entries only)
+	certs      [][]byte // DER certificates (chain or trusted certificate)
+}
+
+// parseJKS decodes the unencrypted structure of a JKS / JCEKS keystore.
+// Secret-key entries (JCEKS tag 3) use Java serialisation and stop parsing.
+func parseJKS(data []byte) ([]jksEntry, error) {
+	r := bytes.NewReader(data[4:])
+	var version, count uint32
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+
+	readUTF := func() (string, error) {
+		var n uint16
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return "", err
+		}
+		b := make([]byte, n)
+		_, err := io.ReadFull(r, b)
+		return string(b), err
+	}
+	readBlob := func() ([]byte, error) {
+		var n uint32
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		if int64(n) > int64(r.Len()) {
+			return nil, fmt.Errorf("truncated keystore")
+		}
+		b := make([]byte, n)
+		_, err := io.ReadFull(r, b)
+		return b, err
+	}
+	readCert := func() ([]byte, error) {
+		if version == 2 {
+			if _, err := readUTF(); err != nil { // certificate type, e.g. "X.509"
+				return nil, err
+			}
+		}
+		return readBlob()
+	}
+
+	var entries []jksEntry
+	for i := uint32(0); i < count; i++ {
+		var tag uint32
+		if err := binary.Read(r, binary.BigEndian, &tag); err != nil {
+			return entries, err
+		}
+		alias, err := readUTF()
+		if err != nil {
+			return entries, err
+		}
+		var ts int64
+		if err := binary.Read(r, binary.BigEndian, &ts); err != nil {
+			return entries, err
+		}
+		e := jksEntry{alias: alias}
+		switch tag {
+		case 1: // private key entry
+			if e.privateKey, err = readBlob(); err != nil {
+				return entries, err
+			}
+			var chain uint32
+			if err := binary.Read(r, binary.BigEndian, &chain); err != nil {
+				return entries, err
+			}
+			for j := uint32(0); j < chain; j++ {
+				der, err := readCert()
+				if err != nil {
+					return entries, err
+				}
+				e.certs = append(e.certs, der)
+			}
+		case 2: // trusted certificate entry
+			der, err := readCert()
+			if err != nil {
+				return entries, err
+			}
+			e.certs = append(e.certs, der)
+		default:
+			return entries, fmt.Errorf("unsupported keystore entry tag %d", tag)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// addKeystore records the keystore container itself.
+func (c *materialCollector) addKeystore(path, format string, protected bool, occ occurrence) {
+	name := filepath.Base(path)
+	props := &cdx.RelatedCryptoMaterialProperties{
+		Type:   cdx.RelatedCryptoMaterialTypeOther,
+		Format: format,
+	}
+	if protected {
+		props.SecuredBy = &cdx.SecuredBy{Mechanism: "password"}
+	}
+	c.add(cdx.Component{
+		BOMRef:      "crypto/keystore/" + filepath.ToSlash(path),
+		Type:        cdx.ComponentTypeCryptographicAsset,
+		Name:        name,
+		Description: format + " keystore",
+		CryptoProperties: &cdx.CryptoProperties{
+			AssetType:                       cdx.CryptoAssetTypeRelatedCryptoMaterial,
+			RelatedCryptoMaterialProperties: props,
+		},
+	}, occ)
+}
+
+// ── Certificates and keys ────────────────────────────────────────────────────
+
+// addCertificate records an X.509 certificate plus the signature algorithm,
+// key algorithm and public key it references.
+func (c *materialCollector) addCertificate(cert *x509.Certificate, format string, occ occurrence) {
+	fp := sha256Hex(cert.Raw)
+
+	sigRef := c.addSignatureAlgorithm(cert.SignatureAlgorithm, occ)
+	pubRef := c.addPublicKey(cert.PublicKey, "X.509", cert.NotAfter.UTC().Format(time.RFC3339), occ)
+
+	name := cert.Subject.CommonName
+	if name == "" {
+		name = cert.Subject.String()
+	}
+	if name == "" {
+		name = "certificate " + fp[:16]
+	}
+
+	var props []cdx.Property
+	props = append(props,
+		cdx.Property{Name: "fingerprintSHA256", Value: fp},
+		cdx.Property{Name: "serialNumber", Value: cert.SerialNumber.String()},
+	)
+	if cert.IsCA {
+		props = append(props, cdx.Property{Name: "isCA", Value: "true"})
+	}
+	if cert.Subject.String() == cert.Issuer.String() {
+		props = append(props, cdx.Property{Name: "selfSigned", Value: "true"})
+	}
+	if c.now.After(cert.NotAfter) {
+		props = append(props, cdx.Property{Name: "certificateExpired", Value: "true"})
+	}
+
+	c.add(cdx.Component{
+		BOMRef:      "crypto/certificate/" + fp,
+		Type:        cdx.ComponentTypeCryptographicAsset,
+		Name:        name,
+		Description: "X.509 certificate issued by " + cert.Issuer.String(),
+		CryptoProperties: &cdx.CryptoProperties{
+			AssetType: cdx.CryptoAssetTypeCertificate,
+			CertificateProperties: &cdx.CertificateProperties{
+				SubjectName:           cert.Subject.String(),
+				IssuerName:            cert.Issuer.String(),
+				NotValidBefore:        cert.NotBefore.UTC().Format(time.RFC3339),
+				NotValidAfter:         cert.NotAfter.UTC().Format(time.RFC3339),
+				SignatureAlgorithmRef: cdx.BOMReference(sigRef),
+				SubjectPublicKeyRef:   cdx.BOMReference(pubRef),
+				CertificateFormat:     "X.509",
+				CertificateExtension:  format,
+			},
+		},
+		Properties: &props,
+	}, occ)
+}
+
+// addPublicKey records a public key and its algorithm, returning the key's
+// bom-ref. expires is the RFC 3339 expiry inherited from a certificate, if any.
+func (c *materialCollector) addPublicKey(pub interface{}, format, expires string, occ occurrence) string {
+	alg, size := keyInfo(pub)
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		der = []byte(fmt.Sprintf("%v", pub))
+	}
+	id := sha256Hex(der)
+	ref := "crypto/public-key/" + id[:16]
+
+	props := &cdx.RelatedCryptoMaterialProperties{
+		Type:           cdx.RelatedCryptoMaterialTypePublicKey,
+		ID:             id,
+		Format:         format,
+		ExpirationDate: expires,
+	}
+	if size > 0 {
+		s := size
+		props.Size = &s
+	}
+	if alg != "" {
+		props.AlgorithmRef = cdx.BOMReference(c.addKeyAlgorithm(alg, size, pub, occ))
+	}
+
+	name := "public key"
+	if alg != "" {
+		name = keyAlgorithmName(alg, size, pub) + " public key"
+	}
+	c.add(cdx.Component{
+		BOMRef: ref,
+		Type:   cdx.ComponentTypeCryptographicAsset,
+		Name:   name,
+		CryptoProperties: &cdx.CryptoProperties{
+			AssetType:                       cdx.CryptoAssetTypeRelatedCryptoMaterial,
+			RelatedCryptoMaterialProperties: props,
+		},
+	}, occ)
+	return ref
+}
+
+// addPrivateKey records a private key committed to the repository. key may be
+// nil when the key is encrypted or in an unrecognised encoding.
+func (c *materialCollector) addPrivateKey(key interface{}, format, id string, protected bool, occ occurrence) {
+	props := &cdx.RelatedCryptoMaterialProperties{
+		Type:   cdx.RelatedCryptoMaterialTypePrivateKey,
+		ID:     id,
+		Format: format,
+	}
+	if protected {
+		props.SecuredBy = &cdx.SecuredBy{Mechanism: "password"}
+	}
+
+	name := "private key"
+	if key != nil {
+		var pub interface{}
+		if signer, ok := key.(crypto.Signer); ok {
+			pub = signer.Public()
+		}
+		alg, size := keyInfo(pub)
+		if alg != "" {
+			if size > 0 {
+				s := size
+				props.Size = &s
+			}
+			props.AlgorithmRef = cdx.BOMReference(c.addKeyAlgorithm(alg, size, pub, occ))
+			name = keyAlgorithmName(alg, size, pub) + " private key"
+		}
+	}
+
+	c.add(cdx.Component{
+		BOMRef:      "crypto/private-key/" + id[:16],
+		Type:        cdx.ComponentTypeCryptographicAsset,
+		Name:        name,
+		Description: "Private key committed to the scanned source tree",
+		CryptoProperties: &cdx.CryptoProperties{
+			AssetType:                       cdx.CryptoAssetTypeRelatedCryptoMaterial,
+			RelatedCryptoMaterialProperties: props,
+		},
+		Properties: &[]cdx.Property{{Name: "privateKeyCommitted", Value: "true"}},
+	}, occ)
+}
+
+// addSignatureAlgorithm records a certificate signature algorithm component.
+func (c *materialCollector) addSignatureAlgorithm(alg x509.SignatureAlgorithm, occ occurrence) string {
+	name := alg.String()
+	ref := "crypto/algorithm/" + name
+	funcs := []cdx.CryptoFunction{cdx.CryptoFunctionSign, cdx.CryptoFunctionVerify}
+	c.add(cdx.Component{
+		BOMRef:      ref,
+		Type:        cdx.ComponentTypeCryptographicAsset,
+		Name:        name,
+		Description: "Certificate signature algorithm",
+		CryptoProperties: &cdx.CryptoProperties{
+			AssetType: cdx.CryptoAssetTypeAlgorithm,
+			AlgorithmProperties: &cdx.CryptoAlgorithmProperties{
+				Primitive:       cdx.CryptoPrimitiveSignature,
+				CryptoFunctions: &funcs,
+			},
+		},
+	}, occ)
+	return ref
+}
+
+// addKeyAlgorithm records the algorithm of a public or private key.
+func (c *materialCollector) addKeyAlgorithm(alg string, size int, pub interface{}, occ occurrence) string {
+	name := keyAlgorithmName(alg, size, pub)
+	ref := "crypto/algorithm/" + name
+
+	primitive := cdx.CryptoPrimitiveSignature
+	funcs := []cdx.CryptoFunction{cdx.CryptoFunctionSign, cdx.CryptoFunctionVerify}
+	switch alg {
+	case "RSA":
+		primitive = cdx.CryptoPrimitivePKE
+		funcs = []cdx.CryptoFunction{cdx.CryptoFunctionEncrypt, cdx.CryptoFunctionDecrypt, cdx.CryptoFunctionSign, cdx.CryptoFunctionVerify}
+	case "ECDH", "X25519":
+		primitive = cdx.CryptoPrimitiveKeyAgree
+		funcs = []cdx.CryptoFunction{cdx.CryptoFunctionKeygen}
+	}
+	params := ""
+	if size > 0 {
+		params = fmt.Sprint(size)
+	}
+
+	c.add(cdx.Component{
+		BOMRef:      ref,
+		Type:        cdx.ComponentTypeCryptographicAsset,
+		Name:        name,
+		Description: "Key algorithm",
+		CryptoProperties: &cdx.CryptoProperties{
+			AssetType: cdx.CryptoAssetTypeAlgorithm,
+			AlgorithmProperties: &cdx.CryptoAlgorithmProperties{
+				Primitive:              primitive,
+				ParameterSetIdentifier: params,
+				Curve:                  curveName(pub),
+				CryptoFunctions:        &funcs,
+			},
+		},
+	}, occ)
+	return ref
+}
+
+// keyInfo returns the algorithm name and key size in bits of a public key.
+func keyInfo(pub interface{}) (string, int) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return "RSA", k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return "ECDSA", k.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return "Ed25519", 256
+	case *dsa.PublicKey: // #nosec G505
+		return "DSA", k.P.BitLen()
+	case *ecdh.PublicKey:
+		if k.Curve() == ecdh.X25519() {
+			return "X25519", 256
+		}
+		return "ECDH", len(k.Bytes()) * 4
+	}
+	return "", 0
+}
+
+// curveName returns the named curve of an elliptic-curve key, if any.
+func curveName(pub interface{}) string {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		return k.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+	return ""
+}
+
+// keyAlgorithmName builds a display name such as "RSA-2048" or "ECDSA-P-256".
+func keyAlgorithmName(alg string, size int, pub interface{}) string {
+	if curve := curveName(pub); curve != "" && curve != alg {
+		return alg + "-" + curve
+	}
+	if alg == "Ed25519" || alg == "X25519" || size == 0 {
+		return alg
+	}
+	return fmt.Sprintf("%s-%d", alg, size)
+}
+
+// ── TLS configuration ────────────────────────────────────────────────────────
+
+// tlsProtocolDirectives are (lower-cased) directives that list TLS versions in
+// nginx, Apache httpd, HAProxy and OpenSSL configuration files.
+var tlsProtocolDirectives = map[string]bool{
+	"ssl_protocols":              true,
+	"sslprotocol":                true,
+	"ssl-min-ver":                true,
+	"ssl-max-ver":                true,
+	"ssl-default-bind-options":   true,
+	"ssl-default-server-options": true,
+	"minprotocol":                true,
+	"maxprotocol":                true,
+}
+
+// tlsCipherDirectives are (lower-cased) directives that list cipher suites.
+var tlsCipherDirectives = map[string]bool{
+	"ssl_ciphers":                   true,
+	"sslciphersuite":                true,
+	"ssl-default-bind-ciphers":      true,
+	"ssl-default-server-ciphers":    true,
+	"ssl-default-bind-ciphersuites": true,
+	"cipherstring":                  true,
+	"ciphersuites":                  true,
+}
+
+// scanTLSConfig extracts TLS protocol versions and cipher suites from a
+// server configuration file and records one TLS protocol component per
+// enabled version.
+func (c *materialCollector) scanTLSConfig(path string, data []byte) {
+	type hit struct {
+		version string
+		line    int
+	}
+	var versions []hit
+	var ciphers []string
+	cipherLine := 0
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		key, value := splitDirective(line)
+		switch {
+		case tlsProtocolDirectives[key]:
+			for _, tok := range strings.Fields(value) {
+				if v := tlsVersion(tok); v != "" {
+					versions = append(versions, hit{version: v, line: lineNo})
+				}
+			}
+		case tlsCipherDirectives[key]:
+			for _, s := range strings.FieldsFunc(value, func(r rune) bool { return r == ':' || r == ' ' || r == ',' }) {
+				s = strings.Trim(s, `"'`)
+				if s == "" || strings.ContainsAny(s[:1], "!-+@") {
+					continue
+				}
+				ciphers = append(ciphers, s)
+			}
+			if cipherLine == 0 {
+				cipherLine = lineNo
+			}
+		}
+	}
+
+	if len(versions) == 0 && len(ciphers) == 0 {
+		return
+	}
+	if len(versions) == 0 {
+		versions = append(versions, hit{line: cipherLine})
+	}
+
+	for _, v := range versions {
+		pp := &cdx.CryptoProtocolProperties{Type: cdx.CryptoProtocolTypeTLS, Version: v.version}
+		if len(ciphers) > 0 {
+			suites := make([]cdx.CipherSuite, 0, len(ciphers))
+			for _, name := range ciphers {
+				suites = append(suites, cdx.CipherSuite{Name: name})
+			}
+			pp.CipherSuites = &suites
+		}
+		ref := "crypto/protocol/tls"
+		name := "TLS"
+		if v.version != "" {
+			ref += "/" + v.version
+			name += " " + v.version
+		}
+		comp := cdx.Component{
+			BOMRef:      ref,
+			Type:        cdx.ComponentTypeCryptographicAsset,
+			Name:        name,
+			Description: "TLS protocol version enabled in server configuration",
+			CryptoProperties: &cdx.CryptoProperties{
+				AssetType:          cdx.CryptoAssetTypeProtocol,
+				ProtocolProperties: pp,
+			},
+		}
+		if deprecatedTLS(v.version) {
+			comp.Properties = &[]cdx.Property{{Name: "deprecatedProtocol", Value: "true"}}
+		}
+		c.add(comp, occurrence{file: path, line: v.line})
+	}
+}
+
+// splitDirective splits "key value;" or "key = value" into a lower-cased key
+// and its value with any trailing semicolon removed.
+func splitDirective(line string) (string, string) {
+	line = strings.TrimSuffix(line, ";")
+	if i := strings.Index(line, "="); i > 0 && !strings.ContainsAny(line[:i], " \t") {
+		return strings.ToLower(strings.TrimSpace(line[:i])), strings.TrimSpace(line[i+1:])
+	}
+	if i := strings.IndexAny(line, " \t="); i > 0 {
+		value := strings.TrimSpace(line[i:])
+		value = strings.TrimSpace(strings.TrimPrefix(value, "="))
+		return strings.ToLower(line[:i]), value
+	}
+	return strings.ToLower(line), ""
+}
+
+// tlsVersion normalises a protocol token ("TLSv1.2", "+TLSv1.3", "SSLv3") to
+// a version string. Tokens excluded with a leading "-" or "!" are ignored.
+func tlsVersion(tok string) string {
+	tok = strings.Trim(tok, `"'`)
+	if strings.HasPrefix(tok, "-") || strings.HasPrefix(tok, "!") {
+		return ""
+	}
+	tok = strings.TrimPrefix(tok, "+")
+	lower := strings.ToLower(tok)
+	switch {
+	case lower == "sslv2" || lower == "sslv3":
+		return "SSLv" + lower[len(lower)-1:]
+	case lower == "tlsv1" || lower == "tlsv1.0":
+		return "1.0"
+	case strings.HasPrefix(lower, "tlsv1."):
+		return strings.TrimPrefix(lower, "tlsv")
+	}
+	return ""
+}
+
+// deprecatedTLS reports whether a protocol version is deprecated (RFC 8996).
+func deprecatedTLS(version string) bool {
+	switch version {
+	case "SSLv2", "SSLv3", "1.0", "1.1":
+		return true
+	}
+	return false
+}
+
+// ── helpers ──────────────────────────────────────────────────────────────────
+
+// protocolProps returns the protocol properties of comp, or nil.
+func protocolProps(comp *cdx.Component) *cdx.CryptoProtocolProperties {
+	if comp.CryptoProperties == nil {
+		return nil
+	}
+	return comp.CryptoProperties.ProtocolProperties
+}
+
+// mergeCipherSuites appends cipher suites from src not already present in dst.
+func mergeCipherSuites(dst, src *cdx.CryptoProtocolProperties) {
+	if src == nil || src.CipherSuites == nil {
+		return
+	}
+	seen := map[string]bool{}
+	var merged []cdx.CipherSuite
+	if dst.CipherSuites != nil {
+		merged = append(merged, *dst.CipherSuites...)
+	}
+	for _, s := range merged {
+		seen[s.Name] = true
+	}
+	for _, s := range *src.CipherSuites {
+		if !seen[s.Name] {
+			seen[s.Name] = true
+			merged = append(merged, s)
+		}
+	}
+	dst.CipherSuites = &merged
+}
+
+// equalLine compares two optional line numbers.
+func equalLine(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// sha256Hex returns the lower-case hex SHA-256 digest of b.
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -380,8 +380,12 @@ type occurrence struct {
 
 // ScanSource walks the directory at path, parses Go source files, and returns
 // a slice of CycloneDX components for every distinct crypto package imported.
+// Certificates, keys, keystores and TLS configuration files found alongside the
+// source are reported as certificate, related-crypto-material and protocol
+// components.
 func ScanSource(path string) ([]cdx.Component, error) {
 	seen := map[string][]occurrence{}
+	material := newMaterialCollector()
 
 	err := filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
 		if err != nil {
@@ -400,6 +404,9 @@ func ScanSource(path string) ([]cdx.Component, error) {
 			return nil
 		}
 		if !strings.HasSuffix(p, ".go") {
+			if isMaterialCandidate(d.Name()) {
+				material.scanFile(p)
+			}
 			return nil
 		}
 
@@ -425,7 +432,7 @@ func ScanSource(path string) ([]cdx.Component, error) {
 		return nil, fmt.Errorf("walking %s: %w", path, err)
 	}
 
-	return buildComponents(seen), nil
+	return append(buildComponents(seen), material.components()...), nil
 }
 
 // buildComponents converts the collected import occurrences into CycloneDX components.
@@ -27,6 +27,31 @@ type Options struct {
 	OutputTo string // write CBOM JSON to this file instead of stdout
 	Format   string // output format: "json" (default) or "table"
 
+	// Provenance recorded in metadata (see bom.Stamp)
+	ToolVersion   string // knoxctl version (version.GitSummary)
+	Deterministic bool   // reproducible output: sorted components, content-derived serial number
+
 	// Signing options — sign the output artifact with cosign.
 	Sign sign.Options
+
+	// inputs are the digests of the scanned image or source tree, resolved
+	// once by Output for both the provenance block and the attestation.
+	inputs []sign.Subject
+}
+
+// recordedOptions are the generation options recorded in the provenance
+// block. Output and signing settings are left out: they do not change what
+// the BOM describes.
+type recordedOptions struct {
+	Path          string `json:"path,omitempty"`
+	Image         string `json:"image,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Group         string `json:"group,omitempty"`
+	Version       string `json:"version,omitempty"`
+	Description   string `json:"description,omitempty"`
+	License       string `json:"license,omitempty"`
+	BOMFile       string `json:"bom,omitempty"`
+	Plugins       string `json:"plugins,omitempty"`
+	Ignore        string `json:"ignore,omitempty"`
+	Deterministic bool   `json:"deterministic,omitempty"`
 }
@@ -3,13 +3,19 @@ package common
 import (
 	"context"
 
+	"github.com/accuknox/accuknox-cli-v2/pkg/download"
 	"github.com/google/go-github/github"
 )
 
-// SetupGitHubClient sets up a GitHub client with the provided PAT, it uses oAuth2 to authenticate
+// SetupGitHubClient sets up a GitHub client that goes through the user's
+// download mirrors, CA bundle and proxy
 func SetupGitHubClient(ctx context.Context) (*github.Client, error) {
+	httpClient, err := download.Client()
+	if err != nil {
+		return nil, err
+	}
 
-	client := github.NewClient(nil)
+	client := github.NewClient(httpClient)
 
 	return client, nil
 }
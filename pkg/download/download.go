@@ -0,0 +1,185 @@
+// Package download provides the HTTP client knoxctl uses to fetch tools,
+// policy templates and its own releases, so that mirrors, custom CA bundles
+// and proxies apply to every download in the same way.
+package download
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigEnv names the environment variable that overrides ConfigPath.
+const ConfigEnv = "KNOXCTL_DOWNLOAD_CONFIG"
+
+// Config holds the user's download settings, e.g.
+//
+//	mirrors:
+//	  github.com/: artifactory.internal/artifactory/github/
+//	  raw.githubusercontent.com/: artifactory.internal/artifactory/github-raw/
+//	caBundle: /etc/pki/corp-root-ca.pem
+//
+// Proxies are taken from $HTTPS_PROXY, $HTTP_PROXY and $NO_PROXY.
+type Config struct {
+	// Mirrors maps URL prefixes to the prefixes that replace them. A prefix
+	// without a scheme is taken to be https://. The longest matching
+	// prefix wins, and it applies to redirects as well.
+	Mirrors map[string]string `yaml:"mirrors"`
+
+	// CABundle is a PEM file of certificates trusted in addition to the
+	// system roots, e.g. the root of a TLS-intercepting proxy.
+	CABundle string `yaml:"caBundle"`
+}
+
+// ConfigPath returns the user's download settings file:
+// $KNOXCTL_DOWNLOAD_CONFIG, or ~/.accuknox-config/download.yaml.
+func ConfigPath() string {
+	if p := os.Getenv(ConfigEnv); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), ".accuknox-config", "download.yaml")
+	}
+	return filepath.Join(home, ".accuknox-config", "download.yaml")
+}
+
+// LoadConfig reads the settings at path. A missing file yields the defaults.
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(path) // #nosec G304 -- the user's own config
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for from, to := range cfg.Mirrors {
+		if _, err := url.Parse(withScheme(from)); err != nil {
+			return nil, fmt.Errorf("%s: invalid mirror prefix %q: %w", path, from, err)
+		}
+		if _, err := url.Parse(withScheme(to)); err != nil {
+			return nil, fmt.Errorf("%s: invalid mirror for %q: %w", path, from, err)
+		}
+	}
+	return cfg, nil
+}
+
+func withScheme(prefix string) string {
+	if strings.Contains(prefix, "://") {
+		return prefix
+	}
+	return "https://" + prefix
+}
+
+// Rewrite returns rawURL with its longest matching mirror prefix replaced,
+// or rawURL itself when no mirror applies. A prefix only matches at a path
+// boundary, so github.com does not match github.company.com.
+func (c *Config) Rewrite(rawURL string) string {
+	best, to := "", ""
+	for from, mirror := range c.Mirrors {
+		from = withScheme(from)
+		rest, ok := strings.CutPrefix(rawURL, from)
+		if !ok || len(from) <= len(best) {
+			continue
+		}
+		if !strings.HasSuffix(from, "/") && rest != "" && !strings.ContainsAny(rest[:1], "/?#") {
+			continue
+		}
+		best, to = from, withScheme(mirror)
+	}
+	if best == "" {
+		return rawURL
+	}
+	return to + strings.TrimPrefix(rawURL, best)
+}
+
+// NewClient returns an HTTP client that applies c to every request. It has
+// no overall timeout, since tool downloads can be large.
+func (c *Config) NewClient() (*http.Client, error) {
+	base, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("unexpected default transport %T", http.DefaultTransport)
+	}
+	tr := base.Clone()
+	tr.Proxy = http.ProxyFromEnvironment
+	if c.CABundle != "" {
+		pem, err := os.ReadFile(c.CABundle) // #nosec G304 -- the user's own CA bundle
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", c.CABundle)
+		}
+		tr.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	var rt http.RoundTripper = tr
+	if len(c.Mirrors) > 0 {
+		rt = &mirrorTransport{cfg: c, base: tr}
+	}
+	return &http.Client{Transport: rt}, nil
+}
+
+// mirrorTransport sends each request to its mirror, if it has one.
+type mirrorTransport struct {
+	cfg  *Config
+	base http.RoundTripper
+}
+
+func (t *mirrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	from := req.URL.String()
+	to := t.cfg.Rewrite(from)
+	if to == from {
+		return t.base.RoundTrip(req)
+	}
+	u, err := url.Parse(to)
+	if err != nil {
+		return nil, fmt.Errorf("mirror for %s: %w", from, err)
+	}
+	r := req.Clone(req.Context())
+	r.URL, r.Host = u, ""
+	return t.base.RoundTrip(r)
+}
+
+var (
+	clientOnce sync.Once
+	client     *http.Client
+	clientErr  error
+)
+
+// Client returns the HTTP client for the settings at ConfigPath.
+func Client() (*http.Client, error) {
+	clientOnce.Do(func() {
+		var cfg *Config
+		if cfg, clientErr = LoadConfig(ConfigPath()); clientErr == nil {
+			client, clientErr = cfg.NewClient()
+		}
+	})
+	return client, clientErr
+}
+
+// Get fetches rawURL with Client.
+func Get(rawURL string) (*http.Response, error) {
+	c, err := Client()
+	if err != nil {
+		return nil, err
+	}
+	return c.Get(rawURL) // #nosec G107 -- callers pass their configured download URLs
+}
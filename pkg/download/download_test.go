@@ -0,0 +1,86 @@
+package download
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	cfg := &Config{Mirrors: map[string]string{
+		"github.com":                   "mirror.internal/github",
+		"https://github.com/anchore/":  "https://mirror.internal/anchore/",
+		"http://plain.example.com/dl/": "https://mirror.internal/plain/",
+	}}
+	for in, want := range map[string]string{
+		"https://github.com/accuknox/x.tar.gz":       "https://mirror.internal/github/accuknox/x.tar.gz",
+		"https://github.com/anchore/syft/v1/syft.gz": "https://mirror.internal/anchore/syft/v1/syft.gz",
+		"https://github.com":                         "https://mirror.internal/github",
+		"https://github.company.com/x":               "https://github.company.com/x",
+		"http://plain.example.com/dl/a.zip":          "https://mirror.internal/plain/a.zip",
+		"https://plain.example.com/dl/a.zip":         "https://plain.example.com/dl/a.zip",
+	} {
+		if got := cfg.Rewrite(in); got != want {
+			t.Errorf("Rewrite(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := LoadConfig(filepath.Join(dir, "missing.yaml"))
+	if err != nil || len(cfg.Mirrors) != 0 || cfg.CABundle != "" {
+		t.Fatalf("missing file: %+v, %v", cfg, err)
+	}
+	path := filepath.Join(dir, "download.yaml")
+	if err := os.WriteFile(path, []byte("mirrors:\n  github.com/: mirror.internal/\ncaBundle: /ca.pem\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadConfig(path)
+	if err != nil || cfg.Mirrors["github.com/"] != "mirror.internal/" || cfg.CABundle != "/ca.pem" {
+		t.Errorf("LoadConfig = %+v, %v", cfg, err)
+	}
+}
+
+func TestNewClient_MirrorWithCABundle(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.URL.Path)
+	}))
+	defer srv.Close()
+	cfg := &Config{Mirrors: map[string]string{"https://github.com/": srv.URL + "/mirror/"}}
+
+	c, err := cfg.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("https://github.com/org/repo/file"); err == nil {
+		t.Error("the mirror's certificate was trusted without a CA bundle")
+	}
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(bundle, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg.CABundle = bundle
+	if c, err = cfg.NewClient(); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get("https://github.com/org/repo/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); string(body) != "/mirror/org/repo/file" {
+		t.Errorf("mirror served %q", body)
+	}
+
+	cfg.CABundle = filepath.Join(t.TempDir(), "missing.pem")
+	if _, err := cfg.NewClient(); err == nil {
+		t.Error("a missing CA bundle was accepted")
+	}
+}
@@ -0,0 +1,121 @@
+//go:build !windows
+
+package imagescan
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	containersapi "github.com/containerd/containerd/api/services/containers/v1"
+	imagesapi "github.com/containerd/containerd/api/services/images/v1"
+	namespacesapi "github.com/containerd/containerd/api/services/namespaces/v1"
+	tasksapi "github.com/containerd/containerd/api/services/tasks/v1"
+	"github.com/containerd/containerd/api/types/task"
+	"github.com/samber/lo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// containerdNamespaceHeader is the gRPC header selecting a containerd
+// namespace.
+const containerdNamespaceHeader = "containerd-namespace"
+
+// listContainerd lists the images, or the images of the containers, in the
+// given namespaces of the containerd instance at sock.
+func listContainerd(ctx context.Context, sock string, namespaces []string, onlyRunningContainers, imageOnly bool) ([]DiscoveredImage, error) {
+	conn, err := grpc.NewClient("unix://"+sock, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("connecting to containerd: %w", err)
+	}
+	defer conn.Close()
+
+	if lo.Contains(namespaces, AllNamespaces) {
+		resp, err := namespacesapi.NewNamespacesClient(conn).List(ctx, &namespacesapi.ListNamespacesRequest{})
+		if err != nil {
+			return nil, fmt.Errorf("listing namespaces: %w", err)
+		}
+		namespaces = nil
+		for _, ns := range resp.Namespaces {
+			namespaces = append(namespaces, ns.Name)
+		}
+	}
+
+	var images []DiscoveredImage
+	for _, ns := range namespaces {
+		nsCtx := metadata.AppendToOutgoingContext(ctx, containerdNamespaceHeader, ns)
+		digests, err := containerdImages(nsCtx, conn)
+		if err != nil {
+			return nil, fmt.Errorf("namespace %s: %w", ns, err)
+		}
+		if imageOnly {
+			names := lo.Keys(digests)
+			sort.Strings(names)
+			for _, name := range names {
+				images = append(images, DiscoveredImage{Name: name, Runtime: "containerd", Socket: sock, Namespace: ns, Digest: digests[name]})
+			}
+			continue
+		}
+		containers, err := containerdContainers(nsCtx, conn, onlyRunningContainers)
+		if err != nil {
+			return nil, fmt.Errorf("namespace %s: %w", ns, err)
+		}
+		for _, c := range containers {
+			images = append(images, DiscoveredImage{Name: c.Image, Runtime: "containerd", Socket: sock, Namespace: ns, Digest: digests[c.Image], Labels: c.Labels})
+		}
+	}
+	return images, nil
+}
+
+// containerdImages returns the digests of the images in the namespace of
+// ctx by name.
+func containerdImages(ctx context.Context, conn *grpc.ClientConn) (map[string]string, error) {
+	resp, err := imagesapi.NewImagesClient(conn).List(ctx, &imagesapi.ListImagesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("listing images: %w", err)
+	}
+	digests := map[string]string{}
+	for _, img := range resp.Images {
+		// Skip the digest-only aliases the CRI plugin records for each image.
+		if strings.HasPrefix(img.Name, "sha256:") {
+			continue
+		}
+		digests[img.Name] = ""
+		if img.Target != nil {
+			digests[img.Name] = img.Target.Digest
+		}
+	}
+	return digests, nil
+}
+
+func containerdContainers(ctx context.Context, conn *grpc.ClientConn, onlyRunning bool) ([]*containersapi.Container, error) {
+	resp, err := containersapi.NewContainersClient(conn).List(ctx, &containersapi.ListContainersRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+
+	var running map[string]bool
+	if onlyRunning {
+		tasks, err := tasksapi.NewTasksClient(conn).List(ctx, &tasksapi.ListTasksRequest{})
+		if err != nil {
+			return nil, fmt.Errorf("listing tasks: %w", err)
+		}
+		running = map[string]bool{}
+		for _, t := range tasks.Tasks {
+			if t.Status == task.Status_RUNNING {
+				running[t.ContainerID] = true
+			}
+		}
+	}
+
+	var containers []*containersapi.Container
+	for _, c := range resp.Containers {
+		if c.Image == "" || (onlyRunning && !running[c.ID]) {
+			continue
+		}
+		containers = append(containers, c)
+	}
+	return containers, nil
+}
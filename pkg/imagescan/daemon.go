@@ -0,0 +1,509 @@
+//go:build !windows
+
+package imagescan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	kubesheildScanner "github.com/accuknox/kubeshield/pkg/scanner/scan"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	stateFile  = "state.json"
+	statusFile = "status.json"
+	lockFile   = "daemon.lock"
+)
+
+// DaemonOptions configures the scan daemon.
+type DaemonOptions struct {
+	Schedule       string        // cron expression of the scan runs
+	Jitter         time.Duration // random delay of up to Jitter before each run
+	MaxConcurrency int           // images scanned in parallel (default 1); uploads run one at a time
+	RunOnStart     bool          // run once immediately instead of waiting for the schedule
+	StateDir       string        // where image state and status are kept (default DefaultStateDir())
+
+	// Local configures discovery and the scanner; reports are written to
+	// Local.OutputDir unless Upload is set.
+	Local LocalOptions
+
+	// Upload, when set, sends the results through the artifact API instead.
+	Upload *kubesheildScanner.ScanConfig
+}
+
+// DaemonStatus is the health and last-run record the daemon keeps in
+// <StateDir>/status.json.
+type DaemonStatus struct {
+	PID       int        `json:"pid"`
+	StartedAt time.Time  `json:"startedAt"`
+	Schedule  string     `json:"schedule"`
+	Mode      string     `json:"mode"` // "local" or "upload"
+	Running   bool       `json:"running"`
+	NextRun   *time.Time `json:"nextRun,omitempty"`
+	LastRun   *RunStatus `json:"lastRun,omitempty"`
+	StoppedAt *time.Time `json:"stoppedAt,omitempty"`
+}
+
+// RunStatus summarises one scan run.
+type RunStatus struct {
+	StartedAt   time.Time `json:"startedAt"`
+	FinishedAt  time.Time `json:"finishedAt,omitempty"`
+	DBUpdatedAt string    `json:"dbUpdatedAt,omitempty"`
+	Discovered  int       `json:"discovered"`
+	Scanned     int       `json:"scanned"`
+	Unchanged   int       `json:"unchanged"`
+	Failed      int       `json:"failed"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Healthy reports whether the daemon is alive and its last run succeeded
+// no longer than maxAge ago (0 disables the age check).
+func (s *DaemonStatus) Healthy(maxAge time.Duration) error {
+	if s.StoppedAt != nil || !processAlive(s.PID) {
+		return fmt.Errorf("daemon (pid %d) is not running", s.PID)
+	}
+	if s.LastRun == nil || s.LastRun.FinishedAt.IsZero() {
+		if s.Running {
+			return nil
+		}
+		return fmt.Errorf("daemon has not completed a scan yet")
+	}
+	if s.LastRun.Error != "" {
+		return fmt.Errorf("last run failed: %s", s.LastRun.Error)
+	}
+	if s.LastRun.Failed > 0 {
+		return fmt.Errorf("last run failed to scan %d image(s)", s.LastRun.Failed)
+	}
+	if maxAge > 0 && time.Since(s.LastRun.FinishedAt) > maxAge {
+		return fmt.Errorf("last run finished %s ago", time.Since(s.LastRun.FinishedAt).Round(time.Second))
+	}
+	return nil
+}
+
+// imageState records the last scan of an image.
+type imageState struct {
+	Runtime     string    `json:"runtime,omitempty"`
+	Digest      string    `json:"digest,omitempty"`
+	DBUpdatedAt string    `json:"dbUpdatedAt,omitempty"`
+	ScannedAt   time.Time `json:"scannedAt"`
+	Reports     []string  `json:"reports,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// scanState is the per-image state kept in <StateDir>/state.json.
+type scanState struct {
+	Images map[string]imageState `json:"images"`
+}
+
+// DefaultStateDir returns ~/.accuknox-config/image-scan.
+func DefaultStateDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), ".accuknox-config", "image-scan")
+	}
+	return filepath.Join(home, ".accuknox-config", "image-scan")
+}
+
+// ReadStatus reads the status file of the daemon using stateDir.
+func ReadStatus(stateDir string) (*DaemonStatus, error) {
+	if stateDir == "" {
+		stateDir = DefaultStateDir()
+	}
+	var s DaemonStatus
+	if err := readJSON(filepath.Join(stateDir, statusFile), &s); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no scan daemon status in %s", stateDir)
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+type daemon struct {
+	opts    DaemonOptions
+	bin     string
+	formats []string
+
+	mu     sync.Mutex
+	status DaemonStatus
+	state  scanState
+}
+
+// trivyBin resolves the scanner the daemon runs.
+var trivyBin = resolveTrivyBin
+
+// RunDaemon scans discovered images on opts.Schedule until ctx is done. Each
+// run updates the vulnerability database once, then rescans only the images
+// that are new, whose digest changed, whose last scan failed, or that were
+// last scanned against an older database. Image state and the daemon status
+// are persisted in opts.StateDir; only one daemon may use a state directory.
+func RunDaemon(ctx context.Context, opts DaemonOptions) error {
+	sched, err := cron.ParseStandard(opts.Schedule)
+	if err != nil {
+		return fmt.Errorf("invalid schedule %q: %w", opts.Schedule, err)
+	}
+	d, unlock, err := newDaemon(opts)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	opts = d.opts
+
+	c := cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)))
+	c.Schedule(sched, cron.FuncJob(func() {
+		if d.sleepJitter(ctx) {
+			d.run(ctx)
+		}
+	}))
+	next := sched.Next(time.Now()).UTC()
+	d.status.NextRun = &next
+	d.writeStatus()
+
+	fmt.Printf("Image scan daemon started (schedule %q, state in %s)\n", opts.Schedule, opts.StateDir)
+	if opts.RunOnStart {
+		d.run(ctx)
+	}
+	c.Start()
+	<-ctx.Done()
+	<-c.Stop().Done()
+
+	d.mu.Lock()
+	stopped := time.Now().UTC()
+	d.status.StoppedAt = &stopped
+	d.status.Running = false
+	d.status.NextRun = nil
+	d.mu.Unlock()
+	d.writeStatus()
+	fmt.Println("Image scan daemon stopped")
+	return nil
+}
+
+// newDaemon locks opts.StateDir and loads the image state and last run kept
+// there. The lock is held until the returned function is called.
+func newDaemon(opts DaemonOptions) (*daemon, func(), error) {
+	if opts.MaxConcurrency < 1 {
+		opts.MaxConcurrency = 1
+	}
+	if opts.StateDir == "" {
+		opts.StateDir = DefaultStateDir()
+	}
+	if err := os.MkdirAll(opts.StateDir, 0o700); err != nil {
+		return nil, nil, fmt.Errorf("creating state directory %s: %w", opts.StateDir, err)
+	}
+	unlock, err := lockStateDir(opts.StateDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	d, err := loadDaemon(opts)
+	if err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	return d, unlock, nil
+}
+
+func loadDaemon(opts DaemonOptions) (*daemon, error) {
+	d := &daemon{opts: opts, state: scanState{Images: map[string]imageState{}}}
+	var err error
+	if d.formats, err = normalizeFormats(opts.Local.Formats); err != nil {
+		return nil, err
+	}
+	if d.bin, err = trivyBin(); err != nil {
+		return nil, fmt.Errorf("error while resolving container image scanner: %v", err)
+	}
+	if opts.Upload != nil {
+		// kubeshield runs the scanner from PATH.
+		prependToPath(filepath.Dir(d.bin))
+	} else if err := os.MkdirAll(opts.Local.OutputDir, 0o750); err != nil {
+		return nil, fmt.Errorf("creating output directory %s: %w", opts.Local.OutputDir, err)
+	}
+	if err := readJSON(filepath.Join(opts.StateDir, stateFile), &d.state); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if d.state.Images == nil {
+		d.state.Images = map[string]imageState{}
+	}
+
+	d.status = DaemonStatus{PID: os.Getpid(), StartedAt: time.Now().UTC(), Schedule: opts.Schedule, Mode: "local"}
+	if opts.Upload != nil {
+		d.status.Mode = "upload"
+	}
+	if last, err := ReadStatus(opts.StateDir); err == nil {
+		d.status.LastRun = last.LastRun
+	}
+	return d, nil
+}
+
+// sleepJitter waits a random delay of up to opts.Jitter, returning false
+// when ctx is done first.
+func (d *daemon) sleepJitter(ctx context.Context) bool {
+	if d.opts.Jitter <= 0 {
+		return true
+	}
+	delay := time.Duration(rand.Int63n(int64(d.opts.Jitter))) // #nosec G404 -- jitter needs no cryptographic randomness
+	select {
+	case <-time.After(delay):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// run performs one scan run and records its outcome.
+func (d *daemon) run(ctx context.Context) {
+	rs := &RunStatus{StartedAt: time.Now().UTC()}
+	d.mu.Lock()
+	d.status.Running = true
+	d.mu.Unlock()
+	d.writeStatus()
+
+	if err := d.scanChanged(ctx, rs); err != nil {
+		rs.Error = err.Error()
+		fmt.Fprintf(os.Stderr, "Image scan run failed: %v\n", err)
+	}
+	rs.FinishedAt = time.Now().UTC()
+	fmt.Printf("Image scan run finished: %d discovered, %d scanned, %d unchanged, %d failed\n",
+		rs.Discovered, rs.Scanned, rs.Unchanged, rs.Failed)
+
+	d.mu.Lock()
+	d.status.Running = false
+	d.status.LastRun = rs
+	if sched, err := cron.ParseStandard(d.opts.Schedule); err == nil {
+		next := sched.Next(time.Now()).UTC()
+		d.status.NextRun = &next
+	}
+	d.mu.Unlock()
+	d.writeStatus()
+}
+
+func (d *daemon) scanChanged(ctx context.Context, rs *RunStatus) error {
+	opts := d.opts.Local
+	if !opts.Offline {
+		if err := runTrivy(d.bin, dbUpdateArgs(&opts)); err != nil {
+			return fmt.Errorf("updating vulnerability database: %w", err)
+		}
+	}
+	rs.DBUpdatedAt = dbUpdatedAt(opts.CacheDir)
+
+	images, err := localImages(opts)
+	if err != nil {
+		return err
+	}
+	rs.Discovered = len(images)
+
+	var pending []DiscoveredImage
+	digests := make(map[string]string, len(images))
+	seen := make(map[string]bool, len(images))
+	for _, img := range images {
+		seen[img.Name] = true
+		digests[img.Name] = imageDigest(ctx, img, opts.Offline)
+		prev, ok := d.state.Images[img.Name]
+		if ok && !needsRescan(prev, digests[img.Name], rs.DBUpdatedAt) {
+			rs.Unchanged++
+			continue
+		}
+		pending = append(pending, img)
+	}
+
+	// The database was updated above: scans only read it.
+	opts.SkipDBUpdate = true
+	if d.opts.MaxConcurrency > 1 {
+		opts.cacheBackend = "memory"
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, d.opts.MaxConcurrency)
+	for _, img := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(img DiscoveredImage) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			st := imageState{Runtime: img.Runtime, Digest: digests[img.Name], DBUpdatedAt: rs.DBUpdatedAt, ScannedAt: time.Now().UTC()}
+			if d.opts.Upload != nil {
+				if err := uploadImages(*d.opts.Upload, opts.HostName, []DiscoveredImage{img}); err != nil {
+					st.Error = err.Error()
+				}
+			} else {
+				r := scanImageLocal(d.bin, img, d.formats, &opts)
+				st.Reports = r.Files
+				if r.Error != nil {
+					st.Error = r.Error.Error()
+				}
+			}
+
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.state.Images[img.Name] = st
+			if st.Error != "" {
+				rs.Failed++
+				fmt.Fprintf(os.Stderr, "Warning: scanning %s: %s\n", img.Name, st.Error)
+			} else {
+				rs.Scanned++
+			}
+		}(img)
+	}
+	wg.Wait()
+
+	// Forget images that are gone, so they are scanned again if they return.
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for name := range d.state.Images {
+		if !seen[name] {
+			delete(d.state.Images, name)
+		}
+	}
+	return writeJSON(filepath.Join(d.opts.StateDir, stateFile), &d.state)
+}
+
+// needsRescan reports whether an image scanned as prev must be scanned
+// again. An unknown digest or database version counts as a change.
+func needsRescan(prev imageState, digest, dbUpdatedAt string) bool {
+	return prev.Error != "" ||
+		digest == "" || prev.Digest != digest ||
+		dbUpdatedAt == "" || prev.DBUpdatedAt != dbUpdatedAt
+}
+
+func (d *daemon) writeStatus() {
+	d.mu.Lock()
+	status := d.status
+	d.mu.Unlock()
+	if err := writeJSON(filepath.Join(d.opts.StateDir, statusFile), &status); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// dbUpdateArgs builds the scanner command line that only refreshes the
+// vulnerability database.
+func dbUpdateArgs(opts *LocalOptions) []string {
+	args := []string{"image", "--quiet", "--download-db-only"}
+	if opts.CacheDir != "" {
+		args = append(args, "--cache-dir", opts.CacheDir)
+	}
+	return args
+}
+
+// dbUpdatedAt returns the UpdatedAt time of the vulnerability database in
+// cacheDir (default: the scanner's own cache), or "" when it is unknown.
+func dbUpdatedAt(cacheDir string) string {
+	if cacheDir == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return ""
+		}
+		cacheDir = filepath.Join(dir, "trivy")
+	}
+	var meta struct {
+		UpdatedAt string `json:"UpdatedAt"`
+	}
+	if err := readJSON(filepath.Join(cacheDir, "db", "metadata.json"), &meta); err != nil {
+		return ""
+	}
+	return meta.UpdatedAt
+}
+
+// imageDigest identifies the content of an image: a digest the reference is
+// pinned to, the digest found at discovery, the image ID reported by a
+// docker or podman runtime, or, unless offline, the manifest digest in the
+// registry. It returns "" when none is known.
+func imageDigest(ctx context.Context, img DiscoveredImage, offline bool) string {
+	if i := strings.LastIndex(img.Name, "@"); i > 0 {
+		return img.Name[i+1:]
+	}
+	if img.Digest != "" {
+		return img.Digest
+	}
+	if img.Runtime == "docker" || img.Runtime == "podman" {
+		socks := []string{img.Socket}
+		if img.Socket == "" {
+			_, socks, _ = DiscoverRuntime("", img.Runtime)
+		}
+		for _, sock := range socks {
+			if id := dockerImageID(ctx, sock, img.Name); id != "" {
+				return id
+			}
+		}
+	}
+	if offline {
+		return ""
+	}
+	ref, err := name.ParseReference(img.Name)
+	if err != nil {
+		return ""
+	}
+	desc, err := remote.Head(ref, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return ""
+	}
+	return desc.Digest.String()
+}
+
+// lockStateDir takes an exclusive lock on stateDir, held until the returned
+// function is called.
+func lockStateDir(stateDir string) (func(), error) {
+	path := filepath.Join(stateDir, lockFile)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("another scan daemon is using %s", stateDir)
+	}
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeJSON replaces path atomically, so readers never see a partial file.
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling %s: %w", filepath.Base(path), err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
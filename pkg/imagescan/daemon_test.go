@@ -0,0 +1,240 @@
+//go:build !windows
+
+package imagescan
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNeedsRescan(t *testing.T) {
+	prev := imageState{Digest: digestA, DBUpdatedAt: "2026-10-18T00:00:00Z"}
+	failed := prev
+	failed.Error = "scan failed"
+
+	cases := []struct {
+		name       string
+		prev       imageState
+		digest, db string
+		wantRescan bool
+	}{
+		{"unchanged", prev, digestA, prev.DBUpdatedAt, false},
+		{"new digest", prev, digestB, prev.DBUpdatedAt, true},
+		{"new database", prev, digestA, "2026-10-19T00:00:00Z", true},
+		{"unknown digest", prev, "", prev.DBUpdatedAt, true},
+		{"unknown database", prev, digestA, "", true},
+		{"never scanned", imageState{}, digestA, prev.DBUpdatedAt, true},
+		{"previous scan failed", failed, digestA, prev.DBUpdatedAt, true},
+	}
+	for _, tc := range cases {
+		if got := needsRescan(tc.prev, tc.digest, tc.db); got != tc.wantRescan {
+			t.Errorf("%s: needsRescan = %v, want %v", tc.name, got, tc.wantRescan)
+		}
+	}
+}
+
+// fakeDaemonTrivy stands in for the scanner in daemon runs: the database
+// update writes metadata with $DB_UPDATED_AT, or fails when $FAIL_DB is set;
+// scans of images named "broken" fail; every other scan logs the image to
+// $RUN_DIR/scanned and the number of scans then running to
+// $RUN_DIR/concurrency.
+const fakeDaemonTrivy = `#!/bin/sh
+out=
+cache=
+for arg; do
+	case $prev in
+	--output) out=$arg ;;
+	--cache-dir) cache=$arg ;;
+	esac
+	prev=$arg
+	last=$arg
+done
+case " $* " in
+*" --download-db-only "*)
+	[ -n "$FAIL_DB" ] && { echo "database unavailable" >&2; exit 1; }
+	mkdir -p "$cache/db"
+	printf '{"UpdatedAt":"%s"}' "$DB_UPDATED_AT" > "$cache/db/metadata.json"
+	exit 0 ;;
+esac
+case $last in
+broken*) echo "cannot scan $last" >&2; exit 1 ;;
+esac
+: > "$RUN_DIR/running.$$"
+ls "$RUN_DIR" | grep -c '^running\.' >> "$RUN_DIR/concurrency"
+echo "$last" >> "$RUN_DIR/scanned"
+sleep 0.2
+rm -f "$RUN_DIR/running.$$"
+printf '%s' '{"Results":[]}' > "$out"
+`
+
+// testDaemonOptions installs the fake scanner and returns daemon options
+// scanning images, with the scanner's log directory.
+func testDaemonOptions(t *testing.T, images ...string) (DaemonOptions, string) {
+	t.Helper()
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "trivy")
+	if err := os.WriteFile(bin, []byte(fakeDaemonTrivy), 0o700); err != nil { // #nosec G306
+		t.Fatalf("writing fake scanner: %v", err)
+	}
+	orig := trivyBin
+	trivyBin = func() (string, error) { return bin, nil }
+	t.Cleanup(func() { trivyBin = orig })
+
+	runDir := filepath.Join(dir, "run")
+	if err := os.Mkdir(runDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("RUN_DIR", runDir)
+	t.Setenv("DB_UPDATED_AT", "2026-10-18T00:00:00Z")
+	return DaemonOptions{
+		Schedule: "0 * * * *",
+		StateDir: filepath.Join(dir, "state"),
+		Local: LocalOptions{
+			Images:    images,
+			OutputDir: filepath.Join(dir, "reports"),
+			CacheDir:  filepath.Join(dir, "cache"),
+		},
+	}, runDir
+}
+
+// tick runs the daemon once, as a fresh process would, and returns its status.
+func tick(t *testing.T, opts DaemonOptions) *DaemonStatus {
+	t.Helper()
+	d, unlock, err := newDaemon(opts)
+	if err != nil {
+		t.Fatalf("newDaemon: %v", err)
+	}
+	defer unlock()
+	d.run(context.Background())
+	status, err := ReadStatus(opts.StateDir)
+	if err != nil {
+		t.Fatalf("ReadStatus: %v", err)
+	}
+	return status
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return strings.Fields(string(data))
+}
+
+func TestDaemonRun_PersistsStateAcrossTicks(t *testing.T) {
+	nginx, redis := "nginx@"+digestA, "redis@"+digestB
+	opts, runDir := testDaemonOptions(t, nginx, redis, "broken:1")
+
+	status := tick(t, opts)
+	last := status.LastRun
+	if last == nil || last.FinishedAt.IsZero() || status.Running {
+		t.Fatalf("status after first tick = %+v, want a finished run", status)
+	}
+	if last.Discovered != 3 || last.Scanned != 2 || last.Failed != 1 || last.Unchanged != 0 {
+		t.Errorf("first run = %+v, want 3 discovered, 2 scanned, 1 failed", last)
+	}
+	if last.DBUpdatedAt != "2026-10-18T00:00:00Z" {
+		t.Errorf("DBUpdatedAt = %q", last.DBUpdatedAt)
+	}
+	if err := status.Healthy(0); err == nil || !strings.Contains(err.Error(), "failed to scan 1 image") {
+		t.Errorf("Healthy = %v, want the failed image reported", err)
+	}
+
+	var state scanState
+	if err := readJSON(filepath.Join(opts.StateDir, stateFile), &state); err != nil {
+		t.Fatalf("reading state: %v", err)
+	}
+	if st := state.Images[nginx]; st.Digest != digestA || st.DBUpdatedAt != last.DBUpdatedAt || len(st.Reports) != 1 || st.Error != "" {
+		t.Errorf("state of %s = %+v", nginx, st)
+	}
+	if st := state.Images["broken:1"]; !strings.Contains(st.Error, "cannot scan broken:1") {
+		t.Errorf("state of broken:1 = %+v, want its error", st)
+	}
+
+	// Unchanged images are skipped; the failed one is retried.
+	status = tick(t, opts)
+	if last := status.LastRun; last.Scanned != 0 || last.Unchanged != 2 || last.Failed != 1 {
+		t.Errorf("second run = %+v, want 2 unchanged and 1 failed", last)
+	}
+
+	// A database update rescans everything, and images that are gone are
+	// forgotten.
+	t.Setenv("DB_UPDATED_AT", "2026-10-19T00:00:00Z")
+	opts.Local.Images = []string{nginx}
+	status = tick(t, opts)
+	if last := status.LastRun; last.Scanned != 1 || last.Unchanged != 0 || last.Failed != 0 {
+		t.Errorf("third run = %+v, want 1 scanned", last)
+	}
+	if err := status.Healthy(0); err != nil {
+		t.Errorf("Healthy = %v", err)
+	}
+	state = scanState{}
+	if err := readJSON(filepath.Join(opts.StateDir, stateFile), &state); err != nil {
+		t.Fatalf("reading state: %v", err)
+	}
+	if len(state.Images) != 1 || state.Images[nginx].DBUpdatedAt != "2026-10-19T00:00:00Z" {
+		t.Errorf("state after third run = %+v", state.Images)
+	}
+
+	scanned := readLines(t, filepath.Join(runDir, "scanned"))
+	if want := 3; len(scanned) != want {
+		t.Errorf("scanner ran on %q, want %d scans", scanned, want)
+	}
+}
+
+func TestDaemonRun_StatusRecordsFailedRun(t *testing.T) {
+	opts, _ := testDaemonOptions(t, "nginx")
+	t.Setenv("FAIL_DB", "1")
+
+	status := tick(t, opts)
+	if status.LastRun == nil || !strings.Contains(status.LastRun.Error, "database unavailable") {
+		t.Fatalf("LastRun = %+v, want the run's error", status.LastRun)
+	}
+	if err := status.Healthy(0); err == nil || !strings.Contains(err.Error(), "last run failed") {
+		t.Errorf("Healthy = %v, want the failed run reported", err)
+	}
+}
+
+func TestDaemon_StateDirLock(t *testing.T) {
+	opts, _ := testDaemonOptions(t, "nginx")
+	_, unlock, err := newDaemon(opts)
+	if err != nil {
+		t.Fatalf("newDaemon: %v", err)
+	}
+	if _, _, err := newDaemon(opts); err == nil || !strings.Contains(err.Error(), "another scan daemon") {
+		t.Errorf("second daemon error = %v, want the state directory reported as in use", err)
+	}
+	unlock()
+	_, unlock, err = newDaemon(opts)
+	if err != nil {
+		t.Fatalf("newDaemon after unlock: %v", err)
+	}
+	unlock()
+}
+
+func TestDaemonRun_MaxConcurrency(t *testing.T) {
+	for _, limit := range []int{1, 2} {
+		opts, runDir := testDaemonOptions(t, "a@"+digestA, "b@"+digestA, "c@"+digestA, "d@"+digestA)
+		opts.MaxConcurrency = limit
+		status := tick(t, opts)
+		if status.LastRun.Scanned != 4 {
+			t.Fatalf("limit %d: run = %+v, want 4 scanned", limit, status.LastRun)
+		}
+		peak := 0
+		for _, line := range readLines(t, filepath.Join(runDir, "concurrency")) {
+			n, err := strconv.Atoi(line)
+			if err != nil {
+				t.Fatalf("concurrency log: %v", err)
+			}
+			peak = max(peak, n)
+		}
+		if peak != limit {
+			t.Errorf("limit %d: at most %d scans ran at once, want %d", limit, peak, limit)
+		}
+	}
+}
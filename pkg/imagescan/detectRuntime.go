@@ -1,7 +1,9 @@
 package imagescan
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 )
 
 // referred from https://github.com/kubearmor/KubeArmor/blob/v1.6.3/KubeArmor/common/common.go#L428-L444
@@ -22,6 +24,40 @@ var containerRuntimeSocketMap = map[string][]string{
 		"/var/run/crio/crio.sock",
 		"/run/crio/crio.sock",
 	},
+	"podman": {
+		"/run/podman/podman.sock",
+		"/var/run/podman/podman.sock",
+	},
+}
+
+// userSocketMap lists the sockets of rootless runtimes, relative to the
+// user's runtime directory
+var userSocketMap = map[string][]string{
+	"docker":     {"docker.sock"},
+	"containerd": {"containerd/containerd.sock"},
+	"podman":     {"podman/podman.sock"},
+}
+
+// userRuntimeDir returns $XDG_RUNTIME_DIR, or /run/user/<uid> when it is unset
+func userRuntimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return dir
+	}
+	if uid := os.Getuid(); uid > 0 {
+		return fmt.Sprintf("/run/user/%d", uid)
+	}
+	return ""
+}
+
+// socketCandidates returns the rootful and rootless socket paths of runtime
+func socketCandidates(runtime string) []string {
+	paths := append([]string(nil), containerRuntimeSocketMap[runtime]...)
+	if dir := userRuntimeDir(); dir != "" {
+		for _, p := range userSocketMap[runtime] {
+			paths = append(paths, filepath.Join(dir, p))
+		}
+	}
+	return paths
 }
 
 func DiscoverRuntime(pathPrefix string, k8sRuntime string) (string, []string, bool) {
@@ -33,9 +69,9 @@ func DiscoverRuntime(pathPrefix string, k8sRuntime string) (string, []string, bo
 func detectRuntimeViaMap(pathPrefix string, runtime string) (string, []string) {
 	var sockPaths []string
 	if runtime != "" {
-		for _, path := range containerRuntimeSocketMap[runtime] {
+		for _, path := range socketCandidates(runtime) {
 			if _, err := os.Stat(pathPrefix + path); err == nil || os.IsPermission(err) {
-				if runtime == "docker" {
+				if runtime == "docker" || runtime == "podman" {
 					path = "unix://" + path
 				}
 				sockPaths = append(sockPaths, path)
@@ -0,0 +1,213 @@
+//go:build !windows
+
+package imagescan
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+)
+
+// Runtimes lists the container runtimes images are discovered from.
+var Runtimes = []string{"docker", "containerd", "cri-o", "nri", "podman"}
+
+// AllNamespaces selects every containerd namespace.
+const AllNamespaces = "*"
+
+// discoveryTimeout bounds the listing of one runtime socket.
+const discoveryTimeout = 30 * time.Second
+
+// Socket is a container runtime socket.
+type Socket struct {
+	Runtime string
+	Path    string
+}
+
+// ParseSocket parses a socket given as "runtime=path", e.g.
+// "podman=/run/user/1000/podman/podman.sock".
+func ParseSocket(s string) (Socket, error) {
+	runtime, path, ok := strings.Cut(s, "=")
+	runtime = strings.TrimSpace(runtime)
+	path = strings.TrimSpace(path)
+	if !ok || runtime == "" || path == "" {
+		return Socket{}, fmt.Errorf("invalid socket %q: expected runtime=path", s)
+	}
+	if !lo.Contains(Runtimes, runtime) {
+		return Socket{}, fmt.Errorf("invalid socket %q: unknown runtime %q (supported: %s)", s, runtime, strings.Join(Runtimes, ", "))
+	}
+	return Socket{Runtime: runtime, Path: socketAddress(runtime, path)}, nil
+}
+
+// DiscoveryOptions selects where images are discovered.
+type DiscoveryOptions struct {
+	// Runtime restricts discovery to one runtime; all are tried when empty.
+	Runtime string
+
+	// Sockets are used in addition to the well-known rootful and rootless
+	// socket paths.
+	Sockets []Socket
+
+	// ContainerdNamespaces lists the containerd namespaces to discover
+	// images in, AllNamespaces for every namespace. When empty, the
+	// runtime's default listing is used.
+	ContainerdNamespaces []string
+
+	OnlyRunningContainers bool
+	OnlyImages            bool
+
+	// Filter selects which of the discovered images are returned.
+	Filter FilterOptions
+}
+
+// DiscoveredImage is an image found in a container runtime, along with
+// where it was found.
+type DiscoveredImage struct {
+	Name      string `json:"name"`
+	Runtime   string `json:"runtime"`
+	Socket    string `json:"socket,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+
+	// Digest identifies the image content when the runtime reports it.
+	Digest string `json:"digest,omitempty"`
+
+	// Labels of the container the image was found in, when known.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Discover lists the images in the container runtimes selected by opts that
+// pass opts.Filter. Images are de-duplicated by digest where the runtime
+// reports one, so that a tag and the digest it points to are listed once,
+// and by name otherwise. Runtimes that are not present or cannot be listed
+// are logged and skipped.
+func Discover(opts DiscoveryOptions, logger *zap.SugaredLogger) []DiscoveredImage {
+	var images []DiscoveredImage
+	for _, sock := range discoverSockets(opts) {
+		found, err := listSocket(sock, opts)
+		if err != nil {
+			logger.Errorf("error while listing the images of %s at %s: %v", sock.Runtime, sock.Path, err)
+			continue
+		}
+		for _, img := range found {
+			if ok, reason := opts.Filter.Match(img); !ok {
+				logger.Debugf("Skipping image %s: %s", img.Name, reason)
+				continue
+			}
+			images = append(images, img)
+		}
+	}
+	return lo.UniqBy(images, dedupKey)
+}
+
+// discoverSockets returns the detected and user-provided sockets of the
+// runtimes selected by opts.
+func discoverSockets(opts DiscoveryOptions) []Socket {
+	runtimes := Runtimes
+	if opts.Runtime != "" {
+		runtimes = []string{opts.Runtime}
+	}
+
+	var socks []Socket
+	for _, r := range runtimes {
+		if _, paths, ok := DiscoverRuntime("", r); ok {
+			for _, p := range paths {
+				socks = append(socks, Socket{Runtime: r, Path: p})
+			}
+		}
+	}
+	for _, s := range opts.Sockets {
+		if lo.Contains(runtimes, s.Runtime) {
+			socks = append(socks, Socket{Runtime: s.Runtime, Path: socketAddress(s.Runtime, s.Path)})
+		}
+	}
+	return lo.UniqBy(socks, func(s Socket) string {
+		return s.Runtime + "=" + s.Path
+	})
+}
+
+// listSocket lists the images or containers of one runtime socket.
+func listSocket(sock Socket, opts DiscoveryOptions) ([]DiscoveredImage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+
+	if sock.Runtime == "containerd" && len(opts.ContainerdNamespaces) > 0 {
+		return listContainerd(ctx, sock.Path, opts.ContainerdNamespaces, opts.OnlyRunningContainers, opts.OnlyImages)
+	}
+
+	// Podman serves the docker API.
+	dockerAPI := sock.Runtime == "docker" || sock.Runtime == "podman"
+	if dockerAPI && !opts.OnlyImages && opts.Filter.hasLabelRules() {
+		images, err := listDockerContainers(ctx, sock.Runtime, sock.Path, opts.OnlyRunningContainers)
+		if err != nil {
+			return nil, err
+		}
+		setDockerDigests(ctx, sock.Path, images)
+		return images, nil
+	}
+
+	runtime := sock.Runtime
+	if runtime == "podman" {
+		runtime = "docker"
+	}
+	found, err := listRuntime(runtime, sock.Path, opts.OnlyRunningContainers, opts.OnlyImages)
+	if err != nil {
+		return nil, err
+	}
+	images := make([]DiscoveredImage, 0, len(found))
+	for _, img := range found {
+		images = append(images, DiscoveredImage{Name: img.Name, Runtime: sock.Runtime, Socket: sock.Path})
+	}
+	if dockerAPI {
+		setDockerDigests(ctx, sock.Path, images)
+	}
+	return images, nil
+}
+
+// socketAddress returns path in the form the runtime's client expects:
+// docker and podman take a URL, the others a file path.
+func socketAddress(runtime, path string) string {
+	switch runtime {
+	case "docker", "podman":
+		if !strings.Contains(path, "://") {
+			return "unix://" + path
+		}
+		return path
+	default:
+		return strings.TrimPrefix(path, "unix://")
+	}
+}
+
+// PrintDiscovered prints the discovered images as a table.
+func PrintDiscovered(images []DiscoveredImage) {
+	sort.SliceStable(images, func(i, j int) bool {
+		if images[i].Runtime != images[j].Runtime {
+			return images[i].Runtime < images[j].Runtime
+		}
+		return images[i].Name < images[j].Name
+	})
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "IMAGE\tRUNTIME\tNAMESPACE\tDIGEST\tSOCKET")
+	for _, img := range images {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", img.Name, img.Runtime, orDash(img.Namespace), orDash(shortDigest(img.Digest)), img.Socket)
+	}
+}
+
+func orDash(s string) string {
+	return lo.Ternary(s == "", "-", s)
+}
+
+// shortDigest abbreviates a digest to its algorithm and first 12 hex digits.
+func shortDigest(digest string) string {
+	if alg, hex, ok := strings.Cut(digest, ":"); ok && len(hex) > 12 {
+		return alg + ":" + hex[:12]
+	}
+	return digest
+}
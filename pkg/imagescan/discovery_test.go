@@ -0,0 +1,161 @@
+//go:build !windows
+
+package imagescan
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestParseSocket(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    Socket
+		wantErr bool
+	}{
+		{in: "podman=/run/user/1000/podman/podman.sock", want: Socket{Runtime: "podman", Path: "unix:///run/user/1000/podman/podman.sock"}},
+		{in: "docker=tcp://127.0.0.1:2375", want: Socket{Runtime: "docker", Path: "tcp://127.0.0.1:2375"}},
+		{in: " containerd = unix:///run/k3s/containerd/containerd.sock ", want: Socket{Runtime: "containerd", Path: "/run/k3s/containerd/containerd.sock"}},
+		{in: "cri-o=/var/run/crio/crio.sock", want: Socket{Runtime: "cri-o", Path: "/var/run/crio/crio.sock"}},
+		{in: "/run/docker.sock", wantErr: true},
+		{in: "docker=", wantErr: true},
+		{in: "rkt=/run/rkt.sock", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := ParseSocket(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseSocket(%q) error = %v, wantErr %v", tc.in, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseSocket(%q) = %+v, want %+v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestShortDigest(t *testing.T) {
+	cases := map[string]string{
+		digestA:          "sha256:aaaaaaaaaaaa",
+		"sha256:abc":     "sha256:abc",
+		"no-digest-here": "no-digest-here",
+		"":               "",
+	}
+	for in, want := range cases {
+		if got := shortDigest(in); got != want {
+			t.Errorf("shortDigest(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// fakeDockerAPI serves the parts of the docker API that discovery uses on a
+// unix socket, and returns the socket's address.
+func fakeDockerAPI(t *testing.T, containers []map[string]interface{}, images map[string]map[string]interface{}) string {
+	t.Helper()
+	sock := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("listening on %s: %v", sock, err)
+	}
+	version := regexp.MustCompile(`^/v[0-9.]+`)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Api-Version", "1.43")
+		w.Header().Set("Content-Type", "application/json")
+		path := version.ReplaceAllString(r.URL.Path, "")
+		switch {
+		case path == "/_ping":
+			_, _ = w.Write([]byte("OK"))
+		case path == "/containers/json":
+			_ = json.NewEncoder(w).Encode(containers)
+		case strings.HasPrefix(path, "/images/") && strings.HasSuffix(path, "/json"):
+			img, ok := images[strings.TrimSuffix(strings.TrimPrefix(path, "/images/"), "/json")]
+			if !ok {
+				http.Error(w, `{"message":"no such image"}`, http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(img)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return "unix://" + sock
+}
+
+func TestDiscover_DockerContainers(t *testing.T) {
+	containers := []map[string]interface{}{
+		{"Id": "1", "Image": "nginx:1.25", "ImageID": "sha256:1", "Labels": map[string]string{"app": "web"}},
+		{"Id": "2", "Image": "nginx@" + digestA, "ImageID": "sha256:1", "Labels": map[string]string{"app": "web"}},
+		{"Id": "3", "Image": "redis:7", "ImageID": "sha256:3", "Labels": map[string]string{"app": "cache"}},
+		{"Id": "4", "Image": "mysql:8", "ImageID": "sha256:4", "Labels": map[string]string{"app": "db"}},
+		{"Id": "5", "Image": "alpine:3", "ImageID": "sha256:5"},
+		{"Id": "6", "Image": "sha256:6", "ImageID": "sha256:6", "Labels": map[string]string{"app": "batch"}},
+	}
+	images := map[string]map[string]interface{}{
+		"nginx:1.25":       {"Id": "sha256:1", "RepoDigests": []string{"nginx@" + digestA}},
+		"nginx@" + digestA: {"Id": "sha256:1", "RepoDigests": []string{"nginx@" + digestA}},
+		"mysql:8":          {"Id": "sha256:4", "RepoDigests": []string{"mysql@" + digestB}},
+		"sha256:6":         {"Id": "sha256:6", "RepoTags": []string{"busybox:latest"}},
+		"busybox:latest":   {"Id": "sha256:6"},
+		"redis:7":          {"Id": "sha256:3"},
+		"alpine:3":         {"Id": "sha256:5"},
+	}
+	sock := fakeDockerAPI(t, containers, images)
+
+	include, _ := ParseRule("label=app")
+	exclude, _ := ParseRule("redis")
+	opts := DiscoveryOptions{
+		Runtime: "podman",
+		Sockets: []Socket{{Runtime: "podman", Path: sock}, {Runtime: "docker", Path: sock}},
+		Filter: FilterOptions{
+			Include: []Rule{include},
+			Exclude: []Rule{exclude},
+			Skip:    []string{digestB},
+		},
+	}
+
+	var got []DiscoveredImage
+	for _, img := range Discover(opts, zap.NewNop().Sugar()) {
+		if img.Socket == sock {
+			got = append(got, img)
+		}
+	}
+	want := []DiscoveredImage{
+		{Name: "nginx:1.25", Runtime: "podman", Socket: sock, Digest: digestA, Labels: map[string]string{"app": "web"}},
+		{Name: "busybox:latest", Runtime: "podman", Socket: sock, Digest: "sha256:6", Labels: map[string]string{"app": "batch"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Discover =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestDiscoverSockets_UserSockets(t *testing.T) {
+	opts := DiscoveryOptions{
+		Runtime: "cri-o",
+		Sockets: []Socket{
+			{Runtime: "cri-o", Path: "unix:///tmp/custom/crio.sock"},
+			{Runtime: "cri-o", Path: "/tmp/custom/crio.sock"},
+			{Runtime: "docker", Path: "/tmp/custom/docker.sock"},
+		},
+	}
+	var got []Socket
+	for _, s := range discoverSockets(opts) {
+		if strings.HasPrefix(s.Path, "/tmp/custom/") || strings.Contains(s.Path, "docker") {
+			got = append(got, s)
+		}
+	}
+	want := []Socket{{Runtime: "cri-o", Path: "/tmp/custom/crio.sock"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("discoverSockets = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,100 @@
+//go:build !windows
+
+package imagescan
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// newDockerClient connects to the docker (or podman) API at host.
+func newDockerClient(host string) (*client.Client, error) {
+	return client.NewClientWithOpts(client.WithHost(host), client.WithAPIVersionNegotiation())
+}
+
+// listDockerContainers lists the images of the containers of the docker or
+// podman runtime at sock along with their labels.
+func listDockerContainers(ctx context.Context, runtime, sock string, onlyRunningContainers bool) ([]DiscoveredImage, error) {
+	cli, err := newDockerClient(sock)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", runtime, err)
+	}
+	defer cli.Close()
+
+	containers, err := cli.ContainerList(ctx, container.ListOptions{All: !onlyRunningContainers})
+	if err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+	var images []DiscoveredImage
+	for _, c := range containers {
+		name := c.Image
+		if strings.HasPrefix(name, "sha256:") {
+			// The tag the container was started from has moved; name the
+			// image after one of its remaining references.
+			inspect, err := cli.ImageInspect(ctx, c.ImageID)
+			if err != nil {
+				continue
+			}
+			refs := append(inspect.RepoTags, inspect.RepoDigests...)
+			if len(refs) == 0 {
+				continue
+			}
+			name = refs[0]
+		}
+		images = append(images, DiscoveredImage{Name: name, Runtime: runtime, Socket: sock, Labels: c.Labels})
+	}
+	return images, nil
+}
+
+// setDockerDigests fills in the digests of images found in the docker or
+// podman runtime at sock: the registry digest of the image's repository
+// when it was pulled, its image ID otherwise.
+func setDockerDigests(ctx context.Context, sock string, images []DiscoveredImage) {
+	cli, err := newDockerClient(sock)
+	if err != nil {
+		return
+	}
+	defer cli.Close()
+	for i := range images {
+		if images[i].Digest == "" {
+			images[i].Digest = dockerDigest(ctx, cli, images[i].Name)
+		}
+	}
+}
+
+func dockerDigest(ctx context.Context, cli *client.Client, image string) string {
+	inspect, err := cli.ImageInspect(ctx, image)
+	if err != nil {
+		return ""
+	}
+	repo := parseImageRef(image)
+	for _, rd := range inspect.RepoDigests {
+		ref := parseImageRef(rd)
+		if ref.registry == repo.registry && ref.repository == repo.repository {
+			return ref.digest
+		}
+	}
+	if len(inspect.RepoDigests) > 0 {
+		return parseImageRef(inspect.RepoDigests[0]).digest
+	}
+	return inspect.ID
+}
+
+// dockerImageID returns the ID of image in the docker or podman runtime at
+// host, or "" when it is not found there.
+func dockerImageID(ctx context.Context, host, image string) string {
+	cli, err := newDockerClient(host)
+	if err != nil {
+		return ""
+	}
+	defer cli.Close()
+	inspect, err := cli.ImageInspect(ctx, image)
+	if err != nil {
+		return ""
+	}
+	return inspect.ID
+}
@@ -0,0 +1,253 @@
+//go:build !windows
+
+package imagescan
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/samber/lo"
+)
+
+// Fields a filter rule matches on.
+const (
+	FieldName     = "name"     // repository, e.g. "docker.io/library/nginx" or "nginx"
+	FieldRegistry = "registry" // registry host, e.g. "gcr.io"
+	FieldTag      = "tag"      // tag, e.g. "1.25-alpine"
+	FieldLabel    = "label"    // label of a container running the image
+)
+
+// FilterFields lists the fields filter rules match on.
+var FilterFields = []string{FieldName, FieldRegistry, FieldTag, FieldLabel}
+
+// Rule matches discovered images on one field against a glob pattern, in
+// which "*" matches any run of characters, including "/".
+type Rule struct {
+	Field   string
+	Key     string // label key, for FieldLabel
+	Pattern string
+	re      *regexp.Regexp
+}
+
+// ParseRule parses a rule given as "field=pattern", or "label=key=pattern"
+// for container labels ("label=key" matches any value). A bare pattern
+// matches the image name.
+func ParseRule(s string) (Rule, error) {
+	field, pattern, ok := strings.Cut(s, "=")
+	if !ok || !lo.Contains(FilterFields, field) {
+		field, pattern = FieldName, s
+	}
+	r := Rule{Field: field, Pattern: pattern}
+	if field == FieldLabel {
+		r.Key, r.Pattern, _ = strings.Cut(pattern, "=")
+		if r.Key == "" {
+			return Rule{}, fmt.Errorf("invalid rule %q: expected label=key[=pattern]", s)
+		}
+		if r.Pattern == "" {
+			r.Pattern = "*"
+		}
+	}
+	if r.Pattern == "" {
+		return Rule{}, fmt.Errorf("invalid rule %q: empty pattern", s)
+	}
+	re, err := globRegexp(r.Pattern)
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid rule %q: %w", s, err)
+	}
+	r.re = re
+	return r, nil
+}
+
+// globRegexp compiles a glob pattern in which "*" matches any characters and
+// "?" a single one.
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, c := range pattern {
+		switch c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// FilterOptions selects which discovered images are scanned. An image is
+// scanned when, for every field that has include rules, at least one of them
+// matches; when no exclude rule matches; and when it is not on the skip
+// list.
+type FilterOptions struct {
+	Include []Rule
+	Exclude []Rule
+
+	// Skip lists images that are scanned elsewhere, such as base images
+	// scanned in CI: name globs (optionally with a tag), or digests
+	// ("sha256:..." or "repository@sha256:...").
+	Skip []string
+}
+
+// ReadSkipFile reads a skip list with one entry per line; blank lines and
+// lines starting with "#" are ignored.
+func ReadSkipFile(path string) ([]string, error) {
+	f, err := os.Open(path) // #nosec G304 -- path is provided by the user
+	if err != nil {
+		return nil, fmt.Errorf("reading skip list: %w", err)
+	}
+	defer f.Close()
+
+	var entries []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			entries = append(entries, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading skip list %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// hasLabelRules reports whether the filter needs container labels.
+func (f *FilterOptions) hasLabelRules() bool {
+	isLabel := func(r Rule) bool { return r.Field == FieldLabel }
+	return lo.ContainsBy(f.Include, isLabel) || lo.ContainsBy(f.Exclude, isLabel)
+}
+
+// Match reports whether img passes the filter, and otherwise why not.
+func (f *FilterOptions) Match(img DiscoveredImage) (bool, string) {
+	ref := parseImageRef(img.Name)
+
+	included := map[string]bool{}
+	for _, r := range f.Include {
+		if _, ok := included[r.Field]; !ok {
+			included[r.Field] = false
+		}
+		if r.matches(img, ref) {
+			included[r.Field] = true
+		}
+	}
+	for field, ok := range included {
+		if !ok {
+			return false, fmt.Sprintf("no %s include rule matches", field)
+		}
+	}
+	for _, r := range f.Exclude {
+		if r.matches(img, ref) {
+			return false, fmt.Sprintf("excluded by %s", r)
+		}
+	}
+	for _, s := range f.Skip {
+		if skipMatches(s, img, ref) {
+			return false, fmt.Sprintf("on the skip list (%s)", s)
+		}
+	}
+	return true, ""
+}
+
+func (r Rule) String() string {
+	if r.Field == FieldLabel {
+		return fmt.Sprintf("%s=%s=%s", r.Field, r.Key, r.Pattern)
+	}
+	return r.Field + "=" + r.Pattern
+}
+
+func (r Rule) matches(img DiscoveredImage, ref imageRef) bool {
+	switch r.Field {
+	case FieldName:
+		return lo.ContainsBy(ref.names(), r.re.MatchString)
+	case FieldRegistry:
+		return r.re.MatchString(ref.registry)
+	case FieldTag:
+		return r.re.MatchString(ref.tag)
+	case FieldLabel:
+		v, ok := img.Labels[r.Key]
+		return ok && r.re.MatchString(v)
+	}
+	return false
+}
+
+// skipMatches reports whether the skip list entry s covers img.
+func skipMatches(s string, img DiscoveredImage, ref imageRef) bool {
+	if strings.HasPrefix(s, "sha256:") || strings.Contains(s, "@") {
+		digest := s[strings.LastIndex(s, "@")+1:]
+		return digest == img.Digest || digest == ref.digest
+	}
+	pattern, tag := s, ""
+	if i := strings.LastIndex(s, ":"); i > strings.LastIndex(s, "/") {
+		pattern, tag = s[:i], s[i+1:]
+	}
+	re, err := globRegexp(pattern)
+	if err != nil || !lo.ContainsBy(ref.names(), re.MatchString) {
+		return false
+	}
+	if tag == "" {
+		return true
+	}
+	tagRe, err := globRegexp(tag)
+	return err == nil && tagRe.MatchString(ref.tag)
+}
+
+// imageRef is the parsed form of an image reference used for matching.
+type imageRef struct {
+	registry   string // "docker.io" for Docker Hub
+	repository string // e.g. "library/nginx"
+	tag        string
+	digest     string
+}
+
+func parseImageRef(image string) imageRef {
+	ref, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return imageRef{repository: image}
+	}
+	r := imageRef{
+		registry:   ref.Context().RegistryStr(),
+		repository: ref.Context().RepositoryStr(),
+	}
+	if r.registry == name.DefaultRegistry {
+		r.registry = "docker.io"
+	}
+	switch v := ref.(type) {
+	case name.Tag:
+		r.tag = v.TagStr()
+	case name.Digest:
+		r.digest = v.DigestStr()
+	}
+	return r
+}
+
+// names returns the forms of the repository a name pattern is matched
+// against: "docker.io/library/nginx", "library/nginx" and "nginx".
+func (r imageRef) names() []string {
+	names := []string{r.repository}
+	if r.registry != "" {
+		names = append(names, r.registry+"/"+r.repository)
+	}
+	if short, ok := strings.CutPrefix(r.repository, "library/"); ok && r.registry == "docker.io" {
+		names = append(names, short)
+	}
+	return names
+}
+
+// dedupKey identifies the content of an image: its digest when known, its
+// name otherwise.
+func dedupKey(img DiscoveredImage) string {
+	if img.Digest != "" {
+		return img.Digest
+	}
+	if d := parseImageRef(img.Name).digest; d != "" {
+		return d
+	}
+	return img.Name
+}
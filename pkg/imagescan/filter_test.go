@@ -0,0 +1,246 @@
+//go:build !windows
+
+package imagescan
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var (
+	digestA = "sha256:" + strings.Repeat("a", 64)
+	digestB = "sha256:" + strings.Repeat("b", 64)
+)
+
+func TestParseRule(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    Rule
+		wantErr bool
+	}{
+		{in: "nginx", want: Rule{Field: FieldName, Pattern: "nginx"}},
+		{in: "name=docker.io/library/*", want: Rule{Field: FieldName, Pattern: "docker.io/library/*"}},
+		{in: "registry=gcr.io", want: Rule{Field: FieldRegistry, Pattern: "gcr.io"}},
+		{in: "tag=1.*-alpine", want: Rule{Field: FieldTag, Pattern: "1.*-alpine"}},
+		{in: "label=app=web", want: Rule{Field: FieldLabel, Key: "app", Pattern: "web"}},
+		{in: "label=app", want: Rule{Field: FieldLabel, Key: "app", Pattern: "*"}},
+		{in: "label=app=", want: Rule{Field: FieldLabel, Key: "app", Pattern: "*"}},
+		{in: "repo=nginx", want: Rule{Field: FieldName, Pattern: "repo=nginx"}},
+		{in: "label==web", wantErr: true},
+		{in: "label=", wantErr: true},
+		{in: "tag=", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := ParseRule(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseRule(%q) error = %v, wantErr %v", tc.in, err, tc.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got.re == nil {
+			t.Errorf("ParseRule(%q) did not compile the pattern", tc.in)
+		}
+		got.re = nil
+		if got != tc.want {
+			t.Errorf("ParseRule(%q) = %+v, want %+v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestGlobRegexp(t *testing.T) {
+	cases := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"nginx", "nginx", true},
+		{"nginx", "nginx-exporter", false},
+		{"docker.io/*", "docker.io/library/nginx", true},
+		{"docker.io/*", "docker_io/library/nginx", false},
+		{"1.2?", "1.25", true},
+		{"1.2?", "1.2", false},
+		{"[abc]+", "[abc]+", true},
+		{"[abc]+", "a", false},
+	}
+	for _, tc := range cases {
+		re, err := globRegexp(tc.pattern)
+		if err != nil {
+			t.Fatalf("globRegexp(%q): %v", tc.pattern, err)
+		}
+		if got := re.MatchString(tc.s); got != tc.want {
+			t.Errorf("glob %q matching %q = %v, want %v", tc.pattern, tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestParseImageRefNames(t *testing.T) {
+	cases := []struct {
+		image string
+		ref   imageRef
+		names []string
+	}{
+		{
+			image: "nginx",
+			ref:   imageRef{registry: "docker.io", repository: "library/nginx", tag: "latest"},
+			names: []string{"library/nginx", "docker.io/library/nginx", "nginx"},
+		},
+		{
+			image: "index.docker.io/library/nginx:1.25",
+			ref:   imageRef{registry: "docker.io", repository: "library/nginx", tag: "1.25"},
+			names: []string{"library/nginx", "docker.io/library/nginx", "nginx"},
+		},
+		{
+			image: "bitnami/redis:7",
+			ref:   imageRef{registry: "docker.io", repository: "bitnami/redis", tag: "7"},
+			names: []string{"bitnami/redis", "docker.io/bitnami/redis"},
+		},
+		{
+			image: "gcr.io/library/app@" + digestA,
+			ref:   imageRef{registry: "gcr.io", repository: "library/app", digest: digestA},
+			names: []string{"library/app", "gcr.io/library/app"},
+		},
+		{
+			image: "localhost:5000/app:dev",
+			ref:   imageRef{registry: "localhost:5000", repository: "app", tag: "dev"},
+			names: []string{"app", "localhost:5000/app"},
+		},
+		{
+			image: "Not A Reference",
+			ref:   imageRef{repository: "Not A Reference"},
+			names: []string{"Not A Reference"},
+		},
+	}
+	for _, tc := range cases {
+		ref := parseImageRef(tc.image)
+		if ref != tc.ref {
+			t.Errorf("parseImageRef(%q) = %+v, want %+v", tc.image, ref, tc.ref)
+		}
+		if names := ref.names(); !reflect.DeepEqual(names, tc.names) {
+			t.Errorf("parseImageRef(%q).names() = %q, want %q", tc.image, names, tc.names)
+		}
+	}
+}
+
+func TestSkipMatches(t *testing.T) {
+	cases := []struct {
+		entry string
+		img   DiscoveredImage
+		want  bool
+	}{
+		{"nginx", DiscoveredImage{Name: "docker.io/library/nginx:1.25"}, true},
+		{"nginx:1.25", DiscoveredImage{Name: "nginx:1.25"}, true},
+		{"nginx:1.25", DiscoveredImage{Name: "nginx:1.26"}, false},
+		{"nginx:1.*", DiscoveredImage{Name: "index.docker.io/library/nginx:1.26"}, true},
+		{"gcr.io/*:latest", DiscoveredImage{Name: "gcr.io/project/app"}, true},
+		{"gcr.io/*:latest", DiscoveredImage{Name: "ghcr.io/project/app"}, false},
+		{"localhost:5000/app", DiscoveredImage{Name: "localhost:5000/app:dev"}, true},
+		{digestA, DiscoveredImage{Name: "nginx", Digest: digestA}, true},
+		{digestA, DiscoveredImage{Name: "nginx", Digest: digestB}, false},
+		{"nginx@" + digestA, DiscoveredImage{Name: "redis@" + digestA}, true},
+		{"nginx@" + digestA, DiscoveredImage{Name: "nginx:1.25"}, false},
+	}
+	for _, tc := range cases {
+		if got := skipMatches(tc.entry, tc.img, parseImageRef(tc.img.Name)); got != tc.want {
+			t.Errorf("skipMatches(%q, %q) = %v, want %v", tc.entry, tc.img.Name, got, tc.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	rules := func(specs ...string) []Rule {
+		var out []Rule
+		for _, s := range specs {
+			r, err := ParseRule(s)
+			if err != nil {
+				t.Fatalf("ParseRule(%q): %v", s, err)
+			}
+			out = append(out, r)
+		}
+		return out
+	}
+	web := DiscoveredImage{Name: "nginx:1.25", Digest: digestA, Labels: map[string]string{"app": "web"}}
+	unlabelled := DiscoveredImage{Name: "gcr.io/project/worker:2.0"}
+
+	cases := []struct {
+		name   string
+		filter FilterOptions
+		img    DiscoveredImage
+		want   bool
+		reason string
+	}{
+		{"no rules", FilterOptions{}, web, true, ""},
+		{"name include", FilterOptions{Include: rules("nginx")}, web, true, ""},
+		{"full name include", FilterOptions{Include: rules("name=docker.io/library/*")}, web, true, ""},
+		{"name include misses", FilterOptions{Include: rules("redis")}, web, false, "no name include rule matches"},
+		{"either name include", FilterOptions{Include: rules("redis", "nginx")}, web, true, ""},
+		{"every field must match", FilterOptions{Include: rules("nginx", "registry=gcr.io")}, web, false, "no registry include rule matches"},
+		{"registry include", FilterOptions{Include: rules("registry=gcr.io")}, unlabelled, true, ""},
+		{"tag include", FilterOptions{Include: rules("tag=1.*")}, web, true, ""},
+		{"label key", FilterOptions{Include: rules("label=app")}, web, true, ""},
+		{"label key absent", FilterOptions{Include: rules("label=app")}, unlabelled, false, "no label include rule matches"},
+		{"label value", FilterOptions{Include: rules("label=app=db")}, web, false, "no label include rule matches"},
+		{"exclude", FilterOptions{Exclude: rules("tag=1.25")}, web, false, "excluded by tag=1.25"},
+		{"exclude label", FilterOptions{Exclude: rules("label=app=w*")}, web, false, "excluded by label=app=w*"},
+		{"exclude beats include", FilterOptions{Include: rules("nginx"), Exclude: rules("registry=docker.io")}, web, false, "excluded by registry=docker.io"},
+		{"skip name and tag", FilterOptions{Skip: []string{"nginx:1.25"}}, web, false, "on the skip list (nginx:1.25)"},
+		{"skip digest", FilterOptions{Skip: []string{digestA}}, web, false, "on the skip list (" + digestA + ")"},
+		{"skip other tag", FilterOptions{Skip: []string{"nginx:1.24"}}, web, true, ""},
+	}
+	for _, tc := range cases {
+		ok, reason := tc.filter.Match(tc.img)
+		if ok != tc.want || reason != tc.reason {
+			t.Errorf("%s: Match = %v, %q; want %v, %q", tc.name, ok, reason, tc.want, tc.reason)
+		}
+	}
+}
+
+func TestHasLabelRules(t *testing.T) {
+	name, _ := ParseRule("nginx")
+	label, _ := ParseRule("label=app")
+	if (&FilterOptions{Include: []Rule{name}}).hasLabelRules() {
+		t.Error("name rule reported as a label rule")
+	}
+	if !(&FilterOptions{Exclude: []Rule{name, label}}).hasLabelRules() {
+		t.Error("label exclude rule not reported")
+	}
+}
+
+func TestDedupKey(t *testing.T) {
+	cases := []struct {
+		img  DiscoveredImage
+		want string
+	}{
+		{DiscoveredImage{Name: "nginx:1.25", Digest: digestA}, digestA},
+		{DiscoveredImage{Name: "nginx@" + digestB}, digestB},
+		{DiscoveredImage{Name: "nginx@" + digestB, Digest: digestA}, digestA},
+		{DiscoveredImage{Name: "nginx:1.25"}, "nginx:1.25"},
+	}
+	for _, tc := range cases {
+		if got := dedupKey(tc.img); got != tc.want {
+			t.Errorf("dedupKey(%+v) = %q, want %q", tc.img, got, tc.want)
+		}
+	}
+}
+
+func TestReadSkipFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "skip.txt")
+	data := "# base images scanned in CI\nnginx:1.25\n\n  " + digestA + "  \n# end\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadSkipFile(path)
+	if err != nil {
+		t.Fatalf("ReadSkipFile: %v", err)
+	}
+	if want := []string{"nginx:1.25", digestA}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadSkipFile = %q, want %q", got, want)
+	}
+	if _, err := ReadSkipFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("expected error for missing skip list")
+	}
+}
@@ -5,6 +5,9 @@ package imagescan
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/accuknox/kubeshield/api/v1beta1"
 	kubesheildDiscovery "github.com/accuknox/kubeshield/pkg/discovery"
@@ -15,7 +18,7 @@ import (
 )
 
 // Discovers the running container images and scans the images using the specified tool
-func DiscoverAndScan(conf kubesheildScanner.ScanConfig, hostName, runtime string, onlyRunningContainers, onlyImages bool) error {
+func DiscoverAndScan(conf kubesheildScanner.ScanConfig, hostName string, discovery DiscoveryOptions) error {
 	zapLogger, err := zap.NewProduction()
 	if err != nil {
 		return fmt.Errorf("failed to initialize logger")
@@ -36,102 +39,124 @@ func DiscoverAndScan(conf kubesheildScanner.ScanConfig, hostName, runtime string
 		zapLogger.Info("Container image scanner ready")
 	}
 
-	conf.Images = discoverImages(hostName, runtime, onlyRunningContainers, onlyImages, zapLogger.Sugar())
-	if len(conf.Images) == 0 {
+	discovered := Discover(discovery, zapLogger.Sugar())
+	if len(discovered) == 0 {
 		return fmt.Errorf("no images found for scanning")
 	}
 
-	// removes duplicate images
-	conf.Images = lo.UniqBy(conf.Images, func(img v1beta1.Image) string {
-		return img.Name
-	})
-
-	for i := range conf.Images {
-		zapLogger.Sugar().Infof("Image Name: %s | Runtime: %s", conf.Images[i].Name, conf.Images[i].Runtime)
+	for _, img := range discovered {
+		zapLogger.Sugar().Infof("Image Name: %s | Runtime: %s", img.Name, img.Runtime)
 	}
 
-	zapLogger.Info("Images Discovered Successfully", zap.Int("Total number of images:", len(conf.Images)))
+	zapLogger.Info("Images Discovered Successfully", zap.Int("Total number of images:", len(discovered)))
 
-	if hostName == "" {
-		hostName, _ = os.Hostname()
+	if err := uploadImages(conf, hostName, discovered); err != nil {
+		return err
 	}
 
-	// Additional fields added along with the scan results while calling artifact API
-	conf.ArtifactConfig.AdditionalData = map[string]any{"host_name": hostName}
-	conf.ScanTool = "trivy" // Default scanning tool
-	conf.ArtifactConfig.AdditionalData["registry_type"] = "vm"
+	zapLogger.Info("Images Scanned Successfully",
+		zap.Int("Total Scanned Images", len(discovered)))
 
-	// Passing nil kubernetes.Clientset, because it won't be required incase of VM container Image scanning
-	imageScanner := kubesheildScanner.New(conf, nil, kubesheildScanner.TrivyConfig{})
-	imageScanner.ScannerHttpClient = httpclient.NewClient(true, nil)
+	return nil
+}
 
-	// Scans the provided images and sends the result back to saas through the artifact API
-	if err := imageScanner.Scan(); err != nil {
-		return fmt.Errorf("error while scanning the images")
+// Converts discovered images to the images scanned by kubeshield
+func toImages(discovered []DiscoveredImage) []v1beta1.Image {
+	images := make([]v1beta1.Image, 0, len(discovered))
+	for _, img := range discovered {
+		runtime, _ := uploadEnv(img)
+		images = append(images, v1beta1.Image{Name: img.Name, Runtime: runtime})
 	}
+	return images
+}
 
-	zapLogger.Info("Images Scanned Successfully",
-		zap.Int("Total Scanned Images", len(conf.Images)))
-
-	return nil
+// uploadEnv returns the runtime kubeshield is given for img, and the
+// environment pointing the scanner at the socket and containerd namespace
+// img was found in: kubeshield runs the scanner from PATH and passes neither
+// on. Podman serves the docker API, so its images are scanned as docker
+// images read from the podman socket.
+func uploadEnv(img DiscoveredImage) (string, []string) {
+	switch img.Runtime {
+	case "docker", "podman":
+		if img.Socket == "" {
+			return "docker", nil
+		}
+		return "docker", []string{"DOCKER_HOST=" + img.Socket, "TRIVY_DOCKER_HOST=" + img.Socket}
+	case "containerd":
+		return img.Runtime, scanEnv(img)
+	}
+	return img.Runtime, nil
 }
 
-// Lists the running containers for the provided runtime, if the runtime is empty it will use the default supported runtimes
-func discoverImages(hostName, runtime string, onlyRunningContainers, imageOnly bool, logger *zap.SugaredLogger) []v1beta1.Image {
-	var (
-		runtimes   = []string{"docker", "containerd", "cri-o", "nri"}
-		imagesList = []v1beta1.Image{}
-	)
+// uploadMu serialises uploads, since each sets the process environment the
+// scanner inherits.
+var uploadMu sync.Mutex
 
-	if runtime != "" {
-		runtimes = []string{runtime}
+// Scans the provided images and sends the result back to saas through the
+// artifact API. Images found through the same socket and namespace are
+// scanned together, with the scanner environment pointing at them.
+func uploadImages(conf kubesheildScanner.ScanConfig, hostName string, discovered []DiscoveredImage) error {
+	if hostName == "" {
+		hostName, _ = os.Hostname()
 	}
 
-	// Fetching images present in all the provided runtimes
-	for _, r := range runtimes {
-		detectedRuntime, criPath, ok := DiscoverRuntime("", r)
-		if !ok {
-			logger.Debugf("Unable to detect runtime for %s", r)
-			continue
-		}
+	// Additional fields added along with the scan results while calling artifact API
+	conf.ArtifactConfig.AdditionalData = map[string]any{"host_name": hostName}
+	conf.ScanTool = "trivy" // Default scanning tool
+	conf.ArtifactConfig.AdditionalData["registry_type"] = "vm"
 
-		// If imageOnly flag is enabled, we only discover images; not containers
-		if imageOnly {
-			// fetch images based on the runtime
-			imagesList = append(imagesList, getImages(detectedRuntime, criPath, logger)...)
-			continue
+	groups := lo.GroupBy(discovered, func(img DiscoveredImage) string {
+		_, env := uploadEnv(img)
+		return strings.Join(env, "\n")
+	})
+	keys := lo.Keys(groups)
+	sort.Strings(keys)
+
+	uploadMu.Lock()
+	defer uploadMu.Unlock()
+	for _, key := range keys {
+		conf.Images = toImages(groups[key])
+		_, env := uploadEnv(groups[key][0])
+		if err := withEnv(env, func() error { return scanImages(conf) }); err != nil {
+			return fmt.Errorf("error while scanning the images")
 		}
-
-		// By default we fetch running containers, unless onlyRunningContainers is set to false
-		imagesList = append(imagesList, getContainers(detectedRuntime, criPath, onlyRunningContainers, logger)...)
 	}
-	return imagesList
+	return nil
 }
 
-// fetches the images based on all the provided runtime and cripath
-func getImages(runtime string, criPath []string, logger *zap.SugaredLogger) []v1beta1.Image {
-	var images = []v1beta1.Image{}
-	for _, path := range criPath {
-		imageList, err := kubesheildDiscovery.ListImages(runtime, path, kubesheildDiscovery.VM)
-		if err != nil {
-			logger.Errorf("error while listing the images for runtime %s: %v\n", runtime, err)
-			continue
-		}
-		images = append(images, imageList...)
+// scanImages scans conf.Images with kubeshield and uploads the results.
+var scanImages = func(conf kubesheildScanner.ScanConfig) error {
+	// Passing nil kubernetes.Clientset, because it won't be required incase of VM container Image scanning
+	imageScanner := kubesheildScanner.New(conf, nil, kubesheildScanner.TrivyConfig{})
+	imageScanner.ScannerHttpClient = httpclient.NewClient(true, nil)
+	return imageScanner.Scan()
+}
+
+// withEnv runs fn with the "KEY=value" entries of env set in the process
+// environment, restoring the previous values afterwards.
+func withEnv(env []string, fn func() error) error {
+	for _, kv := range env {
+		key, value, _ := strings.Cut(kv, "=")
+		prev, had := os.LookupEnv(key)
+		_ = os.Setenv(key, value)
+		defer func() {
+			if had {
+				_ = os.Setenv(key, prev)
+			} else {
+				_ = os.Unsetenv(key)
+			}
+		}()
 	}
-	return images
+	return fn()
 }
 
-// fetches the containers based on all the provided runtime and cripath
-func getContainers(runtime string, criPath []string, onlyRunningContainers bool, logger *zap.SugaredLogger) []v1beta1.Image {
-	var conatainers = []v1beta1.Image{}
-	for _, path := range criPath {
-		containerList, err := kubesheildDiscovery.ListContainers(runtime, path, kubesheildDiscovery.VM, onlyRunningContainers, false)
-		if err != nil {
-			logger.Errorf("error while listing the container images for runtime %s: %v", runtime, err)
-			continue
-		}
-		conatainers = append(conatainers, containerList...)
+// Lists the images, or the images of the containers, of the runtime listening on path
+func listRuntime(runtime, path string, onlyRunningContainers, imageOnly bool) ([]v1beta1.Image, error) {
+	// If imageOnly flag is enabled, we only discover images; not containers
+	if imageOnly {
+		return kubesheildDiscovery.ListImages(runtime, path, kubesheildDiscovery.VM)
 	}
-	return conatainers
+
+	// By default we fetch running containers, unless onlyRunningContainers is set to false
+	return kubesheildDiscovery.ListContainers(runtime, path, kubesheildDiscovery.VM, onlyRunningContainers, false)
 }
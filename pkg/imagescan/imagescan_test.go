@@ -0,0 +1,102 @@
+//go:build !windows
+
+package imagescan
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/accuknox/kubeshield/api/v1beta1"
+	kubesheildScanner "github.com/accuknox/kubeshield/pkg/scanner/scan"
+)
+
+func TestToImages(t *testing.T) {
+	got := toImages([]DiscoveredImage{
+		{Name: "nginx:1.25", Runtime: "docker", Socket: "unix:///run/user/1000/docker.sock"},
+		{Name: "redis:7", Runtime: "podman", Socket: "unix:///run/user/1000/podman/podman.sock"},
+		{Name: "alpine:3", Runtime: "containerd", Socket: "/run/k3s/containerd/containerd.sock", Namespace: "k8s.io"},
+		{Name: "busybox", Runtime: "cri-o"},
+	})
+	want := []v1beta1.Image{
+		{Name: "nginx:1.25", Runtime: "docker"},
+		{Name: "redis:7", Runtime: "docker"},
+		{Name: "alpine:3", Runtime: "containerd"},
+		{Name: "busybox", Runtime: "cri-o"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("toImages = %+v, want %+v", got, want)
+	}
+}
+
+// scanCall is what the stubbed scanner saw in one call.
+type scanCall struct {
+	images []v1beta1.Image
+	env    map[string]string
+}
+
+func stubScanImages(t *testing.T, err error) *[]scanCall {
+	t.Helper()
+	var calls []scanCall
+	orig := scanImages
+	scanImages = func(conf kubesheildScanner.ScanConfig) error {
+		env := map[string]string{}
+		for _, key := range []string{"DOCKER_HOST", "TRIVY_DOCKER_HOST", "CONTAINERD_ADDRESS", "CONTAINERD_NAMESPACE"} {
+			if v, ok := os.LookupEnv(key); ok {
+				env[key] = v
+			}
+		}
+		calls = append(calls, scanCall{images: conf.Images, env: env})
+		return err
+	}
+	t.Cleanup(func() { scanImages = orig })
+	return &calls
+}
+
+func TestUploadImages_NonDefaultSockets(t *testing.T) {
+	for _, key := range []string{"DOCKER_HOST", "TRIVY_DOCKER_HOST", "CONTAINERD_ADDRESS", "CONTAINERD_NAMESPACE"} {
+		t.Setenv(key, "")
+		_ = os.Unsetenv(key)
+	}
+	calls := stubScanImages(t, nil)
+
+	podman := "unix:///run/user/1000/podman/podman.sock"
+	err := uploadImages(kubesheildScanner.ScanConfig{}, "host", []DiscoveredImage{
+		{Name: "redis:7", Runtime: "podman", Socket: podman},
+		{Name: "alpine:3", Runtime: "containerd", Socket: "/run/k3s/containerd/containerd.sock", Namespace: "k8s.io"},
+		{Name: "nginx:1.25", Runtime: "docker"},
+		{Name: "httpd:2", Runtime: "podman", Socket: podman},
+	})
+	if err != nil {
+		t.Fatalf("uploadImages: %v", err)
+	}
+
+	want := []scanCall{
+		{
+			images: []v1beta1.Image{{Name: "nginx:1.25", Runtime: "docker"}},
+			env:    map[string]string{},
+		},
+		{
+			images: []v1beta1.Image{{Name: "alpine:3", Runtime: "containerd"}},
+			env:    map[string]string{"CONTAINERD_ADDRESS": "/run/k3s/containerd/containerd.sock", "CONTAINERD_NAMESPACE": "k8s.io"},
+		},
+		{
+			images: []v1beta1.Image{{Name: "redis:7", Runtime: "docker"}, {Name: "httpd:2", Runtime: "docker"}},
+			env:    map[string]string{"DOCKER_HOST": podman, "TRIVY_DOCKER_HOST": podman},
+		},
+	}
+	if !reflect.DeepEqual(*calls, want) {
+		t.Errorf("scanner calls =\n%+v\nwant\n%+v", *calls, want)
+	}
+	if v, ok := os.LookupEnv("DOCKER_HOST"); ok {
+		t.Errorf("DOCKER_HOST left set to %q", v)
+	}
+}
+
+func TestUploadImages_Error(t *testing.T) {
+	stubScanImages(t, errors.New("boom"))
+	if err := uploadImages(kubesheildScanner.ScanConfig{}, "host", []DiscoveredImage{{Name: "nginx", Runtime: "docker"}}); err == nil {
+		t.Error("expected error from a failed scan")
+	}
+}
@@ -0,0 +1,354 @@
+//go:build !windows
+
+package imagescan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+)
+
+// Report formats written by a local scan.
+const (
+	FormatJSON      = "json"
+	FormatSARIF     = "sarif"
+	FormatCycloneDX = "cyclonedx" // CycloneDX SBOM carrying the vulnerabilities (VEX)
+	FormatTable     = "table"
+)
+
+// ReportFormats lists the supported report formats.
+var ReportFormats = []string{FormatJSON, FormatSARIF, FormatCycloneDX, FormatTable}
+
+// reportExt maps a report format to its file extension.
+var reportExt = map[string]string{
+	FormatJSON:      ".json",
+	FormatSARIF:     ".sarif",
+	FormatCycloneDX: ".cdx.json",
+	FormatTable:     ".txt",
+}
+
+// severities in the order they are reported.
+var severities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNKNOWN"}
+
+// LocalOptions configures a local scan, which writes reports to files
+// instead of uploading them through the artifact API.
+type LocalOptions struct {
+	// Images to scan; when empty, images are discovered from the container
+	// runtimes as for an uploading scan.
+	Images    []string
+	HostName  string
+	Discovery DiscoveryOptions
+
+	OutputDir string   // directory the reports are written to
+	Formats   []string // report formats (default: json)
+	Severity  string   // comma-separated severities to report (default: all)
+
+	// Offline skips vulnerability database updates and remote lookups; the
+	// databases must already be in CacheDir.
+	Offline  bool
+	CacheDir string // scanner cache directory (default: the scanner's own)
+
+	// SkipDBUpdate scans with the vulnerability database already in
+	// CacheDir, without checking for updates.
+	SkipDBUpdate bool
+
+	// cacheBackend overrides the scanner's layer cache, so that concurrent
+	// scans do not contend for the on-disk cache.
+	cacheBackend string
+}
+
+// ImageReport is the outcome of scanning one image locally.
+type ImageReport struct {
+	Image   string
+	Runtime string
+	Files   []string       // reports written, one per format
+	Counts  map[string]int // vulnerabilities by severity
+	Error   error
+}
+
+// ScanLocal scans images with the embedded scanner and writes one report
+// per image and format to opts.OutputDir. Nothing is sent over the network
+// apart from vulnerability database updates, which Offline disables. A
+// summary is printed to stdout; an error is returned when no image could be
+// scanned.
+func ScanLocal(opts LocalOptions) ([]ImageReport, error) {
+	formats, err := normalizeFormats(opts.Formats)
+	if err != nil {
+		return nil, err
+	}
+	bin, err := resolveTrivyBin()
+	if err != nil {
+		return nil, fmt.Errorf("error while resolving container image scanner: %v", err)
+	}
+	if opts.OutputDir == "" {
+		opts.OutputDir = "."
+	}
+	if err := os.MkdirAll(opts.OutputDir, 0o750); err != nil {
+		return nil, fmt.Errorf("creating output directory %s: %w", opts.OutputDir, err)
+	}
+
+	images, err := localImages(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]ImageReport, 0, len(images))
+	scanned := 0
+	for _, img := range images {
+		fmt.Printf("Scanning %s...\n", img.Name)
+		r := scanImageLocal(bin, img, formats, &opts)
+		if r.Error != nil {
+			fmt.Fprintf(os.Stderr, "Warning: scanning %s: %v\n", img.Name, r.Error)
+		} else {
+			scanned++
+		}
+		reports = append(reports, r)
+	}
+
+	printLocalSummary(reports)
+	if scanned == 0 {
+		return reports, fmt.Errorf("no images could be scanned")
+	}
+	return reports, nil
+}
+
+// localImages returns the images named in opts, or those discovered from the
+// container runtimes, without duplicates.
+func localImages(opts LocalOptions) ([]DiscoveredImage, error) {
+	var images []DiscoveredImage
+	if len(opts.Images) > 0 {
+		for _, name := range opts.Images {
+			images = append(images, DiscoveredImage{Name: name, Runtime: opts.Discovery.Runtime})
+		}
+		images = lo.UniqBy(images, func(img DiscoveredImage) string {
+			return img.Name
+		})
+	} else {
+		zapLogger, err := zap.NewProduction()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize logger")
+		}
+		defer func() { _ = zapLogger.Sync() }()
+		images = Discover(opts.Discovery, zapLogger.Sugar())
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("no images found for scanning")
+	}
+	return images, nil
+}
+
+// scanImageLocal scans one image to a JSON report and converts it to the
+// other requested formats, so the image is only analysed once.
+func scanImageLocal(bin string, img DiscoveredImage, formats []string, opts *LocalOptions) ImageReport {
+	r := ImageReport{Image: img.Name, Runtime: img.Runtime}
+	base := filepath.Join(opts.OutputDir, reportName(img))
+
+	jsonPath := base + reportExt[FormatJSON]
+	if err := runTrivy(bin, scanArgs(img, jsonPath, formats, opts), scanEnv(img)...); err != nil {
+		r.Error = err
+		return r
+	}
+	counts, err := countVulnerabilities(jsonPath)
+	if err != nil {
+		r.Error = err
+		return r
+	}
+	r.Counts = counts
+
+	keepJSON := false
+	for _, f := range formats {
+		if f == FormatJSON {
+			keepJSON = true
+			r.Files = append(r.Files, jsonPath)
+			continue
+		}
+		out := base + reportExt[f]
+		args := []string{"convert", "--quiet", "--format", f, "--output", out}
+		if opts.Severity != "" {
+			args = append(args, "--severity", opts.Severity)
+		}
+		if err := runTrivy(bin, append(args, jsonPath)); err != nil {
+			r.Error = fmt.Errorf("writing %s report: %w", f, err)
+			break
+		}
+		r.Files = append(r.Files, out)
+	}
+	if !keepJSON {
+		_ = os.Remove(jsonPath)
+	}
+	return r
+}
+
+// scanArgs builds the scanner command line that writes the JSON report of
+// img to out.
+func scanArgs(img DiscoveredImage, out string, formats []string, opts *LocalOptions) []string {
+	args := []string{"image", "--quiet", "--scanners", "vuln", "--format", FormatJSON, "--output", out}
+	if lo.Contains(formats, FormatCycloneDX) {
+		// The CycloneDX report lists every package, not only vulnerable ones.
+		args = append(args, "--list-all-pkgs")
+	}
+	if opts.Severity != "" {
+		args = append(args, "--severity", opts.Severity)
+	}
+	if opts.CacheDir != "" {
+		args = append(args, "--cache-dir", opts.CacheDir)
+	}
+	if opts.cacheBackend != "" {
+		args = append(args, "--cache-backend", opts.cacheBackend)
+	}
+	if opts.Offline || opts.SkipDBUpdate {
+		args = append(args, "--skip-db-update", "--skip-java-db-update")
+	}
+	if opts.Offline {
+		args = append(args, "--offline-scan")
+	}
+	switch img.Runtime {
+	case "docker", "containerd", "podman":
+		// Read the image from the runtime it was discovered in rather than
+		// pulling it again.
+		src := img.Runtime
+		if !opts.Offline {
+			src += ",remote"
+		}
+		args = append(args, "--image-src", src)
+	}
+	if img.Socket != "" {
+		switch img.Runtime {
+		case "docker":
+			args = append(args, "--docker-host", img.Socket)
+		case "podman":
+			args = append(args, "--podman-host", strings.TrimPrefix(img.Socket, "unix://"))
+		}
+	}
+	return append(args, img.Name)
+}
+
+// scanEnv returns the environment selecting the containerd instance and
+// namespace img was discovered in; the scanner has no flags for them.
+func scanEnv(img DiscoveredImage) []string {
+	if img.Runtime != "containerd" {
+		return nil
+	}
+	var env []string
+	if img.Socket != "" {
+		env = append(env, "CONTAINERD_ADDRESS="+img.Socket)
+	}
+	if img.Namespace != "" {
+		env = append(env, "CONTAINERD_NAMESPACE="+img.Namespace)
+	}
+	return env
+}
+
+// runTrivy runs the scanner with env added to its environment, returning
+// its error output on failure.
+func runTrivy(bin string, args []string, env ...string) error {
+	cmd := exec.Command(bin, args...) // #nosec G204 -- bin is the embedded scanner; args are built by knoxctl
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(out))
+		if msg == "" {
+			return err
+		}
+		return fmt.Errorf("%w: %s", err, msg)
+	}
+	return nil
+}
+
+// trivyReport is the part of the scanner's JSON report needed for the
+// summary.
+type trivyReport struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			Severity string `json:"Severity"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+func countVulnerabilities(path string) (map[string]int, error) {
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("reading report %s: %w", path, err)
+	}
+	var report trivyReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("parsing report %s: %w", path, err)
+	}
+	counts := map[string]int{}
+	for _, res := range report.Results {
+		for _, v := range res.Vulnerabilities {
+			counts[strings.ToUpper(v.Severity)]++
+		}
+	}
+	return counts, nil
+}
+
+// normalizeFormats validates formats, dropping duplicates; it defaults to
+// JSON.
+func normalizeFormats(formats []string) ([]string, error) {
+	var out []string
+	for _, f := range formats {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if f == "" {
+			continue
+		}
+		if _, ok := reportExt[f]; !ok {
+			return nil, fmt.Errorf("unsupported report format %q (supported: %s)", f, strings.Join(ReportFormats, ", "))
+		}
+		if !lo.Contains(out, f) {
+			out = append(out, f)
+		}
+	}
+	if len(out) == 0 {
+		out = []string{FormatJSON}
+	}
+	return out, nil
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// reportName turns an image into a file name, e.g. "docker.io/library/nginx:1.25"
+// from docker → "docker.io_library_nginx_1.25-39789ead". The suffix hashes the
+// full reference and where it came from, so references that flatten to the same
+// name, or the same image in two runtimes, do not overwrite each other's reports.
+func reportName(img DiscoveredImage) string {
+	name := strings.Trim(unsafeNameChars.ReplaceAllString(img.Name, "_"), "_.")
+	if name == "" {
+		name = "image"
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{img.Runtime, img.Socket, img.Namespace, img.Name}, "\x00")))
+	return name + "-" + hex.EncodeToString(sum[:4])
+}
+
+func printLocalSummary(reports []ImageReport) {
+	sort.SliceStable(reports, func(i, j int) bool { return reports[i].Image < reports[j].Image })
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "IMAGE\t%s\tREPORTS\n", strings.Join(severities, "\t"))
+	for _, r := range reports {
+		if r.Error != nil {
+			fmt.Fprintf(w, "%s\t%s\tscan failed\n", r.Image, strings.Repeat("-\t", len(severities)-1)+"-")
+			continue
+		}
+		counts := make([]string, len(severities))
+		for i, s := range severities {
+			counts[i] = fmt.Sprint(r.Counts[s])
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.Image, strings.Join(counts, "\t"), strings.Join(r.Files, ", "))
+	}
+}
@@ -0,0 +1,238 @@
+//go:build !windows
+
+package imagescan
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeTrivy stands in for the scanner: "image" writes a JSON report with one
+// critical and two high vulnerabilities, "convert" writes the format name
+// after checking that the JSON report it converts exists.
+const fakeTrivy = `#!/bin/sh
+cmd=$1
+out=
+format=
+for arg; do
+	case $prev in
+	--output) out=$arg ;;
+	--format) format=$arg ;;
+	esac
+	prev=$arg
+	last=$arg
+done
+case $cmd in
+image)
+	printf '%s' '{"Results":[{"Vulnerabilities":[{"Severity":"CRITICAL"},{"Severity":"high"},{"Severity":"HIGH"}]}]}' > "$out" ;;
+convert)
+	[ -f "$last" ] || { echo "no report $last" >&2; exit 1; }
+	[ "$format" = "$FAIL_FORMAT" ] && { echo "cannot write $format" >&2; exit 1; }
+	printf '%s' "$format" > "$out" ;;
+esac
+`
+
+func writeFakeTrivy(t *testing.T) string {
+	t.Helper()
+	bin := filepath.Join(t.TempDir(), "trivy")
+	if err := os.WriteFile(bin, []byte(fakeTrivy), 0o700); err != nil { // #nosec G306
+		t.Fatalf("writing fake scanner: %v", err)
+	}
+	return bin
+}
+
+func TestScanImageLocal_Formats(t *testing.T) {
+	bin := writeFakeTrivy(t)
+	cases := []struct {
+		name    string
+		formats []string
+		files   map[string]string // report extension → content ("" for JSON)
+	}{
+		{"json", []string{FormatJSON}, map[string]string{".json": ""}},
+		{"sarif", []string{FormatSARIF}, map[string]string{".sarif": "sarif"}},
+		{"cyclonedx", []string{FormatCycloneDX}, map[string]string{".cdx.json": "cyclonedx"}},
+		{"table", []string{FormatTable}, map[string]string{".txt": "table"}},
+		{"all", ReportFormats, map[string]string{
+			".json": "", ".sarif": "sarif", ".cdx.json": "cyclonedx", ".txt": "table",
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			opts := &LocalOptions{OutputDir: dir}
+			img := DiscoveredImage{Name: "docker.io/library/nginx:1.25"}
+			r := scanImageLocal(bin, img, tc.formats, opts)
+			if r.Error != nil {
+				t.Fatalf("scanImageLocal: %v", r.Error)
+			}
+			if r.Counts["CRITICAL"] != 1 || r.Counts["HIGH"] != 2 {
+				t.Errorf("Counts = %v, want 1 critical and 2 high", r.Counts)
+			}
+			if len(r.Files) != len(tc.files) {
+				t.Errorf("Files = %v, want %d reports", r.Files, len(tc.files))
+			}
+			for _, f := range r.Files {
+				ext := strings.TrimPrefix(f, filepath.Join(dir, reportName(img)))
+				want, ok := tc.files[ext]
+				if !ok {
+					t.Errorf("unexpected report %s", f)
+					continue
+				}
+				data, err := os.ReadFile(f) // #nosec G304
+				if err != nil {
+					t.Errorf("reading report: %v", err)
+					continue
+				}
+				if want != "" && string(data) != want {
+					t.Errorf("%s = %q, want %q", ext, data, want)
+				}
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(tc.files) {
+				t.Errorf("output directory has %d files, want %d (intermediate JSON left behind?)", len(entries), len(tc.files))
+			}
+		})
+	}
+}
+
+func TestScanImageLocal_ConvertFails(t *testing.T) {
+	bin := writeFakeTrivy(t)
+	t.Setenv("FAIL_FORMAT", FormatSARIF)
+	opts := &LocalOptions{OutputDir: t.TempDir()}
+	r := scanImageLocal(bin, DiscoveredImage{Name: "nginx"}, []string{FormatSARIF}, opts)
+	if r.Error == nil || !strings.Contains(r.Error.Error(), "writing sarif report") ||
+		!strings.Contains(r.Error.Error(), "cannot write sarif") {
+		t.Errorf("Error = %v, want the scanner's sarif error", r.Error)
+	}
+}
+
+func TestNormalizeFormats(t *testing.T) {
+	cases := []struct {
+		in      []string
+		want    []string
+		wantErr bool
+	}{
+		{nil, []string{FormatJSON}, false},
+		{[]string{"", " "}, []string{FormatJSON}, false},
+		{[]string{"SARIF", " table ", "sarif"}, []string{FormatSARIF, FormatTable}, false},
+		{[]string{"json", "cyclonedx"}, []string{FormatJSON, FormatCycloneDX}, false},
+		{[]string{"spdx"}, nil, true},
+	}
+	for _, tc := range cases {
+		got, err := normalizeFormats(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("normalizeFormats(%q) error = %v, wantErr %v", tc.in, err, tc.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("normalizeFormats(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestScanArgs(t *testing.T) {
+	cases := []struct {
+		name    string
+		img     DiscoveredImage
+		formats []string
+		opts    LocalOptions
+		has     []string
+		hasNot  []string
+	}{
+		{
+			name:    "cyclonedx lists every package",
+			img:     DiscoveredImage{Name: "nginx"},
+			formats: []string{FormatCycloneDX},
+			has:     []string{"--list-all-pkgs"},
+		},
+		{
+			name:    "json only",
+			img:     DiscoveredImage{Name: "nginx"},
+			formats: []string{FormatJSON, FormatSARIF},
+			hasNot:  []string{"--list-all-pkgs", "--image-src"},
+		},
+		{
+			name:    "offline docker",
+			img:     DiscoveredImage{Name: "nginx", Runtime: "docker", Socket: "unix:///run/docker.sock"},
+			formats: []string{FormatJSON},
+			opts:    LocalOptions{Offline: true, Severity: "HIGH,CRITICAL"},
+			has: []string{"--offline-scan", "--skip-db-update", "--image-src docker",
+				"--docker-host unix:///run/docker.sock", "--severity HIGH,CRITICAL"},
+			hasNot: []string{"remote"},
+		},
+		{
+			name:    "online podman",
+			img:     DiscoveredImage{Name: "nginx", Runtime: "podman", Socket: "unix:///run/podman/podman.sock"},
+			formats: []string{FormatJSON},
+			has:     []string{"--image-src podman,remote", "--podman-host /run/podman/podman.sock"},
+			hasNot:  []string{"--offline-scan", "--skip-db-update"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := scanArgs(tc.img, "out.json", tc.formats, &tc.opts)
+			line := strings.Join(args, " ")
+			if !strings.HasPrefix(line, "image --quiet --scanners vuln --format json --output out.json") {
+				t.Errorf("args = %q", line)
+			}
+			if args[len(args)-1] != tc.img.Name {
+				t.Errorf("last arg = %q, want the image", args[len(args)-1])
+			}
+			for _, s := range tc.has {
+				if !strings.Contains(line, s) {
+					t.Errorf("args %q lack %q", line, s)
+				}
+			}
+			for _, s := range tc.hasNot {
+				if strings.Contains(line, s) {
+					t.Errorf("args %q contain %q", line, s)
+				}
+			}
+		})
+	}
+}
+
+func TestReportName(t *testing.T) {
+	cases := map[string]string{
+		"docker.io/library/nginx:1.25":   "docker.io_library_nginx_1.25-",
+		"nginx@sha256:abc":               "nginx_sha256_abc-",
+		"localhost:5000/app/../../x:tag": "localhost_5000_app_.._.._x_tag-",
+		"///":                            "image-",
+	}
+	for in, want := range cases {
+		got := reportName(DiscoveredImage{Name: in})
+		if !strings.HasPrefix(got, want) || len(got) != len(want)+8 {
+			t.Errorf("reportName(%q) = %q, want %q and an 8 character hash", in, got, want)
+		}
+	}
+	if got, want := reportName(DiscoveredImage{Name: "docker.io/library/nginx:1.25", Runtime: "docker"}), "docker.io_library_nginx_1.25-39789ead"; got != want {
+		t.Errorf("reportName = %q, want %q", got, want)
+	}
+}
+
+func TestReportName_Collisions(t *testing.T) {
+	images := []DiscoveredImage{
+		{Name: "a/b:c", Runtime: "docker"},
+		{Name: "a_b:c", Runtime: "docker"},
+		{Name: "a_b_c", Runtime: "docker"},
+		{Name: "a/b:c", Runtime: "podman"},
+		{Name: "a/b:c", Runtime: "containerd", Namespace: "k8s.io"},
+		{Name: "a/b:c", Runtime: "containerd", Namespace: "default"},
+		{Name: "a/b:c", Runtime: "docker", Socket: "unix:///run/user/1000/docker.sock"},
+	}
+	seen := map[string]DiscoveredImage{}
+	for _, img := range images {
+		name := reportName(img)
+		if prev, ok := seen[name]; ok {
+			t.Errorf("%+v and %+v both report to %q", prev, img, name)
+		}
+		seen[name] = img
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	cm "github.com/accuknox/accuknox-cli-v2/pkg/common"
+	"github.com/accuknox/accuknox-cli-v2/pkg/download"
 	"github.com/accuknox/accuknox-cli-v2/pkg/logger"
 	"github.com/mholt/archives"
 )
@@ -659,8 +660,7 @@ func createRandomTempFolder() (string, error) {
 }
 
 func downloadToTemp(sourceURL string) (string, error) {
-	// #nosec G107 -- false positive
-	resp, err := http.Get(sourceURL)
+	resp, err := download.Get(sourceURL)
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch url: %w", err)
 	}
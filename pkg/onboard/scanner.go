@@ -308,7 +308,7 @@ func (cc *ClusterConfig) InstallImagescan() error {
 	var sockPaths []string
 	if cc.Mode == VMMode_Docker {
 		// Detect CRI paths(sockets) for mounting it into the container
-		runtimes := []string{"docker", "containerd", "cri-o", "nri"}
+		runtimes := []string{"docker", "containerd", "cri-o", "nri", "podman"}
 		for _, r := range runtimes {
 			if _, criPaths, ok := imagescan.DiscoverRuntime("", r); ok {
 				for _, criPath := range criPaths {
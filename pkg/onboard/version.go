@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 
 	"github.com/accuknox/accuknox-cli-v2/pkg/common"
+	"github.com/accuknox/accuknox-cli-v2/pkg/download"
 )
 
 const kubearmorBinaryPath = "/opt/kubearmor/kubearmor"
@@ -94,7 +95,7 @@ func DetermineKAVersionLegacy() (string, error) {
 	md5Sum := md5.Sum(kubearmorBinaryData) // #nosec G401 (this is something already present on the system)
 	md5SumString := hex.EncodeToString(md5Sum[:16])
 
-	resp, err := http.Get("https://raw.githubusercontent.com/accuknox/pkgversions/refs/heads/main/versions.json")
+	resp, err := download.Get("https://raw.githubusercontent.com/accuknox/pkgversions/refs/heads/main/versions.json")
 	if err != nil {
 		return "", err
 	}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/accuknox/accuknox-cli-v2/pkg/download"
 	"sigs.k8s.io/yaml"
 )
 
@@ -28,11 +29,14 @@ func NewGenerator(zipURL string) *GetPolicy {
 }
 
 func (gp *GetPolicy) FetchTemplates() error {
-	resp, err := http.Get(gp.ZipURL)
+	resp, err := download.Get(gp.ZipURL)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d for %s", resp.StatusCode, gp.ZipURL)
+	}
 
 	tempZip, err := os.CreateTemp("", "repo-*.zip")
 	if err != nil {
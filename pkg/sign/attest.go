@@ -0,0 +1,227 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package sign
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+)
+
+const (
+	// PayloadType is the DSSE payload type of an in-toto statement.
+	PayloadType = "application/vnd.in-toto+json"
+
+	// StatementType identifies an in-toto Statement v1.
+	StatementType = "https://in-toto.io/Statement/v1"
+
+	// PredicateCycloneDX is the predicate type of a CycloneDX BOM.
+	PredicateCycloneDX = "https://cyclonedx.org/bom"
+)
+
+// Statement is an in-toto Statement v1 whose predicate is a CycloneDX BOM.
+type Statement struct {
+	Type          string          `json:"_type"`
+	Subject       []Subject       `json:"subject"`
+	PredicateType string          `json:"predicateType"`
+	Predicate     json.RawMessage `json:"predicate"`
+}
+
+// Attest wraps the CycloneDX BOM at bomPath in an in-toto statement about
+// subjects and signs it as a DSSE envelope. It is a no-op when opts.Attest
+// is false or bomPath is empty.
+//
+// opts.Subject, when set, replaces subjects. With neither, the BOM file
+// itself is the subject. The signing key is resolved as in Artifact, and
+// the envelope is written to opts.AttestOut or <bomPath>.intoto.jsonl.
+func Attest(bomPath string, subjects []Subject, opts *Options) error {
+	if !opts.Attest || bomPath == "" {
+		return nil
+	}
+
+	if opts.Subject != "" {
+		s, err := ParseSubject(opts.Subject)
+		if err != nil {
+			return fmt.Errorf("attest: %w", err)
+		}
+		subjects = []Subject{s}
+	}
+	if len(subjects) == 0 {
+		digest, err := fileDigest(bomPath)
+		if err != nil {
+			return fmt.Errorf("attest: %w", err)
+		}
+		subjects = []Subject{{Name: filepath.Base(bomPath), Digest: map[string]string{"sha256": digest}}}
+	}
+
+	bom, err := os.ReadFile(bomPath) // #nosec G304
+	if err != nil {
+		return fmt.Errorf("attest: reading BOM %s: %w", bomPath, err)
+	}
+	if !json.Valid(bom) {
+		return fmt.Errorf("attest: %s is not a JSON BOM", bomPath)
+	}
+	payload, err := json.Marshal(Statement{
+		Type:          StatementType,
+		Subject:       subjects,
+		PredicateType: PredicateCycloneDX,
+		Predicate:     compactJSON(bom),
+	})
+	if err != nil {
+		return fmt.Errorf("attest: marshaling statement: %w", err)
+	}
+
+	sv, err := signer(opts)
+	if err != nil {
+		return fmt.Errorf("attest: %w", err)
+	}
+	envelope, err := dsse.WrapSigner(sv, PayloadType).SignMessage(bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("attest: signing statement: %w", err)
+	}
+
+	outPath, err := safePath(opts.AttestOut, bomPath+".intoto.jsonl")
+	if err != nil {
+		return fmt.Errorf("attest: invalid attestation output path: %w", err)
+	}
+	if err := os.WriteFile(outPath, append(envelope, '\n'), 0600); err != nil { // #nosec G703 -- path validated by safePath (cleaned, traversal-rejected, absolute-resolved)
+		return fmt.Errorf("attest: writing attestation %s: %w", outPath, err)
+	}
+
+	fmt.Printf("Attestation signed → %s\n", outPath)
+	for _, s := range subjects {
+		fmt.Printf("  subject            %s\n", s)
+	}
+	fmt.Printf("Verify with:         knoxctl sign verify-attestation --attestation %s --pub %s\n",
+		outPath, pubKeyPath(opts))
+	return nil
+}
+
+// VerifyAttestation checks the DSSE envelope at envPath: its payload type,
+// its signature against keyRef (resolved as in Verify), and that it holds an
+// in-toto Statement v1 with a CycloneDX predicate. When expected is not
+// empty, each expected subject's digest must match a subject of the
+// statement. The verified statement is returned.
+func VerifyAttestation(envPath, keyRef string, expected []Subject) (*Statement, error) {
+	raw, err := readEnvelope(envPath)
+	if err != nil {
+		return nil, fmt.Errorf("verify-attestation: %w", err)
+	}
+	var env ssldsse.Envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return nil, fmt.Errorf("verify-attestation: parsing envelope: %w", err)
+	}
+	if env.PayloadType != PayloadType {
+		return nil, fmt.Errorf("verify-attestation: unexpected payload type %q (want %s)", env.PayloadType, PayloadType)
+	}
+	if len(env.Signatures) == 0 {
+		return nil, fmt.Errorf("verify-attestation: envelope is not signed")
+	}
+
+	verifier, err := loadVerifier(context.Background(), keyRef)
+	if err != nil {
+		return nil, fmt.Errorf("verify-attestation: %w", err)
+	}
+	if err := dsse.WrapVerifier(verifier).VerifySignature(bytes.NewReader(raw), nil); err != nil {
+		return nil, fmt.Errorf("verify-attestation: invalid signature: %w", err)
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("verify-attestation: decoding payload: %w", err)
+	}
+	var st Statement
+	if err := json.Unmarshal(payload, &st); err != nil {
+		return nil, fmt.Errorf("verify-attestation: parsing statement: %w", err)
+	}
+	if err := st.validate(); err != nil {
+		return nil, fmt.Errorf("verify-attestation: %w", err)
+	}
+	for _, want := range expected {
+		if !st.covers(want) {
+			return nil, fmt.Errorf("verify-attestation: subject digest mismatch: expected %s, attestation covers %s",
+				want, subjectList(st.Subject))
+		}
+	}
+
+	fmt.Printf("Attestation OK: %s is signed and covers %s\n", envPath, subjectList(st.Subject))
+	return &st, nil
+}
+
+// validate checks the statement header and that every subject has a digest.
+func (st *Statement) validate() error {
+	if st.Type != StatementType {
+		return fmt.Errorf("unexpected statement type %q (want %s)", st.Type, StatementType)
+	}
+	if st.PredicateType != PredicateCycloneDX {
+		return fmt.Errorf("unexpected predicate type %q (want %s)", st.PredicateType, PredicateCycloneDX)
+	}
+	if len(st.Predicate) == 0 || string(st.Predicate) == "null" {
+		return fmt.Errorf("statement has no predicate")
+	}
+	if len(st.Subject) == 0 {
+		return fmt.Errorf("statement has no subject")
+	}
+	for _, s := range st.Subject {
+		if len(s.Digest) == 0 {
+			return fmt.Errorf("subject %q has no digest", s.Name)
+		}
+	}
+	return nil
+}
+
+// covers reports whether a subject of the statement shares a digest with
+// want. Names are not compared: the same image may be known by several.
+func (st *Statement) covers(want Subject) bool {
+	for _, s := range st.Subject {
+		for alg, d := range want.Digest {
+			if got, ok := s.Digest[alg]; ok && strings.EqualFold(got, d) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readEnvelope returns the first envelope of a .intoto.jsonl file, which
+// holds one JSON envelope per line.
+func readEnvelope(path string) ([]byte, error) {
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("reading attestation %s: %w", path, err)
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for sc.Scan() {
+		if line := bytes.TrimSpace(sc.Bytes()); len(line) > 0 {
+			return line, nil
+		}
+	}
+	return nil, fmt.Errorf("attestation %s is empty", path)
+}
+
+func compactJSON(data []byte) json.RawMessage {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return data
+	}
+	return buf.Bytes()
+}
+
+func subjectList(subjects []Subject) string {
+	names := make([]string, len(subjects))
+	for i, s := range subjects {
+		names[i] = s.String()
+	}
+	return strings.Join(names, ", ")
+}
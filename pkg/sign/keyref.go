@@ -0,0 +1,183 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package sign
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	cosigncrypto "github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/cosign/pkcs11key"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/kms"
+)
+
+// EnvKeyScheme prefixes a key reference naming an environment variable that
+// holds the PEM key, e.g. "env://COSIGN_PRIVATE_KEY".
+const EnvKeyScheme = "env://"
+
+// PasswordEnv is read for the key passphrase when Options.Password is empty,
+// as the cosign CLI does.
+const PasswordEnv = "COSIGN_PASSWORD"
+
+// supportedSchemes is reported when a key reference matches no provider.
+// KMS providers register themselves with sigstore's kms package when their
+// package is imported (see cmd/sign_kms.go).
+const supportedSchemes = "awskms://, gcpkms://, azurekms://, hashivault://, pkcs11:, env://"
+
+// IsKeyURI reports whether ref is a key reference (KMS, PKCS#11 or
+// environment variable) rather than a file path.
+func IsKeyURI(ref string) bool {
+	return strings.Contains(ref, "://") || strings.HasPrefix(ref, pkcs11key.ReferenceScheme)
+}
+
+// keyPassword returns the passphrase for a PEM private key.
+func keyPassword(opts *Options) []byte {
+	if opts.Password != "" {
+		return []byte(opts.Password)
+	}
+	return []byte(os.Getenv(PasswordEnv))
+}
+
+// loadSigner resolves keyRef to a signer. Supported references:
+//
+//   - a path to a cosign PEM private key
+//   - env://VAR: a PEM private key held in environment variable VAR
+//   - awskms://, gcpkms://, azurekms://, hashivault://: a KMS key
+//   - pkcs11:...: a key on a hardware token (requires the pkcs11key build tag)
+func loadSigner(ctx context.Context, keyRef string, password []byte) (signature.SignerVerifier, error) {
+	switch {
+	case strings.HasPrefix(keyRef, pkcs11key.ReferenceScheme):
+		sk, err := pkcs11Key(keyRef, true)
+		if err != nil {
+			return nil, err
+		}
+		sv, err := sk.SignerVerifier()
+		if err != nil {
+			return nil, fmt.Errorf("initializing PKCS#11 signer: %w", err)
+		}
+		return sv, nil
+
+	case strings.HasPrefix(keyRef, EnvKeyScheme):
+		pem, err := envKey(keyRef)
+		if err != nil {
+			return nil, err
+		}
+		sv, err := cosigncrypto.LoadPrivateKey(pem, password, nil)
+		if err != nil {
+			return nil, fmt.Errorf("loading private key from %s: %w", keyRef, err)
+		}
+		return sv, nil
+
+	case strings.Contains(keyRef, "://"):
+		return kmsKey(ctx, keyRef)
+	}
+
+	keyBytes, err := os.ReadFile(keyRef) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("reading key %s: %w", keyRef, err)
+	}
+	sv, err := cosigncrypto.LoadPrivateKey(keyBytes, password, nil)
+	if err != nil {
+		return nil, fmt.Errorf("loading private key: %w", err)
+	}
+	return sv, nil
+}
+
+// loadVerifier resolves keyRef to a verifier. It accepts a PEM public key
+// file or env:// reference, or the same KMS and PKCS#11 references as
+// loadSigner, whose public key is fetched from the provider.
+func loadVerifier(ctx context.Context, keyRef string) (signature.Verifier, error) {
+	var pubPEM []byte
+	switch {
+	case strings.HasPrefix(keyRef, pkcs11key.ReferenceScheme):
+		sk, err := pkcs11Key(keyRef, false)
+		if err != nil {
+			return nil, err
+		}
+		v, err := sk.Verifier()
+		if err != nil {
+			return nil, fmt.Errorf("initializing PKCS#11 verifier: %w", err)
+		}
+		return v, nil
+
+	case strings.HasPrefix(keyRef, EnvKeyScheme):
+		b, err := envKey(keyRef)
+		if err != nil {
+			return nil, err
+		}
+		pubPEM = b
+
+	case strings.Contains(keyRef, "://"):
+		return kmsKey(ctx, keyRef)
+
+	default:
+		b, err := os.ReadFile(keyRef) // #nosec G304
+		if err != nil {
+			return nil, fmt.Errorf("reading public key %s: %w", keyRef, err)
+		}
+		pubPEM = b
+	}
+
+	pub, err := cryptoutils.UnmarshalPEMToPublicKey(pubPEM)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public key: %w", err)
+	}
+	v, err := signature.LoadVerifier(pub, crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("loading verifier: %w", err)
+	}
+	return v, nil
+}
+
+// publicKeyPEM returns the PEM public key of a signer.
+func publicKeyPEM(sv signature.SignerVerifier) ([]byte, error) {
+	pub, err := sv.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("fetching public key: %w", err)
+	}
+	return cryptoutils.MarshalPublicKeyToPEM(pub)
+}
+
+func kmsKey(ctx context.Context, keyRef string) (kms.SignerVerifier, error) {
+	sv, err := kms.Get(ctx, keyRef, crypto.SHA256)
+	var notFound *kms.ProviderNotFoundError
+	if errors.As(err, &notFound) {
+		return nil, fmt.Errorf("unsupported key reference %q (supported: %s, or a file path)", keyRef, supportedSchemes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading KMS key %s: %w", keyRef, err)
+	}
+	return sv, nil
+}
+
+func envKey(keyRef string) ([]byte, error) {
+	name := strings.TrimPrefix(keyRef, EnvKeyScheme)
+	v, ok := os.LookupEnv(name)
+	if !ok || v == "" {
+		return nil, fmt.Errorf("key environment variable $%s is not set", name)
+	}
+	return []byte(v), nil
+}
+
+func pkcs11Key(keyRef string, needPrivate bool) (*pkcs11key.Key, error) {
+	conf := pkcs11key.NewPkcs11UriConfig()
+	if err := conf.Parse(keyRef); err != nil {
+		return nil, fmt.Errorf("parsing PKCS#11 URI: %w", err)
+	}
+	sk, err := pkcs11key.GetKeyWithURIConfig(conf, needPrivate)
+	if err != nil && err.Error() == "unimplemented" {
+		// cosign's stub, compiled in without the pkcs11key build tag.
+		return nil, fmt.Errorf("PKCS#11 keys are not supported by this build (rebuild with -tags pkcs11key)")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening PKCS#11 key: %w", err)
+	}
+	return sk, nil
+}
@@ -0,0 +1,348 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package sign
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+// TrustPolicy is the set of keys and certificate authorities a signature is
+// verified against. A signature is accepted when any one trusted key, or a
+// certificate chaining to a trusted root, verifies it — so keys can be
+// rotated, and several producers trusted, without changing the command.
+type TrustPolicy struct {
+	// Keys are trusted public keys, tried in order.
+	Keys []TrustedKey
+
+	// Cert is the signing certificate, verified against Roots through
+	// Intermediates before its key is used.
+	Cert          *x509.Certificate
+	Roots         *x509.CertPool
+	Intermediates *x509.CertPool
+}
+
+// TrustedKey is a public key of a trust policy and where it came from.
+type TrustedKey struct {
+	ID       string // file path (with #N for the Nth key of a bundle) or key reference
+	verifier signature.Verifier
+}
+
+// TrustOptions lists the sources of a trust policy.
+type TrustOptions struct {
+	// Keys are PEM files, PEM bundles or directories of them holding public
+	// keys or certificates (whose keys are trusted as is), or KMS, PKCS#11
+	// and env:// key references.
+	Keys []string
+
+	// Cert is a PEM signing certificate; further certificates in the same
+	// file are taken as intermediates.
+	Cert string
+
+	// CertChain are PEM files of intermediate certificates.
+	CertChain []string
+
+	// Roots are PEM bundles of trusted root CA certificates; required with
+	// Cert.
+	Roots []string
+}
+
+// VerifyResult is the machine-readable outcome of a policy verification.
+type VerifyResult struct {
+	Artifact    string    `json:"artifact"`
+	Signature   string    `json:"signature"`
+	Verified    bool      `json:"verified"`
+	Key         string    `json:"key,omitempty"`         // ID of the key that matched
+	Fingerprint string    `json:"fingerprint,omitempty"` // SHA-256 of the matching key (DER SubjectPublicKeyInfo)
+	Certificate *CertInfo `json:"certificate,omitempty"` // set when the signing certificate matched
+	KeysTried   int       `json:"keysTried"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// CertInfo identifies a signing certificate.
+type CertInfo struct {
+	Subject  string    `json:"subject"`
+	Issuer   string    `json:"issuer"`
+	Serial   string    `json:"serial"`
+	NotAfter time.Time `json:"notAfter"`
+}
+
+// LoadTrustPolicy builds a trust policy from opts. Directories are read
+// one level deep, in name order; files without PEM keys or certificates
+// (e.g. private keys kept alongside) are skipped.
+func LoadTrustPolicy(ctx context.Context, opts *TrustOptions) (*TrustPolicy, error) {
+	p := &TrustPolicy{}
+	for _, ref := range opts.Keys {
+		keys, err := loadTrustedKeys(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		p.Keys = append(p.Keys, keys...)
+	}
+
+	if opts.Cert != "" {
+		if len(opts.Roots) == 0 {
+			return nil, fmt.Errorf("a signing certificate needs the root CA bundle it chains to")
+		}
+		certs, err := loadCertificates(opts.Cert)
+		if err != nil {
+			return nil, err
+		}
+		p.Cert = certs[0]
+		p.Intermediates = x509.NewCertPool()
+		for _, c := range certs[1:] {
+			p.Intermediates.AddCert(c)
+		}
+		for _, path := range opts.CertChain {
+			chain, err := loadCertificates(path)
+			if err != nil {
+				return nil, err
+			}
+			for _, c := range chain {
+				p.Intermediates.AddCert(c)
+			}
+		}
+		p.Roots = x509.NewCertPool()
+		for _, path := range opts.Roots {
+			roots, err := loadCertificates(path)
+			if err != nil {
+				return nil, err
+			}
+			for _, c := range roots {
+				p.Roots.AddCert(c)
+			}
+		}
+	}
+
+	if len(p.Keys) == 0 && p.Cert == nil {
+		return nil, fmt.Errorf("trust policy has no keys or signing certificate")
+	}
+	return p, nil
+}
+
+// VerifyWithPolicy checks the base-64 signature in sigPath over the file at
+// artifactPath against policy. The result records which key matched; it is
+// returned alongside the error when verification fails.
+func VerifyWithPolicy(artifactPath, sigPath string, policy *TrustPolicy) (*VerifyResult, error) {
+	res := &VerifyResult{Artifact: artifactPath, Signature: sigPath}
+	fail := func(err error) (*VerifyResult, error) {
+		res.Error = err.Error()
+		return res, fmt.Errorf("verify: %w", err)
+	}
+
+	data, err := os.ReadFile(artifactPath) // #nosec G304
+	if err != nil {
+		return fail(fmt.Errorf("reading artifact %s: %w", artifactPath, err))
+	}
+	rawSig, err := os.ReadFile(sigPath) // #nosec G304
+	if err != nil {
+		return fail(fmt.Errorf("reading signature %s: %w", sigPath, err))
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(rawSig)))
+	if err != nil {
+		return fail(fmt.Errorf("decoding signature: %w", err))
+	}
+
+	var certErr error
+	if policy.Cert != nil {
+		res.KeysTried++
+		certErr = verifyCertificate(policy)
+		if certErr == nil {
+			v, err := signature.LoadVerifier(policy.Cert.PublicKey, crypto.SHA256)
+			if err != nil {
+				return fail(fmt.Errorf("loading certificate key: %w", err))
+			}
+			if v.VerifySignature(bytes.NewReader(sig), bytes.NewReader(data)) == nil {
+				res.Verified = true
+				res.Key = "certificate"
+				res.Fingerprint = fingerprint(policy.Cert.PublicKey)
+				res.Certificate = certInfo(policy.Cert)
+				return res, nil
+			}
+		}
+	}
+
+	for _, k := range policy.Keys {
+		res.KeysTried++
+		if k.verifier.VerifySignature(bytes.NewReader(sig), bytes.NewReader(data)) != nil {
+			continue
+		}
+		res.Verified = true
+		res.Key = k.ID
+		if pub, err := k.verifier.PublicKey(); err == nil {
+			res.Fingerprint = fingerprint(pub)
+		}
+		return res, nil
+	}
+
+	if certErr != nil && len(policy.Keys) == 0 {
+		return fail(certErr)
+	}
+	return fail(fmt.Errorf("invalid signature: no trusted key verifies it (%d tried)", res.KeysTried))
+}
+
+// verifyCertificate checks that the signing certificate chains to a trusted
+// root and is valid for code signing now.
+func verifyCertificate(p *TrustPolicy) error {
+	_, err := p.Cert.Verify(x509.VerifyOptions{
+		Roots:         p.Roots,
+		Intermediates: p.Intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return fmt.Errorf("signing certificate %q is not trusted: %w", p.Cert.Subject.String(), err)
+	}
+	return nil
+}
+
+// loadTrustedKeys resolves one key source of a trust policy.
+func loadTrustedKeys(ctx context.Context, ref string) ([]TrustedKey, error) {
+	if IsKeyURI(ref) {
+		v, err := loadVerifier(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		return []TrustedKey{{ID: ref, verifier: v}}, nil
+	}
+
+	info, err := os.Stat(ref)
+	if err != nil {
+		return nil, fmt.Errorf("reading public key %s: %w", ref, err)
+	}
+	if !info.IsDir() {
+		keys, err := pemKeys(ref)
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("no public keys or certificates in %s", ref)
+		}
+		return keys, nil
+	}
+
+	entries, err := os.ReadDir(ref)
+	if err != nil {
+		return nil, fmt.Errorf("reading key directory %s: %w", ref, err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	var keys []TrustedKey
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		k, err := pemKeys(filepath.Join(ref, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k...)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no public keys or certificates in %s", ref)
+	}
+	return keys, nil
+}
+
+// pemKeys returns the public keys in a PEM file: PUBLIC KEY blocks and the
+// keys of CERTIFICATE blocks. Keys of a bundle are identified as path#N.
+func pemKeys(path string) ([]TrustedKey, error) {
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("reading public key %s: %w", path, err)
+	}
+	var pubs []crypto.PublicKey
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "PUBLIC KEY":
+			pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("parsing public key in %s: %w", path, err)
+			}
+			pubs = append(pubs, pub)
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("parsing certificate in %s: %w", path, err)
+			}
+			pubs = append(pubs, cert.PublicKey)
+		}
+	}
+
+	keys := make([]TrustedKey, 0, len(pubs))
+	for i, pub := range pubs {
+		v, err := signature.LoadVerifier(pub, crypto.SHA256)
+		if err != nil {
+			return nil, fmt.Errorf("loading public key in %s: %w", path, err)
+		}
+		id := path
+		if len(pubs) > 1 {
+			id = fmt.Sprintf("%s#%d", path, i+1)
+		}
+		keys = append(keys, TrustedKey{ID: id, verifier: v})
+	}
+	return keys, nil
+}
+
+func loadCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("reading certificates %s: %w", path, err)
+	}
+	var certs []*x509.Certificate
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing certificate in %s: %w", path, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificates in %s", path)
+	}
+	return certs, nil
+}
+
+func fingerprint(pub crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func certInfo(c *x509.Certificate) *CertInfo {
+	return &CertInfo{
+		Subject:  c.Subject.String(),
+		Issuer:   c.Issuer.String(),
+		Serial:   c.SerialNumber.String(),
+		NotAfter: c.NotAfter,
+	}
+}
@@ -5,6 +5,7 @@ package sign
 
 import (
 	"bytes"
+	"context"
 	"crypto"
 	"encoding/base64"
 	"fmt"
@@ -24,7 +25,7 @@ func GenerateKeyPair(opts *Options) error {
 	if prefix == "" {
 		prefix = "cosign"
 	}
-	password := []byte(opts.Password)
+	password := keyPassword(opts)
 	passFunc := func(bool) ([]byte, error) { return password, nil }
 
 	kb, err := cosigncrypto.GenerateKeyPair(passFunc)
@@ -49,7 +50,8 @@ func GenerateKeyPair(opts *Options) error {
 // Key resolution order:
 //  1. If opts.GenerateKey is true, a fresh ECDSA P-256 key pair is created and
 //     written to <KeyOut>.key / <KeyOut>.pub before signing.
-//  2. Otherwise opts.KeyRef (default "cosign.key") is loaded from disk.
+//  2. Otherwise opts.KeyRef (default "cosign.key") is loaded: a PEM file, an
+//     env:// variable, or a KMS or PKCS#11 key reference (see loadSigner).
 //
 // The base-64 encoded signature is written to opts.SigOut, or to
 // <artifactPath>.sig when SigOut is empty.
@@ -58,46 +60,9 @@ func Artifact(artifactPath string, opts *Options) error {
 		return nil
 	}
 
-	password := []byte(opts.Password)
-	passFunc := func(bool) ([]byte, error) { return password, nil }
-
-	var keyBytes []byte
-
-	if opts.GenerateKey {
-		prefix := opts.KeyOut
-		if prefix == "" {
-			prefix = "cosign"
-		}
-		kb, err := cosigncrypto.GenerateKeyPair(passFunc)
-		if err != nil {
-			return fmt.Errorf("sign: generating key pair: %w", err)
-		}
-		privPath := prefix + ".key"
-		pubPath := prefix + ".pub"
-		if err := os.WriteFile(privPath, kb.PrivateBytes, 0600); err != nil {
-			return fmt.Errorf("sign: writing private key %s: %w", privPath, err)
-		}
-		if err := os.WriteFile(pubPath, kb.PublicBytes, 0600); err != nil {
-			return fmt.Errorf("sign: writing public key %s: %w", pubPath, err)
-		}
-		fmt.Printf("Generated key pair → %s (private)  %s (public)\n", privPath, pubPath)
-		keyBytes = kb.PrivateBytes
-	} else {
-		keyRef := opts.KeyRef
-		if keyRef == "" {
-			keyRef = "cosign.key"
-		}
-		b, err := os.ReadFile(keyRef) // #nosec G304
-		if err != nil {
-			return fmt.Errorf("sign: reading key %s: %w", keyRef, err)
-		}
-		keyBytes = b
-	}
-
-	// Load signer from PEM private key.
-	sv, err := cosigncrypto.LoadPrivateKey(keyBytes, password, nil)
+	sv, err := signer(opts)
 	if err != nil {
-		return fmt.Errorf("sign: loading private key: %w", err)
+		return fmt.Errorf("sign: %w", err)
 	}
 
 	// Read the artifact.
@@ -128,14 +93,57 @@ func Artifact(artifactPath string, opts *Options) error {
 	return nil
 }
 
+// signer resolves the signing key described by opts. When GenerateKey is
+// set a fresh key pair is written to <KeyOut>.key / <KeyOut>.pub and opts is
+// pointed at the new key, so a detached signature and an attestation made
+// from the same options share one key.
+func signer(opts *Options) (signature.SignerVerifier, error) {
+	password := keyPassword(opts)
+
+	if opts.GenerateKey {
+		prefix := opts.KeyOut
+		if prefix == "" {
+			prefix = "cosign"
+		}
+		kb, err := cosigncrypto.GenerateKeyPair(func(bool) ([]byte, error) { return password, nil })
+		if err != nil {
+			return nil, fmt.Errorf("generating key pair: %w", err)
+		}
+		privPath := prefix + ".key"
+		pubPath := prefix + ".pub"
+		if err := os.WriteFile(privPath, kb.PrivateBytes, 0600); err != nil {
+			return nil, fmt.Errorf("writing private key %s: %w", privPath, err)
+		}
+		if err := os.WriteFile(pubPath, kb.PublicBytes, 0600); err != nil {
+			return nil, fmt.Errorf("writing public key %s: %w", pubPath, err)
+		}
+		fmt.Printf("Generated key pair → %s (private)  %s (public)\n", privPath, pubPath)
+		sv, err := cosigncrypto.LoadPrivateKey(kb.PrivateBytes, password, nil)
+		if err != nil {
+			return nil, fmt.Errorf("loading private key: %w", err)
+		}
+		opts.GenerateKey = false
+		opts.KeyRef = privPath
+		opts.KeyOut = prefix
+		return sv, nil
+	}
+
+	keyRef := opts.KeyRef
+	if keyRef == "" {
+		keyRef = "cosign.key"
+	}
+	return loadSigner(context.Background(), keyRef, password)
+}
+
 // SignBytes signs data entirely in-memory and returns:
-//   - sigB64: base-64 encoded ECDSA signature
-//   - pubKeyPEM: PEM-encoded public key (only populated when opts.GenerateKey is true)
+//   - sigB64: base-64 encoded signature
+//   - pubKeyPEM: PEM-encoded public key (populated for generated keys and for
+//     KMS and PKCS#11 keys, whose public key is not otherwise at hand)
 //
 // When opts.GenerateKey is true a fresh ephemeral ECDSA P-256 key pair is
 // generated; no key files are written to disk.
-// When opts.GenerateKey is false opts.KeyRef (default "cosign.key") is read
-// from disk, decrypted with opts.Password, and used for signing.
+// When opts.GenerateKey is false opts.KeyRef (default "cosign.key") is
+// resolved as in Artifact and used for signing.
 func SignBytes(data []byte, opts *Options) (sigB64 string, pubKeyPEM []byte, err error) {
 	var sv signature.SignerVerifier
 
@@ -157,13 +165,15 @@ func SignBytes(data []byte, opts *Options) (sigB64 string, pubKeyPEM []byte, err
 		if keyRef == "" {
 			keyRef = "cosign.key"
 		}
-		keyBytes, readErr := os.ReadFile(keyRef) // #nosec G304
-		if readErr != nil {
-			return "", nil, fmt.Errorf("sign: reading key %s: %w", keyRef, readErr)
-		}
-		sv, err = cosigncrypto.LoadPrivateKey(keyBytes, []byte(opts.Password), nil)
+		sv, err = loadSigner(context.Background(), keyRef, keyPassword(opts))
 		if err != nil {
-			return "", nil, fmt.Errorf("sign: loading private key: %w", err)
+			return "", nil, fmt.Errorf("sign: %w", err)
+		}
+		if IsKeyURI(keyRef) && !strings.HasPrefix(keyRef, EnvKeyScheme) {
+			pubKeyPEM, err = publicKeyPEM(sv)
+			if err != nil {
+				return "", nil, fmt.Errorf("sign: %w", err)
+			}
 		}
 	}
 
@@ -175,45 +185,40 @@ func SignBytes(data []byte, opts *Options) (sigB64 string, pubKeyPEM []byte, err
 }
 
 // Verify checks that the base-64 signature in sigPath was produced by the
-// private key corresponding to the PEM public key in pubKeyPath over the file
-// at artifactPath.
-func Verify(artifactPath, sigPath, pubKeyPath string) error {
-	data, err := os.ReadFile(artifactPath) // #nosec G304
+// private key corresponding to keyRef over the file at artifactPath. keyRef is
+// a PEM public key file, a bundle or directory of them, an env:// variable
+// holding one, or a KMS or PKCS#11 key reference. See VerifyWithPolicy for
+// several keys and certificate chains.
+func Verify(artifactPath, sigPath, keyRef string) error {
+	policy, err := LoadTrustPolicy(context.Background(), &TrustOptions{Keys: []string{keyRef}})
 	if err != nil {
-		return fmt.Errorf("verify: reading artifact %s: %w", artifactPath, err)
+		return fmt.Errorf("verify: %w", err)
 	}
-
-	rawSig, err := os.ReadFile(sigPath) // #nosec G304
-	if err != nil {
-		return fmt.Errorf("verify: reading signature %s: %w", sigPath, err)
-	}
-	sigBytes, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(rawSig)))
-	if err != nil {
-		return fmt.Errorf("verify: decoding signature: %w", err)
+	if _, err := VerifyWithPolicy(artifactPath, sigPath, policy); err != nil {
+		return err
 	}
+	fmt.Printf("Signature OK: %s is authentic\n", artifactPath)
+	return nil
+}
 
-	pubPEM, err := os.ReadFile(pubKeyPath) // #nosec G304
-	if err != nil {
-		return fmt.Errorf("verify: reading public key %s: %w", pubKeyPath, err)
-	}
-	pubKey, err := cosigncrypto.PemToECDSAKey(pubPEM)
+// VerifyBytes checks a base-64 signature over data against a PEM public
+// key, for callers that hold all three in memory.
+func VerifyBytes(data, sigB64, pubKeyPEM []byte) error {
+	pub, err := cryptoutils.UnmarshalPEMToPublicKey(pubKeyPEM)
 	if err != nil {
 		return fmt.Errorf("verify: parsing public key: %w", err)
 	}
-
-	verifier, err := signature.LoadECDSAVerifier(pubKey, crypto.SHA256)
+	v, err := signature.LoadVerifier(pub, crypto.SHA256)
 	if err != nil {
 		return fmt.Errorf("verify: loading verifier: %w", err)
 	}
-
-	if err := verifier.VerifySignature(
-		bytes.NewReader(sigBytes),
-		bytes.NewReader(data),
-	); err != nil {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sigB64)))
+	if err != nil {
+		return fmt.Errorf("verify: decoding signature: %w", err)
+	}
+	if err := v.VerifySignature(bytes.NewReader(sig), bytes.NewReader(data)); err != nil {
 		return fmt.Errorf("verify: invalid signature: %w", err)
 	}
-
-	fmt.Printf("Signature OK: %s is authentic\n", artifactPath)
 	return nil
 }
 
@@ -231,12 +236,35 @@ func pubKeyPath(opts *Options) string {
 	if ref == "" {
 		ref = "cosign.key"
 	}
+	if IsKeyURI(ref) && !strings.HasPrefix(ref, EnvKeyScheme) {
+		// cosign resolves KMS and PKCS#11 references itself.
+		return ref
+	}
 	if strings.HasSuffix(ref, ".key") {
 		return strings.TrimSuffix(ref, ".key") + ".pub"
 	}
 	return ref + ".pub"
 }
 
+// OutputFiles returns the files that signing artifactPath with opts may
+// write: the artifact itself, its signature and its attestation. They are
+// listed whether or not signing is enabled, since a previous run may have
+// left them behind.
+func OutputFiles(artifactPath string, opts *Options) []string {
+	return []string{
+		artifactPath,
+		orDefault(opts.SigOut, artifactPath+".sig"),
+		orDefault(opts.AttestOut, artifactPath+".intoto.jsonl"),
+	}
+}
+
+func orDefault(p, defaultPath string) string {
+	if p == "" {
+		return defaultPath
+	}
+	return p
+}
+
 // safePath validates and resolves p (falling back to defaultPath when empty).
 // It rejects relative paths that escape the working directory via ".."
 // components, then converts the result to an absolute path so that the
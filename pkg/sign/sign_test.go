@@ -0,0 +1,450 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package sign
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	cosigncrypto "github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/kms"
+	"github.com/sigstore/sigstore/pkg/signature/kms/fake"
+)
+
+// testKMSScheme is served by a fake KMS whose key is fixed for the life of
+// the test binary, so signatures made through it can be verified through it.
+const testKMSScheme = "testkms://"
+
+var testKMSKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+func init() {
+	kms.AddProvider(testKMSScheme, func(ctx context.Context, _ string, hf crypto.Hash, _ ...signature.RPCOption) (kms.SignerVerifier, error) {
+		return fake.LoadSignerVerifier(context.WithValue(ctx, fake.KmsCtxKey{}, crypto.PrivateKey(testKMSKey)), hf)
+	})
+}
+
+// ── key references ────────────────────────────────────────────────────────────
+
+func TestSignBytes_KMSKey(t *testing.T) {
+	data := []byte(`{"bomFormat":"CycloneDX"}`)
+	sigB64, pubPEM, err := SignBytes(data, &Options{Enabled: true, KeyRef: testKMSScheme + "bom-signing"})
+	if err != nil {
+		t.Fatalf("SignBytes: %v", err)
+	}
+	if !strings.Contains(string(pubPEM), "PUBLIC KEY") {
+		t.Errorf("expected the KMS public key to be returned, got %q", pubPEM)
+	}
+
+	dir := t.TempDir()
+	artifact := writeFile(t, dir, "bom.json", string(data))
+	sig := writeFile(t, dir, "bom.json.sig", sigB64)
+	pub := writeFile(t, dir, "kms.pub", string(pubPEM))
+
+	// Both the exported public key and the KMS reference verify.
+	if err := Verify(artifact, sig, pub); err != nil {
+		t.Errorf("Verify with exported public key: %v", err)
+	}
+	if err := Verify(artifact, sig, testKMSScheme+"bom-signing"); err != nil {
+		t.Errorf("Verify with KMS reference: %v", err)
+	}
+
+	writeFile(t, dir, "bom.json", `{"bomFormat":"tampered"}`)
+	if err := Verify(artifact, sig, testKMSScheme+"bom-signing"); err == nil {
+		t.Error("expected verification of a modified artifact to fail")
+	}
+}
+
+func TestArtifact_EnvKey(t *testing.T) {
+	kb, err := cosigncrypto.GenerateKeyPair(func(bool) ([]byte, error) { return []byte("s3cret"), nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_COSIGN_KEY", string(kb.PrivateBytes))
+	t.Setenv("TEST_COSIGN_PUB", string(kb.PublicBytes))
+	t.Setenv(PasswordEnv, "s3cret")
+
+	dir := t.TempDir()
+	artifact := writeFile(t, dir, "cbom.json", `{"bomFormat":"CycloneDX"}`)
+	opts := &Options{Enabled: true, KeyRef: "env://TEST_COSIGN_KEY", SigOut: filepath.Join(dir, "cbom.sig")}
+	if err := Artifact(artifact, opts); err != nil {
+		t.Fatalf("Artifact: %v", err)
+	}
+	if err := Verify(artifact, opts.SigOut, "env://TEST_COSIGN_PUB"); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cosign.pub")); err == nil {
+		t.Error("no key files should be written for an env:// key")
+	}
+
+	t.Setenv(PasswordEnv, "wrong")
+	if err := Artifact(artifact, opts); err == nil {
+		t.Error("expected the wrong passphrase to be rejected")
+	}
+}
+
+func TestLoadSigner_Errors(t *testing.T) {
+	cases := map[string]string{
+		"env://TEST_UNSET_KEY":          "not set",
+		"nosuchkms://key":               "unsupported key reference",
+		"pkcs11:token=t;object=o":       "PKCS#11",
+		filepath.Join(t.TempDir(), "x"): "reading key",
+	}
+	for ref, want := range cases {
+		_, err := loadSigner(context.Background(), ref, nil)
+		if err == nil {
+			t.Errorf("%s: expected error", ref)
+			continue
+		}
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error %q does not mention %q", ref, err, want)
+		}
+	}
+}
+
+func TestPubKeyPath(t *testing.T) {
+	cases := []struct {
+		opts Options
+		want string
+	}{
+		{Options{}, "cosign.pub"},
+		{Options{KeyRef: "release.key"}, "release.pub"},
+		{Options{GenerateKey: true, KeyOut: "ci"}, "ci.pub"},
+		{Options{KeyRef: "awskms:///alias/bom"}, "awskms:///alias/bom"},
+	}
+	for _, c := range cases {
+		if got := pubKeyPath(&c.opts); got != c.want {
+			t.Errorf("pubKeyPath(%+v) = %q, want %q", c.opts, got, c.want)
+		}
+	}
+}
+
+// ── trust policies ────────────────────────────────────────────────────────────
+
+func TestVerifyWithPolicy_KeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	keys := filepath.Join(dir, "trusted")
+	if err := os.Mkdir(keys, 0700); err != nil {
+		t.Fatal(err)
+	}
+	oldKey, oldPub := testKeyPair(t)
+	_, otherPub := testKeyPair(t)
+	writeFile(t, keys, "2024.pub", string(oldPub))
+	writeFile(t, keys, "README", "not a key")
+	// A bundle of two keys: the current team key and another producer's.
+	newKey, newPub := testKeyPair(t)
+	writeFile(t, keys, "2025.pem", string(newPub)+string(otherPub))
+
+	artifact := writeFile(t, dir, "sbom.json", `{"bomFormat":"CycloneDX"}`)
+	policy, err := LoadTrustPolicy(context.Background(), &TrustOptions{Keys: []string{keys}})
+	if err != nil {
+		t.Fatalf("LoadTrustPolicy: %v", err)
+	}
+	if len(policy.Keys) != 3 {
+		t.Fatalf("expected 3 trusted keys, got %d", len(policy.Keys))
+	}
+
+	for key, want := range map[*ecdsa.PrivateKey]string{
+		oldKey: filepath.Join(keys, "2024.pub"),
+		newKey: filepath.Join(keys, "2025.pem") + "#1",
+	} {
+		sig := writeFile(t, dir, "sbom.json.sig", signWith(t, key, artifact))
+		res, err := VerifyWithPolicy(artifact, sig, policy)
+		if err != nil {
+			t.Fatalf("VerifyWithPolicy: %v", err)
+		}
+		if !res.Verified || res.Key != want || !strings.HasPrefix(res.Fingerprint, "sha256:") {
+			t.Errorf("result = %+v, want key %s", res, want)
+		}
+	}
+
+	strangerKey, _ := testKeyPair(t)
+	sig := writeFile(t, dir, "sbom.json.sig", signWith(t, strangerKey, artifact))
+	res, err := VerifyWithPolicy(artifact, sig, policy)
+	if err == nil || res.Verified || res.KeysTried != 3 || res.Error == "" {
+		t.Errorf("expected an untrusted signature to fail, got %+v (%v)", res, err)
+	}
+}
+
+func TestVerifyWithPolicy_CertificateChain(t *testing.T) {
+	dir := t.TempDir()
+	root, rootKey := testCert(t, "Root CA", nil, nil, true)
+	inter, interKey := testCert(t, "Team CA", root, rootKey, true)
+	leaf, leafKey := testCert(t, "bom-signer", inter, interKey, false)
+
+	rootPath := writeFile(t, dir, "roots.pem", certPEM(root))
+	// The signing certificate file carries its intermediate.
+	certPath := writeFile(t, dir, "signer.pem", certPEM(leaf)+certPEM(inter))
+	artifact := writeFile(t, dir, "cbom.json", `{"bomFormat":"CycloneDX"}`)
+	sig := writeFile(t, dir, "cbom.json.sig", signWith(t, leafKey, artifact))
+
+	policy, err := LoadTrustPolicy(context.Background(), &TrustOptions{Cert: certPath, Roots: []string{rootPath}})
+	if err != nil {
+		t.Fatalf("LoadTrustPolicy: %v", err)
+	}
+	res, err := VerifyWithPolicy(artifact, sig, policy)
+	if err != nil {
+		t.Fatalf("VerifyWithPolicy: %v", err)
+	}
+	if res.Certificate == nil || !strings.Contains(res.Certificate.Subject, "bom-signer") || !strings.Contains(res.Certificate.Issuer, "Team CA") {
+		t.Errorf("unexpected certificate in result: %+v", res.Certificate)
+	}
+
+	// A chain to another root is rejected.
+	otherRoot, _ := testCert(t, "Other Root", nil, nil, true)
+	otherPath := writeFile(t, dir, "other.pem", certPEM(otherRoot))
+	policy, err = LoadTrustPolicy(context.Background(), &TrustOptions{Cert: certPath, Roots: []string{otherPath}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyWithPolicy(artifact, sig, policy); err == nil || !strings.Contains(err.Error(), "not trusted") {
+		t.Errorf("expected an untrusted chain to fail, got %v", err)
+	}
+
+	if _, err := LoadTrustPolicy(context.Background(), &TrustOptions{Cert: certPath}); err == nil {
+		t.Error("expected a certificate without roots to be rejected")
+	}
+}
+
+// ── attestations ──────────────────────────────────────────────────────────────
+
+func TestAttest_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.Mkdir(src, 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, src, "main.go", "package main\n")
+	subject, err := PathSubject(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bomPath := writeFile(t, dir, "cbom.json", "{\n  \"bomFormat\": \"CycloneDX\"\n}")
+	keyRef := testKMSScheme + "attest"
+	opts := &Options{Attest: true, KeyRef: keyRef}
+	if err := Attest(bomPath, []Subject{subject}, opts); err != nil {
+		t.Fatalf("Attest: %v", err)
+	}
+	envPath := bomPath + ".intoto.jsonl"
+
+	st, err := VerifyAttestation(envPath, keyRef, []Subject{subject})
+	if err != nil {
+		t.Fatalf("VerifyAttestation: %v", err)
+	}
+	if st.PredicateType != PredicateCycloneDX || string(st.Predicate) != `{"bomFormat":"CycloneDX"}` {
+		t.Errorf("unexpected statement: %+v", st)
+	}
+
+	// A changed source tree no longer matches the attested subject.
+	writeFile(t, src, "main.go", "package main // changed\n")
+	changed, err := PathSubject(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyAttestation(envPath, keyRef, []Subject{changed}); err == nil || !strings.Contains(err.Error(), "subject digest mismatch") {
+		t.Errorf("expected a subject mismatch, got %v", err)
+	}
+
+	// A different key does not verify the envelope.
+	kb, err := cosigncrypto.GenerateKeyPair(func(bool) ([]byte, error) { return nil, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub := writeFile(t, dir, "other.pub", string(kb.PublicBytes))
+	if _, err := VerifyAttestation(envPath, otherPub, nil); err == nil {
+		t.Error("expected verification with another key to fail")
+	}
+}
+
+func TestAttest_TamperedPayload(t *testing.T) {
+	dir := t.TempDir()
+	bomPath := writeFile(t, dir, "aibom.json", `{"bomFormat":"CycloneDX"}`)
+	keyRef := testKMSScheme + "attest"
+	opts := &Options{Attest: true, KeyRef: keyRef, Subject: "ghcr.io/acme/app@sha256:" + strings.Repeat("ab", 32)}
+	if err := Attest(bomPath, nil, opts); err != nil {
+		t.Fatalf("Attest: %v", err)
+	}
+	envPath := bomPath + ".intoto.jsonl"
+
+	raw, err := os.ReadFile(envPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env map[string]interface{}
+	if err := json.Unmarshal(raw, &env); err != nil {
+		t.Fatal(err)
+	}
+	payload, _ := base64.StdEncoding.DecodeString(env["payload"].(string))
+	env["payload"] = base64.StdEncoding.EncodeToString(bytes.Replace(payload, []byte("CycloneDX"), []byte("Tampered!"), 1))
+	tampered, _ := json.Marshal(env)
+	writeFile(t, dir, "aibom.json.intoto.jsonl", string(tampered))
+
+	if _, err := VerifyAttestation(envPath, keyRef, nil); err == nil || !strings.Contains(err.Error(), "invalid signature") {
+		t.Errorf("expected a signature failure, got %v", err)
+	}
+}
+
+func TestAttest_DefaultSubjectIsBOM(t *testing.T) {
+	dir := t.TempDir()
+	bomPath := writeFile(t, dir, "bom.json", `{"bomFormat":"CycloneDX"}`)
+	keyRef := testKMSScheme + "attest"
+	if err := Attest(bomPath, nil, &Options{Attest: true, KeyRef: keyRef, AttestOut: filepath.Join(dir, "bom.att")}); err != nil {
+		t.Fatalf("Attest: %v", err)
+	}
+	self, err := PathSubject(bomPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := VerifyAttestation(filepath.Join(dir, "bom.att"), keyRef, []Subject{self})
+	if err != nil {
+		t.Fatalf("VerifyAttestation: %v", err)
+	}
+	if st.Subject[0].Name != "bom.json" {
+		t.Errorf("subject name = %q, want bom.json", st.Subject[0].Name)
+	}
+}
+
+func TestTreeDigest(t *testing.T) {
+	a, b := t.TempDir(), t.TempDir()
+	for _, dir := range []string{a, b} {
+		if err := os.MkdirAll(filepath.Join(dir, "pkg", "x"), 0700); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, dir, "go.mod", "module x\n")
+		writeFile(t, filepath.Join(dir, "pkg", "x"), "x.go", "package x\n")
+	}
+	// VCS metadata does not contribute to the digest.
+	if err := os.Mkdir(filepath.Join(b, ".git"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(b, ".git"), "HEAD", "ref: refs/heads/main\n")
+
+	da, err := TreeDigest(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := TreeDigest(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if da != db {
+		t.Errorf("identical trees hash differently: %s != %s", da, db)
+	}
+
+	writeFile(t, b, "extra.txt", "")
+	if dc, _ := TreeDigest(b); dc == da {
+		t.Error("adding a file did not change the digest")
+	}
+}
+
+func TestParseSubject(t *testing.T) {
+	s, err := ParseSubject("ghcr.io/acme/app@SHA256:ABCD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "ghcr.io/acme/app" || s.Digest["sha256"] != "abcd" {
+		t.Errorf("unexpected subject %+v", s)
+	}
+	for _, bad := range []string{"app", "app@sha256", "app@sha256:xyz", "@sha256:ab"} {
+		if _, err := ParseSubject(bad); err == nil {
+			t.Errorf("ParseSubject(%q): expected error", bad)
+		}
+	}
+}
+
+func testKeyPair(t *testing.T) (*ecdsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := cryptoutils.MarshalPublicKeyToPEM(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, pub
+}
+
+func signWith(t *testing.T, key *ecdsa.PrivateKey, path string) string {
+	t.Helper()
+	sv, err := signature.LoadECDSASignerVerifier(key, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := sv.SignMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+// testCert issues a certificate for cn signed by parent (self-signed when
+// parent is nil). Leaf certificates are issued for code signing.
+func testCert(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, ca bool) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  ca,
+	}
+	if ca {
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func certPEM(c *x509.Certificate) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw}))
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package sign
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// Subject is an in-toto resource descriptor: the artifact an attestation is
+// about, identified by name and one or more digests keyed by algorithm.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// String renders the subject as name@alg:hex, using sha256 when present.
+func (s Subject) String() string {
+	alg := "sha256"
+	if _, ok := s.Digest[alg]; !ok {
+		for a := range s.Digest {
+			alg = a
+			break
+		}
+	}
+	return fmt.Sprintf("%s@%s:%s", s.Name, alg, s.Digest[alg])
+}
+
+// ParseSubject parses a subject given as NAME@ALG:HEX, e.g.
+// "registry.example.com/app@sha256:4f3c…".
+func ParseSubject(spec string) (Subject, error) {
+	i := strings.LastIndex(spec, "@")
+	if i <= 0 {
+		return Subject{}, fmt.Errorf("subject %q is not of the form NAME@ALG:HEX", spec)
+	}
+	alg, digest, err := ParseDigest(spec[i+1:])
+	if err != nil {
+		return Subject{}, fmt.Errorf("subject %q: %w", spec, err)
+	}
+	return Subject{Name: spec[:i], Digest: map[string]string{alg: digest}}, nil
+}
+
+// ParseDigest splits an ALG:HEX digest such as "sha256:4f3c…" and checks
+// that the hex part is well formed.
+func ParseDigest(d string) (alg, digest string, err error) {
+	alg, digest, ok := strings.Cut(d, ":")
+	if !ok || alg == "" || digest == "" {
+		return "", "", fmt.Errorf("digest %q is not of the form ALG:HEX", d)
+	}
+	if _, err := hex.DecodeString(digest); err != nil {
+		return "", "", fmt.Errorf("digest %q is not hex encoded", d)
+	}
+	return strings.ToLower(alg), strings.ToLower(digest), nil
+}
+
+// PathSubject returns the subject for a local file or source tree: the
+// SHA-256 of a file, or the tree digest of a directory (see TreeDigest).
+// Files in exclude are left out of a tree digest.
+func PathSubject(path string, exclude ...string) (Subject, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Subject{}, fmt.Errorf("reading subject %s: %w", path, err)
+	}
+	var digest string
+	if info.IsDir() {
+		digest, err = TreeDigest(path, exclude...)
+	} else {
+		digest, err = fileDigest(path)
+	}
+	if err != nil {
+		return Subject{}, err
+	}
+	return Subject{
+		Name:   filepath.ToSlash(filepath.Clean(path)),
+		Digest: map[string]string{"sha256": digest},
+	}, nil
+}
+
+// TreeDigest returns a reproducible SHA-256 digest of the regular files
+// under dir. It hashes a manifest of "<sha256>  <path>\n" lines, one per
+// file, sorted by slash-separated path relative to dir — the output of
+// `sha256sum` over the sorted file list. .git directories and symbolic
+// links are skipped so the digest depends only on checked-out content.
+//
+// Files in exclude — typically the BOM, signature and attestation written
+// into the tree being described — are skipped too, so that regenerating
+// them does not change the digest.
+func TreeDigest(dir string, exclude ...string) (string, error) {
+	skip := make(map[string]bool, len(exclude))
+	for _, e := range exclude {
+		if abs, err := filepath.Abs(e); err == nil {
+			skip[abs] = true
+		}
+	}
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() {
+			if abs, err := filepath.Abs(p); err == nil && skip[abs] {
+				return nil
+			}
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("walking %s: %w", dir, err)
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	for _, f := range files {
+		sum, err := fileDigest(filepath.Join(dir, filepath.FromSlash(f)))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s  %s\n", sum, f)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ImageSubject returns the subject for a container image: its repository
+// and manifest digest. A reference already pinned by digest is used as is;
+// otherwise the tag is resolved against the registry with the default
+// keychain (docker config, credential helpers).
+func ImageSubject(ctx context.Context, image string) (Subject, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return Subject{}, fmt.Errorf("parsing image reference %s: %w", image, err)
+	}
+	repo := ref.Context().Name()
+	if d, ok := ref.(name.Digest); ok {
+		alg, digest, err := ParseDigest(d.DigestStr())
+		if err != nil {
+			return Subject{}, err
+		}
+		return Subject{Name: repo, Digest: map[string]string{alg: digest}}, nil
+	}
+
+	desc, err := remote.Head(ref, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return Subject{}, fmt.Errorf("resolving digest of %s (pin the image by digest or set the subject explicitly): %w", image, err)
+	}
+	return Subject{Name: repo, Digest: map[string]string{desc.Digest.Algorithm: desc.Digest.Hex}}, nil
+}
+
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", path, err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hashing %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
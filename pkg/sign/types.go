@@ -4,16 +4,36 @@
 // Package sign provides artifact signing and verification using the
 // sigstore/cosign library.  Keys are generated as ECDSA P-256 PEM files that
 // are fully compatible with the cosign CLI (cosign sign-blob / cosign
-// verify-blob).
+// verify-blob).  Signing keys may also live in a KMS, HashiCorp Vault, a
+// PKCS#11 token or an environment variable.  BOMs can also be wrapped in
+// in-toto attestations signed as DSSE envelopes (see Attest).
 package sign
 
 // Options controls whether and how an artifact is signed after it is written.
 type Options struct {
-	// Enabled activates signing.  When false all other fields are ignored.
+	// Enabled activates detached signing.  When neither Enabled nor Attest is
+	// set all other fields are ignored.
 	Enabled bool
 
+	// Attest wraps the artifact (a CycloneDX BOM) in a signed in-toto
+	// attestation, written as a DSSE envelope.  It uses the same key fields
+	// as Enabled and may be combined with it.
+	Attest bool
+
+	// AttestOut is the path where the DSSE envelope is written.
+	// Defaults to "<artifact-path>.intoto.jsonl".
+	AttestOut string
+
+	// Subject overrides the attestation subject, given as NAME@ALG:HEX
+	// (e.g. "registry.example.com/app@sha256:…").  By default the subject is
+	// derived from what the BOM describes.
+	Subject string
+
 	// KeyRef is the path to an existing cosign-format PEM private key file
-	// (e.g. "cosign.key").  Mutually exclusive with GenerateKey.
+	// (e.g. "cosign.key"), an env://VAR reference to a PEM key held in an
+	// environment variable, or a KMS (awskms://, gcpkms://, azurekms://,
+	// hashivault://) or PKCS#11 (pkcs11:) key reference.  Mutually exclusive
+	// with GenerateKey.
 	KeyRef string
 
 	// GenerateKey generates a fresh ECDSA P-256 key pair before signing.
@@ -24,8 +44,8 @@ type Options struct {
 	// Defaults to "cosign" → produces "cosign.key" and "cosign.pub".
 	KeyOut string
 
-	// Password is the passphrase used to protect (or unlock) the private key.
-	// An empty string means no passphrase.
+	// Password is the passphrase used to protect (or unlock) a PEM private
+	// key.  When empty, $COSIGN_PASSWORD is used if set.
 	Password string
 
 	// SigOut is the path where the base-64 encoded signature is written.
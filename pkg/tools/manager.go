@@ -21,9 +21,17 @@ import (
 //go:embed tools.yaml
 var embeddedConfig []byte
 
-// Load parses the embedded tools.yaml.
+// Load parses the embedded tools.yaml and applies the user's overlay (see
+// UserConfigPath) on top of it.
 func Load() (*Config, error) {
-	return parse(embeddedConfig)
+	cfg, err := parse(embeddedConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.applyOverlay(UserConfigPath()); err != nil {
+		return nil, err
+	}
+	return cfg, nil
 }
 
 // LoadFromFile parses tools.yaml from the given path (used by build scripts).
@@ -71,51 +79,55 @@ func (t *Tool) ResolveForPlatform(goos, goarch string) (*PlatformConfig, string,
 // For downloaded tools, the lookup order is:
 //  1. Embedded in the knoxctl binary (via //go:embed bins) → extract to versioned cache
 //  2. Next to the knoxctl executable (manual side-by-side deployment)
-//  3. ~/.accuknox-config/tools/ (previously downloaded at runtime)
-//  4. Download from source URL to ~/.accuknox-config/tools/
+//  3. ~/.accuknox-config/tools/<name>/<version>/ (previously downloaded at runtime)
+//  4. Download from source URL to ~/.accuknox-config/tools/<name>/<version>/
 func (t *Tool) EnsureInstalled() (string, error) {
-	installAs := t.installAsForPlatform()
-
-	// 1. Extract from the binary embedded at build time.
-	if path, err := t.extractEmbedded(installAs); err == nil {
-		return path, nil
-	}
-
-	// 2. Check next to the running binary.
-	if execPath, err := os.Executable(); err == nil {
-		candidate := filepath.Join(filepath.Dir(execPath), installAs)
-		if _, err := os.Stat(candidate); err == nil {
-			return candidate, nil
+	st := t.Locate()
+	switch {
+	case st.Err != nil:
+		return "", st.Err
+	case st.Source == SourceEmbedded:
+		return t.extractEmbedded(t.installAsForPlatform())
+	case st.Source == SourceCache:
+		if err := t.checkInstalled(); err != nil {
+			return "", err
 		}
+		return st.Path, nil
+	case st.Installed:
+		return st.Path, nil
 	}
-
-	if t.Builtin {
-		return "", fmt.Errorf("%s binary is not embedded — run 'make prebuild' to build it from the submodule", t.Name)
-	}
-
-	// Resolve platform-specific download config for steps 3 and 4.
-	cfg, _, err := t.ResolveForPlatform(runtime.GOOS, runtime.GOARCH)
-	if err != nil {
+	if err := t.download(st.Path); err != nil {
 		return "", err
 	}
+	return st.Path, nil
+}
 
-	// 3. Check the user's runtime download cache.
-	cacheDir := installDir()
-	cached := filepath.Join(cacheDir, installAs)
-	if _, err := os.Stat(cached); err == nil {
-		return cached, nil
+// download fetches the tool for the current platform into dest, replacing
+// what is there only once the download has been verified and extracted, and
+// records an install receipt next to it.
+func (t *Tool) download(dest string) error {
+	cfg, installAs, err := t.ResolveForPlatform(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return err
 	}
-
-	// 4. Download.
 	fmt.Printf("Downloading %s %s...\n", t.Name, t.Version)
-	if err := os.MkdirAll(cacheDir, 0o750); err != nil { // #nosec G301
-		return "", fmt.Errorf("failed to create install dir: %w", err)
+	if err := os.MkdirAll(filepath.Dir(dest), 0o750); err != nil { // #nosec G301
+		return fmt.Errorf("failed to create install dir: %w", err)
+	}
+	tmp := dest + ".partial"
+	defer os.Remove(tmp)
+	sum, err := t.downloadAndInstall(cfg, installAs, tmp)
+	if err != nil {
+		return fmt.Errorf("failed to install %s: %w", t.Name, err)
 	}
-	if err := downloadAndInstall(cfg, installAs, cached); err != nil {
-		return "", fmt.Errorf("failed to install %s: %w", t.Name, err)
+	if err := os.Rename(tmp, dest); err != nil {
+		return fmt.Errorf("failed to install %s: %w", t.Name, err)
 	}
-	fmt.Printf("Installed %s to %s\n", t.Name, cached)
-	return cached, nil
+	if err := t.writeReceipt(dest, cfg.Source, sum); err != nil {
+		return err
+	}
+	fmt.Printf("Installed %s to %s\n", t.Name, dest)
+	return nil
 }
 
 // installAsForPlatform returns the install filename for the current platform,
@@ -135,7 +147,8 @@ func (t *Tool) installAsForPlatform() string {
 // extractEmbedded reads the tool binary from binsFS (embedded at build time),
 // writes it to a versioned cache directory, and returns its path.
 // The cache is keyed by tool version so a new knoxctl release always extracts
-// a fresh copy instead of reusing a stale one.
+// a fresh copy instead of reusing a stale one, and a copy extracted earlier
+// that no longer matches the embedded binary is replaced.
 // Returns an error if the binary is not embedded (dev builds, unavailable platform).
 func (t *Tool) extractEmbedded(installAs string) (string, error) {
 	data, err := binsFS.ReadFile("bins/" + installAs)
@@ -149,13 +162,20 @@ func (t *Tool) extractEmbedded(installAs string) (string, error) {
 	}
 
 	dest := filepath.Join(extractDir, installAs)
-	if _, err := os.Stat(dest); err == nil {
-		return dest, nil // already extracted from this version
+	if sum, err := fileSHA256(dest); err == nil {
+		want := sha256.Sum256(data)
+		if sum == hex.EncodeToString(want[:]) {
+			return dest, nil // already extracted from this version
+		}
+		fmt.Fprintf(os.Stderr, "warning: %s was modified; extracting it again\n", dest)
 	}
 
 	if err := os.WriteFile(dest, data, 0o755); err != nil { // #nosec G306
 		return "", fmt.Errorf("failed to extract embedded %s: %w", installAs, err)
 	}
+	if err := t.writeReceipt(dest, SourceEmbedded, ""); err != nil {
+		return "", err
+	}
 	return dest, nil
 }
 
@@ -197,7 +217,7 @@ func (t *Tool) DownloadTo(goos, goarch, outputDir string) error {
 		if err := os.MkdirAll(cacheDir, 0o750); err != nil { // #nosec G301
 			return fmt.Errorf("failed to create build cache dir: %w", err)
 		}
-		if err := downloadAndInstall(cfg, installAs, cached); err != nil {
+		if _, err := t.downloadAndInstall(cfg, installAs, cached); err != nil {
 			return fmt.Errorf("failed to download %s for %s/%s: %w", t.Name, goos, goarch, err)
 		}
 		fmt.Printf("  Cached at %s\n", cached)
@@ -237,6 +257,12 @@ func copyFile(src, dst string) error {
 	return err
 }
 
+// cachePath returns where a downloaded tool binary is kept: per tool and
+// version, so pinning another version never reuses a stale binary.
+func (t *Tool) cachePath(installAs string) string {
+	return filepath.Join(installDir(), t.Name, t.Version, installAs)
+}
+
 // installDir returns the user-level tool cache directory.
 func installDir() string {
 	home, err := os.UserHomeDir()
@@ -255,22 +281,23 @@ func (cfg *PlatformConfig) archiveBinary(installAs string) string {
 	return installAs
 }
 
-// downloadAndInstall fetches the source URL, verifies the optional SHA256, then
-// extracts the binary from .tar.gz / .zip archives or saves it directly.
-func downloadAndInstall(cfg *PlatformConfig, installAs, destPath string) error {
-	resp, err := http.Get(cfg.Source) // #nosec G107
+// downloadAndInstall fetches the source URL, verifies it (see verifyDownload),
+// then extracts the binary from .tar.gz / .zip archives or saves it directly.
+// It returns the SHA256 of the download.
+func (t *Tool) downloadAndInstall(cfg *PlatformConfig, installAs, destPath string) (string, error) {
+	resp, err := httpGet(cfg.Source)
 	if err != nil {
-		return fmt.Errorf("download failed: %w", err)
+		return "", fmt.Errorf("download failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download returned HTTP %d for %s", resp.StatusCode, cfg.Source)
+		return "", fmt.Errorf("download returned HTTP %d for %s", resp.StatusCode, cfg.Source)
 	}
 
 	tmp, err := os.CreateTemp("", "knoxctl-tool-*")
 	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
+		return "", fmt.Errorf("failed to create temp file: %w", err)
 	}
 	tmpPath := tmp.Name()
 	defer os.Remove(tmpPath)
@@ -278,25 +305,24 @@ func downloadAndInstall(cfg *PlatformConfig, installAs, destPath string) error {
 	hasher := sha256.New()
 	if _, err := io.Copy(io.MultiWriter(tmp, hasher), resp.Body); err != nil {
 		_ = tmp.Close() // #nosec G104
-		return fmt.Errorf("failed to write download: %w", err)
+		return "", fmt.Errorf("failed to write download: %w", err)
 	}
 	_ = tmp.Close() // #nosec G104
 
-	if cfg.SHA256 != "" {
-		got := hex.EncodeToString(hasher.Sum(nil))
-		if !strings.EqualFold(got, cfg.SHA256) {
-			return fmt.Errorf("SHA256 mismatch: got %s, want %s", got, cfg.SHA256)
-		}
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	if err := t.verifyDownload(cfg, tmpPath, sum); err != nil {
+		return "", err
 	}
 
 	switch {
 	case strings.HasSuffix(cfg.Source, ".tar.gz") || strings.HasSuffix(cfg.Source, ".tgz"):
-		return extractFromTarGz(tmpPath, cfg.archiveBinary(installAs), destPath)
+		err = extractFromTarGz(tmpPath, cfg.archiveBinary(installAs), destPath)
 	case strings.HasSuffix(cfg.Source, ".zip"):
-		return extractFromZip(tmpPath, cfg.archiveBinary(installAs), destPath)
+		err = extractFromZip(tmpPath, cfg.archiveBinary(installAs), destPath)
 	default:
-		return installBinary(tmpPath, destPath)
+		err = installBinary(tmpPath, destPath)
 	}
+	return sum, err
 }
 
 // extractFromTarGz finds the entry whose base name matches binaryName and
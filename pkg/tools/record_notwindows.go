@@ -0,0 +1,15 @@
+//go:build !windows
+
+package tools
+
+import (
+	"os"
+	"syscall"
+)
+
+// ownerOnlyWritable reports whether fi belongs to the current user and
+// cannot be written by anyone else.
+func ownerOnlyWritable(fi os.FileInfo) bool {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	return ok && int(st.Uid) == os.Getuid() && fi.Mode().Perm()&0o022 == 0
+}
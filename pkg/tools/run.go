@@ -0,0 +1,16 @@
+package tools
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Run executes the tool binary as a child process attached to the current
+// stdio and waits for it, for callers that act on the tool's output.
+func Run(binPath string, args []string) error {
+	cmd := exec.Command(binPath, args...) // #nosec G204
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,421 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Where EnsureInstalled finds a tool, in lookup order.
+const (
+	SourceEmbedded   = "embedded"     // embedded in the knoxctl binary
+	SourceSideBySide = "side-by-side" // next to the knoxctl executable
+	SourceCache      = "cache"        // downloaded earlier
+	SourceDownload   = "download"     // downloaded on first use
+)
+
+// Status describes where a tool is resolved from on this platform.
+type Status struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	Source     string `json:"source,omitempty"`
+	Path       string `json:"path,omitempty"`
+	Installed  bool   `json:"installed"`            // Path exists
+	URL        string `json:"url,omitempty"`        // download URL for this platform
+	Overridden bool   `json:"overridden,omitempty"` // changed by the user's tools.yaml
+	Err        error  `json:"-"`                    // why the tool is unavailable
+}
+
+// Locate reports where EnsureInstalled would find the tool, without
+// extracting or downloading anything.
+func (t *Tool) Locate() *Status {
+	installAs := t.installAsForPlatform()
+	st := &Status{Name: t.Name, Version: t.Version, Overridden: t.Overridden}
+
+	if fi, err := fs.Stat(binsFS, "bins/"+installAs); err == nil && fi.Size() > 0 {
+		st.Source = SourceEmbedded
+		st.Path = filepath.Join(embeddedExtractDir(), t.Version, installAs)
+		st.Installed = exists(st.Path)
+		return st
+	}
+	if execPath, err := os.Executable(); err == nil {
+		if candidate := filepath.Join(filepath.Dir(execPath), installAs); exists(candidate) {
+			st.Source, st.Path, st.Installed = SourceSideBySide, candidate, true
+			return st
+		}
+	}
+	if t.Builtin {
+		st.Err = fmt.Errorf("%s binary is not embedded — run 'make prebuild' to build it from the submodule", t.Name)
+		return st
+	}
+	cfg, _, err := t.ResolveForPlatform(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		st.Err = err
+		return st
+	}
+	st.URL = cfg.Source
+	st.Path = t.cachePath(installAs)
+	st.Installed = exists(st.Path)
+	st.Source = SourceDownload
+	if st.Installed {
+		st.Source = SourceCache
+	}
+	return st
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// receipt records what was installed at a path, so the binary can be
+// verified later.
+type receipt struct {
+	Tool           string    `json:"tool"`
+	Version        string    `json:"version"`
+	Source         string    `json:"source"`                   // download URL, or "embedded"
+	DownloadSHA256 string    `json:"downloadSha256,omitempty"` // of the verified download
+	SHA256         string    `json:"sha256"`                   // of the installed binary
+	Installed      time.Time `json:"installed"`
+}
+
+func receiptPath(binPath string) string {
+	return binPath + ".receipt.json"
+}
+
+// writeReceipt records the tool installed at binPath from source, whose
+// download had the SHA256 downloadSum: in a receipt next to the binary, for
+// the user, and in the install record that Verify trusts.
+func (t *Tool) writeReceipt(binPath, source, downloadSum string) error {
+	sum, err := fileSHA256(binPath)
+	if err != nil {
+		return err
+	}
+	r := receipt{
+		Tool:           t.Name,
+		Version:        t.Version,
+		Source:         source,
+		DownloadSHA256: downloadSum,
+		SHA256:         sum,
+		Installed:      time.Now().UTC(),
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(receiptPath(binPath), data, 0o600); err != nil {
+		return fmt.Errorf("failed to write install receipt: %w", err)
+	}
+	return recordInstall(binPath, r)
+}
+
+// installRecordPath returns the file recording the checksum of every binary
+// knoxctl installed. It is kept outside the tool cache, so that whoever can
+// replace a cached binary (a shared or restored cache, say) cannot vouch for
+// it by rewriting the receipt next to it.
+func installRecordPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), ".accuknox-config", "tool-sums.json")
+	}
+	return filepath.Join(home, ".accuknox-config", "tool-sums.json")
+}
+
+// readInstallRecord returns the install record by binary path. It refuses a
+// record that is not a regular file only its owner, the current user, can
+// write.
+func readInstallRecord() (map[string]receipt, error) {
+	path := installRecordPath()
+	fi, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]receipt{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() || !ownerOnlyWritable(fi) {
+		return nil, fmt.Errorf("refusing install record %s: it must be a regular file that only you can write", path)
+	}
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+	record := map[string]receipt{}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to parse install record %s: %w", path, err)
+	}
+	return record, nil
+}
+
+// recordInstall adds r, for the binary at binPath, to the install record.
+func recordInstall(binPath string, r receipt) error {
+	record, err := readInstallRecord()
+	if err != nil {
+		return err
+	}
+	record[binPath] = r
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := installRecordPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil { // #nosec G301
+		return fmt.Errorf("failed to write install record: %w", err)
+	}
+	tmp := path + ".partial"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write install record: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write install record: %w", err)
+	}
+	return nil
+}
+
+// installedAs returns the install record of this version of the tool at
+// binPath, or nil when there is none.
+func (t *Tool) installedAs(binPath string) (*receipt, error) {
+	record, err := readInstallRecord()
+	if err != nil {
+		return nil, err
+	}
+	r, ok := record[binPath]
+	if !ok || r.Tool != t.Name || r.Version != t.Version {
+		return nil, nil
+	}
+	return &r, nil
+}
+
+// fileSHA256 returns the hex SHA256 of a file.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Verification outcomes.
+const (
+	VerifyOK           = "ok"
+	VerifyMismatch     = "mismatch"
+	VerifyUnverified   = "unverified"    // no reference checksum is known
+	VerifyNotInstalled = "not installed" // nothing on disk to verify
+)
+
+// Verification is the outcome of checking an installed tool binary.
+type Verification struct {
+	*Status
+	Result   string `json:"result"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Against  string `json:"against,omitempty"` // what Expected comes from
+}
+
+// Verify checks the SHA256 of the installed tool binary against, in order
+// of preference, the binary embedded in knoxctl, the sha256 in tools.yaml
+// (for sources that are the binary itself rather than an archive), or, for
+// a downloaded binary, the install record written when it was installed
+// (see installRecordPath). The download SHA256 in that record is re-checked
+// against the sha256 in tools.yaml when there is one. A binary next to the
+// knoxctl executable that tools.yaml does not pin is unverified.
+func (t *Tool) Verify() (*Verification, error) {
+	st := t.Locate()
+	v := &Verification{Status: st}
+	if st.Err != nil {
+		return nil, st.Err
+	}
+	if !st.Installed {
+		v.Result = VerifyNotInstalled
+		return v, nil
+	}
+
+	cfg, _, cfgErr := t.ResolveForPlatform(runtime.GOOS, runtime.GOARCH)
+	switch {
+	case st.Source == SourceEmbedded:
+		data, err := binsFS.ReadFile("bins/" + t.installAsForPlatform())
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		v.Expected, v.Against = hex.EncodeToString(sum[:]), "embedded binary"
+	case cfgErr == nil && cfg.SHA256 != "" && !isArchive(cfg.Source):
+		v.Expected, v.Against = strings.ToLower(cfg.SHA256), "tools.yaml"
+	case st.Source == SourceCache:
+		r, err := t.installedAs(st.Path)
+		if err != nil {
+			return nil, err
+		}
+		if r == nil {
+			break
+		}
+		if cfgErr == nil && cfg.SHA256 != "" && !strings.EqualFold(r.DownloadSHA256, cfg.SHA256) {
+			// Installed from another download than tools.yaml names.
+			v.Expected, v.Actual, v.Against = strings.ToLower(cfg.SHA256), r.DownloadSHA256, "tools.yaml"
+			v.Result = VerifyMismatch
+			return v, nil
+		}
+		v.Expected, v.Against = r.SHA256, "install record"
+	}
+
+	actual, err := fileSHA256(st.Path)
+	if err != nil {
+		return nil, err
+	}
+	v.Actual = actual
+	switch {
+	case v.Expected == "":
+		v.Result = VerifyUnverified
+	case v.Expected == actual:
+		v.Result = VerifyOK
+	default:
+		v.Result = VerifyMismatch
+	}
+	return v, nil
+}
+
+// isArchive reports whether a download URL names an archive that the binary
+// is extracted from.
+func isArchive(source string) bool {
+	return strings.HasSuffix(source, ".tar.gz") || strings.HasSuffix(source, ".tgz") || strings.HasSuffix(source, ".zip")
+}
+
+// Install makes the tool available without network access: an embedded
+// binary is extracted and a downloaded one fetched into the cache. With
+// force a cached download is fetched again.
+func (t *Tool) Install(force bool) (*Status, error) {
+	st := t.Locate()
+	if force && st.Source == SourceCache {
+		if err := t.download(st.Path); err != nil {
+			return nil, err
+		}
+		return t.Locate(), nil
+	}
+	if _, err := t.EnsureInstalled(); err != nil {
+		return nil, err
+	}
+	return t.Locate(), nil
+}
+
+// CachedVersions returns the versions of the tool in the download cache.
+func (t *Tool) CachedVersions() []string {
+	entries, err := os.ReadDir(filepath.Join(installDir(), t.Name))
+	if err != nil {
+		return nil
+	}
+	var versions []string
+	for _, e := range entries {
+		if e.IsDir() {
+			versions = append(versions, e.Name())
+		}
+	}
+	return versions
+}
+
+// Upgrade installs the configured version of the tool and removes the other
+// versions from the download cache. It returns the versions removed.
+func (t *Tool) Upgrade() ([]string, error) {
+	if _, err := t.Install(false); err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, v := range t.CachedVersions() {
+		if v == t.Version {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(installDir(), t.Name, v)); err != nil {
+			return removed, fmt.Errorf("failed to remove %s %s: %w", t.Name, v, err)
+		}
+		removed = append(removed, v)
+	}
+	return removed, nil
+}
+
+// StaleCaches returns the cached tool binaries and directories that no tool
+// of cfg uses: other versions in the download and embedded-extraction
+// caches, and downloads from before the cache was versioned. The build
+// cache used by release builds is left alone.
+func (cfg *Config) StaleCaches() ([]string, error) {
+	var stale []string
+	base := installDir()
+	entries, err := os.ReadDir(base)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	installNames := map[string]bool{}
+	for i := range cfg.Tools {
+		name := cfg.Tools[i].installAsForPlatform()
+		installNames[name], installNames[receiptPath(name)] = true, true
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			if installNames[e.Name()] {
+				stale = append(stale, filepath.Join(base, e.Name())) // unversioned download
+			}
+			continue
+		}
+		if t := cfg.Find(e.Name()); t != nil {
+			for _, v := range t.CachedVersions() {
+				if v != t.Version {
+					stale = append(stale, filepath.Join(base, t.Name, v))
+				}
+			}
+		}
+	}
+
+	embedded := embeddedExtractDir()
+	versions, _ := os.ReadDir(embedded)
+	for _, v := range versions {
+		if !v.IsDir() {
+			continue
+		}
+		var keep []string
+		for i := range cfg.Tools {
+			if cfg.Tools[i].Version == v.Name() {
+				name := cfg.Tools[i].installAsForPlatform()
+				keep = append(keep, name, receiptPath(name))
+			}
+		}
+		dir := filepath.Join(embedded, v.Name())
+		if len(keep) == 0 {
+			stale = append(stale, dir)
+			continue
+		}
+		files, _ := os.ReadDir(dir)
+		for _, f := range files {
+			if !slices.Contains(keep, f.Name()) {
+				stale = append(stale, filepath.Join(dir, f.Name()))
+			}
+		}
+	}
+	return stale, nil
+}
+
+// RemoveCaches deletes the given cache paths.
+func RemoveCaches(paths []string) error {
+	for _, p := range paths {
+		if err := os.RemoveAll(p); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", p, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,392 @@
+package tools
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/accuknox/accuknox-cli-v2/pkg/sign"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/sigstore-go/pkg/testing/ca"
+)
+
+// newToolServer serves body as /faketool, a checksum file listing it as
+// /checksums.txt, and the base-64 signature of the checksum file made with
+// the key in $KNOXCTL_TEST_KEY, when set, as /checksums.txt.sig.
+func newToolServer(t *testing.T, body []byte) *httptest.Server {
+	t.Helper()
+	sum := sha256.Sum256(body)
+	checksums := []byte(hex.EncodeToString(sum[:]) + "  faketool\n" + strings.Repeat("0", 64) + "  other\n")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/faketool":
+			_, _ = w.Write(body)
+		case "/checksums.txt":
+			_, _ = w.Write(checksums)
+		case "/checksums.txt.sig":
+			sig, _, err := sign.SignBytes(checksums, &sign.Options{Enabled: true, KeyRef: "env://KNOXCTL_TEST_KEY"})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			_, _ = w.Write([]byte(sig))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestApplyOverlay(t *testing.T) {
+	cfg, err := parse(embeddedConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlay := filepath.Join(t.TempDir(), "tools.yaml")
+	if err := os.WriteFile(overlay, []byte(`
+tools:
+  - name: pkgscan
+    version: "1.50.0"
+    platforms:
+      linux:
+        arm64:
+          source: https://mirror.example.com/syft-arm64.tar.gz
+          binary: syft
+          sha256: "def"
+  - name: sbomgen
+    platforms:
+      linux:
+        amd64:
+          source: https://mirror.example.com/sbomgen
+          sha256: "abc"
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.applyOverlay(overlay); err != nil {
+		t.Fatalf("applyOverlay: %v", err)
+	}
+
+	pkgscan := cfg.Find("pkgscan")
+	want := "https://github.com/anchore/syft/releases/download/v1.50.0/syft_1.50.0_linux_amd64.tar.gz"
+	if got := pkgscan.Platforms["linux"]["amd64"]; !pkgscan.Overridden || pkgscan.Version != "1.50.0" || got.Source != want || got.Binary != "syft" {
+		t.Errorf("pkgscan = %+v, linux/amd64 = %+v", pkgscan, got)
+	}
+	if got := pkgscan.Platforms["darwin"]["arm64"]; !strings.Contains(got.Source, "v1.50.0/syft_1.50.0_darwin_arm64") || got.SHA256 != "" {
+		t.Errorf("pkgscan darwin/arm64 not rewritten: %+v", got)
+	}
+	if got := pkgscan.Platforms["linux"]["arm64"]; got.Source != "https://mirror.example.com/syft-arm64.tar.gz" || got.SHA256 != "def" {
+		t.Errorf("pkgscan linux/arm64 = %+v", got)
+	}
+	if want := "https://github.com/anchore/syft/releases/download/v1.50.0/syft_1.50.0_checksums.txt"; pkgscan.Integrity.Checksums != want ||
+		pkgscan.Integrity.Signature != want+".sig" || pkgscan.Integrity.Certificate != want+".pem" {
+		t.Errorf("pkgscan integrity = %+v", pkgscan.Integrity)
+	}
+	sbomgen := cfg.Find("sbomgen")
+	if got := sbomgen.Platforms["linux"]["amd64"]; got.Source != "https://mirror.example.com/sbomgen" || got.SHA256 != "abc" {
+		t.Errorf("sbomgen linux/amd64 = %+v", got)
+	}
+	if got := sbomgen.Platforms["darwin"]["arm64"]; got.SHA256 == "" || sbomgen.Version != "12.1.3" {
+		t.Errorf("untouched sbomgen platform changed: %+v", got)
+	}
+
+	if err := os.WriteFile(overlay, []byte("tools:\n  - name: nope\n    version: \"1\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.applyOverlay(overlay); err == nil {
+		t.Error("an overlay naming an unknown tool was accepted")
+	}
+	if err := cfg.applyOverlay(filepath.Join(t.TempDir(), "missing.yaml")); err != nil {
+		t.Errorf("missing overlay: %v", err)
+	}
+}
+
+func TestMerge_VersionWithSomePlatforms(t *testing.T) {
+	tool := &Tool{
+		Name:    "tool",
+		Version: "1.0.0",
+		Platforms: map[string]Platform{
+			"linux":  {"amd64": {Source: "https://example.com/v1.0.0/tool-linux", SHA256: "aaa"}},
+			"darwin": {"arm64": {Source: "https://example.com/v1.0.0/tool-darwin", SHA256: "bbb"}},
+		},
+	}
+	tool.merge(&Tool{
+		Version:   "1.1.0",
+		Platforms: map[string]Platform{"linux": {"amd64": {Source: "https://mirror.example.com/tool", SHA256: "ccc"}}},
+	})
+	if got := tool.Platforms["linux"]["amd64"]; got.Source != "https://mirror.example.com/tool" || got.SHA256 != "ccc" {
+		t.Errorf("given platform = %+v", got)
+	}
+	if got := tool.Platforms["darwin"]["arm64"]; got.Source != "https://example.com/v1.1.0/tool-darwin" || got.SHA256 != "" {
+		t.Errorf("other platform = %+v, want the 1.1.0 URL without the 1.0.0 sha256", got)
+	}
+}
+
+func TestInstallVerifyAndClean(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	srv := newToolServer(t, []byte("#!/bin/sh\necho tool\n"))
+
+	tool := &Tool{
+		Name:      "faketool",
+		InstallAs: "faketool",
+		Version:   "2.0.0",
+		Platforms: map[string]Platform{runtime.GOOS: {runtime.GOARCH: {Source: srv.URL + "/faketool"}}},
+		Integrity: &Integrity{Checksums: srv.URL + "/checksums.txt"},
+	}
+	if installAs := tool.installAsForPlatform(); installAs != "faketool" {
+		t.Skipf("install name on %s is %s", runtime.GOOS, installAs)
+	}
+	if st := tool.Locate(); st.Source != SourceDownload || st.Installed {
+		t.Fatalf("before install: %+v", st)
+	}
+	st, err := tool.Install(false)
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	if st.Source != SourceCache || st.Path != filepath.Join(home, ".accuknox-config", "tools", "faketool", "2.0.0", "faketool") {
+		t.Errorf("after install: %+v", st)
+	}
+
+	v, err := tool.Verify()
+	if err != nil || v.Result != VerifyOK || v.Against != "install record" {
+		t.Fatalf("Verify = %+v, %v", v, err)
+	}
+	if err := os.WriteFile(st.Path, []byte("tampered"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := tool.Verify(); v.Result != VerifyMismatch {
+		t.Errorf("Verify after tampering = %s", v.Result)
+	}
+	// The receipt in the cache does not vouch for the binary.
+	sum, _ := fileSHA256(st.Path)
+	forged, _ := json.Marshal(receipt{Tool: tool.Name, Version: tool.Version, SHA256: sum})
+	if err := os.WriteFile(receiptPath(st.Path), forged, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := tool.Verify(); v.Result != VerifyMismatch {
+		t.Errorf("Verify after rewriting the receipt = %s", v.Result)
+	}
+	if _, err := tool.EnsureInstalled(); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("EnsureInstalled of a tampered binary: %v", err)
+	}
+	if _, err := tool.Install(true); err != nil {
+		t.Fatalf("Install --force: %v", err)
+	}
+	if _, err := tool.EnsureInstalled(); err != nil {
+		t.Errorf("EnsureInstalled after reinstalling: %v", err)
+	}
+
+	// An older cached version and an unversioned download are stale.
+	old := filepath.Join(home, ".accuknox-config", "tools", "faketool", "1.0.0")
+	legacy := filepath.Join(home, ".accuknox-config", "tools", "faketool.receipt.json")
+	_ = os.MkdirAll(old, 0o750)
+	_ = os.WriteFile(legacy, nil, 0o600)
+	cfg := &Config{Tools: []Tool{*tool}}
+	stale, err := cfg.StaleCaches()
+	if err != nil || !slices.Equal(stale, []string{old, legacy}) {
+		t.Errorf("StaleCaches = %v, %v", stale, err)
+	}
+	if removed, err := tool.Upgrade(); err != nil || !slices.Equal(removed, []string{"1.0.0"}) {
+		t.Errorf("Upgrade = %v, %v", removed, err)
+	}
+}
+
+func TestVerify_InstallRecord(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	srv := newToolServer(t, []byte("#!/bin/sh\necho tool\n"))
+	tool := &Tool{
+		Name:      "faketool",
+		InstallAs: "faketool",
+		Version:   "2.0.0",
+		Platforms: map[string]Platform{runtime.GOOS: {runtime.GOARCH: {Source: srv.URL + "/faketool"}}},
+		Integrity: &Integrity{Checksums: srv.URL + "/checksums.txt"},
+	}
+	if _, err := tool.Install(false); err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+
+	record := installRecordPath()
+	if err := os.Remove(record); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := tool.Verify(); err != nil || v.Result != VerifyUnverified {
+		t.Errorf("Verify without an install record = %+v, %v", v, err)
+	}
+	if _, err := tool.EnsureInstalled(); err == nil || !strings.Contains(err.Error(), "cannot be verified") {
+		t.Errorf("EnsureInstalled without an install record: %v", err)
+	}
+
+	if _, err := tool.Install(true); err != nil {
+		t.Fatalf("Install --force: %v", err)
+	}
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if err := os.Chmod(record, 0o666); err != nil { // #nosec G302
+		t.Fatal(err)
+	}
+	if _, err := tool.Verify(); err == nil || !strings.Contains(err.Error(), "only you can write") {
+		t.Errorf("Verify with a writable install record: %v", err)
+	}
+}
+
+func TestVerify_SideBySideUnverified(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	tool := &Tool{Name: "sidetool", InstallAs: "knoxctl-test-sidetool", Version: "1.0.0"}
+	path := filepath.Join(filepath.Dir(exe), tool.installAsForPlatform())
+	if err := os.WriteFile(path, []byte("tool"), 0o600); err != nil {
+		t.Skipf("cannot write next to the test binary: %v", err)
+	}
+	defer os.Remove(path)
+	// Even a matching receipt next to it does not vouch for it.
+	sum, _ := fileSHA256(path)
+	forged, _ := json.Marshal(receipt{Tool: tool.Name, Version: tool.Version, SHA256: sum})
+	if err := os.WriteFile(receiptPath(path), forged, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(receiptPath(path))
+
+	v, err := tool.Verify()
+	if err != nil || v.Source != SourceSideBySide || v.Result != VerifyUnverified {
+		t.Errorf("Verify = %+v, %v", v, err)
+	}
+}
+
+func TestVerifyDownload(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := newToolServer(t, []byte("tool"))
+	toolFor := func(in *Integrity, sum string) *Tool {
+		return &Tool{
+			Name:      "faketool",
+			InstallAs: "faketool",
+			Version:   "1.0.0",
+			Platforms: map[string]Platform{runtime.GOOS: {runtime.GOARCH: {Source: srv.URL + "/faketool", SHA256: sum}}},
+			Integrity: in,
+		}
+	}
+	install := func(tool *Tool) error {
+		cfg := tool.Platforms[runtime.GOOS][runtime.GOARCH]
+		_, err := tool.downloadAndInstall(&cfg, "faketool", filepath.Join(t.TempDir(), "faketool"))
+		return err
+	}
+
+	if err := install(toolFor(nil, "")); err == nil || !strings.Contains(err.Error(), "no SHA256 or signature") {
+		t.Errorf("unverifiable download: %v", err)
+	}
+	InsecureSkipVerify = true
+	if err := install(toolFor(nil, "")); err != nil {
+		t.Errorf("unverifiable download with InsecureSkipVerify: %v", err)
+	}
+	if err := install(toolFor(nil, strings.Repeat("0", 64))); err == nil {
+		t.Error("a checksum mismatch was accepted with InsecureSkipVerify")
+	}
+	InsecureSkipVerify = false
+
+	if err := install(toolFor(&Integrity{Checksums: srv.URL + "/checksums.txt"}, "")); err != nil {
+		t.Errorf("checksum file: %v", err)
+	}
+
+	// A cosign key signs the checksum file.
+	keyDir := t.TempDir()
+	t.Setenv(sign.PasswordEnv, "")
+	if err := sign.GenerateKeyPair(&sign.Options{KeyOut: filepath.Join(keyDir, "k")}); err != nil {
+		t.Fatal(err)
+	}
+	priv, _ := os.ReadFile(filepath.Join(keyDir, "k.key"))
+	pub, _ := os.ReadFile(filepath.Join(keyDir, "k.pub"))
+	t.Setenv("KNOXCTL_TEST_KEY", string(priv))
+	signed := &Integrity{Checksums: srv.URL + "/checksums.txt", Signature: srv.URL + "/checksums.txt.sig", PublicKey: string(pub)}
+	if err := install(toolFor(signed, "")); err != nil {
+		t.Errorf("signed checksum file: %v", err)
+	}
+	if err := sign.GenerateKeyPair(&sign.Options{KeyOut: filepath.Join(keyDir, "other")}); err != nil {
+		t.Fatal(err)
+	}
+	other, _ := os.ReadFile(filepath.Join(keyDir, "other.pub"))
+	signed.PublicKey = string(other)
+	if err := install(toolFor(signed, "")); err == nil {
+		t.Error("a checksum file signed by another key was accepted")
+	}
+}
+
+func TestCheckKeyless(t *testing.T) {
+	vs, err := ca.NewVirtualSigstore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const (
+		identity = "https://github.com/acme/tool/.github/workflows/release.yaml@refs/heads/main"
+		issuer   = "https://token.actions.githubusercontent.com"
+	)
+	cert, key, err := vs.GenerateLeafCert(identity, issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte(strings.Repeat("0", 64) + "  tool.tar.gz\n")
+	digest := sha256.Sum256(data)
+	sig, err := key.(crypto.Signer).Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	// The virtual instance has no Rekor server to look the entry up in.
+	opts := func() *cosign.CheckOpts {
+		return &cosign.CheckOpts{TrustedMaterial: vs, IgnoreTlog: true, IgnoreSCT: true}
+	}
+
+	in := &Integrity{CertificateIdentity: identity, CertificateOIDCIssuer: issuer}
+	if err := in.checkKeyless(data, sig, certPEM, opts()); err != nil {
+		t.Errorf("valid signature: %v", err)
+	}
+	b64 := []byte(base64.StdEncoding.EncodeToString(certPEM))
+	if err := in.checkKeyless(data, []byte(base64.StdEncoding.EncodeToString(sig)), b64, opts()); err != nil {
+		t.Errorf("base64-encoded signature and certificate: %v", err)
+	}
+	if err := in.checkKeyless(append(data, 'x'), sig, certPEM, opts()); err == nil {
+		t.Error("a signature over other data was accepted")
+	}
+	regexp := &Integrity{CertificateIdentityRegexp: `^https://github\.com/acme/tool/`, CertificateOIDCIssuer: issuer}
+	if err := regexp.checkKeyless(data, sig, certPEM, opts()); err != nil {
+		t.Errorf("identity regexp: %v", err)
+	}
+	other := &Integrity{CertificateIdentity: "https://github.com/evil/tool/.github/workflows/release.yaml@refs/heads/main", CertificateOIDCIssuer: issuer}
+	if err := other.checkKeyless(data, sig, certPEM, opts()); err == nil {
+		t.Error("a certificate issued to another identity was accepted")
+	}
+	if err := (&Integrity{}).checkKeyless(data, sig, certPEM, opts()); err == nil {
+		t.Error("a keyless signature was accepted without an identity")
+	}
+}
+
+func TestLookupChecksum(t *testing.T) {
+	list := []byte("# sums\naaa  dist/tool.tar.gz\nbbb *tool.zip\nSHA256 (tool.exe) = ccc\n")
+	for name, want := range map[string]string{"tool.tar.gz": "aaa", "tool.zip": "bbb", "tool.exe": "ccc"} {
+		if got, ok := lookupChecksum(list, name); !ok || got != want {
+			t.Errorf("lookupChecksum(%s) = %q, %v", name, got, ok)
+		}
+	}
+	if _, ok := lookupChecksum(list, "missing"); ok {
+		t.Error("found a checksum for an unlisted file")
+	}
+	if got, ok := lookupChecksum([]byte("ddd\n"), "anything"); !ok || got != "ddd" {
+		t.Errorf("bare hash = %q, %v", got, ok)
+	}
+}
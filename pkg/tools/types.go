@@ -16,6 +16,9 @@ type Tool struct {
 	Version     string              `yaml:"version"`
 	Builtin     bool                `yaml:"builtin"`   // binary is built from source, not downloaded
 	Platforms   map[string]Platform `yaml:"platforms"` // keyed by GOOS
+	Integrity   *Integrity          `yaml:"integrity"` // upstream checksum file and signature
+
+	Overridden bool `yaml:"-"` // changed by the user's tools.yaml overlay
 }
 
 // Platform maps GOARCH → PlatformConfig.
@@ -28,3 +31,23 @@ type PlatformConfig struct {
 	InstallAs string `yaml:"install_as"` // overrides tool-level InstallAs when set
 	Binary    string `yaml:"binary"`     // filename of binary inside archive (optional; defaults to install_as)
 }
+
+// Integrity names what a vendor publishes to verify its downloads: a SHA256
+// checksum file listing them, and a detached signature over that file (or
+// over the download itself when there is no checksum file), checked with a
+// cosign public key or an OpenPGP key kept in tools.yaml, or made keylessly
+// with a Sigstore certificate issued to the vendor's release workflow.
+type Integrity struct {
+	Checksums string `yaml:"checksums"`  // URL of a sha256sum-style checksum file
+	Signature string `yaml:"signature"`  // URL of the detached signature
+	PublicKey string `yaml:"public_key"` // PEM cosign public key
+	GPGKey    string `yaml:"gpg_key"`    // ASCII-armoured OpenPGP public key
+
+	// Keyless signing: the URL of the Fulcio certificate the signature was
+	// made with, and the identity and OIDC issuer it must have been issued
+	// to. The identity is matched exactly, or as a regular expression.
+	Certificate               string `yaml:"certificate"`
+	CertificateIdentity       string `yaml:"certificate_identity"`
+	CertificateIdentityRegexp string `yaml:"certificate_identity_regexp"`
+	CertificateOIDCIssuer     string `yaml:"certificate_oidc_issuer"`
+}
@@ -0,0 +1,285 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/accuknox/accuknox-cli-v2/pkg/download"
+	"github.com/accuknox/accuknox-cli-v2/pkg/sign"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	rekorclient "github.com/sigstore/rekor/pkg/client"
+	"golang.org/x/crypto/openpgp" // frozen upstream, but sufficient to check detached signatures
+)
+
+// SkipVerifyEnv names the environment variable that sets InsecureSkipVerify.
+const SkipVerifyEnv = "KNOXCTL_INSECURE_SKIP_VERIFY"
+
+// InsecureSkipVerify accepts downloads for which no SHA256 or signature is
+// known, and cached binaries that cannot be re-verified, with a warning.
+// A checksum or signature that is known but does not match is never
+// accepted. It is set by --insecure-skip-verify or $KNOXCTL_INSECURE_SKIP_VERIFY.
+var InsecureSkipVerify, _ = strconv.ParseBool(os.Getenv(SkipVerifyEnv))
+
+// maxIntegrityFile bounds checksum and signature downloads.
+const maxIntegrityFile = 1 << 20
+
+// verifyDownload checks a downloaded file, whose SHA256 is sum, against the
+// sha256 of its platform entry and the tool's integrity settings. It fails
+// closed: unless InsecureSkipVerify is set, a download must be covered by
+// at least one of them.
+func (t *Tool) verifyDownload(cfg *PlatformConfig, file, sum string) error {
+	verified := false
+	if cfg.SHA256 != "" {
+		if !strings.EqualFold(sum, cfg.SHA256) {
+			return fmt.Errorf("SHA256 mismatch: got %s, want %s", sum, cfg.SHA256)
+		}
+		verified = true
+	}
+
+	if in := t.Integrity; in != nil {
+		switch {
+		case in.Checksums != "":
+			list, err := fetchSmall(in.Checksums)
+			if err != nil {
+				return fmt.Errorf("fetching checksum file: %w", err)
+			}
+			if in.Signature != "" {
+				if err := in.checkSignature(list); err != nil {
+					return fmt.Errorf("checksum file %s: %w", in.Checksums, err)
+				}
+			}
+			name := downloadName(cfg.Source)
+			want, ok := lookupChecksum(list, name)
+			if !ok {
+				return fmt.Errorf("%s is not listed in %s", name, in.Checksums)
+			}
+			if !strings.EqualFold(sum, want) {
+				return fmt.Errorf("SHA256 mismatch: got %s, want %s (from %s)", sum, want, in.Checksums)
+			}
+			verified = true
+		case in.Signature != "":
+			data, err := os.ReadFile(file) // #nosec G304 -- our own temp file
+			if err != nil {
+				return err
+			}
+			if err := in.checkSignature(data); err != nil {
+				return fmt.Errorf("%s: %w", cfg.Source, err)
+			}
+			verified = true
+		}
+	}
+
+	if !verified {
+		if !InsecureSkipVerify {
+			return fmt.Errorf("no SHA256 or signature is known for %s; add a sha256 or integrity entry for %s to tools.yaml, or pass --insecure-skip-verify", cfg.Source, t.Name)
+		}
+		fmt.Fprintf(os.Stderr, "warning: installing %s without verification (--insecure-skip-verify)\n", t.Name)
+	}
+	return nil
+}
+
+// checkSignature fetches the detached signature and verifies it over data
+// with the configured key or keyless certificate.
+func (in *Integrity) checkSignature(data []byte) error {
+	sig, err := fetchSmall(in.Signature)
+	if err != nil {
+		return fmt.Errorf("fetching signature: %w", err)
+	}
+	verifiers := 0
+	for _, v := range []string{in.PublicKey, in.GPGKey, in.Certificate} {
+		if v != "" {
+			verifiers++
+		}
+	}
+	switch {
+	case verifiers > 1:
+		return fmt.Errorf("integrity sets more than one of public_key, gpg_key and certificate")
+	case in.PublicKey != "":
+		return sign.VerifyBytes(data, sig, []byte(in.PublicKey))
+	case in.GPGKey != "":
+		keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(in.GPGKey))
+		if err != nil {
+			return fmt.Errorf("reading OpenPGP key: %w", err)
+		}
+		if bytes.HasPrefix(bytes.TrimSpace(sig), []byte("-----BEGIN PGP SIGNATURE")) {
+			_, err = openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(data), bytes.NewReader(sig))
+		} else {
+			_, err = openpgp.CheckDetachedSignature(keyring, bytes.NewReader(data), bytes.NewReader(sig))
+		}
+		if err != nil {
+			return fmt.Errorf("invalid OpenPGP signature: %w", err)
+		}
+		return nil
+	case in.Certificate != "":
+		cert, err := fetchSmall(in.Certificate)
+		if err != nil {
+			return fmt.Errorf("fetching certificate: %w", err)
+		}
+		co, err := keylessCheckOpts()
+		if err != nil {
+			return err
+		}
+		return in.checkKeyless(data, sig, cert, co)
+	default:
+		return fmt.Errorf("integrity has a signature but no public_key, gpg_key or certificate")
+	}
+}
+
+// rekorURL is the transparency log keyless signatures are looked up in.
+const rekorURL = "https://rekor.sigstore.dev"
+
+// keylessCheckOpts returns the cosign options for checking keyless
+// signatures against the Sigstore public-good instance: its trusted root,
+// fetched and cached through TUF, and a Rekor client to find the log entry
+// proving the signature was made while its certificate was valid.
+var keylessCheckOpts = func() (*cosign.CheckOpts, error) {
+	trusted, err := cosign.TrustedRoot()
+	if err != nil {
+		return nil, fmt.Errorf("fetching the Sigstore trusted root: %w", err)
+	}
+	rekor, err := rekorclient.GetRekorClient(rekorURL)
+	if err != nil {
+		return nil, fmt.Errorf("creating Rekor client: %w", err)
+	}
+	return &cosign.CheckOpts{TrustedMaterial: trusted, RekorClient: rekor}, nil
+}
+
+// checkKeyless verifies a keyless cosign signature over data, made with the
+// Fulcio certificate cert, which must have been issued to the configured
+// identity by the configured OIDC issuer.
+func (in *Integrity) checkKeyless(data, sig, cert []byte, co *cosign.CheckOpts) error {
+	if (in.CertificateIdentity == "" && in.CertificateIdentityRegexp == "") || in.CertificateOIDCIssuer == "" {
+		return fmt.Errorf("integrity has a certificate but no certificate_identity or certificate_oidc_issuer")
+	}
+	co.Identities = []cosign.Identity{{
+		Issuer:        in.CertificateOIDCIssuer,
+		Subject:       in.CertificateIdentity,
+		SubjectRegExp: in.CertificateIdentityRegexp,
+	}}
+	s, err := static.NewSignature(data, base64Text(sig), static.WithCertChain(pemText(cert), nil))
+	if err != nil {
+		return err
+	}
+	if _, err := cosign.VerifyBlobSignature(context.Background(), s, co); err != nil {
+		return fmt.Errorf("invalid keyless signature: %w", err)
+	}
+	return nil
+}
+
+// base64Text returns a signature file's contents as base64: cosign writes
+// them base64-encoded, other tools as raw bytes.
+func base64Text(sig []byte) string {
+	text := strings.TrimSpace(string(sig))
+	if _, err := base64.StdEncoding.DecodeString(text); err == nil {
+		return text
+	}
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+// pemText returns a certificate file's contents as PEM; cosign can write
+// them base64-encoded.
+func pemText(cert []byte) []byte {
+	if bytes.HasPrefix(bytes.TrimSpace(cert), []byte("-----BEGIN")) {
+		return cert
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(cert))); err == nil {
+		return decoded
+	}
+	return cert
+}
+
+// lookupChecksum finds the SHA256 of name in a checksum file in sha256sum
+// ("<hex>  <name>", "<hex> *<name>") or BSD ("SHA256 (<name>) = <hex>")
+// format. A file holding a single bare hash applies to any name.
+func lookupChecksum(list []byte, name string) (string, bool) {
+	sc := bufio.NewScanner(bytes.NewReader(list))
+	var lines []string
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	for _, line := range lines {
+		if rest, ok := strings.CutPrefix(line, "SHA256 ("); ok {
+			if file, sum, ok := strings.Cut(rest, ") = "); ok && file == name {
+				return sum, true
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 2 && path.Base(strings.TrimPrefix(fields[1], "*")) == name {
+			return fields[0], true
+		}
+	}
+	if len(lines) == 1 && len(strings.Fields(lines[0])) == 1 {
+		return lines[0], true
+	}
+	return "", false
+}
+
+// downloadName returns the file name a download URL names.
+func downloadName(source string) string {
+	if u, err := url.Parse(source); err == nil {
+		return path.Base(u.Path)
+	}
+	return path.Base(source)
+}
+
+// fetchSmall downloads a checksum or signature file.
+func fetchSmall(rawURL string) ([]byte, error) {
+	resp, err := httpGet(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d for %s", resp.StatusCode, rawURL)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxIntegrityFile+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxIntegrityFile {
+		return nil, fmt.Errorf("%s is larger than %d bytes", rawURL, maxIntegrityFile)
+	}
+	return data, nil
+}
+
+// httpGet fetches a tool download through the user's mirrors, CA bundle and
+// proxy (see download.Config).
+func httpGet(rawURL string) (*http.Response, error) {
+	return download.Get(rawURL)
+}
+
+// checkInstalled re-verifies an installed binary before it is run (see
+// Verify). A mismatch always fails; a binary that cannot be verified is only
+// accepted with InsecureSkipVerify.
+func (t *Tool) checkInstalled() error {
+	v, err := t.Verify()
+	if err != nil {
+		return err
+	}
+	switch v.Result {
+	case VerifyOK:
+		return nil
+	case VerifyMismatch:
+		return fmt.Errorf("%s at %s does not match its %s (SHA256 %s, want %s); reinstall it with 'knoxctl tools install --force %s'",
+			t.Name, v.Path, v.Against, v.Actual, v.Expected, t.Name)
+	}
+	if !InsecureSkipVerify {
+		return fmt.Errorf("%s at %s cannot be verified; reinstall it with 'knoxctl tools install --force %s', or pass --insecure-skip-verify", t.Name, v.Path, t.Name)
+	}
+	fmt.Fprintf(os.Stderr, "warning: running %s without verification (--insecure-skip-verify)\n", t.Name)
+	return nil
+}
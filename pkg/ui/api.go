@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/accuknox/accuknox-cli-v2/pkg/sign"
+)
+
+// apiV1 is the prefix of the versioned REST API described by
+// /api/v1/openapi.yaml. The same handlers stay mounted under /api for the
+// bundled UI.
+const apiV1 = "/api/v1"
+
+// api mounts an API handler under both /api/v1 and the unversioned /api.
+func (s *Server) api(path string, h http.HandlerFunc) {
+	s.mux.HandleFunc(apiV1+path, h)
+	s.mux.HandleFunc("/api"+path, h)
+}
+
+// handleOpenAPI serves the OpenAPI description of /api/v1.
+func handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	_, _ = w.Write(OpenAPISpec)
+}
+
+// wantsSSE reports whether a job request should be answered with an event
+// stream rather than synchronous JSON. An explicit Accept header decides;
+// otherwise the legacy /api routes stream, as the bundled UI expects, and
+// /api/v1 replies with JSON.
+func wantsSSE(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(strings.TrimSpace(accept), ";")
+		switch strings.TrimSpace(mediaType) {
+		case "text/event-stream":
+			return true
+		case "application/json":
+			return false
+		}
+	}
+	return !strings.HasPrefix(r.URL.Path, apiV1+"/")
+}
+
+// jobBOM looks up a job and reads the BOM it produced.
+func (s *Server) jobBOM(id string) (*job, []byte, error) {
+	j, ok := s.jobs.get(id)
+	if !ok {
+		return nil, nil, fmt.Errorf("no such job: %s", id)
+	}
+	data, err := j.bom()
+	if err != nil {
+		return nil, nil, err
+	}
+	return j, data, nil
+}
+
+// handleSign signs a BOM, given inline as "bom" or as the "jobId" of the job
+// that produced it, and returns the base-64 signature and, for generated and
+// KMS keys, the PEM public key. Signatures of a job's BOM are also kept with
+// the job's files.
+func (s *Server) handleSign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		BOM   string `json:"bom"`
+		JobID string `json:"jobId"`
+		signReq
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, "invalid request: "+err.Error())
+		return
+	}
+	data := []byte(req.BOM)
+	var j *job
+	if len(data) == 0 && req.JobID != "" {
+		var err error
+		if j, data, err = s.jobBOM(req.JobID); err != nil {
+			writeErr(w, err.Error())
+			return
+		}
+	}
+	if len(data) == 0 {
+		writeErr(w, "bom payload is empty")
+		return
+	}
+	sigB64, pubKeyPEM, err := sign.SignBytes(data, &sign.Options{
+		Enabled:     true,
+		GenerateKey: req.SignGenerateKey,
+		KeyRef:      req.SignKeyRef,
+		Password:    req.SignPassword,
+	})
+	if err != nil {
+		writeErr(w, "signing failed: "+err.Error())
+		return
+	}
+	resp := map[string]interface{}{"signed": true, "signature": sigB64}
+	if len(pubKeyPEM) > 0 {
+		resp["pubKey"] = string(pubKeyPEM)
+	}
+	if j != nil {
+		if err := j.saveSignature(j.snapshot().Result, sigB64, string(pubKeyPEM)); err != nil {
+			writeErr(w, err.Error())
+			return
+		}
+		resp["jobId"] = j.info.ID
+		resp["files"] = j.snapshot().Files
+	}
+	writeJSON(w, resp)
+}
@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestOpenAPISpec_MatchesRoutes(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]interface{} `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(OpenAPISpec, &spec); err != nil {
+		t.Fatalf("parsing openapi.yaml: %v", err)
+	}
+	s, err := NewServer(&Options{Addr: "127.0.0.1:10100", JobsDir: t.TempDir(), Headless: true})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	for path := range spec.Paths {
+		req := httptest.NewRequest(http.MethodGet, apiV1+strings.ReplaceAll(strings.ReplaceAll(path, "{id}", "x"), "{name}", "y"), nil)
+		if _, pattern := s.mux.Handler(req); pattern != apiV1+path {
+			t.Errorf("spec path %s is served by %q", path, pattern)
+		}
+	}
+	if _, pattern := s.mux.Handler(httptest.NewRequest(http.MethodGet, "/", nil)); pattern != "" {
+		t.Errorf("headless server serves the UI at %q", pattern)
+	}
+}
+
+func TestOpenAPISpec_IsPublic(t *testing.T) {
+	_, h := newTestServer(t)
+	rec := serve(h, httptest.NewRequest(http.MethodGet, "http://localhost:10100/api/v1/openapi.yaml", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "openapi: 3") {
+		t.Errorf("spec: %d %s", rec.Code, rec.Body)
+	}
+	rec = serve(h, httptest.NewRequest(http.MethodGet, "http://localhost:10100/api/v1/version", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated API call: got %d, want 401", rec.Code)
+	}
+}
+
+func TestNewServer_Token(t *testing.T) {
+	if _, err := NewServer(&Options{JobsDir: t.TempDir(), Token: "short"}); err == nil {
+		t.Error("a short token was accepted")
+	}
+	s, err := NewServer(&Options{Addr: "127.0.0.1:10100", JobsDir: t.TempDir(), Token: "0123456789abcdef"})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:10100/api/v1/version", nil)
+	req.Header.Set(tokenHeader, "0123456789abcdef")
+	if rec := serve(s.secure(s.mux), req); rec.Code != http.StatusOK {
+		t.Errorf("request with the given token: got %d", rec.Code)
+	}
+}
+
+func TestStartJob_Responses(t *testing.T) {
+	s, h := newTestServer(t)
+	var fail error
+	s.api("/test", func(w http.ResponseWriter, r *http.Request) {
+		s.startJob(w, r, JobSBOM, nil, func(ctx context.Context, j *job) error {
+			j.send("log", map[string]string{"line": "scanning"})
+			if fail != nil {
+				return fail
+			}
+			return j.complete("sbom.json", 1, []byte(`{"bomFormat":"CycloneDX"}`), signReq{})
+		})
+	})
+	post := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "http://localhost:10100"+path, nil)
+		req.Header.Set("Authorization", "Bearer "+s.token)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		return serve(h, req)
+	}
+
+	rec := post("/api/v1/test", "")
+	var resp struct {
+		Job    Job
+		Result struct{ Result, ResultFile string }
+		Log    []string
+	}
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &resp) != nil {
+		t.Fatalf("sync: %d %s", rec.Code, rec.Body)
+	}
+	if resp.Job.Status != JobSucceeded || resp.Result.ResultFile != "sbom.json" || resp.Result.Result == "" || !slices.Equal(resp.Log, []string{"scanning"}) {
+		t.Errorf("sync response = %+v", resp)
+	}
+	if rec.Header().Get("X-Knoxctl-Job") != resp.Job.ID {
+		t.Errorf("X-Knoxctl-Job = %q, want %q", rec.Header().Get("X-Knoxctl-Job"), resp.Job.ID)
+	}
+
+	for _, tc := range []struct{ path, accept string }{
+		{"/api/test", ""},
+		{"/api/v1/test", "text/event-stream"},
+	} {
+		rec := post(tc.path, tc.accept)
+		if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" || !strings.Contains(rec.Body.String(), "event: complete") {
+			t.Errorf("%s (Accept %q): %s %s", tc.path, tc.accept, ct, rec.Body)
+		}
+	}
+	if rec := post("/api/test", "application/json"); rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("legacy route with Accept: application/json: %s", rec.Body)
+	}
+
+	fail = errors.New("boom")
+	if rec := post("/api/v1/test", ""); rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"error":"boom"`) {
+		t.Errorf("failed job: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestHandleSign_Job(t *testing.T) {
+	s, h := newTestServer(t)
+	j, _ := s.jobs.start(JobSBOM, nil, func(ctx context.Context, j *job) error {
+		return j.complete("sbom.json", 1, []byte(`{"bomFormat":"CycloneDX"}`), signReq{})
+	})
+	waitJob(t, s.jobs, j.info.ID)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:10100/api/v1/sign",
+		strings.NewReader(`{"jobId":"`+j.info.ID+`","signGenerateKey":true}`))
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	rec := serve(h, req)
+	var resp struct {
+		Signature, PubKey string
+		Files             []string
+	}
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &resp) != nil {
+		t.Fatalf("sign: %d %s", rec.Code, rec.Body)
+	}
+	if resp.Signature == "" || resp.PubKey == "" || !slices.Contains(resp.Files, "sbom.json.sig") || !slices.Contains(resp.Files, "sbom.json.pub") {
+		t.Errorf("sign response = %+v", resp)
+	}
+}
@@ -0,0 +1,876 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package ui
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job statuses.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job kinds.
+const (
+	JobSBOM      = "sbom"
+	JobCBOM      = "cbom"
+	JobCBOMImage = "cbom-image"
+	JobAIBOM     = "aibom"
+	JobRun       = "run"
+)
+
+const (
+	jobFile    = "job.json"
+	eventsFile = "events.jsonl"
+	logFile    = "output.log"
+
+	// maxJobHistory is the number of finished jobs kept; older ones are
+	// removed as new jobs start.
+	maxJobHistory = 500
+
+	// jobConcurrency is the number of jobs run at once; others wait queued.
+	jobConcurrency = 2
+)
+
+// Job is the persisted record of a UI operation.
+type Job struct {
+	ID       string                 `json:"id"`
+	Kind     string                 `json:"kind"`
+	Status   string                 `json:"status"`
+	Inputs   map[string]interface{} `json:"inputs,omitempty"` // request, with credentials masked
+	Created  time.Time              `json:"created"`
+	Started  *time.Time             `json:"started,omitempty"`
+	Finished *time.Time             `json:"finished,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+	Count    int                    `json:"count,omitempty"`  // components in the produced BOM
+	Result   string                 `json:"result,omitempty"` // the produced BOM, one of Files
+	Files    []string               `json:"files,omitempty"`  // produced files, downloadable by name
+}
+
+// active reports whether the job has yet to finish.
+func (j *Job) active() bool {
+	return j.Status == JobQueued || j.Status == JobRunning
+}
+
+// jobEvent is one SSE event of a job. Seq numbers events from 1 and is sent
+// as the SSE id, so a client can resume a stream with Last-Event-ID.
+type jobEvent struct {
+	Seq   int             `json:"seq"`
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// jobFunc does the work of a job, reporting progress through j.send. It
+// returns the error the job failed with; a job that succeeds sends its own
+// "complete" event.
+type jobFunc func(ctx context.Context, j *job) error
+
+// job is a Job along with its event log. Events are kept in memory while the
+// job runs and read back from its directory once it has finished.
+type job struct {
+	dir string
+
+	mu      sync.Mutex
+	info    Job
+	events  []jobEvent
+	seq     int
+	changed chan struct{} // closed and replaced on every event
+	cancel  context.CancelFunc
+	evFile  *os.File
+	logFile *os.File
+}
+
+// jobManager runs UI operations as jobs and keeps their history under dir,
+// one directory per job.
+type jobManager struct {
+	dir   string
+	slots chan struct{}
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+// DefaultJobsDir returns ~/.accuknox-config/ui/jobs.
+func DefaultJobsDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), ".accuknox-config", "ui", "jobs")
+	}
+	return filepath.Join(home, ".accuknox-config", "ui", "jobs")
+}
+
+// newJobManager loads the job history in dir. Jobs left queued or running by
+// a previous server are marked failed.
+func newJobManager(dir string) (*jobManager, error) {
+	if dir == "" {
+		dir = DefaultJobsDir()
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating job directory %s: %w", dir, err)
+	}
+	m := &jobManager{
+		dir:   dir,
+		slots: make(chan struct{}, jobConcurrency),
+		jobs:  map[string]*job{},
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading job directory %s: %w", dir, err)
+	}
+	for _, e := range entries {
+		// Job IDs are UUIDs; anything else in the directory is not a job.
+		if _, err := uuid.Parse(e.Name()); err != nil || !e.IsDir() {
+			continue
+		}
+		j := &job{dir: filepath.Join(dir, e.Name()), changed: make(chan struct{})}
+		data, err := os.ReadFile(filepath.Join(j.dir, jobFile)) // #nosec G304 -- inside the job directory
+		if err != nil || json.Unmarshal(data, &j.info) != nil || j.info.ID != e.Name() {
+			continue
+		}
+		if j.info.active() {
+			now := time.Now().UTC()
+			j.info.Status = JobFailed
+			j.info.Error = "interrupted: knoxctl ui stopped before the job finished"
+			j.info.Finished = &now
+			_ = j.save()
+		}
+		m.jobs[j.info.ID] = j
+	}
+	return m, nil
+}
+
+// start records a new job and runs fn in the background once a slot is
+// free. inputs is the job's request; credentials in it are masked before it
+// is stored.
+func (m *jobManager) start(kind string, inputs interface{}, fn jobFunc) (*job, error) {
+	id := uuid.NewString()
+	j := &job{
+		dir:     filepath.Join(m.dir, id),
+		changed: make(chan struct{}),
+		info: Job{
+			ID:      id,
+			Kind:    kind,
+			Status:  JobQueued,
+			Inputs:  jobInputs(inputs),
+			Created: time.Now().UTC(),
+		},
+	}
+	if err := os.MkdirAll(j.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating job directory: %w", err)
+	}
+	var err error
+	if j.evFile, err = os.OpenFile(filepath.Join(j.dir, eventsFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600); err != nil { // #nosec G304 -- inside the job directory
+		return nil, fmt.Errorf("creating job event log: %w", err)
+	}
+	if j.logFile, err = os.OpenFile(filepath.Join(j.dir, logFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600); err != nil { // #nosec G304 -- inside the job directory
+		j.evFile.Close()
+		return nil, fmt.Errorf("creating job log: %w", err)
+	}
+	j.info.Files = []string{logFile}
+	if err := j.save(); err != nil {
+		j.closeFiles()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+	j.send("job", map[string]string{"id": id, "kind": kind})
+
+	m.mu.Lock()
+	m.jobs[id] = j
+	m.mu.Unlock()
+	m.prune()
+
+	go m.run(ctx, j, fn)
+	return j, nil
+}
+
+func (m *jobManager) run(ctx context.Context, j *job, fn jobFunc) {
+	defer j.cancel()
+
+	select {
+	case m.slots <- struct{}{}:
+		defer func() { <-m.slots }()
+	case <-ctx.Done():
+		j.finish(JobCancelled, "cancelled")
+		return
+	}
+
+	j.mu.Lock()
+	now := time.Now().UTC()
+	j.info.Status = JobRunning
+	j.info.Started = &now
+	_ = j.save()
+	j.mu.Unlock()
+
+	err := fn(ctx, j)
+	switch {
+	case ctx.Err() != nil:
+		j.finish(JobCancelled, "cancelled")
+	case err != nil:
+		j.finish(JobFailed, err.Error())
+	default:
+		j.finish(JobSucceeded, "")
+	}
+}
+
+// get returns the job with the given ID.
+func (m *jobManager) get(id string) (*job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	return j, ok
+}
+
+// list returns the jobs, newest first.
+func (m *jobManager) list() []Job {
+	m.mu.Lock()
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		jobs = append(jobs, j.snapshot())
+	}
+	m.mu.Unlock()
+	sort.Slice(jobs, func(a, b int) bool {
+		return jobs[a].Created.After(jobs[b].Created)
+	})
+	return jobs
+}
+
+// remove deletes a finished job and its files.
+func (m *jobManager) remove(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return os.ErrNotExist
+	}
+	if info := j.snapshot(); info.active() {
+		return fmt.Errorf("job %s is %s; cancel it first", id, info.Status)
+	}
+	delete(m.jobs, id)
+	return os.RemoveAll(j.dir)
+}
+
+// prune removes the oldest finished jobs beyond maxJobHistory.
+func (m *jobManager) prune() {
+	jobs := m.list()
+	finished := 0
+	for _, j := range jobs {
+		if j.active() {
+			continue
+		}
+		if finished++; finished > maxJobHistory {
+			_ = m.remove(j.ID)
+		}
+	}
+}
+
+// stats counts the jobs that succeeded, for the dashboard.
+func (m *jobManager) stats() DashboardStats {
+	var st DashboardStats
+	for _, j := range m.list() {
+		if j.Status != JobSucceeded {
+			continue
+		}
+		switch j.Kind {
+		case JobSBOM:
+			st.SBOM++
+		case JobCBOM, JobCBOMImage:
+			st.CBOM++
+		case JobAIBOM:
+			st.AIBOM++
+		case JobRun:
+			if args, ok := j.Inputs["args"].([]interface{}); ok && len(args) > 0 && args[0] == "image-scan" {
+				st.Scan++
+			}
+		}
+	}
+	return st
+}
+
+func (j *job) snapshot() Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	info := j.info
+	info.Files = append([]string(nil), j.info.Files...)
+	return info
+}
+
+// send records an event of the job, appends it to the job's event log and
+// wakes up the streams following the job. "log", "progress" and "error"
+// messages also go to the job's output log.
+func (j *job) send(event string, data interface{}) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		b, _ = json.Marshal(errMsg(err))
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.seq++
+	ev := jobEvent{Seq: j.seq, Event: event, Data: b}
+	j.events = append(j.events, ev)
+	if j.evFile != nil {
+		_ = json.NewEncoder(j.evFile).Encode(diskEvent(ev))
+	}
+	if j.logFile != nil {
+		var msg struct {
+			Line    string `json:"line"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(b, &msg) == nil && (msg.Line != "" || msg.Message != "") {
+			line := msg.Line
+			if line == "" {
+				line = "[" + event + "] " + msg.Message
+			}
+			fmt.Fprintln(j.logFile, line)
+		}
+	}
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// complete stores the BOM a job produced as name, along with its signature
+// when signing was requested, and sends the "complete" event.
+func (j *job) complete(name string, count int, bomJSON []byte, sr signReq) error {
+	payload := buildComplete(count, bomJSON, sr)
+	if err := os.WriteFile(filepath.Join(j.dir, name), bomJSON, 0o600); err != nil {
+		return fmt.Errorf("saving %s: %w", name, err)
+	}
+	j.mu.Lock()
+	j.info.Count = count
+	j.info.Result = name
+	j.info.Files = append(j.info.Files, name)
+	_ = j.save()
+	j.mu.Unlock()
+
+	sig, _ := payload["signature"].(string)
+	pub, _ := payload["pubKey"].(string)
+	if err := j.saveSignature(name, sig, pub); err != nil {
+		return err
+	}
+	payload["jobId"] = j.info.ID
+	payload["resultFile"] = name
+
+	j.send("complete", payload)
+	return nil
+}
+
+// saveSignature stores the signature of the job's file name alongside it as
+// name.sig, with the public key, when there is one, as name.pub. A public
+// key left by an earlier signature is removed.
+func (j *job) saveSignature(name, sig, pub string) error {
+	if sig == "" {
+		return nil
+	}
+	if err := os.WriteFile(filepath.Join(j.dir, name+".sig"), []byte(sig), 0o600); err != nil {
+		return fmt.Errorf("saving signature: %w", err)
+	}
+	pubPath := filepath.Join(j.dir, name+".pub")
+	if pub != "" {
+		if err := os.WriteFile(pubPath, []byte(pub), 0o600); err != nil {
+			return fmt.Errorf("saving public key: %w", err)
+		}
+	} else if err := os.Remove(pubPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing public key: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.info.Files = slices.DeleteFunc(j.info.Files, func(f string) bool {
+		return f == name+".sig" || f == name+".pub"
+	})
+	j.info.Files = append(j.info.Files, name+".sig")
+	if pub != "" {
+		j.info.Files = append(j.info.Files, name+".pub")
+	}
+	return j.save()
+}
+
+// bom returns the BOM the job produced.
+func (j *job) bom() ([]byte, error) {
+	info := j.snapshot()
+	if info.Result == "" {
+		return nil, fmt.Errorf("job %s produced no BOM", info.ID)
+	}
+	data, err := os.ReadFile(filepath.Join(j.dir, info.Result)) // #nosec G304 -- a file of the job
+	if err != nil {
+		return nil, fmt.Errorf("reading the BOM of job %s: %w", info.ID, err)
+	}
+	return data, nil
+}
+
+// finish records the outcome of the job. Failed and cancelled jobs get a
+// final "error" event.
+func (j *job) finish(status, errText string) {
+	if status != JobSucceeded {
+		j.send("error", map[string]string{"message": errText})
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now().UTC()
+	j.info.Status = status
+	j.info.Error = errText
+	j.info.Finished = &now
+	_ = j.save()
+	j.closeFiles()
+	// Release the in-memory log; followers read it back from disk.
+	j.events = nil
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// cancelJob cancels a queued or running job.
+func (j *job) cancelJob() {
+	j.mu.Lock()
+	cancel := j.cancel
+	j.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// eventsAfter returns the job's events numbered above seq, whether the job
+// has finished, and a channel closed when further events are sent.
+func (j *job) eventsAfter(seq int) ([]jobEvent, bool, <-chan struct{}, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !j.info.active() {
+		events, err := j.readEvents()
+		if err != nil {
+			return nil, true, nil, err
+		}
+		return eventsAbove(events, seq), true, nil, nil
+	}
+	return eventsAbove(j.events, seq), false, j.changed, nil
+}
+
+func eventsAbove(events []jobEvent, seq int) []jobEvent {
+	i := sort.Search(len(events), func(i int) bool { return events[i].Seq > seq })
+	return append([]jobEvent(nil), events[i:]...)
+}
+
+// readEvents reads the job's event log, putting the BOM back into the
+// "complete" event.
+func (j *job) readEvents() ([]jobEvent, error) {
+	f, err := os.Open(filepath.Join(j.dir, eventsFile)) // #nosec G304 -- inside the job directory
+	if err != nil {
+		return nil, fmt.Errorf("reading job events: %w", err)
+	}
+	defer f.Close()
+
+	var events []jobEvent
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var ev jobEvent
+		if json.Unmarshal(sc.Bytes(), &ev) != nil {
+			continue
+		}
+		if ev.Event == "complete" {
+			ev.Data = j.withResult(ev.Data)
+		}
+		events = append(events, ev)
+	}
+	return events, sc.Err()
+}
+
+// withResult adds the BOM named by a stored "complete" event back into it.
+func (j *job) withResult(data json.RawMessage) json.RawMessage {
+	var payload map[string]interface{}
+	if json.Unmarshal(data, &payload) != nil {
+		return data
+	}
+	name, _ := payload["resultFile"].(string)
+	if name == "" || name != filepath.Base(name) {
+		return data
+	}
+	bom, err := os.ReadFile(filepath.Join(j.dir, name)) // #nosec G304 -- a file of the job
+	if err != nil {
+		return data
+	}
+	payload["result"] = string(bom)
+	if b, err := json.Marshal(payload); err == nil {
+		return b
+	}
+	return data
+}
+
+// diskEvent returns ev as stored in the event log: the BOM of a "complete"
+// event is kept in its own file rather than repeated in the log.
+func diskEvent(ev jobEvent) jobEvent {
+	if ev.Event != "complete" {
+		return ev
+	}
+	var payload map[string]interface{}
+	if json.Unmarshal(ev.Data, &payload) != nil || payload["resultFile"] == nil {
+		return ev
+	}
+	delete(payload, "result")
+	if b, err := json.Marshal(payload); err == nil {
+		ev.Data = b
+	}
+	return ev
+}
+
+// save writes the job record; the caller holds j.mu or owns j.
+func (j *job) save() error {
+	data, err := json.MarshalIndent(j.info, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(j.dir, jobFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("saving job: %w", err)
+	}
+	return os.Rename(tmp, filepath.Join(j.dir, jobFile))
+}
+
+func (j *job) closeFiles() {
+	if j.evFile != nil {
+		j.evFile.Close()
+		j.evFile = nil
+	}
+	if j.logFile != nil {
+		j.logFile.Close()
+		j.logFile = nil
+	}
+}
+
+// redacted replaces credentials in the job history. Unlike maskToken it keeps
+// nothing of the value, since job.json outlives the credential's use.
+const redacted = "••••••••"
+
+// secretInputs are the request fields never stored in the job history.
+var secretInputs = map[string]bool{
+	"token":           true,
+	"secretAccessKey": true,
+	"sessionToken":    true,
+	"signPassword":    true,
+}
+
+// jobInputs converts a request to the map stored with the job, with
+// credentials redacted.
+func jobInputs(req interface{}) map[string]interface{} {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil
+	}
+	var inputs map[string]interface{}
+	if json.Unmarshal(b, &inputs) != nil {
+		return nil
+	}
+	for k, v := range inputs {
+		if s, ok := v.(string); ok && secretInputs[k] && s != "" {
+			inputs[k] = redacted
+		}
+	}
+	return inputs
+}
+
+// maskArgs returns command-line args with the values of credential flags
+// redacted.
+func maskArgs(args []string) []string {
+	masked := append([]string(nil), args...)
+	for i := 1; i < len(masked); i++ {
+		if !strings.Contains(args[i-1], "=") && secretFlag(args[i-1]) {
+			masked[i] = redacted
+		} else if flag, _, ok := strings.Cut(masked[i], "="); ok && secretFlag(flag) {
+			masked[i] = flag + "=" + redacted
+		}
+	}
+	return masked
+}
+
+// secretFlag reports whether a command-line flag takes a credential.
+func secretFlag(flag string) bool {
+	if !strings.HasPrefix(flag, "-") {
+		return false
+	}
+	flag = strings.ToLower(flag)
+	return strings.Contains(flag, "token") || strings.Contains(flag, "password") || strings.Contains(flag, "secret")
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Handlers
+// ──────────────────────────────────────────────────────────────────────────────
+
+// jobResponse is the synchronous JSON reply to a job request.
+type jobResponse struct {
+	Job    Job             `json:"job"`
+	Result json.RawMessage `json:"result,omitempty"` // payload of the "complete" event
+	Log    []string        `json:"log,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// startJob starts a job for a request and replies in the form the client
+// asked for (see wantsSSE): its events as SSE, or a jobResponse once it
+// finishes. The job carries on when the client disconnects; it can be
+// followed again from /api/v1/jobs/{id}/events. With "?detach=true" the job
+// is returned at once with 202 Accepted instead.
+func (s *Server) startJob(w http.ResponseWriter, r *http.Request, kind string, inputs interface{}, fn jobFunc) {
+	j, err := s.jobs.start(kind, inputs, fn)
+	if err != nil {
+		writeErr(w, "failed to start job: "+err.Error())
+		return
+	}
+	w.Header().Set("X-Knoxctl-Job", j.info.ID)
+	if detach, _ := strconv.ParseBool(r.URL.Query().Get("detach")); detach {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(j.snapshot())
+		return
+	}
+	if wantsSSE(r) {
+		streamJob(w, r, j, 0)
+		return
+	}
+	resp, err := j.wait(r.Context())
+	if err != nil {
+		return // the client went away; the job carries on
+	}
+	code := http.StatusOK
+	switch resp.Job.Status {
+	case JobFailed:
+		code = http.StatusUnprocessableEntity
+	case JobCancelled:
+		code = http.StatusConflict
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// wait blocks until the job finishes and collects its outcome, or returns
+// the context's error if it ends first.
+func (j *job) wait(ctx context.Context) (*jobResponse, error) {
+	resp := &jobResponse{}
+	seq := 0
+	for {
+		events, done, changed, err := j.eventsAfter(seq)
+		if err != nil {
+			return nil, err
+		}
+		for _, ev := range events {
+			seq = ev.Seq
+			var msg struct {
+				Line    string `json:"line"`
+				Message string `json:"message"`
+			}
+			_ = json.Unmarshal(ev.Data, &msg)
+			switch ev.Event {
+			case "complete":
+				resp.Result = ev.Data
+			case "error":
+				resp.Error = msg.Message
+			default:
+				if msg.Line != "" {
+					resp.Log = append(resp.Log, msg.Line)
+				} else if msg.Message != "" {
+					resp.Log = append(resp.Log, msg.Message)
+				}
+			}
+		}
+		if done {
+			resp.Job = j.snapshot()
+			return resp, nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// streamJob writes the events of j numbered above seq as SSE until the job
+// finishes or the client goes away.
+func streamJob(w http.ResponseWriter, r *http.Request, j *job, seq int) {
+	flusher, ok := sseInit(w)
+	if !ok {
+		return
+	}
+	for {
+		events, done, changed, err := j.eventsAfter(seq)
+		if err != nil {
+			writeEvent(w, jobEvent{Event: "error", Data: mustJSON(errMsg(err))})
+			flusher.Flush()
+			return
+		}
+		for _, ev := range events {
+			writeEvent(w, ev)
+			seq = ev.Seq
+		}
+		flusher.Flush()
+		if done {
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleJobs lists the job history, newest first, optionally filtered by
+// ?kind= and ?status= and limited by ?limit=.
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	kind, status := r.URL.Query().Get("kind"), r.URL.Query().Get("status")
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	jobs := []Job{}
+	for _, j := range s.jobs.list() {
+		if (kind != "" && j.Kind != kind) || (status != "" && j.Status != status) {
+			continue
+		}
+		if limit > 0 && len(jobs) == limit {
+			break
+		}
+		jobs = append(jobs, j)
+	}
+	writeJSON(w, jobs)
+}
+
+// handleJob serves GET (details) and DELETE (remove from the history) for a
+// job.
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.lookupJob(w, r)
+	if !ok {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, j.snapshot())
+	case http.MethodDelete:
+		if err := s.jobs.remove(j.info.ID); err != nil {
+			deny(w, r, err.Error(), http.StatusConflict)
+			return
+		}
+		writeJSON(w, map[string]string{"status": "deleted"})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleJobCancel cancels a queued or running job.
+func (s *Server) handleJobCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	j, ok := s.lookupJob(w, r)
+	if !ok {
+		return
+	}
+	j.cancelJob()
+	writeJSON(w, map[string]string{"status": "cancelling"})
+}
+
+// handleJobEvents streams the events of a job as SSE, from the start or
+// after the Last-Event-ID header or ?after= sequence number, following the
+// job until it finishes.
+func (s *Server) handleJobEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	j, ok := s.lookupJob(w, r)
+	if !ok {
+		return
+	}
+	after := r.Header.Get("Last-Event-ID")
+	if a := r.URL.Query().Get("after"); a != "" {
+		after = a
+	}
+	seq, _ := strconv.Atoi(after)
+	streamJob(w, r, j, seq)
+}
+
+// handleJobFile downloads a file a job produced.
+func (s *Server) handleJobFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	j, ok := s.lookupJob(w, r)
+	if !ok {
+		return
+	}
+	name := r.PathValue("name")
+	info := j.snapshot()
+	found := false
+	for _, f := range info.Files {
+		found = found || f == name
+	}
+	if !found {
+		deny(w, r, "no such file", http.StatusNotFound)
+		return
+	}
+	f, err := os.Open(filepath.Join(j.dir, name)) // #nosec G304 -- name is one of the job's files
+	if err != nil {
+		deny(w, r, "no such file", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	switch filepath.Ext(name) {
+	case ".json":
+		w.Header().Set("Content-Type", "application/json")
+	default:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	short := info.ID
+	if len(short) > 8 {
+		short = short[:8]
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", info.Kind+"-"+short+"-"+name))
+	_, _ = io.Copy(w, f)
+}
+
+func (s *Server) lookupJob(w http.ResponseWriter, r *http.Request) (*job, bool) {
+	j, ok := s.jobs.get(r.PathValue("id"))
+	if !ok {
+		deny(w, r, "no such job", http.StatusNotFound)
+	}
+	return j, ok
+}
+
+func writeEvent(w io.Writer, ev jobEvent) {
+	if ev.Seq > 0 {
+		fmt.Fprintf(w, "id: %d\n", ev.Seq)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Event, ev.Data)
+}
+
+func mustJSON(v interface{}) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return json.RawMessage(`{}`)
+	}
+	return b
+}
@@ -0,0 +1,244 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitJob waits for a job to finish and returns its record.
+func waitJob(t *testing.T, m *jobManager, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		j, ok := m.get(id)
+		if !ok {
+			t.Fatalf("job %s not found", id)
+		}
+		if info := j.snapshot(); !info.active() {
+			return info
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Job{}
+}
+
+func TestJobManager_PersistsHistory(t *testing.T) {
+	dir := t.TempDir()
+	m, err := newJobManager(dir)
+	if err != nil {
+		t.Fatalf("newJobManager: %v", err)
+	}
+	req := map[string]interface{}{"source": ".", "token": "hf_0123456789abcdef"}
+	j, err := m.start(JobSBOM, req, func(ctx context.Context, j *job) error {
+		j.send("log", map[string]string{"line": "scanning"})
+		return j.complete("sbom.json", 2, []byte(`{"components":[{},{}]}`), signReq{})
+	})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	info := waitJob(t, m, j.info.ID)
+	if info.Status != JobSucceeded || info.Count != 2 {
+		t.Fatalf("got status %s count %d", info.Status, info.Count)
+	}
+	if info.Inputs["token"] == "hf_0123456789abcdef" {
+		t.Errorf("token stored in the clear")
+	}
+	if got := strings.Join(info.Files, ","); got != "output.log,sbom.json" {
+		t.Errorf("files = %s", got)
+	}
+	if log, _ := os.ReadFile(filepath.Join(dir, j.info.ID, logFile)); !strings.Contains(string(log), "scanning") {
+		t.Errorf("output.log = %q", log)
+	}
+
+	// The stored event log leaves the BOM out and replay puts it back.
+	stored, _ := os.ReadFile(filepath.Join(dir, j.info.ID, eventsFile))
+	if strings.Contains(string(stored), "components") {
+		t.Errorf("BOM repeated in the event log")
+	}
+	events, done, _, err := j.eventsAfter(1)
+	if err != nil || !done {
+		t.Fatalf("eventsAfter: done %v err %v", done, err)
+	}
+	if len(events) != 2 || events[0].Event != "log" || events[1].Event != "complete" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	if !strings.Contains(string(events[1].Data), `\"components\"`) {
+		t.Errorf("complete event lacks the BOM: %s", events[1].Data)
+	}
+
+	m2, err := newJobManager(dir)
+	if err != nil {
+		t.Fatalf("reloading: %v", err)
+	}
+	if jobs := m2.list(); len(jobs) != 1 || jobs[0].ID != j.info.ID {
+		t.Fatalf("reloaded history %+v", jobs)
+	}
+	if st := m2.stats(); st.SBOM != 1 || st.CBOM != 0 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestJobManager_CancelAndFailure(t *testing.T) {
+	m, err := newJobManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("newJobManager: %v", err)
+	}
+	started := make(chan struct{})
+	j, _ := m.start(JobRun, nil, func(ctx context.Context, j *job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-started
+	j.cancelJob()
+	if info := waitJob(t, m, j.info.ID); info.Status != JobCancelled {
+		t.Errorf("cancelled job status = %s", info.Status)
+	}
+
+	j, _ = m.start(JobCBOM, nil, func(ctx context.Context, j *job) error {
+		return errors.New("no Go files")
+	})
+	if info := waitJob(t, m, j.info.ID); info.Status != JobFailed || info.Error != "no Go files" {
+		t.Errorf("failed job: status %s error %q", info.Status, info.Error)
+	}
+	if err := m.remove(j.info.ID); err != nil {
+		t.Errorf("remove: %v", err)
+	}
+	if _, ok := m.get(j.info.ID); ok {
+		t.Errorf("removed job still listed")
+	}
+}
+
+func TestJobManager_MarksInterruptedJobs(t *testing.T) {
+	dir := t.TempDir()
+	m, _ := newJobManager(dir)
+	release := make(chan struct{})
+	j, _ := m.start(JobRun, nil, func(ctx context.Context, j *job) error {
+		<-release
+		return nil
+	})
+
+	m2, err := newJobManager(dir)
+	if err != nil {
+		t.Fatalf("reloading: %v", err)
+	}
+	got, ok := m2.get(j.info.ID)
+	if !ok {
+		t.Fatalf("job not reloaded")
+	}
+	if info := got.snapshot(); info.Status != JobFailed || !strings.HasPrefix(info.Error, "interrupted") {
+		t.Errorf("got status %s error %q", info.Status, info.Error)
+	}
+	close(release)
+	waitJob(t, m, j.info.ID)
+}
+
+func TestJobManager_IgnoresNonUUIDDirectories(t *testing.T) {
+	dir := t.TempDir()
+	for _, id := range []string{"abc", "not-a-uuid-at-all"} {
+		if err := os.MkdirAll(filepath.Join(dir, id), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := json.Marshal(Job{ID: id, Kind: JobRun, Status: JobSucceeded})
+		if err := os.WriteFile(filepath.Join(dir, id, jobFile), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, err := newJobManager(dir)
+	if err != nil {
+		t.Fatalf("newJobManager: %v", err)
+	}
+	if jobs := m.list(); len(jobs) != 0 {
+		t.Errorf("loaded %d jobs from non-UUID directories", len(jobs))
+	}
+}
+
+func TestJobsAPI(t *testing.T) {
+	s, h := newTestServer(t)
+	j, _ := s.jobs.start(JobAIBOM, nil, func(ctx context.Context, j *job) error {
+		j.send("progress", progress(50, "half way"))
+		return j.complete("aibom.json", 1, []byte(`{"bomFormat":"CycloneDX"}`), signReq{})
+	})
+	waitJob(t, s.jobs, j.info.ID)
+
+	get := func(path string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:10100"+path, nil)
+		req.Header.Set("Authorization", "Bearer "+s.token)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		return serve(h, req)
+	}
+
+	var jobs []Job
+	if rec := get("/api/jobs?kind=aibom"); rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &jobs) != nil || len(jobs) != 1 {
+		t.Fatalf("list: %d %s", rec.Code, rec.Body)
+	}
+	if rec := get("/api/jobs?kind=sbom"); strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("filtered list: %s", rec.Body)
+	}
+
+	rec := get("/api/jobs/" + j.info.ID + "/events")
+	if body := rec.Body.String(); !strings.Contains(body, "id: 1\nevent: job") || !strings.Contains(body, "event: complete") {
+		t.Errorf("events: %s", body)
+	}
+	rec = get("/api/jobs/"+j.info.ID+"/events", "Last-Event-ID", "2")
+	if body := rec.Body.String(); strings.Contains(body, "event: progress") || !strings.Contains(body, "event: complete") {
+		t.Errorf("resumed events: %s", body)
+	}
+
+	rec = get("/api/jobs/" + j.info.ID + "/files/aibom.json")
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"bomFormat":"CycloneDX"}` {
+		t.Errorf("download: %d %s", rec.Code, rec.Body)
+	}
+	if rec := get("/api/jobs/" + j.info.ID + "/files/job.json"); rec.Code != http.StatusNotFound {
+		t.Errorf("download of an unlisted file: got %d, want 404", rec.Code)
+	}
+	if rec := get("/api/jobs/nope"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown job: got %d, want 404", rec.Code)
+	}
+
+	var cfg AppConfig
+	if rec := get("/api/config"); json.Unmarshal(rec.Body.Bytes(), &cfg) != nil || cfg.Dashboard.AIBOM != 1 {
+		t.Errorf("dashboard = %+v", cfg.Dashboard)
+	}
+}
+
+func TestMaskArgs(t *testing.T) {
+	got := strings.Join(maskArgs([]string{"onboard", "vm", "node", "--auth-token", "abcd1234efgh5678", "--join-token=wxyz9876stuv5432", "--url", "cp.example"}), " ")
+	want := "onboard vm node --auth-token •••••••• --join-token=•••••••• --url cp.example"
+	if got != want {
+		t.Errorf("maskArgs = %q, want %q", got, want)
+	}
+}
+
+func TestJobInputs(t *testing.T) {
+	got := jobInputs(map[string]string{
+		"token":           "abcd1234efgh5678",
+		"secretAccessKey": "wxyz9876stuv5432",
+		"sessionToken":    "",
+		"region":          "us-east-1",
+	})
+	want := map[string]interface{}{
+		"token":           redacted,
+		"secretAccessKey": redacted,
+		"sessionToken":    "",
+		"region":          "us-east-1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("jobInputs = %v, want %v", got, want)
+	}
+}
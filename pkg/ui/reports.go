@@ -0,0 +1,363 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"github.com/accuknox/accuknox-cli-v2/pkg/bom"
+	"github.com/accuknox/accuknox-cli-v2/pkg/scan"
+)
+
+// Report kinds. The scan kinds are named after the files "knoxctl scan"
+// writes, knoxctl_scan_<kind>_<time>.json.
+const (
+	ReportProcessTree = "process_tree"
+	ReportNetwork     = "network_events"
+	ReportAlerts      = "processed_alerts"
+	ReportBOM         = "bom"
+)
+
+// maxReportSize bounds the reports and BOMs the UI loads.
+const maxReportSize = 256 << 20
+
+// reportFile is a report or BOM found in the reports directory.
+type reportFile struct {
+	Name    string    `json:"name"`
+	Kind    string    `json:"kind"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// reportView is a report or BOM decoded for display. Exactly one of the
+// content fields is set, according to Kind.
+type reportView struct {
+	Kind string `json:"kind"`
+	Name string `json:"name,omitempty"`
+
+	ProcessTree   []*scan.ProcessNode  `json:"processTree,omitempty"`
+	NetworkEvents []*scan.NetworkEvent `json:"networkEvents,omitempty"`
+	Alerts        []scan.Alert         `json:"alerts,omitempty"`
+	BOM           *bomView             `json:"bom,omitempty"`
+}
+
+// bomView summarises a CycloneDX document: its subject and a flat list of
+// its components, nested components included.
+type bomView struct {
+	SpecVersion  string         `json:"specVersion"`
+	SerialNumber string         `json:"serialNumber,omitempty"`
+	Timestamp    string         `json:"timestamp,omitempty"`
+	Subject      string         `json:"subject,omitempty"`
+	Types        map[string]int `json:"types"`
+	Components   []bomComponent `json:"components"`
+}
+
+// bomComponent is a component of a BOM with the details the browser shows:
+// crypto properties for CBOM assets and the model card for AIBOM models.
+type bomComponent struct {
+	BOMRef      string                `json:"bomRef,omitempty"`
+	Type        string                `json:"type"`
+	Group       string                `json:"group,omitempty"`
+	Name        string                `json:"name"`
+	Version     string                `json:"version,omitempty"`
+	PURL        string                `json:"purl,omitempty"`
+	Licenses    string                `json:"licenses,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Parent      string                `json:"parent,omitempty"` // bom-ref of the enclosing component
+	Crypto      *cdx.CryptoProperties `json:"cryptoProperties,omitempty"`
+	Model       *cdx.MLModelCard      `json:"modelCard,omitempty"`
+	Properties  []cdx.Property        `json:"properties,omitempty"`
+}
+
+// listReports returns the scan reports and CycloneDX BOMs in dir, newest
+// first.
+func listReports(dir string) ([]reportFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", dir, err)
+	}
+	files := []reportFile{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		kind := reportKindFromName(e.Name())
+		if kind == "" && looksLikeBOM(filepath.Join(dir, e.Name())) {
+			kind = ReportBOM
+		}
+		if kind == "" {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, reportFile{Name: e.Name(), Kind: kind, Size: info.Size(), ModTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime.After(files[j].ModTime)
+	})
+	return files, nil
+}
+
+// reportKindFromName returns the kind of a scan report from its file name.
+func reportKindFromName(name string) string {
+	for _, kind := range []string{ReportProcessTree, ReportNetwork, ReportAlerts} {
+		if strings.HasPrefix(name, "knoxctl_scan_"+kind+"_") {
+			return kind
+		}
+	}
+	return ""
+}
+
+// looksLikeBOM reports whether the start of a JSON file declares a
+// CycloneDX document.
+func looksLikeBOM(path string) bool {
+	f, err := os.Open(path) // #nosec G304 -- a file of the reports directory
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, 4096)
+	n, _ := io.ReadFull(f, head)
+	return bytes.Contains(head[:n], []byte(`"bomFormat"`)) && bytes.Contains(head[:n], []byte(cdx.BOMFormat))
+}
+
+// decodeReport decodes a scan report or BOM, telling which from its
+// content.
+func decodeReport(data []byte) (*reportView, error) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("parsing report: %w", err)
+	}
+	switch {
+	case probe["bomFormat"] != nil:
+		b, err := bom.Decode(data)
+		if err != nil {
+			return nil, err
+		}
+		return &reportView{Kind: ReportBOM, BOM: newBOMView(b)}, nil
+
+	case probe["roots"] != nil:
+		var forest scan.ProcessForest
+		if err := json.Unmarshal(data, &forest); err != nil {
+			return nil, fmt.Errorf("parsing process tree: %w", err)
+		}
+		return &reportView{Kind: ReportProcessTree, ProcessTree: forest.Roots}, nil
+
+	case probe["networkEvents"] != nil:
+		var network struct {
+			NetworkEvents []*scan.NetworkEvent `json:"networkEvents"`
+		}
+		if err := json.Unmarshal(data, &network); err != nil {
+			return nil, fmt.Errorf("parsing network events: %w", err)
+		}
+		sort.SliceStable(network.NetworkEvents, func(i, j int) bool {
+			a, b := network.NetworkEvents[i], network.NetworkEvents[j]
+			if a.ProcessName != b.ProcessName {
+				return a.ProcessName < b.ProcessName
+			}
+			return a.PID < b.PID
+		})
+		return &reportView{Kind: ReportNetwork, NetworkEvents: network.NetworkEvents}, nil
+
+	default:
+		// Processed alerts are keyed by PID, then by alert.
+		var byPID map[int32]map[string]*scan.AlertPair
+		if err := json.Unmarshal(data, &byPID); err != nil {
+			return nil, fmt.Errorf("not a knoxctl scan report or CycloneDX BOM")
+		}
+		alerts := []scan.Alert{}
+		for _, pairs := range byPID {
+			for _, p := range pairs {
+				if p != nil && p.CustomAlert.PolicyName != "" {
+					alerts = append(alerts, p.CustomAlert)
+				}
+			}
+		}
+		if len(alerts) == 0 && len(byPID) > 0 {
+			return nil, fmt.Errorf("not a knoxctl scan report or CycloneDX BOM")
+		}
+		sort.SliceStable(alerts, func(i, j int) bool {
+			if alerts[i].Severity.Value != alerts[j].Severity.Value {
+				return alerts[i].Severity.Value > alerts[j].Severity.Value
+			}
+			if alerts[i].PolicyName != alerts[j].PolicyName {
+				return alerts[i].PolicyName < alerts[j].PolicyName
+			}
+			return alerts[i].PID < alerts[j].PID
+		})
+		return &reportView{Kind: ReportAlerts, Alerts: alerts}, nil
+	}
+}
+
+func newBOMView(b *cdx.BOM) *bomView {
+	v := &bomView{
+		SpecVersion:  b.SpecVersion.String(),
+		SerialNumber: b.SerialNumber,
+		Types:        map[string]int{},
+		Components:   []bomComponent{},
+	}
+	if b.Metadata != nil {
+		v.Timestamp = b.Metadata.Timestamp
+		if c := b.Metadata.Component; c != nil {
+			v.Subject = strings.TrimSuffix(c.Name+"@"+c.Version, "@")
+		}
+	}
+	var walk func(cs *[]cdx.Component, parent string)
+	walk = func(cs *[]cdx.Component, parent string) {
+		if cs == nil {
+			return
+		}
+		for i := range *cs {
+			c := &(*cs)[i]
+			bc := bomComponent{
+				BOMRef:      c.BOMRef,
+				Type:        string(c.Type),
+				Group:       c.Group,
+				Name:        c.Name,
+				Version:     c.Version,
+				PURL:        c.PackageURL,
+				Licenses:    licenseNames(c.Licenses),
+				Description: c.Description,
+				Parent:      parent,
+				Crypto:      c.CryptoProperties,
+				Model:       c.ModelCard,
+			}
+			if c.Properties != nil {
+				bc.Properties = *c.Properties
+			}
+			v.Types[bc.Type]++
+			v.Components = append(v.Components, bc)
+			walk(c.Components, c.BOMRef)
+		}
+	}
+	walk(b.Components, "")
+	return v
+}
+
+// licenseNames renders licenses as a comma-separated list.
+func licenseNames(ls *cdx.Licenses) string {
+	if ls == nil {
+		return ""
+	}
+	var names []string
+	for _, lc := range *ls {
+		switch {
+		case lc.Expression != "":
+			names = append(names, lc.Expression)
+		case lc.License != nil && lc.License.ID != "":
+			names = append(names, lc.License.ID)
+		case lc.License != nil:
+			names = append(names, lc.License.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// filterComponents keeps the components of type typ (when set) that
+// mention q (when set) in their name, group, version, purl, licenses or
+// crypto asset type.
+func (v *bomView) filterComponents(q, typ string) {
+	q = strings.ToLower(q)
+	kept := v.Components[:0]
+	for _, c := range v.Components {
+		if typ != "" && c.Type != typ {
+			continue
+		}
+		if q != "" {
+			text := strings.ToLower(strings.Join([]string{c.Name, c.Group, c.Version, c.PURL, c.Licenses}, " "))
+			if c.Crypto != nil {
+				text += " " + strings.ToLower(string(c.Crypto.AssetType))
+			}
+			if !strings.Contains(text, q) {
+				continue
+			}
+		}
+		kept = append(kept, c)
+	}
+	v.Components = kept
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Handlers
+// ──────────────────────────────────────────────────────────────────────────────
+
+// handleReports lists the scan reports and BOMs in the reports directory.
+func (s *Server) handleReports(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	files, err := listReports(s.reportsDir)
+	if err != nil {
+		writeErr(w, err.Error())
+		return
+	}
+	dir, _ := filepath.Abs(s.reportsDir)
+	writeJSON(w, map[string]interface{}{"dir": dir, "files": files})
+}
+
+// handleReport decodes a report or BOM of the reports directory. BOM
+// components can be filtered with ?q= and ?type=.
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := r.PathValue("name")
+	if name != filepath.Base(name) || !strings.HasSuffix(name, ".json") {
+		deny(w, r, "no such report", http.StatusNotFound)
+		return
+	}
+	f, err := os.Open(filepath.Join(s.reportsDir, name)) // #nosec G304 -- a file of the reports directory
+	if err != nil {
+		deny(w, r, "no such report", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	s.writeReport(w, r, name, f)
+}
+
+// handleReportView decodes a report or BOM uploaded as the request body, for
+// files outside the reports directory and BOMs from the job history.
+func (s *Server) handleReportView(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.writeReport(w, r, r.URL.Query().Get("name"), r.Body)
+}
+
+func (s *Server) writeReport(w http.ResponseWriter, r *http.Request, name string, src io.Reader) {
+	data, err := io.ReadAll(io.LimitReader(src, maxReportSize+1))
+	if err != nil {
+		writeErr(w, "reading report: "+err.Error())
+		return
+	}
+	if len(data) > maxReportSize {
+		writeErr(w, fmt.Sprintf("report is larger than %d MiB", maxReportSize>>20))
+		return
+	}
+	view, err := decodeReport(data)
+	if err != nil {
+		writeErr(w, err.Error())
+		return
+	}
+	view.Name = name
+	if view.BOM != nil {
+		view.BOM.filterComponents(r.URL.Query().Get("q"), r.URL.Query().Get("type"))
+	}
+	writeJSON(w, view)
+}
@@ -0,0 +1,132 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package ui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"github.com/accuknox/accuknox-cli-v2/pkg/scan"
+)
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return b
+}
+
+func testCBOM(t *testing.T) []byte {
+	t.Helper()
+	b := cdx.NewBOM()
+	b.Metadata = &cdx.Metadata{Component: &cdx.Component{Name: "payments", Version: "1.2.0"}}
+	b.Components = &[]cdx.Component{
+		{
+			BOMRef: "app", Type: cdx.ComponentTypeApplication, Name: "payments",
+			Components: &[]cdx.Component{{
+				BOMRef: "crypto/aes", Type: cdx.ComponentTypeCryptographicAsset, Name: "AES-256-GCM",
+				CryptoProperties: &cdx.CryptoProperties{AssetType: cdx.CryptoAssetTypeAlgorithm},
+			}},
+		},
+		{BOMRef: "pkg:golang/x/crypto", Type: cdx.ComponentTypeLibrary, Name: "golang.org/x/crypto", Version: "v0.31.0", PackageURL: "pkg:golang/golang.org/x/crypto@v0.31.0"},
+	}
+	return mustMarshal(t, b)
+}
+
+func TestDecodeReport(t *testing.T) {
+	tree := mustMarshal(t, &scan.ProcessForest{Roots: []*scan.ProcessNode{{
+		ProcessName: "bash", PID: 1,
+		Children: []*scan.ProcessNode{{ProcessName: "curl", PID: 2, PPID: 1, Command: "curl example.com"}},
+	}}})
+	v, err := decodeReport(tree)
+	if err != nil || v.Kind != ReportProcessTree || len(v.ProcessTree) != 1 || len(v.ProcessTree[0].Children) != 1 {
+		t.Fatalf("process tree: %+v, %v", v, err)
+	}
+
+	network := mustMarshal(t, map[string]interface{}{"networkEvents": []*scan.NetworkEvent{
+		{ProcessName: "wget", PID: 9, RemoteIP: "10.0.0.1", Port: 443, Flow: "egress", Protocol: "TCP"},
+		{ProcessName: "curl", PID: 2, RemoteDomain: "example.com", Port: 80, Flow: "egress", Protocol: "TCP"},
+	}})
+	v, err = decodeReport(network)
+	if err != nil || v.Kind != ReportNetwork || len(v.NetworkEvents) != 2 || v.NetworkEvents[0].ProcessName != "curl" {
+		t.Fatalf("network events: %+v, %v", v, err)
+	}
+
+	alerts := mustMarshal(t, map[int32]map[string]map[string]interface{}{
+		2: {"a": {"CustomAlert": scan.Alert{PolicyName: "low", Severity: scan.SeverityLow}}},
+		3: {"b": {"CustomAlert": scan.Alert{PolicyName: "critical", Severity: scan.SeverityCritical}}},
+	})
+	v, err = decodeReport(alerts)
+	if err != nil || v.Kind != ReportAlerts || len(v.Alerts) != 2 || v.Alerts[0].PolicyName != "critical" {
+		t.Fatalf("alerts: %+v, %v", v, err)
+	}
+
+	v, err = decodeReport(testCBOM(t))
+	if err != nil || v.Kind != ReportBOM {
+		t.Fatalf("BOM: %+v, %v", v, err)
+	}
+	if v.BOM.Subject != "payments@1.2.0" || len(v.BOM.Components) != 3 || v.BOM.Types["cryptographic-asset"] != 1 {
+		t.Fatalf("BOM view: %+v", v.BOM)
+	}
+	if c := v.BOM.Components[1]; c.Parent != "app" || c.Crypto == nil || c.Crypto.AssetType != cdx.CryptoAssetTypeAlgorithm {
+		t.Errorf("nested crypto asset: %+v", c)
+	}
+	v.BOM.filterComponents("AES", "")
+	if len(v.BOM.Components) != 1 || v.BOM.Components[0].Name != "AES-256-GCM" {
+		t.Errorf("filtered components: %+v", v.BOM.Components)
+	}
+
+	if _, err := decodeReport([]byte(`{"hello":"world"}`)); err == nil {
+		t.Errorf("decoded an unrelated document")
+	}
+}
+
+func TestReportsAPI(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewServer(&Options{Addr: "127.0.0.1:10100", JobsDir: t.TempDir(), ReportsDir: dir})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	h := s.secure(s.mux)
+	write := func(name string, data []byte) {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("knoxctl_scan_network_events_2025-01-01_10-00-00.json", []byte(`{"networkEvents":[]}`))
+	write("cbom.json", testCBOM(t))
+	write("package.json", []byte(`{"name":"not-a-report"}`))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "http://localhost:10100"+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+s.token)
+		return serve(h, req)
+	}
+
+	var list struct {
+		Files []reportFile `json:"files"`
+	}
+	if rec := do(http.MethodGet, "/api/reports", ""); json.Unmarshal(rec.Body.Bytes(), &list) != nil || len(list.Files) != 2 {
+		t.Fatalf("list: %s", rec.Body)
+	}
+
+	var v reportView
+	if rec := do(http.MethodGet, "/api/reports/cbom.json?type=library", ""); json.Unmarshal(rec.Body.Bytes(), &v) != nil || len(v.BOM.Components) != 1 {
+		t.Fatalf("view: %s", rec.Body)
+	}
+	if rec := do(http.MethodGet, "/api/reports/..%2Fjobs.json", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("path outside the reports directory: got %d, want 404", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/reports/view?name=upload.json", `{"roots":[]}`); json.Unmarshal(rec.Body.Bytes(), &v) != nil || v.Kind != ReportProcessTree || v.Name != "upload.json" {
+		t.Errorf("upload: %s", rec.Body)
+	}
+}
@@ -0,0 +1,297 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package ui
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// sessionCookie carries the session token once the browser has opened the
+// URL printed on start.
+const sessionCookie = "knoxctl_ui_session"
+
+// tokenHeader carries the session token for non-browser clients, as an
+// alternative to "Authorization: Bearer <token>".
+const tokenHeader = "X-Knoxctl-Token"
+
+// MinTokenLength is the shortest API token Options.Token may give.
+const MinTokenLength = 16
+
+// DefaultRunCommands lists the read-only knoxctl commands /api/run may invoke.
+// An entry of several words ("pkgscan scan") allows that command and its
+// subcommands. Commands that change the host or schedule work, such as
+// "image-scan" and "onboard vm", must be allowed with Options.AllowCommands.
+var DefaultRunCommands = []string{
+	"probe",
+	"pkgscan scan",
+	"version",
+}
+
+// newSessionToken returns a random 256-bit token, hex encoded.
+func newSessionToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating session token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// secure wraps the mux with the session checks: the Host header must name
+// the server, cross-origin requests are refused, and every request must
+// carry the session token in the session cookie, a tokenHeader or bearer
+// header. Opening "/?token=<token>" sets the cookie and redirects to "/", so
+// the token does not stay in the address bar.
+func (s *Server) secure(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedHost(r.Host) {
+			deny(w, r, "unknown host", http.StatusMisdirectedRequest)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if !s.allowedOrigin(origin) {
+				deny(w, r, "cross-origin request refused", http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+tokenHeader)
+			w.Header().Add("Vary", "Origin")
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+
+		if t := r.URL.Query().Get("token"); t != "" && r.Method == http.MethodGet && s.validToken(t) {
+			http.SetCookie(w, &http.Cookie{
+				Name:     sessionCookie,
+				Value:    t,
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   s.tlsConfig != nil,
+				SameSite: http.SameSiteStrictMode,
+			})
+			q := r.URL.Query()
+			q.Del("token")
+			u := *r.URL
+			u.RawQuery = q.Encode()
+			http.Redirect(w, r, u.RequestURI(), http.StatusSeeOther)
+			return
+		}
+		if r.URL.Path == apiV1+"/openapi.yaml" {
+			// The API description is public so clients can discover how to
+			// authenticate.
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !s.authenticated(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="knoxctl"`)
+			deny(w, r, "unauthorized: open the URL printed by 'knoxctl ui', which carries the session token, or send the API token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// deny rejects a request, with a JSON error body for API calls so the UI can
+// show the reason.
+func deny(w http.ResponseWriter, r *http.Request, msg string, code int) {
+	if !strings.HasPrefix(r.URL.Path, "/api/") {
+		http.Error(w, msg, code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+func (s *Server) authenticated(r *http.Request) bool {
+	if c, err := r.Cookie(sessionCookie); err == nil && s.validToken(c.Value) {
+		return true
+	}
+	if t := r.Header.Get(tokenHeader); t != "" {
+		return s.validToken(t)
+	}
+	if t, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return s.validToken(t)
+	}
+	return false
+}
+
+func (s *Server) validToken(t string) bool {
+	return subtle.ConstantTimeCompare([]byte(t), []byte(s.token)) == 1
+}
+
+// allowedHost reports whether host (a Host header) names this server, which
+// guards against DNS rebinding.
+func (s *Server) allowedHost(host string) bool {
+	h, _, err := net.SplitHostPort(host)
+	if err != nil {
+		h = host
+	}
+	h = strings.Trim(h, "[]")
+	switch h {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	bind, _, _ := net.SplitHostPort(s.addr)
+	if bind == "" || bind == "0.0.0.0" || bind == "::" {
+		// Listening on every interface: accept the addresses of this host.
+		return isLocalAddress(h)
+	}
+	return strings.EqualFold(h, bind)
+}
+
+// allowedOrigin reports whether origin is the UI's own origin.
+func (s *Server) allowedOrigin(origin string) bool {
+	scheme := "http://"
+	if s.tlsConfig != nil {
+		scheme = "https://"
+	}
+	host, ok := strings.CutPrefix(origin, scheme)
+	if !ok {
+		return false
+	}
+	_, port, err := net.SplitHostPort(host)
+	return err == nil && port == portFrom(s.addr) && s.allowedHost(host)
+}
+
+func isLocalAddress(h string) bool {
+	ip := net.ParseIP(h)
+	if ip == nil {
+		return false
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok && n.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLoopback reports whether addr ("host:port") only listens on loopback.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// allowedRun reports whether args invoke one of the commands /api/run may
+// run.
+func (s *Server) allowedRun(args []string) bool {
+	for _, c := range s.runCommands {
+		words := strings.Fields(c)
+		if len(words) == 0 || len(args) < len(words) {
+			continue
+		}
+		match := true
+		for i, w := range words {
+			if args[i] != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// loadTLS returns the server's TLS configuration: the given certificate and
+// key, or a self-signed certificate for the listen address generated for
+// this session.
+func loadTLS(certFile, keyFile, addr string) (*tls.Config, string, error) {
+	var cert tls.Certificate
+	var err error
+	if certFile != "" || keyFile != "" {
+		cert, err = tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, "", fmt.Errorf("loading TLS certificate: %w", err)
+		}
+	} else if cert, err = selfSignedCert(addr); err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(cert.Certificate[0])
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	return cfg, hex.EncodeToString(sum[:]), nil
+}
+
+// selfSignedCert generates an ECDSA P-256 certificate valid for a day for
+// localhost, the loopback addresses and the host of addr.
+func selfSignedCert(addr string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("generating TLS key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("generating certificate serial: %w", err)
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "knoxctl ui", Organization: []string{"AccuKnox"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil && host != "" && host != "localhost" {
+		if ip := net.ParseIP(host); ip == nil {
+			tmpl.DNSNames = append(tmpl.DNSNames, host)
+		} else if !ip.IsUnspecified() && !ip.IsLoopback() {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("creating TLS certificate: %w", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// maskToken hides all but the ends of a stored credential, for showing the
+// user which control-plane token is configured. Job history uses redacted.
+func maskToken(t string) string {
+	if t == "" {
+		return ""
+	}
+	if len(t) <= 8 {
+		return "••••••••"
+	}
+	return t[:4] + "••••" + t[len(t)-4:]
+}
@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of KubeArmor
+
+package ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestServer(t *testing.T) (*Server, http.Handler) {
+	t.Helper()
+	s, err := NewServer(&Options{Addr: "127.0.0.1:10100", Version: "test", JobsDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	return s, s.secure(s.mux)
+}
+
+func serve(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSecure_RequiresToken(t *testing.T) {
+	s, h := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:10100/api/version", nil)
+	if rec := serve(h, req); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("no token: got %d, want 401", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "http://localhost:10100/api/version", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	if rec := serve(h, req); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong token: got %d, want 401", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "http://localhost:10100/api/version", nil)
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	if rec := serve(h, req); rec.Code != http.StatusOK {
+		t.Fatalf("bearer token: got %d, want 200", rec.Code)
+	}
+}
+
+func TestSecure_TokenURLSetsCookie(t *testing.T) {
+	s, h := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:10100/?token="+s.token, nil)
+	rec := serve(h, req)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/" {
+		t.Fatalf("got %d to %q, want redirect to /", rec.Code, rec.Header().Get("Location"))
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != sessionCookie || !cookies[0].HttpOnly {
+		t.Fatalf("unexpected cookies %+v", cookies)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "http://localhost:10100/api/version", nil)
+	req.AddCookie(cookies[0])
+	if rec := serve(h, req); rec.Code != http.StatusOK {
+		t.Fatalf("cookie: got %d, want 200", rec.Code)
+	}
+}
+
+func TestSecure_RefusesForeignOriginAndHost(t *testing.T) {
+	s, h := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:10100/api/run", strings.NewReader(`{"args":["version"]}`))
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	req.Header.Set("Origin", "https://evil.example")
+	if rec := serve(h, req); rec.Code != http.StatusForbidden {
+		t.Fatalf("foreign origin: got %d, want 403", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "http://rebound.example:10100/api/version", nil)
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	if rec := serve(h, req); rec.Code != http.StatusMisdirectedRequest {
+		t.Fatalf("foreign host: got %d, want 421", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodOptions, "http://localhost:10100/api/run", nil)
+	req.Header.Set("Origin", "http://localhost:10100")
+	rec := serve(h, req)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "http://localhost:10100" {
+		t.Fatalf("own origin preflight: got %d, allow-origin %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestAllowedRun(t *testing.T) {
+	s, _ := newTestServer(t)
+	for args, want := range map[string]bool{
+		"image-scan --local":          false,
+		"onboard vm cp-node --url x":  false,
+		"probe":                       true,
+		"onboard cluster":             false,
+		"onboard":                     false,
+		"uninstall":                   false,
+		"--kubeconfig x probe":        false,
+		"pkgscan scan dir:.":          true,
+		"pkgscan --help":              false,
+		"version":                     true,
+		"image-scanner --nonexistent": false,
+	} {
+		if got := s.allowedRun(strings.Fields(args)); got != want {
+			t.Errorf("allowedRun(%q) = %v, want %v", args, got, want)
+		}
+	}
+}
+
+func TestAllowedRun_AllowCommands(t *testing.T) {
+	s, err := NewServer(&Options{
+		Addr:          "127.0.0.1:10100",
+		Version:       "test",
+		JobsDir:       t.TempDir(),
+		AllowCommands: []string{"image-scan", "onboard vm"},
+	})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	for args, want := range map[string]bool{
+		"image-scan --schedule @hourly": true,
+		"onboard vm cp-node --url x":    true,
+		"onboard cluster":               false,
+		"version":                       true,
+	} {
+		if got := s.allowedRun(strings.Fields(args)); got != want {
+			t.Errorf("allowedRun(%q) = %v, want %v", args, got, want)
+		}
+	}
+}
+
+func TestMaskToken(t *testing.T) {
+	if got := maskToken("abcd1234efgh5678"); got != "abcd••••5678" {
+		t.Errorf("maskToken = %q", got)
+	}
+	if got := maskToken("short"); strings.Contains(got, "short") {
+		t.Errorf("maskToken leaked a short token: %q", got)
+	}
+}
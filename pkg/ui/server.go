@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -29,9 +30,51 @@ import (
 
 // Server is the knoxctl embedded web UI HTTP server.
 type Server struct {
-	addr    string
-	version string
-	mux     *http.ServeMux
+	addr        string
+	version     string
+	mux         *http.ServeMux
+	token       string      // per-session token required on every request
+	tlsConfig   *tls.Config // nil when serving plain HTTP
+	certSHA256  string      // fingerprint of the TLS certificate
+	runCommands []string    // commands /api/run may invoke
+	jobs        *jobManager
+	reportsDir  string // where scan reports and BOMs are browsed
+	headless    bool   // API only: no bundled UI
+	noBrowser   bool   // never open a browser
+	tokenGiven  bool   // the token came from Options.Token and is not printed
+}
+
+// Options configures the UI server.
+type Options struct {
+	Addr    string // listen address, e.g. "127.0.0.1:10100"
+	Version string
+
+	// TLS serves HTTPS, with CertFile and KeyFile when set and with a
+	// self-signed certificate generated on start otherwise.
+	TLS      bool
+	CertFile string
+	KeyFile  string
+
+	// AllowCommands extends DefaultRunCommands, e.g. with "image-scan".
+	AllowCommands []string
+
+	// JobsDir keeps the job history (default DefaultJobsDir()).
+	JobsDir string
+
+	// ReportsDir holds the "knoxctl scan" reports and BOM files the UI
+	// browses (default the working directory).
+	ReportsDir string
+
+	// Headless serves the REST API only: the bundled UI is not served and
+	// no browser is opened.
+	Headless bool
+
+	// NoBrowser serves the UI without opening it in a browser.
+	NoBrowser bool
+
+	// Token is the API token clients must send, at least MinTokenLength
+	// characters. A random session token is generated when it is empty.
+	Token string
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -42,7 +85,7 @@ type Server struct {
 // ~/.knoxctl-cfg.yaml (Linux/macOS) or knoxctl-cfg.yaml (Windows).
 type AppConfig struct {
 	BOM       BOMSettings    `yaml:"bom"       json:"bom"`
-	Dashboard DashboardStats `yaml:"dashboard" json:"dashboard"`
+	Dashboard DashboardStats `yaml:"-"         json:"dashboard"` // derived from the job history
 }
 
 // BOMSettings holds the AccuKnox control-plane connection parameters used to
@@ -54,7 +97,8 @@ type BOMSettings struct {
 	Token        string `yaml:"token"         json:"token"`
 }
 
-// DashboardStats holds the persistent operation counters shown on the dashboard.
+// DashboardStats holds the operation counters shown on the dashboard: the
+// number of jobs of each kind in the history that succeeded.
 type DashboardStats struct {
 	SBOM  int `yaml:"sbom"  json:"sbom"`
 	CBOM  int `yaml:"cbom"  json:"cbom"`
@@ -91,83 +135,159 @@ func saveAppConfig(cfg AppConfig) error {
 	return os.WriteFile(configFilePath(), data, 0600) // #nosec G306
 }
 
-// NewServer creates a new Server listening on addr (e.g. "0.0.0.0:10100").
-func NewServer(addr, version string) *Server {
-	s := &Server{addr: addr, version: version}
+// NewServer creates a new Server listening on opts.Addr with a fresh
+// session token.
+func NewServer(opts *Options) (*Server, error) {
+	token := opts.Token
+	var err error
+	if token == "" {
+		if token, err = newSessionToken(); err != nil {
+			return nil, err
+		}
+	} else if len(token) < MinTokenLength {
+		return nil, fmt.Errorf("the API token must be at least %d characters long", MinTokenLength)
+	}
+	s := &Server{
+		addr:        opts.Addr,
+		version:     opts.Version,
+		token:       token,
+		runCommands: append(append([]string{}, DefaultRunCommands...), opts.AllowCommands...),
+		reportsDir:  opts.ReportsDir,
+		headless:    opts.Headless,
+		noBrowser:   opts.Headless || opts.NoBrowser,
+		tokenGiven:  opts.Token != "",
+	}
+	if s.reportsDir == "" {
+		s.reportsDir = "."
+	}
+	if opts.TLS || opts.CertFile != "" || opts.KeyFile != "" {
+		if s.tlsConfig, s.certSHA256, err = loadTLS(opts.CertFile, opts.KeyFile, opts.Addr); err != nil {
+			return nil, err
+		}
+	}
+	if s.jobs, err = newJobManager(opts.JobsDir); err != nil {
+		return nil, err
+	}
 	s.mux = http.NewServeMux()
 	s.registerRoutes()
-	return s
+	return s, nil
 }
 
-// Start starts the HTTP server and opens the UI in the default browser.
+// Start starts the HTTP server and, unless told otherwise, opens the UI in
+// the default browser.
 func (s *Server) Start() error {
-	url := "http://localhost:" + portFrom(s.addr)
-	fmt.Printf("knoxctl UI  →  %s\n", url)
+	scheme := "http"
+	if s.tlsConfig != nil {
+		scheme = "https"
+	}
+	base := scheme + "://localhost:" + portFrom(s.addr)
+	if s.headless {
+		fmt.Printf("knoxctl API  →  %s%s  (spec: %s%s/openapi.yaml)\n", base, apiV1, base, apiV1)
+		if !s.tokenGiven {
+			fmt.Printf("API token: %s\n", s.token)
+		}
+	} else if s.tokenGiven {
+		fmt.Printf("knoxctl UI  →  %s/?token=<your API token>\n", base)
+	} else {
+		fmt.Printf("knoxctl UI  →  %s/?token=%s\n", base, s.token)
+	}
 	fmt.Printf("Listening on %s  (Ctrl-C to stop)\n", s.addr)
+	if s.certSHA256 != "" {
+		fmt.Printf("TLS certificate SHA-256: %s\n", s.certSHA256)
+	}
+	if !isLoopback(s.addr) {
+		fmt.Fprintf(os.Stderr, "Warning: the server is reachable from other hosts on %s; anyone with the token can run knoxctl commands here\n", s.addr)
+	}
 
-	// Open browser after a short delay so the server is ready.
-	go func() {
-		time.Sleep(500 * time.Millisecond)
-		openBrowser(url)
-	}()
+	if !s.noBrowser && !s.tokenGiven {
+		// Open browser after a short delay so the server is ready.
+		go func() {
+			time.Sleep(500 * time.Millisecond)
+			openBrowser(base + "/?token=" + s.token)
+		}()
+	}
 
 	srv := &http.Server{
 		Addr:              s.addr,
-		Handler:           s.mux,
+		Handler:           s.secure(s.mux),
 		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig:         s.tlsConfig,
+	}
+	if s.tlsConfig != nil {
+		return srv.ListenAndServeTLS("", "")
 	}
 	return srv.ListenAndServe()
 }
 
 // registerRoutes wires all HTTP handlers.
 func (s *Server) registerRoutes() {
-	// Static assets — serve the embedded SPA for every non-API route.
-	sub, _ := fs.Sub(StaticFS, "static")
-	fileServer := http.FileServer(http.FS(sub))
-	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		// All non-API paths fall through to index.html (SPA routing).
-		if r.URL.Path != "/" {
-			_, err := fs.Stat(sub, strings.TrimPrefix(r.URL.Path, "/"))
-			if err != nil {
-				r.URL.Path = "/"
+	if !s.headless {
+		// Static assets — serve the embedded SPA for every non-API route.
+		sub, _ := fs.Sub(StaticFS, "static")
+		fileServer := http.FileServer(http.FS(sub))
+		s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			// All non-API paths fall through to index.html (SPA routing).
+			if r.URL.Path != "/" {
+				_, err := fs.Stat(sub, strings.TrimPrefix(r.URL.Path, "/"))
+				if err != nil {
+					r.URL.Path = "/"
+				}
 			}
-		}
-		fileServer.ServeHTTP(w, r)
-	})
+			fileServer.ServeHTTP(w, r)
+		})
+	}
+
+	// API — description
+	s.mux.HandleFunc(apiV1+"/openapi.yaml", handleOpenAPI)
 
 	// API — version
-	s.mux.HandleFunc("/api/version", cors(s.handleVersion))
+	s.api("/version", s.handleVersion)
 
 	// API — config (GET=load, POST=save)
-	s.mux.HandleFunc("/api/config", cors(s.handleConfig))
-	s.mux.HandleFunc("/api/config/projects", cors(s.handleFetchProjects))
-	s.mux.HandleFunc("/api/config/labels", cors(s.handleFetchLabels))
+	s.api("/config", s.handleConfig)
+	s.api("/config/projects", s.handleFetchProjects)
+	s.api("/config/labels", s.handleFetchLabels)
 
 	// API — SBOM
-	s.mux.HandleFunc("/api/sbom/generate", cors(s.handleSBOM))
-	s.mux.HandleFunc("/api/sbom/publish", cors(func(w http.ResponseWriter, r *http.Request) {
+	s.api("/sbom/generate", s.handleSBOM)
+	s.api("/sbom/publish", func(w http.ResponseWriter, r *http.Request) {
 		s.handlePublishBOM(w, r, "sbom")
-	}))
+	})
 
 	// API — CBOM publish
-	s.mux.HandleFunc("/api/cbom/publish", cors(func(w http.ResponseWriter, r *http.Request) {
+	s.api("/cbom/publish", func(w http.ResponseWriter, r *http.Request) {
 		s.handlePublishBOM(w, r, "cbom")
-	}))
+	})
 
 	// API — AIBOM publish
-	s.mux.HandleFunc("/api/aibom/publish", cors(func(w http.ResponseWriter, r *http.Request) {
+	s.api("/aibom/publish", func(w http.ResponseWriter, r *http.Request) {
 		s.handlePublishBOM(w, r, "aibom")
-	}))
+	})
 
 	// API — CBOM
-	s.mux.HandleFunc("/api/cbom/source", cors(s.handleCBOMSource))
-	s.mux.HandleFunc("/api/cbom/image", cors(s.handleCBOMImage))
+	s.api("/cbom/source", s.handleCBOMSource)
+	s.api("/cbom/image", s.handleCBOMImage)
 
 	// API — AIBOM
-	s.mux.HandleFunc("/api/aibom/generate", cors(s.handleAIBOM))
+	s.api("/aibom/generate", s.handleAIBOM)
+
+	// API — signing
+	s.api("/sign", s.handleSign)
 
 	// API — generic CLI runner (image-scan, probe, vm, sbom)
-	s.mux.HandleFunc("/api/run", cors(s.handleRun))
+	s.api("/run", s.handleRun)
+
+	// API — job history
+	s.api("/jobs", s.handleJobs)
+	s.api("/jobs/{id}", s.handleJob)
+	s.api("/jobs/{id}/cancel", s.handleJobCancel)
+	s.api("/jobs/{id}/events", s.handleJobEvents)
+	s.api("/jobs/{id}/files/{name}", s.handleJobFile)
+
+	// API — scan reports and BOM browser
+	s.api("/reports", s.handleReports)
+	s.api("/reports/view", s.handleReportView)
+	s.api("/reports/{name}", s.handleReport)
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -201,17 +321,25 @@ func binarySHA256() string {
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
-// handleConfig serves GET (load) and POST (save) for the persistent app config.
+// handleConfig serves GET (load) and POST (save) for the persistent app
+// config. The control-plane token is never sent back: GET masks it, and a
+// POST carrying the masked value keeps the stored token.
 func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		writeJSON(w, loadAppConfig())
+		cfg := loadAppConfig()
+		cfg.BOM.Token = maskToken(cfg.BOM.Token)
+		cfg.Dashboard = s.jobs.stats()
+		writeJSON(w, cfg)
 	case http.MethodPost:
 		var cfg AppConfig
 		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
 			writeErr(w, "invalid request: "+err.Error())
 			return
 		}
+		if stored := loadAppConfig().BOM.Token; stored != "" && cfg.BOM.Token == maskToken(stored) {
+			cfg.BOM.Token = stored
+		}
 		if err := saveAppConfig(cfg); err != nil {
 			writeErr(w, "failed to save config: "+err.Error())
 			return
@@ -306,12 +434,21 @@ func (s *Server) handlePublishBOM(w http.ResponseWriter, r *http.Request, bomTyp
 		return
 	}
 	var req struct {
-		BOM string `json:"bom"`
+		BOM   string `json:"bom"`
+		JobID string `json:"jobId"` // publish the BOM a job produced instead
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeErr(w, "invalid request: "+err.Error())
 		return
 	}
+	if req.BOM == "" && req.JobID != "" {
+		_, data, err := s.jobBOM(req.JobID)
+		if err != nil {
+			writeErr(w, err.Error())
+			return
+		}
+		req.BOM = string(data)
+	}
 	if req.BOM == "" {
 		writeErr(w, "bom payload is empty")
 		return
@@ -397,122 +534,96 @@ func (s *Server) handleSBOM(w http.ResponseWriter, r *http.Request) {
 		req.Format = "cyclonedx-json"
 	}
 
-	send, flush, ok := sseInit(w)
-	if !ok {
-		return
-	}
-
-	send("progress", progress(10, "Initialising Software BOM generation…"))
-	flush()
-	if ctxDone(r.Context(), send, flush) {
-		return
-	}
+	s.startJob(w, r, JobSBOM, req, func(ctx context.Context, j *job) error {
+		send := j.send
+		send("progress", progress(10, "Initialising Software BOM generation…"))
 
-	// pkgscan writes output via "-o format=file".
-	tmpDir, err := os.MkdirTemp("", "knoxctl-sbom-*")
-	if err != nil {
-		send("error", errMsg(err))
-		flush()
-		return
-	}
-	defer func() {
-		if err := os.RemoveAll(tmpDir); err != nil {
-			fmt.Fprintf(os.Stderr, "warning: failed to remove temp dir %s: %v\n", tmpDir, err)
+		// pkgscan writes output via "-o format=file".
+		tmpDir, err := os.MkdirTemp("", "knoxctl-sbom-*")
+		if err != nil {
+			return err
 		}
-	}()
-	outFile := filepath.Join(tmpDir, "sbom.json")
+		defer func() {
+			if err := os.RemoveAll(tmpDir); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: failed to remove temp dir %s: %v\n", tmpDir, err)
+			}
+		}()
+		outFile := filepath.Join(tmpDir, "sbom.json")
 
-	// Prefix source with scheme when explicitly chosen.
-	source := req.Source
-	if req.Scheme != "" {
-		source = req.Scheme + ":" + source
-	}
+		// Prefix source with scheme when explicitly chosen.
+		source := req.Source
+		if req.Scheme != "" {
+			source = req.Scheme + ":" + source
+		}
 
-	args := []string{"pkgscan", "scan", source, "-o", req.Format + "=" + outFile}
+		args := []string{"pkgscan", "scan", source, "-o", req.Format + "=" + outFile}
 
-	// Use basename as the SBOM source name when the input looks like a filesystem path,
-	// so the output doesn't embed full local directory paths.
-	scheme := req.Scheme
-	if scheme == "" {
-		scheme = "dir" // default when no scheme is set
-	}
-	if scheme == "dir" || scheme == "file" || scheme == "oci-dir" || scheme == "oci-archive" {
-		if name := filepath.Base(req.Source); name != "" && name != "." {
-			args = append(args, "--source-name", name)
+		// Use basename as the SBOM source name when the input looks like a filesystem path,
+		// so the output doesn't embed full local directory paths.
+		scheme := req.Scheme
+		if scheme == "" {
+			scheme = "dir" // default when no scheme is set
 		}
-	}
-	for _, pat := range strings.Split(req.Exclude, ",") {
-		if pat = strings.TrimSpace(pat); pat != "" {
-			args = append(args, "--exclude", pat)
+		if scheme == "dir" || scheme == "file" || scheme == "oci-dir" || scheme == "oci-archive" {
+			if name := filepath.Base(req.Source); name != "" && name != "." {
+				args = append(args, "--source-name", name)
+			}
+		}
+		for _, pat := range strings.Split(req.Exclude, ",") {
+			if pat = strings.TrimSpace(pat); pat != "" {
+				args = append(args, "--exclude", pat)
+			}
 		}
-	}
-
-	send("progress", progress(20, "Scanning "+source+" for packages…"))
-	flush()
-
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Minute)
-	defer cancel()
 
-	cmd := exec.CommandContext(ctx, resolveKnoxctl(), args...) // #nosec G204
-	cmd.Stdout = &lineWriter{send: send, flush: flush, event: "log"}
-	cmd.Stderr = &lineWriter{send: send, flush: flush, event: "log"}
+		send("progress", progress(20, "Scanning "+source+" for packages…"))
 
-	if err := cmd.Start(); err != nil {
-		send("error", errMsg(err))
-		flush()
-		return
-	}
-	done := make(chan error, 1)
-	go func() { done <- cmd.Wait() }()
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+		defer cancel()
 
-	select {
-	case err := <-done:
-		if err != nil && ctx.Err() == nil {
-			send("error", errMsg(err))
-			flush()
-			return
-		}
-		if ctx.Err() != nil {
-			return
+		cmd := exec.CommandContext(ctx, resolveKnoxctl(), args...) // #nosec G204
+		cmd.Stdout = &lineWriter{send: send, event: "log"}
+		cmd.Stderr = &lineWriter{send: send, event: "log"}
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
 		}
-	case <-ctx.Done():
-		<-done
-		return
-	}
 
-	send("progress", progress(80, "Reading SBOM output…"))
-	flush()
+		send("progress", progress(80, "Reading SBOM output…"))
 
-	bomJSON, err := os.ReadFile(outFile) // #nosec G304 — path inside os.MkdirTemp dir
-	if err != nil {
-		send("error", errMsg(fmt.Errorf("pkgscan produced no output file: %w", err)))
-		flush()
-		return
-	}
+		bomJSON, err := os.ReadFile(outFile) // #nosec G304 — path inside os.MkdirTemp dir
+		if err != nil {
+			return fmt.Errorf("pkgscan produced no output file: %w", err)
+		}
 
-	// Count top-level components (works for cyclonedx-json, pkgscan-json, and spdx).
-	var parsed struct {
-		Components *[]json.RawMessage `json:"components"` // CycloneDX
-		Artifacts  *[]json.RawMessage `json:"artifacts"`  // pkgscan native
-		Packages   *[]json.RawMessage `json:"packages"`   // SPDX
-	}
-	count := 0
-	if json.Unmarshal(bomJSON, &parsed) == nil {
-		switch {
-		case parsed.Components != nil:
-			count = len(*parsed.Components)
-		case parsed.Artifacts != nil:
-			count = len(*parsed.Artifacts)
-		case parsed.Packages != nil:
-			count = len(*parsed.Packages)
+		// Count top-level components (works for cyclonedx-json, pkgscan-json, and spdx).
+		var parsed struct {
+			Components *[]json.RawMessage `json:"components"` // CycloneDX
+			Artifacts  *[]json.RawMessage `json:"artifacts"`  // pkgscan native
+			Packages   *[]json.RawMessage `json:"packages"`   // SPDX
+		}
+		count := 0
+		if json.Unmarshal(bomJSON, &parsed) == nil {
+			switch {
+			case parsed.Components != nil:
+				count = len(*parsed.Components)
+			case parsed.Artifacts != nil:
+				count = len(*parsed.Artifacts)
+			case parsed.Packages != nil:
+				count = len(*parsed.Packages)
+			}
 		}
-	}
 
-	// Rebrand tool metadata: replace syft/Anchore references with knoxctl/AccuKnox.
-	bomJSON = rebrandSBOM(bomJSON)
+		// Rebrand tool metadata: replace syft/Anchore references with knoxctl/AccuKnox.
+		bomJSON = rebrandSBOM(bomJSON)
 
-	send("complete", buildComplete(count, bomJSON, req.signReq))
-	flush()
+		name := "sbom.json"
+		if !strings.HasSuffix(req.Format, "json") {
+			name = "sbom.spdx"
+		}
+		return j.complete(name, count, bomJSON, req.signReq)
+	})
 }
 
 // sbomReplacer performs all vendor-name substitutions in a single pass over the
@@ -568,53 +679,38 @@ func (s *Server) handleCBOMSource(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	send, flush, ok := sseInit(w)
-	if !ok {
-		return
-	}
-
-	send("progress", progress(10, "Initialising source scanner…"))
-	flush()
-	if ctxDone(r.Context(), send, flush) {
-		return
-	}
-
-	opts := &cbom.Options{
-		Path:        req.Path,
-		Name:        req.Name,
-		Group:       req.Group,
-		Version:     req.Version,
-		Description: req.Description,
-		License:     req.License,
-		Format:      "json",
-	}
+	s.startJob(w, r, JobCBOM, req, func(ctx context.Context, j *job) error {
+		send := j.send
+		send("progress", progress(10, "Initialising source scanner…"))
 
-	send("progress", progress(30, "Scanning Go source files for cryptographic imports…"))
-	flush()
+		opts := &cbom.Options{
+			Path:        req.Path,
+			Name:        req.Name,
+			Group:       req.Group,
+			Version:     req.Version,
+			Description: req.Description,
+			License:     req.License,
+			Format:      "json",
+		}
 
-	bom, err := cbom.GenerateFromSource(opts)
-	if err != nil {
-		send("error", errMsg(err))
-		flush()
-		return
-	}
-	if ctxDone(r.Context(), send, flush) {
-		return
-	}
+		send("progress", progress(30, "Scanning Go source files for cryptographic imports…"))
 
-	send("progress", progress(80, "Building Cryptography BOM…"))
-	flush()
+		bom, err := cbom.GenerateFromSource(opts)
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 
-	out, err := json.MarshalIndent(bom, "", "  ")
-	if err != nil {
-		send("error", errMsg(err))
-		flush()
-		return
-	}
+		send("progress", progress(80, "Building Cryptography BOM…"))
 
-	count := cbom.ComponentCount(bom)
-	send("complete", buildComplete(count, out, req.signReq))
-	flush()
+		out, err := json.MarshalIndent(bom, "", "  ")
+		if err != nil {
+			return err
+		}
+		return j.complete("cbom.json", cbom.ComponentCount(bom), out, req.signReq)
+	})
 }
 
 func (s *Server) handleCBOMImage(w http.ResponseWriter, r *http.Request) {
@@ -638,51 +734,36 @@ func (s *Server) handleCBOMImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	send, flush, ok := sseInit(w)
-	if !ok {
-		return
-	}
-
-	send("progress", progress(10, "Initialising image scanner…"))
-	flush()
-	if ctxDone(r.Context(), send, flush) {
-		return
-	}
-
-	opts := &cbom.Options{
-		Image:   req.Image,
-		Name:    req.Name,
-		Plugins: req.Plugins,
-		Ignore:  req.Ignore,
-		Format:  "json",
-	}
+	s.startJob(w, r, JobCBOMImage, req, func(ctx context.Context, j *job) error {
+		send := j.send
+		send("progress", progress(10, "Initialising image scanner…"))
 
-	send("progress", progress(30, "Pulling and scanning container image…"))
-	flush()
+		opts := &cbom.Options{
+			Image:   req.Image,
+			Name:    req.Name,
+			Plugins: req.Plugins,
+			Ignore:  req.Ignore,
+			Format:  "json",
+		}
 
-	bom, err := cbom.GenerateFromImage(opts)
-	if err != nil {
-		send("error", errMsg(err))
-		flush()
-		return
-	}
-	if ctxDone(r.Context(), send, flush) {
-		return
-	}
+		send("progress", progress(30, "Pulling and scanning container image…"))
 
-	send("progress", progress(80, "Building Cryptography BOM…"))
-	flush()
+		bom, err := cbom.GenerateFromImage(opts)
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 
-	out, err := json.MarshalIndent(bom, "", "  ")
-	if err != nil {
-		send("error", errMsg(err))
-		flush()
-		return
-	}
+		send("progress", progress(80, "Building Cryptography BOM…"))
 
-	count := cbom.ComponentCount(bom)
-	send("complete", buildComplete(count, out, req.signReq))
-	flush()
+		out, err := json.MarshalIndent(bom, "", "  ")
+		if err != nil {
+			return err
+		}
+		return j.complete("cbom.json", cbom.ComponentCount(bom), out, req.signReq)
+	})
 }
 
 func (s *Server) handleAIBOM(w http.ResponseWriter, r *http.Request) {
@@ -714,123 +795,84 @@ func (s *Server) handleAIBOM(w http.ResponseWriter, r *http.Request) {
 	if req.Source == "" {
 		req.Source = "huggingface"
 	}
-
-	send, flush, ok := sseInit(w)
-	if !ok {
-		return
+	// Default the HuggingFace component name to the full model ID
+	// (org/model) so that provenance is clear in the BOM metadata.
+	if req.Source != "bedrock" && req.Name == "" {
+		req.Name = req.ModelID
 	}
 
-	send("progress", progress(10, "Connecting to model registry…"))
-	flush()
-	if ctxDone(r.Context(), send, flush) {
-		return
-	}
+	s.startJob(w, r, JobAIBOM, req, func(ctx context.Context, j *job) error {
+		send := j.send
+		send("progress", progress(10, "Connecting to model registry…"))
 
-	if req.Source == "bedrock" {
-		if req.Region == "" {
-			send("error", errMsg(fmt.Errorf("region is required for AWS Bedrock")))
-			flush()
-			return
+		if req.Source == "bedrock" {
+			if req.Region == "" {
+				return fmt.Errorf("region is required for AWS Bedrock")
+			}
+			send("progress", progress(30, "Fetching AWS Bedrock model catalog…"))
+
+			bedrockOpts := &aibom.BedrockOptions{
+				Region:                req.Region,
+				UseDefaultCredentials: req.UseDefaultCredentials || req.AccessKeyID == "",
+				AccessKeyID:           req.AccessKeyID,
+				SecretAccessKey:       req.SecretAccessKey,
+				SessionToken:          req.SessionToken,
+				ModelID:               req.ModelID,
+				Name:                  req.Name,
+				Version:               req.Version,
+				Manufacturer:          req.Manufacturer,
+				Format:                "json",
+			}
+
+			cdxBOM, err := aibom.GenerateFromBedrock(bedrockOpts)
+			if err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			send("progress", progress(80, "Building AI/ML BOM…"))
+
+			out, err := json.MarshalIndent(cdxBOM, "", "  ")
+			if err != nil {
+				return err
+			}
+			return j.complete("aibom.json", aibom.ModelCount(cdxBOM), out, req.signReq)
 		}
-		send("progress", progress(30, "Fetching AWS Bedrock model catalog…"))
-		flush()
-
-		bedrockOpts := &aibom.BedrockOptions{
-			Region:                req.Region,
-			UseDefaultCredentials: req.UseDefaultCredentials || req.AccessKeyID == "",
-			AccessKeyID:           req.AccessKeyID,
-			SecretAccessKey:       req.SecretAccessKey,
-			SessionToken:          req.SessionToken,
-			ModelID:               req.ModelID,
-			Name:                  req.Name,
-			Version:               req.Version,
-			Manufacturer:          req.Manufacturer,
-			Format:                "json",
+
+		// HuggingFace (default)
+		if req.ModelID == "" {
+			return fmt.Errorf("modelId is required for HuggingFace")
+		}
+
+		opts := &aibom.Options{
+			ModelID:      req.ModelID,
+			Token:        req.Token,
+			Name:         req.Name,
+			Version:      req.Version,
+			Manufacturer: req.Manufacturer,
+			Format:       "json",
 		}
 
-		cdxBOM, err := aibom.GenerateFromBedrock(bedrockOpts)
+		send("progress", progress(30, "Fetching model metadata…"))
+
+		cdxBOM, err := aibom.Generate(opts)
 		if err != nil {
-			send("error", errMsg(err))
-			flush()
-			return
+			return err
 		}
-		if ctxDone(r.Context(), send, flush) {
-			return
+		if ctx.Err() != nil {
+			return ctx.Err()
 		}
 
 		send("progress", progress(80, "Building AI/ML BOM…"))
-		flush()
 
 		out, err := json.MarshalIndent(cdxBOM, "", "  ")
 		if err != nil {
-			send("error", errMsg(err))
-			flush()
-			return
+			return err
 		}
-		count := aibom.ModelCount(cdxBOM)
-		send("complete", buildComplete(count, out, req.signReq))
-		flush()
-		return
-	}
-
-	// HuggingFace (default)
-	if req.ModelID == "" {
-		send("error", errMsg(fmt.Errorf("modelId is required for HuggingFace")))
-		flush()
-		return
-	}
-	// Default the component name to the full model ID (org/model) so that
-	// provenance is clear in the BOM metadata.
-	if req.Name == "" {
-		req.Name = req.ModelID
-	}
-
-	opts := &aibom.Options{
-		ModelID:      req.ModelID,
-		Token:        req.Token,
-		Name:         req.Name,
-		Version:      req.Version,
-		Manufacturer: req.Manufacturer,
-		Format:       "json",
-	}
-
-	send("progress", progress(30, "Fetching model metadata…"))
-	flush()
-
-	cdxBOM, err := aibom.Generate(opts)
-	if err != nil {
-		send("error", errMsg(err))
-		flush()
-		return
-	}
-	if ctxDone(r.Context(), send, flush) {
-		return
-	}
-
-	send("progress", progress(80, "Building AI/ML BOM…"))
-	flush()
-
-	out, err := json.MarshalIndent(cdxBOM, "", "  ")
-	if err != nil {
-		send("error", errMsg(err))
-		flush()
-		return
-	}
-
-	count := aibom.ModelCount(cdxBOM)
-	send("complete", buildComplete(count, out, req.signReq))
-	flush()
-}
-
-// ctxDone returns true and sends an SSE error event if the request context has
-// been cancelled (e.g. because the client aborted the fetch).
-func ctxDone(ctx context.Context, send func(string, interface{}), flush func()) bool {
-	if ctx.Err() == nil {
-		return false
-	}
-	send("error", errMsg(ctx.Err()))
-	flush()
-	return true
+		return j.complete("aibom.json", aibom.ModelCount(cdxBOM), out, req.signReq)
+	})
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -847,7 +889,8 @@ type signReq struct {
 
 // buildComplete builds the SSE "complete" payload.  When signing is requested
 // it calls sign.SignBytes in-memory and appends the result; the JSON
-// payload will contain "signed", "signature", and (for generated keys) "pubKey".
+// payload will contain "signed", "signature", and (for generated and KMS keys)
+// "pubKey".
 func buildComplete(count int, bomJSON []byte, sr signReq) map[string]interface{} {
 	payload := map[string]interface{}{
 		"count":  count,
@@ -875,9 +918,10 @@ func buildComplete(count int, bomJSON []byte, sr signReq) map[string]interface{}
 	return payload
 }
 
-// handleRun executes an arbitrary knoxctl sub-command and streams its
-// stdout/stderr line-by-line as SSE progress events.  Used for operations that
-// are best delegated to the CLI (image-scan, probe, vm-onboard, etc.).
+// handleRun executes an allow-listed knoxctl sub-command as a job and
+// streams its stdout/stderr line-by-line as SSE log events.  Used for
+// operations that are best delegated to the CLI (image-scan, probe,
+// vm-onboard, etc.).
 func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -894,55 +938,33 @@ func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
 		writeErr(w, "args are required")
 		return
 	}
-
-	send, flush, ok := sseInit(w)
-	if !ok {
+	if !s.allowedRun(req.Args) {
+		deny(w, r, fmt.Sprintf("knoxctl %s may not be run from the UI (allowed: %s)", req.Args[0], strings.Join(s.runCommands, ", ")), http.StatusForbidden)
 		return
 	}
 
-	self := resolveKnoxctl()
-
-	// Derive a timeout context from the *request* context so that an
-	// AbortController on the client side (which closes the HTTP connection
-	// and therefore cancels r.Context()) propagates to the subprocess.
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Minute)
-	defer cancel()
-
-	cmd := exec.CommandContext(ctx, self, req.Args...) // #nosec G204
-	cmd.Stdout = &lineWriter{send: send, flush: flush, event: "log"}
-	cmd.Stderr = &lineWriter{send: send, flush: flush, event: "log"}
+	inputs := map[string]interface{}{"args": maskArgs(req.Args)}
+	s.startJob(w, r, JobRun, inputs, func(ctx context.Context, j *job) error {
+		// The job context is cancelled from /api/jobs/{id}/cancel (the
+		// Stop button); exec.CommandContext then kills the subprocess.
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+		defer cancel()
 
-	send("progress", progress(10, "Running: knoxctl "+strings.Join(req.Args, " ")))
-	flush()
+		cmd := exec.CommandContext(ctx, resolveKnoxctl(), req.Args...) // #nosec G204
+		cmd.Stdout = &lineWriter{send: j.send, event: "log"}
+		cmd.Stderr = &lineWriter{send: j.send, event: "log"}
 
-	if err := cmd.Start(); err != nil {
-		send("error", errMsg(err))
-		flush()
-		return
-	}
+		j.send("progress", progress(10, "Running: knoxctl "+strings.Join(maskArgs(req.Args), " ")))
 
-	// Wait for the process to finish in a goroutine so we can also listen for
-	// context cancellation (client disconnect / Stop button / timeout).
-	done := make(chan error, 1)
-	go func() { done <- cmd.Wait() }()
-
-	select {
-	case err := <-done:
-		// Process exited normally (or with an error).
-		if err != nil && ctx.Err() == nil {
-			send("error", errMsg(err))
-			flush()
-		} else if ctx.Err() == nil {
-			send("complete", map[string]interface{}{"message": "Command completed successfully."})
-			flush()
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
 		}
-		// If ctx is already done the client is gone — no point writing.
-
-	case <-ctx.Done():
-		// Client disconnected or timeout hit.
-		// exec.CommandContext will send SIGKILL; wait for it to exit.
-		<-done
-	}
+		j.send("complete", map[string]interface{}{"message": "Command completed successfully.", "jobId": j.info.ID})
+		return nil
+	})
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -951,23 +973,18 @@ func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
 
 type sendFn = func(event string, data interface{})
 
-func sseInit(w http.ResponseWriter) (sendFn, func(), bool) {
+// sseInit sets the headers of an SSE response.
+func sseInit(w http.ResponseWriter) (http.Flusher, bool) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		http.Error(w, "streaming not supported", http.StatusInternalServerError)
-		return nil, nil, false
+		return nil, false
 	}
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("X-Accel-Buffering", "no")
-
-	send := func(event string, data interface{}) {
-		b, _ := json.Marshal(data)
-		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
-	}
-	flush := func() { flusher.Flush() }
-	return send, flush, true
+	return flusher, true
 }
 
 func progress(pct int, msg string) map[string]interface{} {
@@ -978,10 +995,9 @@ func errMsg(err error) map[string]interface{} {
 	return map[string]interface{}{"message": err.Error()}
 }
 
-// lineWriter streams each written chunk as an SSE event.
+// lineWriter sends each written line as an event.
 type lineWriter struct {
 	send  sendFn
-	flush func()
 	event string
 }
 
@@ -990,7 +1006,6 @@ func (lw *lineWriter) Write(p []byte) (int, error) {
 	for _, line := range lines {
 		if line != "" {
 			lw.send(lw.event, map[string]string{"line": line})
-			lw.flush()
 		}
 	}
 	return len(p), nil
@@ -1000,19 +1015,6 @@ func (lw *lineWriter) Write(p []byte) (int, error) {
 // HTTP helpers
 // ──────────────────────────────────────────────────────────────────────────────
 
-func cors(h http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
-		h(w, r)
-	}
-}
-
 func writeJSON(w http.ResponseWriter, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(v); err != nil {
@@ -1037,9 +1039,12 @@ func portFrom(addr string) string {
 	return addr
 }
 
-// resolveKnoxctl returns the path to the knoxctl binary.
-// It checks the current working directory first, then falls back to PATH.
+// resolveKnoxctl returns the path to the knoxctl binary: the running
+// executable, else one in the current working directory, else from PATH.
 func resolveKnoxctl() string {
+	if exe, err := os.Executable(); err == nil {
+		return exe
+	}
 	binary := "knoxctl"
 	if runtime.GOOS == "windows" {
 		binary = "knoxctl.exe"
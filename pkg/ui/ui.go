@@ -8,3 +8,8 @@ import "embed"
 
 //go:embed static
 var StaticFS embed.FS
+
+// OpenAPISpec is the OpenAPI 3 description of the /api/v1 REST API.
+//
+//go:embed openapi.yaml
+var OpenAPISpec []byte
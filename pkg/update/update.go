@@ -5,13 +5,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 
 	"github.com/accuknox/accuknox-cli-v2/pkg/common"
+	"github.com/accuknox/accuknox-cli-v2/pkg/download"
 	"github.com/accuknox/accuknox-cli-v2/pkg/version"
 	selfupdate "github.com/creativeprojects/go-selfupdate"
+	"github.com/creativeprojects/go-selfupdate/update"
 	"github.com/fatih/color"
 	"github.com/google/go-github/github"
 )
@@ -72,7 +76,7 @@ func SelfUpdate() error {
 		fmt.Println("Could not locate executable path")
 		return errors.New("could not locate exec path")
 	}
-	if err := selfupdate.UpdateTo(ctx, *asset.BrowserDownloadURL, *asset.Name, exe); err != nil {
+	if err := updateTo(*asset.BrowserDownloadURL, *asset.Name, exe); err != nil {
 		if strings.Contains(err.Error(), "permission denied") {
 			color.Red("use [sudo knoxctl selfupdate]")
 		}
@@ -81,3 +85,22 @@ func SelfUpdate() error {
 	fmt.Println("Update successful. [" + latestVersion + "]")
 	return nil
 }
+
+// updateTo replaces the executable at exe with the one in the release asset
+// at assetURL. It does what selfupdate.UpdateTo does, but downloads through
+// the user's mirrors, CA bundle and proxy.
+func updateTo(assetURL, assetName, exe string) error {
+	resp, err := download.Get(assetURL)
+	if err != nil {
+		return fmt.Errorf("error downloading %s: %w", assetURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error downloading %s: HTTP %d", assetURL, resp.StatusCode)
+	}
+	bin, err := selfupdate.DecompressCommand(resp.Body, assetName, filepath.Base(exe), runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return err
+	}
+	return update.Apply(bin, update.Options{TargetPath: exe})
+}
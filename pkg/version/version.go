@@ -3,10 +3,10 @@ package version
 import (
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"strings"
 
+	"github.com/accuknox/accuknox-cli-v2/pkg/download"
 	"github.com/accuknox/accuknox-cli-v2/pkg/onboard"
 	"github.com/kubearmor/kubearmor-client/k8s"
 )
@@ -63,7 +63,7 @@ func PrintVersion(c *k8s.Client, o Option) error {
 }
 
 func fetchReleaseVersion() (string, error) {
-	resp, err := http.Get(releaseVersionPage)
+	resp, err := download.Get(releaseVersionPage)
 	if err != nil {
 		return "", err
 	}